@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"path/filepath"
@@ -14,6 +15,12 @@ import (
 	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
 	"github.com/RyanLadmia/plateforme-safebase/internal/routes"
 	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/auditlog"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/logger"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/mailer"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/oidc"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/tracing"
 	"github.com/RyanLadmia/plateforme-safebase/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -21,22 +28,50 @@ import (
 func main() {
 	// Load config from environment variables
 	cfg := config.LoadConfig()
+	// Structured logger (JSON by default) with secret redaction and correlation IDs
+	logger.Init(logger.Options{Level: cfg.LOG_LEVEL, Format: cfg.LOG_FORMAT})
+	// OpenTelemetry tracing exported over OTLP/HTTP (disabled unless TRACING_ENABLED=true)
+	shutdownTracing, err := tracing.Init(context.Background(), *config.GetTracingConfig())
+	if err != nil {
+		log.Fatalf(config.Red+"Failed to initialize tracing: %v"+config.Reset, err)
+	}
+	defer shutdownTracing(context.Background())
+	// Master encryption keys (database credentials and backups): refuse to start without them
+	keyring, err := security.LoadKeyring(config.GetKeyringConfig())
+	if err != nil {
+		log.Fatalf(config.Red+"Encryption keys missing or invalid (set ENCRYPTION_KEY or ENCRYPTION_KEY_FILE): %v"+config.Reset, err)
+	}
+	security.SetDefaultKeyring(keyring)
+	log.Printf(config.Green+"Encryption keyring loaded (primary key: %s)"+config.Reset, keyring.PrimaryKeyID())
 	// Connection to PostgreSQL database
 	database := db.ConnectPostgres(cfg)
+	// Trace every GORM query as a child of the current request/job span
+	if err := database.Use(tracing.NewGormPlugin()); err != nil {
+		log.Fatalf(config.Red+"Failed to register GORM tracing plugin: %v"+config.Reset, err)
+	}
 
 	// Automatic migration of tables (creation/update of structures)
 	// For now, we only migrate the models necessary for authentication
 	log.Println(config.Yellow + "Running database migrations..." + config.Reset)
 
 	if err := database.AutoMigrate(
-		&models.Role{},          // Role table
-		&models.User{},          // User table
-		&models.Session{},       // Session table
-		&models.Database{},      // Database table
-		&models.Backup{},        // Backup table
-		&models.Schedule{},      // Schedule table
-		&models.Restore{},       // Restore table
-		&models.ActionHistory{}, // Action history table
+		&models.Role{},                 // Role table
+		&models.Permission{},           // Permission table (role_permissions join table)
+		&models.User{},                 // User table
+		&models.Session{},              // Session table
+		&models.ConsumedRefreshToken{}, // Rotated refresh tokens (reuse detection)
+		&models.RecoveryCode{},         // Two-factor recovery codes table
+		&models.Setting{},              // Runtime settings table (e.g. local login switch)
+		&models.Team{},                 // Team table (shared and personal workspaces)
+		&models.TeamMember{},           // Team membership table
+		&models.Database{},             // Database table
+		&models.APIToken{},             // Personal API tokens table
+		&models.Backup{},               // Backup table
+		&models.Schedule{},             // Schedule table
+		&models.Restore{},              // Restore table
+		&models.ActionHistory{},        // Action history table
+		&models.AuditAnchor{},          // Heads of the action history chain copied into backup storage
+		&models.Quota{},                // Quota overrides per user and per team
 		// &models.Alert{},         // Alert table (for later)
 	); err != nil {
 		log.Fatalf(config.Red+"Failed to migrate database: %v"+config.Reset, err)
@@ -54,48 +89,173 @@ func main() {
 	scheduleRepo := repositories.NewScheduleRepository(database)
 	roleRepo := repositories.NewRoleRepository(database)
 	restoreRepo := repositories.NewRestoreRepository(database)
+	teamRepo := repositories.NewTeamRepository(database)
+	quotaRepo := repositories.NewQuotaRepository(database)
 	actionHistoryRepo := repositories.NewActionHistoryRepository(database)
+	recoveryCodeRepo := repositories.NewRecoveryCodeRepository(database)
+
+	// Chain the action history recorded before hash chaining (tamper evidence, see AuditService)
+	if sealed, err := actionHistoryRepo.SealLegacyRows(); err != nil {
+		log.Fatalf(config.Red+"Failed to chain the action history: %v"+config.Reset, err)
+	} else if sealed > 0 {
+		log.Printf(config.Green+"%d action history row(s) chained"+config.Reset, sealed)
+	}
 
 	// Initialize services (business logic)
+	authConfig := config.GetAuthConfig()
 	authService := services.NewAuthService(
 		userRepo,
 		sessionRepo,
-		cfg.JWT_SECRET, // Secret key to sign JWT tokens
-		24*time.Hour,   // Token validity duration (24h)
+		cfg.JWT_SECRET,            // Secret key to sign JWT tokens
+		authConfig.AccessTokenTTL, // Access token validity duration (15 min by default)
 	)
+	// Refresh token validity: a device stays signed in as long as it refreshes within this delay
+	authService.SetRefreshTokenTTL(authConfig.RefreshTokenTTL)
+	// One-time recovery codes for two-factor authentication
+	authService.SetRecoveryCodeRepository(recoveryCodeRepo)
+	// Password reset links are emailed through SMTP (only logged when SMTP_HOST is not set)
+	authService.SetPasswordResetTTL(authConfig.PasswordResetTTL)
+	authService.SetInvitationTTL(authConfig.InvitationTTL) // Set-password links of accounts created by an administrator
+	var mailService mailer.Mailer = mailer.LogMailer{}
+	if smtpConfig := config.GetSMTPConfig(); smtpConfig.Host != "" {
+		smtpMailer, err := mailer.NewSMTPMailer(*smtpConfig)
+		if err != nil {
+			log.Fatalf(config.Red+"Invalid SMTP configuration: %v"+config.Reset, err)
+		}
+		mailService = smtpMailer
+	} else {
+		log.Println(config.Yellow + "SMTP_HOST not set - emails will not be delivered" + config.Reset)
+	}
+	authService.SetMailer(mailService, config.GetFrontendURL()+"/reset-password")
+	// Account and IP lockout after repeated failed logins (alert emails with LOGIN_ALERTS_ENABLED)
+	authService.SetLoginProtection(config.GetLoginProtectionConfig())
+	// Runtime settings (password login can be turned off by an admin once SSO is configured)
+	authService.SetSettingRepository(repositories.NewSettingRepository(database))
+	// Personal API tokens for automation and CI (Authorization: Bearer sb_...)
+	authService.SetAPITokenRepository(repositories.NewAPITokenRepository(database))
+	// OpenID Connect single sign-on (only when OIDC_ISSUER_URL is set)
+	if oidcConfig := config.GetOIDCConfig(); oidcConfig.IssuerURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		provider, err := oidc.NewProvider(ctx, oidcConfig, nil)
+		cancel()
+		if err != nil {
+			log.Fatalf(config.Red+"Invalid OIDC configuration: %v"+config.Reset, err)
+		}
+		var groupRoles []services.OIDCGroupRole
+		for _, mapping := range config.GetOIDCGroupRoles() {
+			groupRoles = append(groupRoles, services.OIDCGroupRole(mapping))
+		}
+		authService.SetOIDCProvider(provider, groupRoles)
+		log.Printf(config.Green+"OIDC single sign-on enabled (issuer: %s)"+config.Reset, oidcConfig.IssuerURL)
+	}
 
 	// Initialize backup service with backup directory
 	backupDir := filepath.Join(".", "db", "backups")
 	databaseService := services.NewDatabaseService(databaseRepo, backupRepo, restoreRepo, scheduleRepo, nil) // backupService will be set later
 	userService := services.NewUserService(userRepo, roleRepo, actionHistoryRepo)
+	// Permission catalog and built-in roles (admin, user, operator, auditor)
+	roleService := services.NewRoleService(roleRepo, actionHistoryRepo)
+	if err := roleService.SyncBuiltInRoles(); err != nil {
+		log.Fatalf(config.Red+"Failed to seed roles and permissions: %v"+config.Reset, err)
+	}
 	backupService := services.NewBackupService(backupRepo, databaseService, userService, backupDir)
 	// Set backupService reference in databaseService to enable cascade deletion
 	databaseService.SetBackupService(backupService)
+	backupService.SetKeyring(keyring)
 	scheduleService := services.NewScheduleService(scheduleRepo, databaseRepo, backupService)
 	restoreService := services.NewRestoreService(restoreRepo, backupService, databaseService, userService)
 	actionHistoryService := services.NewActionHistoryService(actionHistoryRepo)
+	// Forwarding of every recorded action to syslog/SIEM and a JSON lines file (only when configured)
+	var auditSinks []auditlog.Sink
+	if syslogConfig := config.GetAuditSyslogConfig(); syslogConfig.Address != "" {
+		syslogSink, err := auditlog.NewSyslogSink(*syslogConfig)
+		if err != nil {
+			log.Fatalf(config.Red+"Invalid audit syslog configuration: %v"+config.Reset, err)
+		}
+		auditSinks = append(auditSinks, syslogSink)
+	}
+	if path := config.GetAuditLogFile(); path != "" {
+		fileSink, err := auditlog.NewFileSink(path)
+		if err != nil {
+			log.Fatalf(config.Red+"Invalid audit log file: %v"+config.Reset, err)
+		}
+		auditSinks = append(auditSinks, fileSink)
+	}
+	if len(auditSinks) > 0 {
+		auditForwarder := auditlog.NewForwarder(config.GetAuditForwardOptions(), auditSinks...)
+		actionHistoryService.SetForwarder(auditForwarder)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			auditForwarder.Close(ctx)
+		}()
+		for _, sink := range auditSinks {
+			log.Printf(config.Green+"Action history forwarded to %s"+config.Reset, sink.Name())
+		}
+	}
+	healthService := services.NewHealthService(database, backupService, restoreService, scheduleService)
+	keyRotationService := services.NewKeyRotationService(backupRepo, databaseRepo, keyring)
 
 	// Set action history service for all services that need logging
 	databaseService.SetActionHistoryService(actionHistoryService)
 	backupService.SetActionHistoryService(actionHistoryService)
 	scheduleService.SetActionHistoryService(actionHistoryService)
 	restoreService.SetActionHistoryService(actionHistoryService)
+	keyRotationService.SetActionHistoryService(actionHistoryService)
+	authService.SetActionHistoryService(actionHistoryService)
+	keyRotationService.SetBackupService(backupService)
+
+	// Teams: databases (and their backups, schedules and restores) are shared by the members of their team
+	authorizationService := services.NewAuthorizationService(teamRepo)
+	databaseService.SetAuthorizationService(authorizationService)
+	backupService.SetAuthorizationService(authorizationService)
+	scheduleService.SetAuthorizationService(authorizationService)
+	restoreService.SetAuthorizationService(authorizationService)
+	restoreService.SetMailer(mailService, config.GetFrontendURL()+"/user/backups") // Restores into protected databases
+	authService.SetAuthorizationService(authorizationService)
+	teamService := services.NewTeamService(teamRepo, userRepo, databaseRepo, authorizationService, actionHistoryRepo)
+	// Move the databases created before teams into the personal workspace of their creator
+	if migrated, err := teamService.MigratePersonalWorkspaces(); err != nil {
+		log.Fatalf(config.Red+"Failed to migrate databases to personal workspaces: %v"+config.Reset, err)
+	} else if migrated > 0 {
+		log.Printf(config.Green+"%d database(s) moved to personal workspaces"+config.Reset, migrated)
+	}
+
+	// Quotas: databases, schedules and backup storage per user (defaults from the environment) and per team
+	quotaService := services.NewQuotaService(quotaRepo, userRepo, teamRepo, config.GetQuotaConfig())
+	quotaService.SetActionHistoryService(actionHistoryService)
+	databaseService.SetQuotaService(quotaService)
+	scheduleService.SetQuotaService(quotaService)
+	backupService.SetQuotaService(quotaService)
+
+	// Admin-wide views of the resources of every user, jobs started or cancelled on their behalf
+	adminService := services.NewAdminService(databaseRepo, backupRepo, restoreRepo, scheduleRepo, backupService, restoreService)
+	adminService.SetActionHistoryService(actionHistoryService)
+
+	// Account deletion: resources reassigned to another user or purged (backup objects included) in the background
+	userDeletionService := services.NewUserDeletionService(repositories.NewUserDeletionRepository(database), userRepo, teamRepo, backupService, scheduleService)
+	userDeletionService.SetActionHistoryService(actionHistoryService)
+	userDeletionService.SetAuthorizationService(authorizationService)
+
+	// Personal data export and account erasure requested by the user, after a grace period (GDPR)
+	privacyService := services.NewPrivacyService(userRepo, databaseRepo, scheduleRepo, backupRepo, actionHistoryRepo,
+		repositories.NewUserDeletionRepository(database), userDeletionService)
+	privacyService.SetActionHistoryService(actionHistoryService)
+	privacyService.SetErasureGracePeriod(config.GetErasureGracePeriod())
+
+	// Tamper-evident action history: chain verification and anchoring of its head into backup storage
+	auditService := services.NewAuditService(actionHistoryRepo, backupService)
 
 	// Initialize Mega service for cloud storage
 	megaConfig := config.GetMegaConfig()
 	if megaConfig.Email != "" && megaConfig.Password != "" {
-		megaService, err := services.NewMegaService(*megaConfig)
+		megaService, err := services.NewMegaService(services.MegaConfig(*megaConfig))
 		if err != nil {
 			log.Printf(config.Yellow+"Avertissement: Impossible d'initialiser Mega: %v"+config.Reset, err)
 			log.Println(config.Yellow + "Les sauvegardes seront stockées localement uniquement" + config.Reset)
 		} else {
 			log.Println(config.Green + "Service Mega initialisé avec succès" + config.Reset)
 			backupService.SetCloudStorage(megaService)
-
-			// Initialize encryption service
-			encryptionService := services.NewEncryptionService("SafeBaseMasterKey2025!")
-			backupService.SetEncryptionService(encryptionService)
-			log.Println(config.Green + "Service de chiffrement AES-256 initialisé" + config.Reset)
 		}
 	} else {
 		log.Println(config.Yellow + "Configuration Mega manquante - stockage local uniquement" + config.Reset)
@@ -114,21 +274,46 @@ func main() {
 
 	// Initialize handlers (HTTP controllers)
 	authHandler := handlers.NewAuthHandler(authService)
+	authHandler.SetFrontendURL(config.GetFrontendURL()) // Redirection après la connexion SSO
 	databaseHandler := handlers.NewDatabaseHandler(databaseService)
+	databaseHandler.SetAuthorizationService(authorizationService)
 	backupHandler := handlers.NewBackupHandler(backupService)
+	backupHandler.SetAuthorizationService(authorizationService)
 	scheduleHandler := handlers.NewScheduleHandler(scheduleService)
 	userHandler := handlers.NewUserHandler(userService)
+	userHandler.SetAuthService(authService)
+	userHandler.SetRoleService(roleService)
+	userHandler.SetUserDeletionService(userDeletionService)
 	profileHandler := handlers.NewProfileHandler(userService, authService)
+	profileHandler.SetPrivacyService(privacyService)
 	restoreHandler := handlers.NewRestoreHandler(restoreService)
+	restoreHandler.SetAuthorizationService(authorizationService)
+	teamHandler := handlers.NewTeamHandler(teamService)
+	quotaHandler := handlers.NewQuotaHandler(quotaService)
+	adminHandler := handlers.NewAdminHandler(adminService)
 	actionHistoryHandler := handlers.NewActionHistoryHandler(actionHistoryService)
 	testHandler := handlers.NewTestHandler(userRepo)
+	healthHandler := handlers.NewHealthHandler(healthService)
+	keyRotationHandler := handlers.NewKeyRotationHandler(keyRotationService)
+	auditHandler := handlers.NewAuditHandler(auditService)
 
 	// Initialize middleware
 	authMiddleware := middlewares.NewAuthMiddleware(cfg.JWT_SECRET)
+	// Reject tokens of revoked sessions (logout, session revocation, refresh token reuse)
+	authMiddleware.SetSessionValidator(authService)
+	// Accept scoped personal API tokens on the routes they are allowed on
+	authMiddleware.SetAPITokenValidator(authService)
+	// Check the permissions of the role on every request (RequirePermission)
+	authMiddleware.SetPermissionChecker(roleService)
 
 	// Configure the Gin server
 	gin.SetMode(gin.ReleaseMode)
-	server := gin.Default()
+	server := gin.New()
+	server.Use(gin.Recovery())
+	// Correlation ID, trace span and structured access log for every request
+	server.Use(middlewares.RequestID())
+	server.Use(middlewares.Tracing())
+	server.Use(middlewares.RequestLogger())
 	if err := server.SetTrustedProxies([]string{"127.0.0.1"}); err != nil {
 		log.Fatalf("Failed to set trusted proxies: %v", err)
 	}
@@ -141,14 +326,8 @@ func main() {
 		c.JSON(200, gin.H{"message": "Safebase API is running!"})
 	})
 
-	// Health check endpoint for monitoring and CI/CD
-	server.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "healthy",
-			"service": "safebase-api",
-			"version": "1.0.0",
-		})
-	})
+	// Health check endpoints for monitoring and CI/CD (/health, /health/live, /health/ready)
+	routes.SetupHealthRoutes(server, healthHandler)
 
 	// Integration of authentication routes (/auth/register, /auth/login, /auth/logout)
 	routes.AuthRoutes(server, authHandler, cfg.JWT_SECRET)
@@ -158,9 +337,14 @@ func main() {
 	routes.SetupBackupRoutes(server, backupHandler, authMiddleware)
 	routes.SetupScheduleRoutes(server, scheduleHandler, authMiddleware)
 	routes.SetupRestoreRoutes(server, restoreHandler, authMiddleware)
+	routes.SetupTeamRoutes(server, teamHandler, authMiddleware)
+	routes.SetupQuotaRoutes(server, quotaHandler, authMiddleware)
+	routes.SetupAdminRoutes(server, adminHandler, authMiddleware)
 	routes.UserRoutes(server, userHandler, authMiddleware)
+	routes.SetupKeyRotationRoutes(server, keyRotationHandler, authMiddleware)
 	routes.ProfileRoutes(server, profileHandler, authMiddleware)
 	routes.SetupActionHistoryRoutes(server, actionHistoryHandler, authMiddleware)
+	routes.SetupAuditRoutes(server, auditHandler, authMiddleware)
 
 	// Test routes (only in non-production)
 	routes.TestRoutes(server, testHandler)
@@ -172,10 +356,13 @@ func main() {
 	// Start background workers
 	go utils.StartSessionCleanupWorker(sessionRepo)
 	go utils.StartBackupCleanupWorker(backupRepo, workerPool)
+	go utils.StartAccountErasureWorker(privacyService)
+	go utils.StartAuditAnchorWorker(auditService, config.GetAuditAnchorInterval())
 
 	// Pass worker pool to backup service
 	backupService.SetWorkerPool(workerPool)
 	restoreService.SetWorkerPool(workerPool)
+	healthService.SetWorkerPool(workerPool)
 
 	// Start the cron scheduler and load active schedules
 	scheduleService.StartScheduler()
@@ -0,0 +1,183 @@
+// Command safebase-decrypt decrypts a backup object downloaded from the storage bucket,
+// without the SafeBase server or its database.
+//
+// Usage:
+//
+//	safebase-decrypt -info backup.zip                         # print the header
+//	ENCRYPTION_KEY_FILE=keys.json safebase-decrypt -o dump.zip backup.zip
+//	safebase-decrypt -key-id k2 -key <base64> -o dump.zip backup.zip
+//	SAFEBASE_PASSPHRASE=... safebase-decrypt -o dump.zip backup.zip  # zero-knowledge backups
+//
+// Master keys are read like the server does (ENCRYPTION_KEY, ENCRYPTION_KEYS, ENCRYPTION_KEY_FILE)
+// unless -key or -key-file is given. The key must be the one recorded in the header: keep retired
+// master keys archived, a rotation only re-wraps the copy of the data key stored in the database.
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/config"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/container"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "safebase-decrypt:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("safebase-decrypt", flag.ContinueOnError)
+	output := flags.String("o", "", "output file (default: original filename from the header, '-' for stdout)")
+	info := flags.Bool("info", false, "print the header as JSON and exit")
+	keyFile := flags.String("key-file", "", "JSON key file (same format as ENCRYPTION_KEY_FILE)")
+	key := flags.String("key", "", "base64 master key")
+	keyID := flags.String("key-id", "", "ID of the -key master key (default: key ID of the header)")
+	passphraseEnv := flags.String("passphrase-env", "SAFEBASE_PASSPHRASE", "environment variable holding the passphrase of zero-knowledge backups")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: safebase-decrypt [flags] <object>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("exactly one input object is required")
+	}
+
+	in, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	reader := bufio.NewReader(in)
+
+	if *info {
+		header, _, err := container.ReadHeader(reader)
+		if err != nil {
+			return describeReadError(err)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(header)
+	}
+
+	unwrap := func(header *container.Header) ([]byte, error) {
+		wrapped, err := base64.StdEncoding.DecodeString(header.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid wrapped key in header: %w", err)
+		}
+		if header.KeyID == security.PassphraseKeyID {
+			return unlockWithPassphrase(header, wrapped, os.Getenv(*passphraseEnv))
+		}
+		keyring, err := loadKeyring(*keyFile, *key, *keyID, header.KeyID)
+		if err != nil {
+			return nil, err
+		}
+		return keyring.UnwrapDataKey(header.KeyID, wrapped)
+	}
+
+	// Write to a temporary file first so a failed authentication never leaves a partial output behind
+	var tmp *os.File
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		tmp, err = os.CreateTemp(filepath.Dir(*output), ".safebase-decrypt-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		out = tmp
+	}
+
+	header, err := container.Decrypt(out, reader, unwrap)
+	if err != nil {
+		if tmp != nil {
+			tmp.Close()
+		}
+		return describeReadError(err)
+	}
+	if tmp == nil {
+		return nil
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	target := *output
+	if target == "" {
+		target = safeFilename(header.Filename)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "decrypted %s (%s, %d bytes, %s) to %s\n", header.Filename, header.DBType, header.PlaintextSize, header.Checksum, target)
+	return nil
+}
+
+// loadKeyring builds the keyring from the flags, or from the server environment variables
+func loadKeyring(keyFile, key, keyID, headerKeyID string) (*security.Keyring, error) {
+	cfg := config.GetKeyringConfig()
+	switch {
+	case keyFile != "":
+		cfg = security.KeyringConfig{KeyFile: keyFile}
+	case key != "":
+		if keyID == "" {
+			keyID = headerKeyID
+		}
+		cfg = security.KeyringConfig{Keys: map[string]string{keyID: key}, PrimaryKeyID: keyID}
+	}
+	keyring, err := security.LoadKeyring(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("no usable master key (use -key, -key-file or ENCRYPTION_KEY*): %w", err)
+	}
+	return keyring, nil
+}
+
+// unlockWithPassphrase opens the data key of a zero-knowledge backup
+func unlockWithPassphrase(header *container.Header, sealed []byte, passphrase string) ([]byte, error) {
+	if header.Passphrase == nil {
+		return nil, errors.New("passphrase key missing from header")
+	}
+	wrappedPrivateKey, err := base64.StdEncoding.DecodeString(header.Passphrase.WrappedPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase key in header: %w", err)
+	}
+	passphraseKey := &security.PassphraseKey{KDF: header.Passphrase.KDF, WrappedPrivateKey: wrappedPrivateKey}
+	privateKey, err := passphraseKey.Unlock(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return security.OpenWithPrivateKey(privateKey, sealed)
+}
+
+// describeReadError explains objects written before the container format
+func describeReadError(err error) error {
+	if errors.Is(err, container.ErrNotContainer) {
+		return fmt.Errorf("%w: objects written before the container format can only be downloaded through the API", err)
+	}
+	if errors.Is(err, security.ErrPassphraseRequired) {
+		return fmt.Errorf("%w: this backup is zero-knowledge, set SAFEBASE_PASSPHRASE (or -passphrase-env)", err)
+	}
+	return err
+}
+
+// safeFilename keeps the base name of the header filename (it comes from the object, not the user)
+func safeFilename(name string) string {
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	if name == "" || name == "." || name == ".." {
+		return "backup.zip"
+	}
+	return name
+}
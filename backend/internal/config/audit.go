@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/pkg/auditlog"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/version"
+)
+
+// GetAuditAnchorInterval returns how often the head of the action history chain is copied into backup storage
+func GetAuditAnchorInterval() time.Duration {
+	return getEnvAsDuration("AUDIT_ANCHOR_INTERVAL", 24*time.Hour)
+}
+
+// GetAuditSyslogConfig returns the syslog collector the action history is forwarded to (empty address = disabled)
+func GetAuditSyslogConfig() *auditlog.SyslogConfig {
+	return &auditlog.SyslogConfig{
+		Address:  os.Getenv("AUDIT_SYSLOG_ADDR"),
+		Network:  getEnv("AUDIT_SYSLOG_NETWORK", auditlog.NetworkUDP),
+		Format:   getEnv("AUDIT_SYSLOG_FORMAT", auditlog.FormatRFC5424),
+		Facility: getEnvAsInt("AUDIT_SYSLOG_FACILITY", 13),
+		CAFile:   os.Getenv("AUDIT_SYSLOG_TLS_CA"),
+		Version:  version.Get().Version,
+	}
+}
+
+// GetAuditLogFile returns the file the action history is appended to as JSON lines (empty = disabled)
+func GetAuditLogFile() string {
+	return os.Getenv("AUDIT_LOG_FILE")
+}
+
+// GetAuditForwardOptions returns the buffering and retries of the action history forwarding
+func GetAuditForwardOptions() auditlog.Options {
+	return auditlog.Options{
+		BufferSize: getEnvAsInt("AUDIT_FORWARD_BUFFER", 1000),
+		MaxRetries: getEnvAsInt("AUDIT_FORWARD_RETRIES", 5),
+		RetryDelay: getEnvAsDuration("AUDIT_FORWARD_RETRY_DELAY", time.Second),
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+)
+
+// AuthConfig holds the lifetimes of access, refresh, password reset and invitation tokens
+type AuthConfig struct {
+	AccessTokenTTL   time.Duration
+	RefreshTokenTTL  time.Duration
+	PasswordResetTTL time.Duration
+	InvitationTTL    time.Duration
+}
+
+// GetAuthConfig returns the token lifetimes from environment variables (Go durations, e.g. "15m", "720h")
+func GetAuthConfig() *AuthConfig {
+	return &AuthConfig{
+		AccessTokenTTL:   getEnvAsDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:  getEnvAsDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		PasswordResetTTL: getEnvAsDuration("PASSWORD_RESET_TTL", time.Hour),
+		InvitationTTL:    getEnvAsDuration("INVITATION_TTL", 7*24*time.Hour),
+	}
+}
+
+// GetErasureGracePeriod returns the delay between an account erasure request and the erasure (ACCOUNT_ERASURE_GRACE)
+func GetErasureGracePeriod() time.Duration {
+	return getEnvAsDuration("ACCOUNT_ERASURE_GRACE", 30*24*time.Hour)
+}
+
+// GetLoginProtectionConfig returns the brute-force protection thresholds of the login
+// (models.DefaultLoginProtectionConfig for the variables that are not set)
+func GetLoginProtectionConfig() models.LoginProtectionConfig {
+	defaults := models.DefaultLoginProtectionConfig()
+	return models.LoginProtectionConfig{
+		MaxAttempts:     getEnvAsInt("LOGIN_MAX_ATTEMPTS", defaults.MaxAttempts),
+		LockoutDuration: getEnvAsDuration("LOGIN_LOCKOUT_DURATION", defaults.LockoutDuration),
+		MaxLockout:      getEnvAsDuration("LOGIN_MAX_LOCKOUT", defaults.MaxLockout),
+		IPMaxAttempts:   getEnvAsInt("LOGIN_IP_MAX_ATTEMPTS", defaults.IPMaxAttempts),
+		IPWindow:        getEnvAsDuration("LOGIN_IP_WINDOW", defaults.IPWindow),
+		NotifyUser:      getEnvAsBool("LOGIN_ALERTS_ENABLED", defaults.NotifyUser),
+	}
+}
+
+// getEnvAsInt gets an environment variable as integer with a fallback value (0 disables the check)
+func getEnvAsInt(key string, fallback int) int {
+	if value := os.Getenv(key); value != "" {
+		if number, err := strconv.Atoi(value); err == nil && number >= 0 {
+			return number
+		}
+	}
+	return fallback
+}
+
+// getEnvAsDuration gets an environment variable as duration with a fallback value
+func getEnvAsDuration(key string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			return duration
+		}
+	}
+	return fallback
+}
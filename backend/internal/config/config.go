@@ -17,6 +17,8 @@ type Config struct {
 	DB_USER     string
 	DB_PASSWORD string
 	DB_NAME     string
+	LOG_LEVEL   string
+	LOG_FORMAT  string
 }
 
 // ANSI color codes for console output
@@ -46,5 +48,7 @@ func LoadConfig() *Config {
 		DB_USER:     os.Getenv("DB_USER"),
 		DB_PASSWORD: os.Getenv("DB_PASSWORD"),
 		DB_NAME:     os.Getenv("DB_NAME"),
+		LOG_LEVEL:   getEnv("LOG_LEVEL", "info"),
+		LOG_FORMAT:  getEnv("LOG_FORMAT", "json"),
 	}
 }
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+)
+
+// GetKeyringConfig returns the master key configuration from environment variables.
+//
+//	ENCRYPTION_KEY_FILE        JSON key file {"primary": "...", "keys": {"id": "base64"}}
+//	ENCRYPTION_KEYS            inline keys "id1:base64,id2:base64" (rotation)
+//	ENCRYPTION_KEY             single base64 key, registered under ENCRYPTION_KEY_ID (default "primary")
+//	ENCRYPTION_PRIMARY_KEY_ID  key used for new ciphertexts
+//	LEGACY_DB_ENCRYPTION_KEY   key of credentials stored before key IDs existed (read only)
+//	LEGACY_BACKUP_SALT         salt of backups stored before key IDs existed (read only)
+func GetKeyringConfig() security.KeyringConfig {
+	keys := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv("ENCRYPTION_KEYS"), ",") {
+		if id, key, ok := strings.Cut(strings.TrimSpace(entry), ":"); ok {
+			keys[id] = key
+		}
+	}
+
+	primaryID := os.Getenv("ENCRYPTION_PRIMARY_KEY_ID")
+	if key := os.Getenv("ENCRYPTION_KEY"); key != "" {
+		id := getEnv("ENCRYPTION_KEY_ID", "primary")
+		keys[id] = key
+		if primaryID == "" {
+			primaryID = id
+		}
+	}
+
+	return security.KeyringConfig{
+		KeyFile:          os.Getenv("ENCRYPTION_KEY_FILE"),
+		Keys:             keys,
+		PrimaryKeyID:     primaryID,
+		LegacyKey:        os.Getenv("LEGACY_DB_ENCRYPTION_KEY"),
+		LegacyBackupSalt: os.Getenv("LEGACY_BACKUP_SALT"),
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/RyanLadmia/plateforme-safebase/pkg/mailer"
+)
+
+// GetSMTPConfig returns the SMTP configuration from environment variables (empty host = emails are only logged)
+func GetSMTPConfig() *mailer.SMTPConfig {
+	port, _ := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	return &mailer.SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     getEnv("SMTP_FROM", "SafeBase <no-reply@safebase.local>"),
+	}
+}
+
+// GetFrontendURL returns the public URL of the frontend, used to build links sent by email
+func GetFrontendURL() string {
+	return strings.TrimRight(getEnv("FRONTEND_URL", "http://localhost:5173"), "/")
+}
@@ -2,13 +2,17 @@ package config
 
 import (
 	"os"
-
-	"github.com/RyanLadmia/plateforme-safebase/internal/services"
 )
 
+// MegaConfig holds the Mega account used as cloud storage
+type MegaConfig struct {
+	Email    string
+	Password string
+}
+
 // GetMegaConfig returns Mega configuration from environment variables
-func GetMegaConfig() *services.MegaConfig {
-	return &services.MegaConfig{
+func GetMegaConfig() *MegaConfig {
+	return &MegaConfig{
 		Email:    os.Getenv("MEGA_EMAIL"),
 		Password: os.Getenv("MEGA_PASSWORD"),
 	}
@@ -3,13 +3,20 @@ package config
 import (
 	"os"
 	"strconv"
-
-	"github.com/RyanLadmia/plateforme-safebase/internal/services"
 )
 
+// MinIOConfig holds the MinIO connection settings
+type MinIOConfig struct {
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	BucketName      string
+	UseSSL          bool
+}
+
 // GetMinIOConfig returns MinIO configuration from environment variables
-func GetMinIOConfig() *services.MinIOConfig {
-	return &services.MinIOConfig{
+func GetMinIOConfig() *MinIOConfig {
+	return &MinIOConfig{
 		Endpoint:        getEnv("MINIO_ENDPOINT", "localhost:9000"),
 		AccessKeyID:     getEnv("MINIO_ACCESS_KEY", "minioadmin"),
 		SecretAccessKey: getEnv("MINIO_SECRET_KEY", "minioadmin"),
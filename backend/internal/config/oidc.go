@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/RyanLadmia/plateforme-safebase/pkg/oidc"
+)
+
+// GetOIDCConfig returns the single sign-on client configuration (empty issuer = SSO disabled)
+func GetOIDCConfig() oidc.Config {
+	return oidc.Config{
+		IssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/auth/oidc/callback"),
+		Scopes:       strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+		GroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+	}
+}
+
+// OIDCGroupRole maps a group of the identity provider to an application role
+type OIDCGroupRole struct {
+	Group string
+	Role  string
+}
+
+// GetOIDCGroupRoles parses OIDC_GROUP_ROLES ("group=role,group=role"), the first matching group wins
+func GetOIDCGroupRoles() []OIDCGroupRole {
+	var mappings []OIDCGroupRole
+	for _, entry := range strings.Split(os.Getenv("OIDC_GROUP_ROLES"), ",") {
+		group, role, found := strings.Cut(entry, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !found || group == "" || role == "" {
+			continue
+		}
+		mappings = append(mappings, OIDCGroupRole{Group: group, Role: role})
+	}
+	return mappings
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+)
+
+// GetQuotaConfig returns the default limits of every user (0 = unlimited, administrators override them per user)
+func GetQuotaConfig() models.QuotaLimits {
+	return models.QuotaLimits{
+		MaxDatabases:               getEnvAsInt("QUOTA_MAX_DATABASES", 0),
+		MaxSchedules:               getEnvAsInt("QUOTA_MAX_SCHEDULES", 0),
+		MinScheduleIntervalMinutes: getEnvAsInt("QUOTA_MIN_SCHEDULE_INTERVAL_MINUTES", 0),
+		MaxBackupBytes:             getEnvAsInt64("QUOTA_MAX_BACKUP_BYTES", 0),
+	}
+}
+
+// getEnvAsInt64 gets an environment variable as 64-bit integer with a fallback value (0 disables the check)
+func getEnvAsInt64(key string, fallback int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil && number >= 0 {
+			return number
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/RyanLadmia/plateforme-safebase/pkg/tracing"
+)
+
+// GetTracingConfig returns the OpenTelemetry configuration from environment variables
+func GetTracingConfig() *tracing.Config {
+	return &tracing.Config{
+		Enabled:     getEnvAsBool("TRACING_ENABLED", false),
+		Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
+		Insecure:    getEnvAsBool("OTEL_EXPORTER_OTLP_INSECURE", true),
+		ServiceName: getEnv("OTEL_SERVICE_NAME", "safebase-api"),
+		SampleRatio: getEnvAsFloat("OTEL_TRACES_SAMPLER_ARG", 1.0),
+	}
+}
+
+// getEnvAsFloat gets an environment variable as float with a fallback value
+func getEnvAsFloat(key string, fallback float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return fallback
+}
@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
 	"github.com/RyanLadmia/plateforme-safebase/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -19,7 +25,157 @@ func NewActionHistoryHandler(actionHistoryService *services.ActionHistoryService
 	}
 }
 
-// GetUserActionHistory returns action history for the authenticated user
+// parseHistoryTime parses an RFC 3339 date-time or a date. A date used as the end of a range covers the whole day.
+func parseHistoryTime(value string, endOfDay bool) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// parseHistoryFilter reads the action history filters of the query string:
+// action (comma separated), resource_type, resource_id, ip, q, from, to, metadata[key], cursor, page and limit.
+// user_id and admin_action are only read when the caller may see the history of every user.
+func parseHistoryFilter(c *gin.Context, allUsers bool) (repositories.HistoryFilter, error) {
+	filter := repositories.HistoryFilter{
+		ResourceType: c.Query("resource_type"),
+		IpAddress:    strings.TrimSpace(c.Query("ip")),
+		Search:       strings.TrimSpace(c.Query("q")),
+		Page:         1,
+		Limit:        20,
+	}
+	for _, action := range strings.Split(c.Query("action"), ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			filter.Actions = append(filter.Actions, action)
+		}
+	}
+	if value := c.Query("resource_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, errors.New("ID de ressource invalide")
+		}
+		filter.ResourceId = uint(id)
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := parseHistoryTime(value, false)
+		if err != nil {
+			return filter, errors.New("date de début invalide (RFC 3339 ou AAAA-MM-JJ)")
+		}
+		filter.From = from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseHistoryTime(value, true)
+		if err != nil {
+			return filter, errors.New("date de fin invalide (RFC 3339 ou AAAA-MM-JJ)")
+		}
+		filter.To = to
+	}
+	if metadata := c.QueryMap("metadata"); len(metadata) > 0 {
+		for key := range metadata {
+			if !repositories.IsValidHistoryMetadataKey(key) {
+				return filter, fmt.Errorf("clé de métadonnée invalide: %s", key)
+			}
+		}
+		filter.Metadata = metadata
+	}
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, errors.New("curseur invalide")
+		}
+		filter.Cursor = uint(cursor)
+	}
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		filter.Page = p
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 100 {
+		filter.Limit = l
+	}
+
+	if allUsers {
+		if value := c.Query("user_id"); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return filter, errors.New("ID d'utilisateur invalide")
+			}
+			filter.UserId = uint(id)
+		}
+		if value := c.Query("admin_action"); value != "" {
+			adminAction, err := strconv.ParseBool(value)
+			if err != nil {
+				return filter, errors.New("valeur de admin_action invalide")
+			}
+			filter.AdminAction = &adminAction
+		}
+	}
+	return filter, nil
+}
+
+// respondHistorySearch writes a page of action history. Without a cursor the page is counted; with one the client
+// follows next_cursor, which is null on the last page.
+func (h *ActionHistoryHandler) respondHistorySearch(c *gin.Context, filter repositories.HistoryFilter) {
+	histories, nextCursor, err := h.actionHistoryService.SearchActionHistory(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'historique: " + err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"history":     histories,
+		"limit":       filter.Limit,
+		"next_cursor": nil,
+	}
+	if nextCursor != 0 {
+		response["next_cursor"] = nextCursor
+	}
+	if filter.Cursor == 0 {
+		total, err := h.actionHistoryService.CountActionHistory(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'historique: " + err.Error()})
+			return
+		}
+		response["total"] = total
+		response["page"] = filter.Page
+		response["total_pages"] = (total + int64(filter.Limit) - 1) / int64(filter.Limit)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// streamHistoryExport streams the action history matching a filter as an attachment
+func (h *ActionHistoryHandler) streamHistoryExport(c *gin.Context, filter repositories.HistoryFilter) {
+	format := c.DefaultQuery("format", services.HistoryExportCSV)
+	if !services.IsValidHistoryExportFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidExportFormat.Error()})
+		return
+	}
+
+	contentTypes := map[string]string{
+		services.HistoryExportCSV:    "text/csv; charset=utf-8",
+		services.HistoryExportJSON:   "application/json",
+		services.HistoryExportNDJSON: "application/x-ndjson",
+	}
+	filename := fmt.Sprintf("historique_%s.%s", time.Now().Format("2006-01-02_15-04-05"), format)
+	c.Header("Content-Type", contentTypes[format])
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	// The headers are sent: an error can only interrupt the download
+	rows, err := h.actionHistoryService.ExportActionHistory(c.Writer, format, filter, c.GetUint("user_id"), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "action history export failed", "format", format, "rows", rows, "error", err)
+		c.Abort()
+	}
+}
+
+// GetUserActionHistory returns action history for the authenticated user, filtered by the query string (see parseHistoryFilter)
 func (h *ActionHistoryHandler) GetUserActionHistory(c *gin.Context) {
 	// Get user ID from context
 	userID, exists := c.Get("user_id")
@@ -28,39 +184,54 @@ func (h *ActionHistoryHandler) GetUserActionHistory(c *gin.Context) {
 		return
 	}
 
-	// Parse pagination parameters
-	page := 1
-	limit := 20
+	filter, err := parseHistoryFilter(c, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.UserId = userID.(uint)
 
-	if pageParam := c.Query("page"); pageParam != "" {
-		if p, err := strconv.Atoi(pageParam); err == nil && p > 0 {
-			page = p
-		}
+	h.respondHistorySearch(c, filter)
+}
+
+// ExportUserActionHistory streams the action history of the authenticated user as CSV, JSON or NDJSON
+func (h *ActionHistoryHandler) ExportUserActionHistory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
 	}
 
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	filter, err := parseHistoryFilter(c, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	filter.UserId = userID.(uint)
+
+	h.streamHistoryExport(c, filter)
+}
 
-	// Get action history
-	histories, total, err := h.actionHistoryService.GetUserActionHistory(userID.(uint), page, limit)
+// SearchAuditHistory returns the action history of every user matching the filters (audit.read permission, checked by the route)
+func (h *ActionHistoryHandler) SearchAuditHistory(c *gin.Context) {
+	filter, err := parseHistoryFilter(c, true)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'historique: " + err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Calculate pagination info
-	totalPages := (total + int64(limit) - 1) / int64(limit)
+	h.respondHistorySearch(c, filter)
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"history":     histories,
-		"total":       total,
-		"page":        page,
-		"limit":       limit,
-		"total_pages": totalPages,
-	})
+// ExportAuditHistory streams the action history of every user matching the filters (audit.read permission, checked by the route)
+func (h *ActionHistoryHandler) ExportAuditHistory(c *gin.Context) {
+	filter, err := parseHistoryFilter(c, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.streamHistoryExport(c, filter)
 }
 
 // GetActionHistoryByType returns action history filtered by resource type
@@ -161,15 +332,8 @@ func (h *ActionHistoryHandler) GetResourceActionHistory(c *gin.Context) {
 	})
 }
 
-// GetRecentActionHistory returns recent action history (admin only)
+// GetRecentActionHistory returns recent action history of every user (audit.read permission, checked by the route)
 func (h *ActionHistoryHandler) GetRecentActionHistory(c *gin.Context) {
-	// Check if user is admin
-	isAdmin, exists := c.Get("is_admin")
-	if !exists || !isAdmin.(bool) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Accès réservé aux administrateurs"})
-		return
-	}
-
 	// Parse pagination parameters
 	page := 1
 	limit := 20
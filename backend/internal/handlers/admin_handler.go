@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler exposes the databases, backups, restores and schedules of every user to administrators
+type AdminHandler struct {
+	adminService *services.AdminService
+}
+
+// Constructor for AdminHandler
+func NewAdminHandler(adminService *services.AdminService) *AdminHandler {
+	return &AdminHandler{
+		adminService: adminService,
+	}
+}
+
+// parseAdminFilter reads the filter and pagination query parameters (user_id, team_id, database_id, status, search, page, limit)
+func parseAdminFilter(c *gin.Context) repositories.AdminFilter {
+	filter := repositories.AdminFilter{
+		Status: c.Query("status"),
+		Search: c.Query("search"),
+		Page:   1,
+		Limit:  20,
+	}
+	if id, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil {
+		filter.UserId = uint(id)
+	}
+	if id, err := strconv.ParseUint(c.Query("team_id"), 10, 32); err == nil {
+		filter.TeamId = uint(id)
+	}
+	if id, err := strconv.ParseUint(c.Query("database_id"), 10, 32); err == nil {
+		filter.DatabaseId = uint(id)
+	}
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		filter.Page = p
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 100 {
+		filter.Limit = l
+	}
+	return filter
+}
+
+// respondAdminList writes a page of an admin listing
+func respondAdminList(c *gin.Context, key string, items interface{}, total int64, filter repositories.AdminFilter) {
+	c.JSON(http.StatusOK, gin.H{
+		key:           items,
+		"total":       total,
+		"page":        filter.Page,
+		"limit":       filter.Limit,
+		"total_pages": (total + int64(filter.Limit) - 1) / int64(filter.Limit),
+	})
+}
+
+// ListDatabases GET /api/admin/databases
+func (h *AdminHandler) ListDatabases(c *gin.Context) {
+	filter := parseAdminFilter(c)
+	databases, total, err := h.adminService.ListDatabases(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des bases de données: " + err.Error()})
+		return
+	}
+	respondAdminList(c, "databases", databases, total, filter)
+}
+
+// ListBackups GET /api/admin/backups
+func (h *AdminHandler) ListBackups(c *gin.Context) {
+	filter := parseAdminFilter(c)
+	backups, total, err := h.adminService.ListBackups(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des sauvegardes: " + err.Error()})
+		return
+	}
+	respondAdminList(c, "backups", backups, total, filter)
+}
+
+// ListRestores GET /api/admin/restores
+func (h *AdminHandler) ListRestores(c *gin.Context) {
+	filter := parseAdminFilter(c)
+	restores, total, err := h.adminService.ListRestores(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des restaurations: " + err.Error()})
+		return
+	}
+	respondAdminList(c, "restores", restores, total, filter)
+}
+
+// ListSchedules GET /api/admin/schedules
+func (h *AdminHandler) ListSchedules(c *gin.Context) {
+	filter := parseAdminFilter(c)
+	schedules, total, err := h.adminService.ListSchedules(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des planifications: " + err.Error()})
+		return
+	}
+	respondAdminList(c, "schedules", schedules, total, filter)
+}
+
+// TriggerBackup POST /api/admin/databases/:id/backup
+func (h *AdminHandler) TriggerBackup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	backup, err := h.adminService.TriggerBackup(c.Request.Context(), adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(quotaErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Sauvegarde lancée pour le compte du propriétaire", "backup": backup})
+}
+
+// RunSchedule POST /api/admin/schedules/:id/run
+func (h *AdminHandler) RunSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	backup, err := h.adminService.RunSchedule(c.Request.Context(), adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(quotaErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Planification exécutée", "backup": backup})
+}
+
+// CancelBackup POST /api/admin/backups/:id/cancel
+func (h *AdminHandler) CancelBackup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	backup, err := h.adminService.CancelBackup(adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(cancelErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sauvegarde annulée", "backup": backup})
+}
+
+// CancelRestore POST /api/admin/restores/:id/cancel
+func (h *AdminHandler) CancelRestore(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	restore, err := h.adminService.CancelRestore(adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(cancelErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Restauration annulée", "restore": restore})
+}
+
+// cancelErrorStatus returns 409 for a job that can no longer be cancelled and 404 otherwise
+func cancelErrorStatus(err error) int {
+	if errors.Is(err, services.ErrJobNotCancellable) {
+		return http.StatusConflict
+	}
+	return http.StatusNotFound
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPITokenRequest represents the request body for creating a personal API token
+type CreateAPITokenRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Scopes      []string   `json:"scopes" binding:"required"`
+	DatabaseIDs []uint     `json:"database_ids"` // Vide : toutes les bases de l'utilisateur
+	ExpiresAt   *time.Time `json:"expires_at"`   // Absent : pas d'expiration
+}
+
+// apiTokenResponse returns a token without its secret
+func apiTokenResponse(token models.APIToken) gin.H {
+	return gin.H{
+		"id":           token.Id,
+		"name":         token.Name,
+		"prefix":       token.Prefix,
+		"scopes":       token.ScopeList(),
+		"database_ids": token.DatabaseIDList(),
+		"expires_at":   token.ExpiresAt,
+		"last_used_at": token.LastUsedAt,
+		"last_used_ip": token.LastUsedIP,
+		"revoked_at":   token.RevokedAt,
+		"created_at":   token.CreatedAt,
+	}
+}
+
+// GetAPITokens GET /api/profile/tokens
+func (h *ProfileHandler) GetAPITokens(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des tokens d'API"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, apiTokenResponse(token))
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": result, "available_scopes": services.APITokenScopes})
+}
+
+// CreateAPIToken POST /api/profile/tokens (le token en clair n'est retourné qu'une seule fois)
+func (h *ProfileHandler) CreateAPIToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+
+	plaintext, token, err := h.authService.CreateAPIToken(userID.(uint), services.CreateAPITokenInput{
+		Name:        req.Name,
+		Scopes:      req.Scopes,
+		DatabaseIDs: req.DatabaseIDs,
+		ExpiresAt:   req.ExpiresAt,
+	}, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Token d'API créé. Copiez-le maintenant, il ne sera plus affiché.",
+		"token":     plaintext,
+		"api_token": apiTokenResponse(*token),
+	})
+}
+
+// RevokeAPIToken DELETE /api/profile/tokens/:id
+func (h *ProfileHandler) RevokeAPIToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de token invalide"})
+		return
+	}
+
+	if err := h.authService.RevokeAPIToken(userID.(uint), uint(tokenID), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token d'API révoqué"})
+}
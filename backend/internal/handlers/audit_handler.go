@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler handles the verification and anchoring of the action history chain
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+// NewAuditHandler constructor
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// VerifyChain GET /api/admin/audit/verify
+// Parcourt toute la chaîne : 200 si elle est intacte, 409 avec la première ligne en défaut sinon.
+func (h *AuditHandler) VerifyChain(c *gin.Context) {
+	result, err := h.auditService.VerifyChain(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	status := http.StatusOK
+	if !result.Valid {
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{"verification": result})
+}
+
+// AnchorHead POST /api/admin/audit/anchor
+func (h *AuditHandler) AnchorHead(c *gin.Context) {
+	anchor, created, err := h.auditService.AnchorHead(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	message := "Tête de la chaîne ancrée dans le stockage"
+	if !created {
+		message = "Tête de la chaîne déjà ancrée"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "anchor": anchor})
+}
+
+// GetAnchors GET /api/admin/audit/anchors
+func (h *AuditHandler) GetAnchors(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	anchors, err := h.auditService.GetAnchors(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"anchors": anchors})
+}
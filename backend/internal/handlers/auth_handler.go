@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
@@ -14,6 +18,7 @@ import (
 // AuthHandler contient le service d'authentification
 type AuthHandler struct {
 	authService *services.AuthService
+	frontendURL string // Page d'arrivée après une connexion SSO
 }
 
 // NewAuthHandler constructeur
@@ -62,6 +67,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// Call service to register user
 	if err := h.authService.Register(&user); err != nil {
 		log.Printf("Erreur lors de l'inscription: %v", err)
+		if errors.Is(err, services.ErrLocalLoginDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -81,45 +90,73 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // Login endpoint: POST /auth/login
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email      string `json:"email"`
+		Password   string `json:"password"`
+		DeviceName string `json:"device_name"` // Optionnel, déduit du user agent sinon
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
 
-	// Call service to login
-	token, err := h.authService.Login(req.Email, req.Password)
+	// Call service to login (opens a new session for this device, other devices stay signed in)
+	pair, err := h.authService.LoginWithMetadata(req.Email, req.Password, sessionMetadata(c, req.DeviceName))
+	var challenge *services.TwoFactorChallenge
+	if errors.As(err, &challenge) {
+		// Mot de passe correct : la session n'est ouverte qu'après le second facteur (POST /auth/2fa/verify)
+		respondTwoFactorChallenge(c, challenge)
+		return
+	}
+	if respondLoginLocked(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrLocalLoginDisabled) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "sso_required": true})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Définir le cookie HTTP-only sécurisé
-	// En production, le cookie sera sécurisé avec HTTPS
-	isProduction := os.Getenv("GO_ENV") == "production"
-	c.SetCookie(
-		"auth_token",                // nom du cookie
-		token,                       // valeur (JWT token)
-		int(24*time.Hour.Seconds()), // maxAge en secondes (24h)
-		"/",                         // path
-		"",                          // domain (vide = domaine actuel)
-		isProduction,                // secure (true en production avec HTTPS)
-		true,                        // httpOnly (empêche l'accès via JavaScript)
-	)
+	h.respondWithSession(c, pair, nil)
+}
+
+// respondLoginLocked answers 429 with Retry-After while the account or the IP address is locked out
+func respondLoginLocked(c *gin.Context, err error) bool {
+	var locked *services.LoginLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	retryAfter := int(time.Until(locked.Until).Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       err.Error(),
+		"message":     "Trop de tentatives de connexion échouées, réessayez plus tard",
+		"retry_after": retryAfter,
+	})
+	return true
+}
+
+// respondWithSession sets the session cookies and returns the tokens with the user info
+func (h *AuthHandler) respondWithSession(c *gin.Context, pair *services.TokenPair, extra gin.H) {
+	// Définir les cookies HTTP-only sécurisés (token d'accès + refresh token)
+	h.setSessionCookies(c, pair)
 
 	// Récupérer les infos utilisateur pour la réponse
-	user, err := h.authService.GetUserFromToken(token)
+	user, err := h.authService.GetUserFromToken(pair.AccessToken)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user info"})
 		return
 	}
 
-	// Respond with user info AND token
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Connexion réussie",
-		"token":   token, // Ajout du token dans la réponse
+	// Respond with user info AND tokens
+	response := gin.H{
+		"message":            "Connexion réussie",
+		"token":              pair.AccessToken, // Ajout du token dans la réponse
+		"expires_in":         int(time.Until(pair.AccessExpiresAt).Seconds()),
+		"refresh_token":      pair.RefreshToken,
+		"refresh_expires_at": pair.RefreshExpiresAt,
 		"user": gin.H{
 			"id":        user.Id,
 			"firstname": user.Firstname,
@@ -128,11 +165,64 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			"role_id":   user.RoleID,
 			"role":      user.Role, // Inclure le rôle complet
 		},
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Refresh endpoint: POST /auth/refresh
+// Le refresh token est lu dans le corps ({"refresh_token": "..."}) ou dans le cookie refresh_token.
+// Chaque appel renvoie un nouveau refresh token : l'ancien devient inutilisable.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	refreshToken := req.RefreshToken
+	if refreshToken == "" {
+		refreshToken, _ = c.Cookie(refreshCookieName)
+	}
+	if refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token required"})
+		return
+	}
+
+	pair, err := h.authService.Refresh(refreshToken, sessionMetadata(c, ""))
+	if err != nil {
+		h.clearSessionCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setSessionCookies(c, pair)
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Session renouvelée",
+		"token":              pair.AccessToken,
+		"expires_in":         int(time.Until(pair.AccessExpiresAt).Seconds()),
+		"refresh_token":      pair.RefreshToken,
+		"refresh_expires_at": pair.RefreshExpiresAt,
 	})
 }
 
 // Logout endpoint: POST /auth/logout
 func (h *AuthHandler) Logout(c *gin.Context) {
+	// Le refresh token identifie la session même quand le token d'accès a expiré
+	if refreshToken, err := c.Cookie(refreshCookieName); err == nil && refreshToken != "" {
+		if err := h.authService.RevokeRefreshToken(refreshToken); err != nil {
+			slog.WarnContext(c.Request.Context(), "failed to revoke refresh token on logout", "error", err)
+		}
+		h.clearSessionCookies(c)
+		c.JSON(http.StatusOK, gin.H{"message": "Déconnexion réussie"})
+		return
+	}
+
 	// Récupérer le token depuis le cookie
 	token, err := c.Cookie("auth_token")
 	if err != nil {
@@ -146,18 +236,94 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	// Supprimer le cookie en définissant une expiration passée
+	// Supprimer les cookies en définissant une expiration passée
+	h.clearSessionCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Déconnexion réussie"})
+}
+
+// ForgotPassword endpoint: POST /auth/forgot-password
+// La réponse est toujours la même, que le compte existe ou non.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	h.authService.RequestPasswordReset(req.Email, c.ClientIP(), c.Request.UserAgent())
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Si un compte correspond à cet email, un lien de réinitialisation vient d'être envoyé",
+	})
+}
+
+// ResetPassword endpoint: POST /auth/reset-password
+// Le token reçu par email n'est utilisable qu'une fois ; toutes les sessions de l'utilisateur sont fermées.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token           string `json:"token" binding:"required"`
+		Password        string `json:"password" binding:"required"`
+		ConfirmPassword string `json:"confirm_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.Password != req.ConfirmPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "passwords do not match"})
+		return
+	}
+
+	if err := h.authService.ResetPassword(req.Token, req.Password, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Les sessions ont été révoquées, y compris celle de ce navigateur le cas échéant
+	h.clearSessionCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Mot de passe réinitialisé, veuillez vous reconnecter"})
+}
+
+// refreshCookieName is the HTTP-only cookie holding the refresh token, only sent to /auth
+const refreshCookieName = "refresh_token"
+
+// setSessionCookies stores the access token and the refresh token in HTTP-only cookies
+func (h *AuthHandler) setSessionCookies(c *gin.Context, pair *services.TokenPair) {
+	// En production, les cookies sont sécurisés avec HTTPS
+	isProduction := os.Getenv("GO_ENV") == "production"
+	accessMaxAge := int(time.Until(pair.AccessExpiresAt).Seconds())
+	refreshMaxAge := int(time.Until(pair.RefreshExpiresAt).Seconds())
+
 	c.SetCookie(
-		"auth_token",
-		"",
-		-1, // maxAge négatif pour supprimer le cookie
-		"/",
-		"",
-		false,
-		true,
+		"auth_token",     // nom du cookie
+		pair.AccessToken, // valeur (JWT token)
+		accessMaxAge,     // maxAge en secondes (durée du token d'accès)
+		"/",              // path
+		"",               // domain (vide = domaine actuel)
+		isProduction,     // secure (true en production avec HTTPS)
+		true,             // httpOnly (empêche l'accès via JavaScript)
 	)
+	// Le refresh token n'est envoyé qu'aux routes /auth (refresh et logout)
+	c.SetCookie(refreshCookieName, pair.RefreshToken, refreshMaxAge, "/auth", "", isProduction, true)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Déconnexion réussie"})
+// clearSessionCookies removes both session cookies
+func (h *AuthHandler) clearSessionCookies(c *gin.Context) {
+	isProduction := os.Getenv("GO_ENV") == "production"
+	c.SetCookie("auth_token", "", -1, "/", "", isProduction, true) // maxAge négatif pour supprimer le cookie
+	c.SetCookie(refreshCookieName, "", -1, "/auth", "", isProduction, true)
+}
+
+// sessionMetadata collects the device information recorded with a session
+func sessionMetadata(c *gin.Context, deviceName string) services.SessionMetadata {
+	return services.SessionMetadata{
+		DeviceName: strings.TrimSpace(deviceName),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
 }
 
 // GetCurrentUser endpoint: GET /auth/me
@@ -171,6 +337,10 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 
 	// Récupérer les infos utilisateur
 	user, err := h.authService.GetUserFromToken(token)
+	if errors.Is(err, services.ErrSessionRevoked) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "session revoked or expired"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 		return
@@ -1,15 +1,20 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
 	"github.com/gin-gonic/gin"
 )
 
 type BackupHandler struct {
 	backupService *services.BackupService
+	authz         *services.AuthorizationService
 }
 
 // Constructor for BackupHandler
@@ -19,6 +24,30 @@ func NewBackupHandler(backupService *services.BackupService) *BackupHandler {
 	}
 }
 
+// SetAuthorizationService enables team based access checks (nil keeps per-user ownership)
+func (h *BackupHandler) SetAuthorizationService(authz *services.AuthorizationService) {
+	h.authz = authz
+}
+
+// PassphraseHeader carries the passphrase of zero-knowledge backups (kept out of URLs and request bodies logged by proxies)
+const PassphraseHeader = "X-Backup-Passphrase"
+
+// passphraseErrorStatus maps a passphrase error to its HTTP status
+func passphraseErrorStatus(err error) int {
+	if errors.Is(err, security.ErrPassphraseRequired) {
+		return http.StatusBadRequest
+	}
+	return http.StatusForbidden
+}
+
+// passphraseErrorMessage returns the user-facing message of a passphrase error
+func passphraseErrorMessage(err error) string {
+	if errors.Is(err, security.ErrPassphraseRequired) {
+		return "Phrase secrète requise pour cette sauvegarde (en-tête " + PassphraseHeader + ")"
+	}
+	return "Phrase secrète incorrecte"
+}
+
 // CreateBackup creates a new backup for a database
 func (h *BackupHandler) CreateBackup(c *gin.Context) {
 	databaseIDParam := c.Param("database_id")
@@ -47,9 +76,9 @@ func (h *BackupHandler) CreateBackup(c *gin.Context) {
 	// Extract IP address
 	ipAddress := c.ClientIP()
 
-	backup, err := h.backupService.CreateBackup(uint(databaseID), userID.(uint), ipAddress, requestBody.UserAgent)
+	backup, err := h.backupService.CreateBackupContext(c.Request.Context(), uint(databaseID), userID.(uint), ipAddress, requestBody.UserAgent)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la sauvegarde: " + err.Error()})
+		c.JSON(quotaErrorStatus(err, http.StatusInternalServerError), gin.H{"error": "Erreur lors de la création de la sauvegarde: " + err.Error()})
 		return
 	}
 
@@ -74,6 +103,15 @@ func (h *BackupHandler) GetBackups(c *gin.Context) {
 		return
 	}
 
+	// Un token d'API restreint ne voit que les sauvegardes de ses bases
+	allowed := []models.Backup{}
+	for _, backup := range backups {
+		if middlewares.APITokenAllowsDatabase(c, backup.DatabaseId) {
+			allowed = append(allowed, backup)
+		}
+	}
+	backups = allowed
+
 	c.JSON(http.StatusOK, gin.H{
 		"backups": backups,
 	})
@@ -101,10 +139,10 @@ func (h *BackupHandler) GetBackupsByDatabase(c *gin.Context) {
 		return
 	}
 
-	// Filter backups to only show those belonging to the user
+	// Filter backups to only show those the user can access
 	var userBackups []interface{}
 	for _, backup := range backups {
-		if backup.UserId == userID.(uint) {
+		if h.authz.CanAccessResource(userID.(uint), backup.UserId, backup.DatabaseId, services.TeamActionView) {
 			userBackups = append(userBackups, backup)
 		}
 	}
@@ -136,8 +174,9 @@ func (h *BackupHandler) GetBackup(c *gin.Context) {
 		return
 	}
 
-	// Verify user ownership
-	if backup.UserId != userID.(uint) {
+	// Verify user access (and the databases of a restricted API token)
+	if !h.authz.CanAccessResource(userID.(uint), backup.UserId, backup.DatabaseId, services.TeamActionView) ||
+		!middlewares.APITokenAllowsDatabase(c, backup.DatabaseId) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès non autorisé"})
 		return
 	}
@@ -199,8 +238,9 @@ func (h *BackupHandler) DownloadBackup(c *gin.Context) {
 		return
 	}
 
-	// Verify user ownership
-	if backup.UserId != userID.(uint) {
+	// Verify user access (and the databases of a restricted API token)
+	if !h.authz.CanAccessResource(userID.(uint), backup.UserId, backup.DatabaseId, services.TeamActionOperate) ||
+		!middlewares.APITokenAllowsDatabase(c, backup.DatabaseId) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès non autorisé"})
 		return
 	}
@@ -216,7 +256,11 @@ func (h *BackupHandler) DownloadBackup(c *gin.Context) {
 	userAgent := c.GetHeader("User-Agent")
 
 	// Download the file data from the service (MinIO or local)
-	fileData, err := h.backupService.DownloadBackupWithLogging(uint(id), userID.(uint), ipAddress, userAgent)
+	fileData, err := h.backupService.DownloadBackupWithPassphraseLogging(c.Request.Context(), uint(id), userID.(uint), c.GetHeader(PassphraseHeader), ipAddress, userAgent)
+	if services.IsPassphraseError(err) {
+		c.JSON(passphraseErrorStatus(err), gin.H{"error": passphraseErrorMessage(err)})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du téléchargement: " + err.Error()})
 		return
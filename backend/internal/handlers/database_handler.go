@@ -1,11 +1,12 @@
 package handlers
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/internal/services"
 	"github.com/gin-gonic/gin"
@@ -13,6 +14,7 @@ import (
 
 type DatabaseHandler struct {
 	databaseService *services.DatabaseService
+	authz           *services.AuthorizationService
 }
 
 // Constructor for DatabaseHandler
@@ -22,6 +24,11 @@ func NewDatabaseHandler(databaseService *services.DatabaseService) *DatabaseHand
 	}
 }
 
+// SetAuthorizationService enables team based access checks (nil keeps per-user ownership)
+func (h *DatabaseHandler) SetAuthorizationService(authz *services.AuthorizationService) {
+	h.authz = authz
+}
+
 // CreateDatabase creates a new database configuration
 func (h *DatabaseHandler) CreateDatabase(c *gin.Context) {
 	var request models.DatabaseCreateRequest
@@ -58,10 +65,11 @@ func (h *DatabaseHandler) CreateDatabase(c *gin.Context) {
 		DbName:   request.DbName,
 		URL:      request.URL, // Store the original URL if provided
 		UserId:   userID.(uint),
+		TeamId:   request.TeamId,
 	}
 
 	if err := h.databaseService.CreateDatabase(database, userID.(uint), ipAddress, userAgent); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la base de données: " + err.Error()})
+		c.JSON(quotaErrorStatus(err, http.StatusInternalServerError), gin.H{"error": "Erreur lors de la création de la base de données: " + err.Error()})
 		return
 	}
 
@@ -91,6 +99,15 @@ func (h *DatabaseHandler) GetDatabases(c *gin.Context) {
 		return
 	}
 
+	// Un token d'API restreint ne voit que ses bases
+	allowed := []models.Database{}
+	for _, database := range databases {
+		if middlewares.APITokenAllowsDatabase(c, database.Id) {
+			allowed = append(allowed, database)
+		}
+	}
+	databases = allowed
+
 	c.JSON(http.StatusOK, gin.H{
 		"databases": databases,
 	})
@@ -118,8 +135,8 @@ func (h *DatabaseHandler) GetDatabase(c *gin.Context) {
 		return
 	}
 
-	// Verify user ownership
-	if database.UserId != userID.(uint) {
+	// Verify that the user may read the database
+	if !h.authz.CanAccessDatabase(userID.(uint), database, services.TeamActionView) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès non autorisé"})
 		return
 	}
@@ -161,8 +178,8 @@ func (h *DatabaseHandler) UpdateDatabase(c *gin.Context) {
 		return
 	}
 
-	// Verify user ownership
-	if existingDatabase.UserId != userID.(uint) {
+	// Verify that the user may manage the database
+	if !h.authz.CanAccessDatabase(userID.(uint), existingDatabase, services.TeamActionManage) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès non autorisé"})
 		return
 	}
@@ -226,21 +243,16 @@ func (h *DatabaseHandler) UpdateDatabasePartial(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
-	fmt.Printf("[DEBUG] UpdateDatabasePartial: Looking for database ID %d for user %d\n", id, userID.(uint))
-
 	// Get existing database
 	existingDatabase, err := h.databaseService.GetDatabaseByID(uint(id))
 	if err != nil {
-		fmt.Printf("[DEBUG] UpdateDatabasePartial: Database not found - error: %v\n", err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Base de données introuvable"})
 		return
 	}
 
-	fmt.Printf("[DEBUG] UpdateDatabasePartial: Found database %d, owner is %d, current user is %d\n", existingDatabase.Id, existingDatabase.UserId, userID.(uint))
-
-	// Verify user ownership
-	if existingDatabase.UserId != userID.(uint) {
-		fmt.Printf("[DEBUG] UpdateDatabasePartial: Access denied - database owner %d != user %d\n", existingDatabase.UserId, userID.(uint))
+	// Verify that the user may manage the database
+	if !h.authz.CanAccessDatabase(userID.(uint), existingDatabase, services.TeamActionManage) {
+		slog.WarnContext(c.Request.Context(), "database rename denied", "database_id", existingDatabase.Id, "user_id", userID)
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès non autorisé"})
 		return
 	}
@@ -263,26 +275,19 @@ func (h *DatabaseHandler) UpdateDatabasePartial(c *gin.Context) {
 	// Store old name for logging
 	newName := strings.TrimSpace(request.Name)
 
-	fmt.Printf("[DEBUG] UpdateDatabasePartial: About to update database ID %d with name '%s'\n", existingDatabase.Id, newName)
-
 	if err := h.databaseService.UpdateDatabaseName(existingDatabase.Id, newName, userID.(uint), ipAddress, userAgent); err != nil {
-		fmt.Printf("[DEBUG] UpdateDatabasePartial: UpdateDatabaseNameWithLogging failed - error: %v\n", err)
+		slog.ErrorContext(c.Request.Context(), "database rename failed", "database_id", existingDatabase.Id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour: " + err.Error()})
 		return
 	}
 
-	fmt.Printf("[DEBUG] UpdateDatabasePartial: UpdateDatabaseNameWithLogging succeeded, now fetching updated database ID %d\n", existingDatabase.Id)
-
 	// Get the updated database to return it
 	updatedDatabase, err := h.databaseService.GetDatabaseByID(uint(existingDatabase.Id))
 	if err != nil {
-		fmt.Printf("[DEBUG] UpdateDatabasePartial: Failed to get updated database ID %d - error: %v\n", existingDatabase.Id, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de la base mise à jour: " + err.Error()})
 		return
 	}
 
-	fmt.Printf("[DEBUG] UpdateDatabasePartial: Successfully retrieved updated database ID %d, name '%s'\n", updatedDatabase.Id, updatedDatabase.Name)
-
 	// Return database without sensitive data for security
 	responseDb := *updatedDatabase
 	responseDb.Password = "" // Don't expose password in response
@@ -316,8 +321,8 @@ func (h *DatabaseHandler) GetDatabaseWithBackupCount(c *gin.Context) {
 		return
 	}
 
-	// Verify user ownership
-	if database.UserId != userID.(uint) {
+	// Verify that the user may read the database
+	if !h.authz.CanAccessDatabase(userID.(uint), database, services.TeamActionView) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès non autorisé"})
 		return
 	}
@@ -370,3 +375,127 @@ func (h *DatabaseHandler) DeleteDatabase(c *gin.Context) {
 		"message": "Base de données supprimée avec succès",
 	})
 }
+
+// SetBackupPassphrase enables zero-knowledge backups for a database or changes its passphrase
+func (h *DatabaseHandler) SetBackupPassphrase(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	var request struct {
+		Passphrase        string `json:"passphrase" binding:"required"`
+		CurrentPassphrase string `json:"current_passphrase"` // required to change an existing passphrase
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+
+	err = h.databaseService.SetBackupPassphrase(uint(id), userID.(uint), request.Passphrase, request.CurrentPassphrase, c.ClientIP(), c.GetHeader("User-Agent"))
+	if services.IsPassphraseError(err) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Phrase secrète actuelle incorrecte ou manquante"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Phrase secrète enregistrée : les nouvelles sauvegardes ne pourront être déchiffrées qu'avec elle. Elle ne peut pas être récupérée en cas de perte.",
+	})
+}
+
+// DisableBackupPassphrase stops protecting new backups with the passphrase
+func (h *DatabaseHandler) DisableBackupPassphrase(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	var request struct {
+		CurrentPassphrase string `json:"current_passphrase" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+
+	err = h.databaseService.DisableBackupPassphrase(uint(id), userID.(uint), request.CurrentPassphrase, c.ClientIP(), c.GetHeader("User-Agent"))
+	if services.IsPassphraseError(err) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Phrase secrète incorrecte"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Phrase secrète désactivée pour les nouvelles sauvegardes. Les sauvegardes existantes la nécessitent toujours.",
+	})
+}
+
+// SetProtection enables or disables the four-eyes approval of restores into a database
+func (h *DatabaseHandler) SetProtection(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	var request struct {
+		Protected *bool `json:"protected" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+
+	awaitingConfirmation, err := h.databaseService.SetDatabaseProtection(uint(id), userID.(uint), *request.Protected, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if awaitingConfirmation {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":               "Désactivation demandée : un autre administrateur de la base doit la confirmer.",
+			"protected":             true,
+			"awaiting_confirmation": true,
+		})
+		return
+	}
+
+	message := "Protection désactivée : les restaurations sont de nouveau exécutées immédiatement."
+	if *request.Protected {
+		message = "Base de données protégée : chaque restauration devra être approuvée par un second utilisateur."
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "protected": *request.Protected})
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/version"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthService *services.HealthService
+}
+
+// Constructor for HealthHandler
+func NewHealthHandler(healthService *services.HealthService) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+	}
+}
+
+// Health is the legacy health endpoint kept for existing monitors and CI/CD
+func (h *HealthHandler) Health(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "safebase-api",
+		"version": version.Get().Version,
+	})
+}
+
+// Live answers as long as the process can serve HTTP requests (no dependency is checked)
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "alive",
+		"service": "safebase-api",
+		"version": version.Get().Version,
+		"uptime":  h.healthService.Uptime().String(),
+	})
+}
+
+// Ready checks every dependency and returns 503 when a critical one is down
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.healthService.CheckReadiness(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// KeyRotationHandler handles master key administration for admins
+type KeyRotationHandler struct {
+	keyRotationService *services.KeyRotationService
+}
+
+// NewKeyRotationHandler constructor
+func NewKeyRotationHandler(keyRotationService *services.KeyRotationService) *KeyRotationHandler {
+	return &KeyRotationHandler{keyRotationService: keyRotationService}
+}
+
+// GetKeys GET /api/admin/keys
+func (h *KeyRotationHandler) GetKeys(c *gin.Context) {
+	keys, err := h.keyRotationService.GetKeyUsage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// StartRotation POST /api/admin/keys/rotate
+func (h *KeyRotationHandler) StartRotation(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	job, err := h.keyRotationService.StartRotation(userID.(uint), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Rotation des clés démarrée",
+		"job":     job,
+	})
+}
+
+// StartObjectMigration POST /api/admin/keys/migrate-objects
+func (h *KeyRotationHandler) StartObjectMigration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	job, err := h.keyRotationService.StartObjectMigration(userID.(uint), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Re-chiffrement des sauvegardes démarré",
+		"job":     job,
+	})
+}
+
+// GetRotation GET /api/admin/keys/rotations/:id
+func (h *KeyRotationHandler) GetRotation(c *gin.Context) {
+	job, err := h.keyRotationService.GetJob(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie binds the authorization request to the browser that started it (login CSRF)
+const oidcStateCookie = "oidc_state"
+
+// SetFrontendURL sets the frontend the browser returns to after a single sign-on login
+func (h *AuthHandler) SetFrontendURL(frontendURL string) {
+	h.frontendURL = frontendURL
+}
+
+// GetLoginOptions endpoint: GET /auth/oidc/config
+// Indique au frontend s'il doit afficher le bouton SSO et le formulaire de mot de passe.
+func (h *AuthHandler) GetLoginOptions(c *gin.Context) {
+	c.JSON(http.StatusOK, h.authService.GetAuthSettings())
+}
+
+// OIDCLogin endpoint: GET /auth/oidc/login
+// Redirige le navigateur vers le fournisseur d'identité (authorization code + PKCE).
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	authURL, state, err := h.authService.BeginOIDCLogin()
+	if errors.Is(err, services.ErrOIDCNotConfigured) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start single sign-on"})
+		return
+	}
+
+	// SameSite=Lax : le cookie est renvoyé par la redirection de retour du fournisseur
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, 600, "/auth/oidc", "", os.Getenv("GO_ENV") == "production", true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback endpoint: GET /auth/oidc/callback
+// Termine la connexion SSO, pose les cookies de session et renvoie vers le frontend.
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	state := c.Query("state")
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, "/auth/oidc", "", os.Getenv("GO_ENV") == "production", true)
+
+	if providerError := c.Query("error"); providerError != "" {
+		slog.WarnContext(c.Request.Context(), "SSO login refused by provider", "error", providerError, "description", c.Query("error_description"))
+		h.redirectSSOError(c, "Connexion SSO annulée ou refusée")
+		return
+	}
+	if state == "" || cookieState != state {
+		h.redirectSSOError(c, services.ErrOIDCStateInvalid.Error())
+		return
+	}
+
+	pair, err := h.authService.CompleteOIDCLogin(c.Request.Context(), state, c.Query("code"), sessionMetadata(c, ""))
+	if err != nil {
+		h.redirectSSOError(c, err.Error())
+		return
+	}
+
+	h.setSessionCookies(c, pair)
+	c.Redirect(http.StatusFound, h.frontendURL+"/")
+}
+
+// redirectSSOError sends the browser back to the login page with the reason of the failure
+func (h *AuthHandler) redirectSSOError(c *gin.Context, message string) {
+	c.Redirect(http.StatusFound, h.frontendURL+"/login?sso_error="+url.QueryEscape(message))
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SetPrivacyService sets the service for personal data export and account erasure
+func (h *ProfileHandler) SetPrivacyService(privacyService *services.PrivacyService) {
+	h.privacyService = privacyService
+}
+
+// ExportData GET /api/profile/export
+// Archive ZIP : profil, bases de données (sans secrets), planifications, catalogue des sauvegardes et historique.
+func (h *ProfileHandler) ExportData(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+	if h.privacyService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Export des données non disponible"})
+		return
+	}
+
+	data, filename, err := h.privacyService.ExportUserData(userID.(uint), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", data)
+}
+
+// RequestErasure DELETE /api/profile
+// Le compte reste utilisable jusqu'à la date d'effacement et la demande peut être annulée.
+func (h *ProfileHandler) RequestErasure(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+	if h.privacyService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Effacement du compte non disponible"})
+		return
+	}
+
+	// Mot de passe requis sauf pour les comptes liés au fournisseur d'identité
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	scheduledAt, err := h.privacyService.RequestErasure(userID.(uint), req.Password, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":              "Effacement du compte programmé",
+		"erasure_scheduled_at": scheduledAt,
+	})
+}
+
+// CancelErasure POST /api/profile/erasure/cancel
+func (h *ProfileHandler) CancelErasure(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+	if h.privacyService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Effacement du compte non disponible"})
+		return
+	}
+
+	if err := h.privacyService.CancelErasure(userID.(uint), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrErasureNotScheduled) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Effacement du compte annulé"})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/services"
 	"github.com/gin-gonic/gin"
@@ -9,8 +10,9 @@ import (
 
 // ProfileHandler handles user profile operations
 type ProfileHandler struct {
-	userService *services.UserService
-	authService *services.AuthService
+	userService    *services.UserService
+	authService    *services.AuthService
+	privacyService *services.PrivacyService
 }
 
 // NewProfileHandler constructor
@@ -141,13 +143,90 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"user": gin.H{
-			"id":        user.Id,
-			"firstname": user.Firstname,
-			"lastname":  user.Lastname,
-			"email":     user.Email,
-			"role":      user.Role,
-			"active":    user.Active,
+			"id":                   user.Id,
+			"firstname":            user.Firstname,
+			"lastname":             user.Lastname,
+			"email":                user.Email,
+			"role":                 user.Role,
+			"active":               user.Active,
+			"erasure_scheduled_at": user.ErasureScheduledAt,
 		},
 	})
 }
 
+
+// GetSessions GET /api/profile/sessions
+func (h *ProfileHandler) GetSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+	currentSessionID, _ := c.Get("session_id")
+
+	sessions, err := h.authService.ListSessions(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des sessions"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, gin.H{
+			"id":           session.Id,
+			"device_name":  session.DeviceName,
+			"ip_address":   session.IPAddress,
+			"user_agent":   session.UserAgent,
+			"created_at":   session.CreatedAt,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.RefreshExpiresAt,
+			"current":      currentSessionID == session.Id,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": result})
+}
+
+// RevokeSession DELETE /api/profile/sessions/:id
+func (h *ProfileHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de session invalide"})
+		return
+	}
+
+	if err := h.authService.RevokeSession(userID.(uint), uint(sessionID), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session révoquée"})
+}
+
+// RevokeOtherSessions DELETE /api/profile/sessions (déconnexion de tous les autres appareils)
+func (h *ProfileHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+	currentSessionID, ok := c.Get("session_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Session courante inconnue"})
+		return
+	}
+
+	count, err := h.authService.RevokeOtherSessions(userID.(uint), currentSessionID.(uint), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Autres sessions révoquées", "revoked": count})
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type QuotaHandler struct {
+	quotaService *services.QuotaService
+}
+
+// Constructor for QuotaHandler
+func NewQuotaHandler(quotaService *services.QuotaService) *QuotaHandler {
+	return &QuotaHandler{
+		quotaService: quotaService,
+	}
+}
+
+// quotaErrorStatus returns 403 for quota errors and the fallback status otherwise
+func quotaErrorStatus(err error, fallback int) int {
+	if errors.Is(err, services.ErrQuotaExceeded) {
+		return http.StatusForbidden
+	}
+	return fallback
+}
+
+// GetMyUsage GET /api/profile/usage
+func (h *QuotaHandler) GetMyUsage(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	usage, err := h.quotaService.GetUsage(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du calcul de la consommation: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"usage": usage})
+}
+
+// GetUserQuota GET /api/admin/users/:id/quota
+func (h *QuotaHandler) GetUserQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	report, err := h.quotaService.GetUserReport(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du calcul de la consommation: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"quota": report, "defaults": h.quotaService.DefaultLimits()})
+}
+
+// SetUserQuota PUT /api/admin/users/:id/quota
+func (h *QuotaHandler) SetUserQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	var request models.QuotaOverride
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	report, err := h.quotaService.SetUserQuota(adminID.(uint), uint(id), request, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quota de l'utilisateur mis à jour", "quota": report})
+}
+
+// ResetUserQuota DELETE /api/admin/users/:id/quota
+func (h *QuotaHandler) ResetUserQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.quotaService.ResetUserQuota(adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quota de l'utilisateur réinitialisé aux valeurs par défaut"})
+}
+
+// GetTeamQuota GET /api/admin/teams/:id/quota
+func (h *QuotaHandler) GetTeamQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	report, err := h.quotaService.GetTeamReport(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du calcul de la consommation: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"quota": report})
+}
+
+// SetTeamQuota PUT /api/admin/teams/:id/quota
+func (h *QuotaHandler) SetTeamQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	var request models.QuotaOverride
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	report, err := h.quotaService.SetTeamQuota(adminID.(uint), uint(id), request, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quota de l'équipe mis à jour", "quota": report})
+}
+
+// ResetTeamQuota DELETE /api/admin/teams/:id/quota
+func (h *QuotaHandler) ResetTeamQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.quotaService.ResetTeamQuota(adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quota de l'équipe supprimé"})
+}
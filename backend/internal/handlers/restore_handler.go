@@ -1,15 +1,18 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
 type RestoreHandler struct {
 	restoreService *services.RestoreService
+	authz          *services.AuthorizationService
 }
 
 // Constructor for RestoreHandler
@@ -19,6 +22,11 @@ func NewRestoreHandler(restoreService *services.RestoreService) *RestoreHandler
 	}
 }
 
+// SetAuthorizationService enables team based access checks (nil keeps per-user ownership)
+func (h *RestoreHandler) SetAuthorizationService(authz *services.AuthorizationService) {
+	h.authz = authz
+}
+
 // CreateRestore creates a new restore operation
 func (h *RestoreHandler) CreateRestore(c *gin.Context) {
 	backupIDParam := c.Param("backup_id")
@@ -46,12 +54,24 @@ func (h *RestoreHandler) CreateRestore(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
-	restore, err := h.restoreService.CreateRestore(uint(backupID), uint(databaseID), userID.(uint), ipAddress, userAgent)
+	restore, err := h.restoreService.CreateRestoreWithPassphraseContext(c.Request.Context(), uint(backupID), uint(databaseID), userID.(uint), c.GetHeader(PassphraseHeader), ipAddress, userAgent)
+	if services.IsPassphraseError(err) {
+		c.JSON(passphraseErrorStatus(err), gin.H{"error": passphraseErrorMessage(err)})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de la restauration: " + err.Error()})
 		return
 	}
 
+	if restore.Status == models.RestoreStatusAwaitingApproval {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Base de données protégée : la restauration sera exécutée après l'approbation d'un second utilisateur.",
+			"restore": restore,
+		})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Restauration créée avec succès. Le processus de restauration a commencé.",
 		"restore": restore,
@@ -100,10 +120,10 @@ func (h *RestoreHandler) GetRestoresByDatabase(c *gin.Context) {
 		return
 	}
 
-	// Filter restores to only show those belonging to the user
+	// Filter restores to only show those the user can access
 	var userRestores []interface{}
 	for _, restore := range restores {
-		if restore.UserId == userID.(uint) {
+		if h.authz.CanAccessResource(userID.(uint), restore.UserId, restore.DatabaseId, services.TeamActionView) {
 			userRestores = append(userRestores, restore)
 		}
 	}
@@ -135,10 +155,10 @@ func (h *RestoreHandler) GetRestoresByBackup(c *gin.Context) {
 		return
 	}
 
-	// Filter restores to only show those belonging to the user
+	// Filter restores to only show those the user can access
 	var userRestores []interface{}
 	for _, restore := range restores {
-		if restore.UserId == userID.(uint) {
+		if h.authz.CanAccessResource(userID.(uint), restore.UserId, restore.DatabaseId, services.TeamActionView) {
 			userRestores = append(userRestores, restore)
 		}
 	}
@@ -170,8 +190,8 @@ func (h *RestoreHandler) GetRestore(c *gin.Context) {
 		return
 	}
 
-	// Verify user ownership
-	if restore.UserId != userID.(uint) {
+	// Verify user access
+	if !h.authz.CanAccessResource(userID.(uint), restore.UserId, restore.DatabaseId, services.TeamActionView) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Accès non autorisé"})
 		return
 	}
@@ -180,3 +200,89 @@ func (h *RestoreHandler) GetRestore(c *gin.Context) {
 		"restore": restore,
 	})
 }
+
+// restoreApprovalErrorStatus maps an approval workflow error to its HTTP status
+func restoreApprovalErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrRestoreNotAwaitingApproval):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrRestoreApprovalExpired):
+		return http.StatusGone
+	case errors.Is(err, services.ErrSelfApproval), errors.Is(err, services.ErrRestoreReviewForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// GetPendingApprovals returns the restores into protected databases awaiting the approval of the user
+func (h *RestoreHandler) GetPendingApprovals(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	restores, err := h.restoreService.GetPendingApprovals(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des demandes d'approbation: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"restores": restores})
+}
+
+// ApproveRestore approves and starts a restore into a protected database
+func (h *RestoreHandler) ApproveRestore(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de restauration invalide"})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	restore, err := h.restoreService.ApproveRestore(c.Request.Context(), uint(id), userID.(uint), c.GetHeader(PassphraseHeader), c.ClientIP(), c.GetHeader("User-Agent"))
+	if services.IsPassphraseError(err) {
+		c.JSON(passphraseErrorStatus(err), gin.H{"error": passphraseErrorMessage(err)})
+		return
+	}
+	if err != nil {
+		c.JSON(restoreApprovalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Restauration approuvée. Le processus de restauration a commencé.",
+		"restore": restore,
+	})
+}
+
+// RejectRestore rejects a restore into a protected database
+func (h *RestoreHandler) RejectRestore(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de restauration invalide"})
+		return
+	}
+	var request struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	restore, err := h.restoreService.RejectRestore(uint(id), userID.(uint), request.Reason, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(restoreApprovalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Restauration rejetée", "restore": restore})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SetRoleService sets the service used to manage custom roles and their permissions
+func (h *UserHandler) SetRoleService(roleService *services.RoleService) {
+	h.roleService = roleService
+}
+
+// CreateRoleRequest represents the request body for creating a custom role
+type CreateRoleRequest struct {
+	Name             string   `json:"name" binding:"required"`
+	Description      string   `json:"description"`
+	Permissions      []string `json:"permissions" binding:"required"`
+	RequireTwoFactor bool     `json:"require_two_factor"`
+}
+
+// UpdateRolePermissionsRequest represents the request body for replacing the permissions of a role
+type UpdateRolePermissionsRequest struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// roleErrorStatus maps a role management error to its HTTP status
+func roleErrorStatus(err error) int {
+	if errors.Is(err, services.ErrBuiltInRole) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// GetPermissions GET /api/admin/roles/permissions
+func (h *UserHandler) GetPermissions(c *gin.Context) {
+	if h.roleService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Role management not available"})
+		return
+	}
+	permissions, err := h.roleService.ListPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"permissions": permissions})
+}
+
+// CreateRole POST /api/admin/roles
+func (h *UserHandler) CreateRole(c *gin.Context) {
+	if h.roleService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Role management not available"})
+		return
+	}
+	var req CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	role, err := h.roleService.CreateRole(adminID.(uint), req.Name, req.Description, req.Permissions, req.RequireTwoFactor, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Role created", "role": role})
+}
+
+// UpdateRolePermissions PUT /api/admin/roles/:id/permissions
+func (h *UserHandler) UpdateRolePermissions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+	if h.roleService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Role management not available"})
+		return
+	}
+	var req UpdateRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	role, err := h.roleService.UpdateRolePermissions(adminID.(uint), uint(id), req.Permissions, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Role permissions updated", "role": role})
+}
+
+// DeleteRole DELETE /api/admin/roles/:id
+func (h *UserHandler) DeleteRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+	if h.roleService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Role management not available"})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.roleService.DeleteRole(adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
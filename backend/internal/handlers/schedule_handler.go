@@ -44,7 +44,7 @@ func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
 
 	schedule, err := h.scheduleService.CreateSchedule(request.DatabaseID, userID.(uint), request.Name, request.CronExpression, ipAddress, userAgent)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du schedule: " + err.Error()})
+		c.JSON(quotaErrorStatus(err, http.StatusInternalServerError), gin.H{"error": "Erreur lors de la création du schedule: " + err.Error()})
 		return
 	}
 
@@ -144,7 +144,7 @@ func (h *ScheduleHandler) UpdateSchedule(c *gin.Context) {
 
 	schedule, err := h.scheduleService.UpdateSchedule(uint(id), userID.(uint), name, cronExpr, activePtr, ipAddress, userAgent)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour du schedule: " + err.Error()})
+		c.JSON(quotaErrorStatus(err, http.StatusInternalServerError), gin.H{"error": "Erreur lors de la mise à jour du schedule: " + err.Error()})
 		return
 	}
 
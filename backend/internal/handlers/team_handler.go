@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type TeamHandler struct {
+	teamService *services.TeamService
+}
+
+// Constructor for TeamHandler
+func NewTeamHandler(teamService *services.TeamService) *TeamHandler {
+	return &TeamHandler{
+		teamService: teamService,
+	}
+}
+
+// TeamRequest represents the request body for creating or renaming a team
+type TeamRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// TeamMemberRequest represents the request body for adding a member to a team
+type TeamMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+// TeamMemberRoleRequest represents the request body for changing the role of a member
+type TeamMemberRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// teamErrorStatus maps a team error to its HTTP status
+func teamErrorStatus(err error) int {
+	if errors.Is(err, services.ErrTeamAccessDenied) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// GetTeams GET /api/teams
+func (h *TeamHandler) GetTeams(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	teams, err := h.teamService.ListTeams(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des équipes: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"teams": teams})
+}
+
+// GetTeam GET /api/teams/:id
+func (h *TeamHandler) GetTeam(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	team, err := h.teamService.GetTeam(userID.(uint), uint(id))
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"team": team})
+}
+
+// CreateTeam POST /api/teams
+func (h *TeamHandler) CreateTeam(c *gin.Context) {
+	var request TeamRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	team, err := h.teamService.CreateTeam(userID.(uint), request.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Équipe créée avec succès", "team": team})
+}
+
+// RenameTeam PUT /api/teams/:id
+func (h *TeamHandler) RenameTeam(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	var request TeamRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	team, err := h.teamService.RenameTeam(userID.(uint), uint(id), request.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Équipe mise à jour avec succès", "team": team})
+}
+
+// DeleteTeam DELETE /api/teams/:id
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.teamService.DeleteTeam(userID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Équipe supprimée avec succès"})
+}
+
+// AddMember POST /api/teams/:id/members
+func (h *TeamHandler) AddMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	var request TeamMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	member, err := h.teamService.AddMember(userID.(uint), uint(id), request.Email, request.Role, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Membre ajouté avec succès", "member": member})
+}
+
+// UpdateMemberRole PUT /api/teams/:id/members/:user_id
+func (h *TeamHandler) UpdateMemberRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	memberID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de membre invalide"})
+		return
+	}
+	var request TeamMemberRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.teamService.UpdateMemberRole(userID.(uint), uint(id), uint(memberID), request.Role, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Rôle du membre mis à jour"})
+}
+
+// RemoveMember DELETE /api/teams/:id/members/:user_id
+func (h *TeamHandler) RemoveMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	memberID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de membre invalide"})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.teamService.RemoveMember(userID.(uint), uint(id), uint(memberID), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Membre retiré de l'équipe"})
+}
+
+// MoveDatabase PUT /api/databases/:id/team
+func (h *TeamHandler) MoveDatabase(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	var request struct {
+		TeamId uint `json:"team_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.teamService.MoveDatabase(userID.(uint), uint(id), request.TeamId, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(teamErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Base de données déplacée avec succès"})
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// twoFactorErrorStatus maps 2FA service errors to HTTP status codes
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorChallengeInvalid):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrTwoFactorEnforced):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// respondTwoFactorChallenge answers a correct password login that still needs a second factor.
+// No cookie is set: the partial token only works on /auth/2fa/setup and /auth/2fa/verify.
+func respondTwoFactorChallenge(c *gin.Context, challenge *services.TwoFactorChallenge) {
+	message := "Code de double authentification requis"
+	if challenge.SetupRequired {
+		message = "La double authentification est obligatoire pour votre rôle : configurez-la pour continuer"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":             message,
+		"two_factor_required": true,
+		"setup_required":      challenge.SetupRequired,
+		"two_factor_token":    challenge.Token,
+		"expires_in":          int(time.Until(challenge.ExpiresAt).Seconds()),
+	})
+}
+
+// SetupTwoFactorChallenge endpoint: POST /auth/2fa/setup
+// Enrolment during login for users whose role requires 2FA (partial token from /auth/login).
+func (h *AuthHandler) SetupTwoFactorChallenge(c *gin.Context) {
+	var req struct {
+		Token string `json:"two_factor_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	setup, err := h.authService.BeginTwoFactorSetupWithChallenge(req.Token)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, setup)
+}
+
+// VerifyTwoFactor endpoint: POST /auth/2fa/verify
+// Completes the login with a TOTP code or a recovery code and opens the session.
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req struct {
+		Token      string `json:"two_factor_token" binding:"required"`
+		Code       string `json:"code" binding:"required"` // Code TOTP à 6 chiffres ou code de secours
+		DeviceName string `json:"device_name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	pair, recoveryCodes, err := h.authService.VerifyTwoFactor(req.Token, req.Code, sessionMetadata(c, req.DeviceName))
+	if respondLoginLocked(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var extra gin.H
+	if recoveryCodes != nil {
+		// Enrôlement terminé pendant la connexion : les codes ne sont affichés qu'une fois
+		extra = gin.H{"recovery_codes": recoveryCodes}
+	}
+	h.respondWithSession(c, pair, extra)
+}
+
+// GetTwoFactorStatus GET /api/profile/2fa
+func (h *ProfileHandler) GetTwoFactorStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	status, err := h.authService.GetTwoFactorStatus(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// SetupTwoFactor POST /api/profile/2fa/setup
+// Retourne le secret et l'URI otpauth:// à afficher en QR code ; la 2FA n'est active qu'après confirmation.
+func (h *ProfileHandler) SetupTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	setup, err := h.authService.BeginTwoFactorSetup(userID.(uint))
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, setup)
+}
+
+// ConfirmTwoFactor POST /api/profile/2fa/confirm
+func (h *ProfileHandler) ConfirmTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(userID.(uint), req.Code, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Double authentification activée",
+		"recovery_codes": codes,
+	})
+}
+
+// RegenerateRecoveryCodes POST /api/profile/2fa/recovery-codes
+func (h *ProfileHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(userID.(uint), req.Code, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Codes de secours régénérés",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor DELETE /api/profile/2fa
+func (h *ProfileHandler) DisableTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides"})
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(userID.(uint), req.Password, req.Code, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Double authentification désactivée"})
+}
+
+// ResetUserTwoFactor DELETE /api/admin/users/:id/2fa
+func (h *UserHandler) ResetUserTwoFactor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if h.authService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Two-factor management not available"})
+		return
+	}
+
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.authService.ResetTwoFactor(adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// GetAllRoles GET /api/admin/roles
+func (h *UserHandler) GetAllRoles(c *gin.Context) {
+	roles, err := h.userService.GetAllRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve roles"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// SetRoleTwoFactor PUT /api/admin/roles/:id/two-factor
+func (h *UserHandler) SetRoleTwoFactor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var req struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	role, err := h.userService.SetRoleTwoFactorRequired(adminID.(uint), uint(id), *req.Required, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Role two-factor policy updated", "role": role})
+}
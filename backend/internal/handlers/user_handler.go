@@ -10,7 +10,10 @@ import (
 
 // UserHandler handles user management operations for admins
 type UserHandler struct {
-	userService *services.UserService
+	userService     *services.UserService
+	authService     *services.AuthService
+	roleService     *services.RoleService
+	deletionService *services.UserDeletionService
 }
 
 // NewUserHandler constructor
@@ -18,6 +21,16 @@ func NewUserHandler(userService *services.UserService) *UserHandler {
 	return &UserHandler{userService: userService}
 }
 
+// SetAuthService sets the auth service used to revoke user sessions
+func (h *UserHandler) SetAuthService(authService *services.AuthService) {
+	h.authService = authService
+}
+
+// SetUserDeletionService sets the service deleting accounts in the background
+func (h *UserHandler) SetUserDeletionService(deletionService *services.UserDeletionService) {
+	h.deletionService = deletionService
+}
+
 // GetAllUsers GET /api/admin/users
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
 	users, err := h.userService.GetAllUsers()
@@ -154,3 +167,214 @@ func (h *UserHandler) ActivateUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "User activated successfully"})
 }
+
+// RevokeUserSessions DELETE /api/admin/users/:id/sessions
+func (h *UserHandler) RevokeUserSessions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if h.authService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Session management not available"})
+		return
+	}
+
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	count, err := h.authService.RevokeAllSessions(adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User sessions revoked", "revoked": count})
+}
+
+// UnlockUser POST /api/admin/users/:id/unlock
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if h.authService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Account unlock not available"})
+		return
+	}
+
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.authService.UnlockAccount(adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
+
+// GetAuthSettings GET /api/admin/auth/settings
+func (h *UserHandler) GetAuthSettings(c *gin.Context) {
+	if h.authService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication settings not available"})
+		return
+	}
+	c.JSON(http.StatusOK, h.authService.GetAuthSettings())
+}
+
+// UpdateAuthSettings PUT /api/admin/auth/settings
+// Désactiver la connexion par mot de passe impose le SSO (les administrateurs la conservent en secours).
+func (h *UserHandler) UpdateAuthSettings(c *gin.Context) {
+	if h.authService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication settings not available"})
+		return
+	}
+
+	var req struct {
+		LocalLoginEnabled *bool `json:"local_login_enabled" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.authService.SetLocalLoginEnabled(adminID.(uint), *req.LocalLoginEnabled, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Authentication settings updated", "settings": h.authService.GetAuthSettings()})
+}
+
+// CreateUserRequest represents the request body for creating a user. Without a password, the user is
+// invited by email to choose one.
+type CreateUserRequest struct {
+	Firstname string `json:"firstname" binding:"required"`
+	Lastname  string `json:"lastname" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	RoleID    *uint  `json:"role_id,omitempty"`
+	Password  string `json:"password,omitempty"`
+}
+
+// CreateUser POST /api/admin/users
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	user, err := h.userService.CreateUser(adminID.(uint), req.Firstname, req.Lastname, req.Email, req.Password, req.RoleID, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Password != "" {
+		c.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "user": user, "invitation_sent": false})
+		return
+	}
+
+	if h.authService == nil {
+		c.JSON(http.StatusCreated, gin.H{"message": "User created, invitation not available", "user": user, "invitation_sent": false})
+		return
+	}
+	if err := h.authService.InviteUser(adminID.(uint), user.Id, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusCreated, gin.H{"message": "User created, invitation failed: " + err.Error(), "user": user, "invitation_sent": false})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "User created and invited", "user": user, "invitation_sent": true})
+}
+
+// InviteUser POST /api/admin/users/:id/invite
+// Sends a new set-password link, the previous one stops working.
+func (h *UserHandler) InviteUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if h.authService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Invitations not available"})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	if err := h.authService.InviteUser(adminID.(uint), uint(id), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation sent"})
+}
+
+// DeleteUserRequest represents the request body for deleting a user
+type DeleteUserRequest struct {
+	Mode         string `json:"mode" binding:"required"` // reassign or purge
+	TargetUserID uint   `json:"target_user_id,omitempty"`
+}
+
+// DeleteUser DELETE /api/admin/users/:id
+// The account is deactivated at once, its resources are reassigned or purged in the background.
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if h.deletionService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "User deletion not available"})
+		return
+	}
+	var req DeleteUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	job, err := h.deletionService.StartDeletion(adminID.(uint), uint(id), req.Mode, req.TargetUserID, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "User deletion started", "job": job})
+}
+
+// GetUserDeletion GET /api/admin/user-deletions/:id
+func (h *UserHandler) GetUserDeletion(c *gin.Context) {
+	if h.deletionService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "User deletion not available"})
+		return
+	}
+	job, err := h.deletionService.GetJob(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
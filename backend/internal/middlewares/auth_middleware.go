@@ -2,15 +2,58 @@ package middlewares
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
 	"github.com/gin-gonic/gin"
 )
 
+// SessionValidator vérifie qu'un token d'accès correspond toujours à une session ouverte
+// et retourne l'identifiant de cette session (implémenté par services.AuthService)
+type SessionValidator interface {
+	ValidateSession(token string) (uint, error)
+}
+
+// APITokenValidator vérifie un token d'API personnel ("sb_...") et retourne le token avec son utilisateur
+// (implémenté par services.AuthService)
+type APITokenValidator interface {
+	ValidateAPIToken(token, ipAddress string) (*models.APIToken, error)
+}
+
+// PermissionChecker indique si un rôle accorde une permission (implémenté par services.RoleService)
+type PermissionChecker interface {
+	HasPermission(role, permission string) bool
+}
+
+// apiTokenPrefix distingue les tokens d'API des JWT de session
+const apiTokenPrefix = "sb_"
+
+// apiTokenRoute décrit une route accessible avec un token d'API
+type apiTokenRoute struct {
+	scope         string // Scope requis
+	databaseParam string // Paramètre contenant l'ID de la base de données (vérifié contre les restrictions du token)
+}
+
+// apiTokenRoutes liste les seules routes accessibles avec un token d'API, par "MÉTHODE chemin".
+// Toute autre route (profil, administration, configuration des bases, suppressions...) est refusée.
+var apiTokenRoutes = map[string]apiTokenRoute{
+	"GET /api/databases":                      {scope: "databases:read"},
+	"GET /api/databases/:id":                  {scope: "databases:read", databaseParam: "id"},
+	"GET /api/backups":                        {scope: "backups:read"},
+	"GET /api/backups/:id":                    {scope: "backups:read"},
+	"GET /api/backups/:id/download":           {scope: "backups:download"},
+	"GET /api/backups/database/:database_id":  {scope: "backups:read", databaseParam: "database_id"},
+	"POST /api/backups/database/:database_id": {scope: "backups:create", databaseParam: "database_id"},
+}
+
 // AuthMiddleware structure pour encapsuler le middleware d'authentification
 type AuthMiddleware struct {
-	jwtSecret string
+	jwtSecret   string
+	sessions    SessionValidator
+	apiTokens   APITokenValidator
+	permissions PermissionChecker
 }
 
 // NewAuthMiddleware crée une nouvelle instance d'AuthMiddleware
@@ -20,7 +63,24 @@ func NewAuthMiddleware(jwtSecret string) *AuthMiddleware {
 	}
 }
 
-// RequireAuth vérifie que le token JWT est présent et valide
+// SetSessionValidator active la vérification de la session en base à chaque requête :
+// une session révoquée (déconnexion, révocation, réutilisation de refresh token) est refusée
+// même si son JWT n'a pas encore expiré
+func (am *AuthMiddleware) SetSessionValidator(sessions SessionValidator) {
+	am.sessions = sessions
+}
+
+// SetAPITokenValidator active l'authentification par token d'API personnel (Authorization: Bearer sb_...)
+func (am *AuthMiddleware) SetAPITokenValidator(apiTokens APITokenValidator) {
+	am.apiTokens = apiTokens
+}
+
+// SetPermissionChecker active la vérification des permissions des rôles (RequirePermission)
+func (am *AuthMiddleware) SetPermissionChecker(permissions PermissionChecker) {
+	am.permissions = permissions
+}
+
+// RequireAuth vérifie que le token JWT (ou le token d'API) est présent et valide
 func (am *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var token string
@@ -35,6 +95,12 @@ func (am *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			}
 		}
 
+		// Token d'API personnel (automatisation, CI) : uniquement via le header
+		if strings.HasPrefix(token, apiTokenPrefix) {
+			am.authenticateAPIToken(c, token)
+			return
+		}
+
 		// Si pas de token dans le header, essayer le cookie HTTP-only (plus sécurisé)
 		if token == "" {
 			cookieToken, err := c.Cookie("auth_token")
@@ -52,12 +118,24 @@ func (am *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 
 		// Vérifie le token
 		claims, err := security.VerifyJWT(am.jwtSecret, token)
-		if err != nil {
+		// Les tokens restreints (ex. second facteur en attente) ne donnent pas accès à l'API
+		if err != nil || claims.Purpose != "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
 			c.Abort()
 			return
 		}
 
+		// Vérifie que la session n'a pas été révoquée
+		if am.sessions != nil {
+			sessionID, err := am.sessions.ValidateSession(token)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "session revoked or expired"})
+				c.Abort()
+				return
+			}
+			c.Set("session_id", sessionID)
+		}
+
 		// Stocke les claims dans le contexte pour qu'ils soient accessibles dans le handler
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
@@ -67,6 +145,80 @@ func (am *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	}
 }
 
+// authenticateAPIToken authentifie une requête portant un token d'API : la route doit figurer dans
+// apiTokenRoutes, le token doit avoir le scope requis et, le cas échéant, accès à la base de données visée
+func (am *AuthMiddleware) authenticateAPIToken(c *gin.Context, token string) {
+	if am.apiTokens == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+		c.Abort()
+		return
+	}
+	apiToken, err := am.apiTokens.ValidateAPIToken(token, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid, revoked or expired API token"})
+		c.Abort()
+		return
+	}
+
+	route, allowed := apiTokenRoutes[c.Request.Method+" "+c.FullPath()]
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "this endpoint is not available with an API token"})
+		c.Abort()
+		return
+	}
+	if !apiToken.HasScope(route.scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API token is missing the " + route.scope + " scope"})
+		c.Abort()
+		return
+	}
+	if route.databaseParam != "" {
+		// Fail closed: an unparsable ID must not bypass the database restriction
+		databaseID, err := strconv.ParseUint(c.Param(route.databaseParam), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid database id"})
+			c.Abort()
+			return
+		}
+		if !apiToken.AllowsDatabase(uint(databaseID)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API token is not allowed on this database"})
+			c.Abort()
+			return
+		}
+	}
+
+	role := ""
+	if apiToken.User.Role != nil {
+		role = apiToken.User.Role.Name
+	}
+	c.Set("user_id", apiToken.UserId)
+	c.Set("user_email", apiToken.User.Email)
+	c.Set("user_role", role)
+	c.Set("api_token_id", apiToken.Id)
+	c.Set("api_token_databases", apiToken.DatabaseIDList())
+
+	c.Next()
+}
+
+// APITokenAllowsDatabase indique si la requête peut accéder à une base de données : toujours vrai pour
+// une session, limité aux bases du token pour un token d'API restreint (utilisé par les handlers pour
+// les ressources dont la base n'apparaît pas dans l'URL, ex. une sauvegarde ou une liste)
+func APITokenAllowsDatabase(c *gin.Context, databaseID uint) bool {
+	value, exists := c.Get("api_token_databases")
+	if !exists {
+		return true
+	}
+	databaseIDs, _ := value.([]uint)
+	if len(databaseIDs) == 0 {
+		return true
+	}
+	for _, id := range databaseIDs {
+		if id == databaseID {
+			return true
+		}
+	}
+	return false
+}
+
 // RequireRole vérifie que l'utilisateur a le rôle requis
 func (am *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -94,3 +246,35 @@ func (am *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequirePermission vérifie que le rôle de l'utilisateur accorde la permission requise.
+// Les permissions sont lues à chaque requête : modifier un rôle s'applique sans nouvelle connexion.
+// Sans PermissionChecker, seul le rôle admin (qui détient toutes les permissions) est accepté.
+func (am *AuthMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRole, exists := c.Get("user_role")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "role information missing"})
+			c.Abort()
+			return
+		}
+		roleStr, ok := userRole.(string)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid role format"})
+			c.Abort()
+			return
+		}
+
+		allowed := roleStr == "admin"
+		if am.permissions != nil {
+			allowed = am.permissions.HasPermission(roleStr, permission)
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions", "required_permission": permission})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
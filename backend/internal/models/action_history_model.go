@@ -1,6 +1,11 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,9 +22,123 @@ type ActionHistory struct {
 	IpAddress    string    `gorm:"size:45" json:"ip_address,omitempty"`
 	UserAgent    string    `gorm:"type:text" json:"user_agent,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
+
+	// Action performed by an administrator on the resources of another user (metadata.on_behalf_of)
+	AdminAction bool `gorm:"not null;default:false;index" json:"admin_action"`
+
+	// Tamper evidence: hash of the content chained to the hash of the previous row. The IP address, user agent and
+	// PrivacyMetadataKeys are covered through PrivacyDigest, so anonymizing them on an account erasure keeps the
+	// chain valid.
+	Hash          string `gorm:"size:64;index" json:"hash,omitempty"`
+	PrevHash      string `gorm:"size:64" json:"prev_hash,omitempty"`
+	PrivacyDigest string `gorm:"size:64" json:"-"`
 }
 
 // TableName specifies the table name for ActionHistory
 func (ActionHistory) TableName() string {
 	return "action_histories"
 }
+
+// PrivacyMetadataKeys are the metadata entries holding client IP addresses and device names. Like the IP address
+// and user agent columns they are covered by PrivacyDigest instead of the hash, and removed on an account erasure.
+var PrivacyMetadataKeys = []string{"session_ip", "device_name"}
+
+// ComputePrivacyDigest returns the digest of the IP address, user agent and privacy metadata covered by the hash chain
+func (h *ActionHistory) ComputePrivacyDigest() string {
+	data := h.IpAddress + "\x00" + h.UserAgent
+	for _, entry := range privacyMetadataEntries(h.Metadata) {
+		data += "\x00" + entry
+	}
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasPrivacyData reports whether the row still holds an IP address, a user agent or privacy metadata
+func (h *ActionHistory) HasPrivacyData() bool {
+	return h.IpAddress != "" || h.UserAgent != "" || len(privacyMetadataEntries(h.Metadata)) > 0
+}
+
+// RemovePrivacyMetadata returns the metadata without PrivacyMetadataKeys, and whether any was removed
+func RemovePrivacyMetadata(metadata string) (string, bool) {
+	values := decodeMetadataObject(metadata)
+	removed := false
+	for _, key := range PrivacyMetadataKeys {
+		if _, exists := values[key]; exists {
+			delete(values, key)
+			removed = true
+		}
+	}
+	if !removed {
+		return metadata, false
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return metadata, false
+	}
+	return string(data), true
+}
+
+// privacyMetadataEntries returns the privacy metadata as key=value entries, in PrivacyMetadataKeys order
+func privacyMetadataEntries(metadata string) []string {
+	values := decodeMetadataObject(metadata)
+	var entries []string
+	for _, key := range PrivacyMetadataKeys {
+		if value, exists := values[key]; exists {
+			data, _ := json.Marshal(value)
+			entries = append(entries, key+"="+string(data))
+		}
+	}
+	return entries
+}
+
+// decodeMetadataObject decodes JSON object metadata, nil for anything else
+func decodeMetadataObject(metadata string) map[string]interface{} {
+	if metadata == "" {
+		return nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(metadata), &values); err != nil {
+		return nil
+	}
+	return values
+}
+
+// ComputeHash returns the chained hash of the row from its content, PrevHash and PrivacyDigest
+func (h *ActionHistory) ComputeHash() string {
+	fields := []string{
+		h.PrevHash,
+		strconv.FormatUint(uint64(h.UserId), 10),
+		h.Action,
+		h.ResourceType,
+		strconv.FormatUint(uint64(h.ResourceId), 10),
+		h.Description,
+		canonicalMetadata(h.Metadata),
+		h.PrivacyDigest,
+		strconv.FormatBool(h.AdminAction),
+		h.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalMetadata normalizes the JSON metadata: PostgreSQL jsonb does not keep the key order nor the spacing.
+// The privacy metadata is left out, PrivacyDigest covers it.
+func canonicalMetadata(metadata string) string {
+	if metadata == "" {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(metadata), &value); err != nil {
+		return metadata
+	}
+	if values, ok := value.(map[string]interface{}); ok {
+		for _, key := range PrivacyMetadataKeys {
+			delete(values, key)
+		}
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return metadata
+	}
+	return string(canonical)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIToken est un token d'API personnel (automatisation, CI) : nommé, révocable, limité à des scopes
+// et éventuellement à certaines bases de données. Seul le hash SHA-256 du token est stocké.
+type APIToken struct {
+	Id          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	Prefix      string     `gorm:"size:16;not null" json:"prefix"` // Début du token, pour le reconnaître dans la liste
+	TokenHash   string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Scopes      string     `gorm:"size:255;not null" json:"-"` // Liste séparée par des virgules (backups:create, ...)
+	DatabaseIDs string     `gorm:"size:255" json:"-"`          // Vide = toutes les bases de l'utilisateur
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `gorm:"size:45" json:"last_used_ip,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UserId      uint       `gorm:"index;not null" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserId" json:"-"`
+}
+
+// ScopeList returns the scopes granted to the token
+func (t *APIToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+// HasScope reports whether the token grants a scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, granted := range t.ScopeList() {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// DatabaseIDList returns the databases the token is restricted to (empty = no restriction)
+func (t *APIToken) DatabaseIDList() []uint {
+	ids := []uint{}
+	for _, value := range strings.Split(t.DatabaseIDs, ",") {
+		if id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// AllowsDatabase reports whether the token may act on a database
+func (t *APIToken) AllowsDatabase(databaseID uint) bool {
+	ids := t.DatabaseIDList()
+	if len(ids) == 0 {
+		return true
+	}
+	for _, id := range ids {
+		if id == databaseID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// AuditAnchor records a head of the action history chain copied into backup storage. A row deleted or rewritten
+// after the anchor no longer matches it, even at the end of the chain.
+type AuditAnchor struct {
+	Id         uint      `gorm:"primaryKey" json:"id"`
+	HistoryId  uint      `gorm:"index;not null" json:"history_id"` // Last action history row of the chain
+	Hash       string    `gorm:"size:64;not null" json:"hash"`
+	Rows       int64     `gorm:"not null" json:"rows"` // Chained rows at anchoring time
+	RemotePath string    `gorm:"type:text;not null" json:"remote_path"`
+	CreatedAt  time.Time `json:"created_at"`
+}
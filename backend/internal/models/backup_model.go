@@ -11,9 +11,11 @@ type Backup struct {
 	Filename   string         `gorm:"size:255;not null" json:"filename"`
 	Filepath   string         `gorm:"type:text;not null" json:"filepath"`
 	Size       int64          `gorm:"not null;default:0" json:"size"`                   // Taille en bytes
-	Status     string         `gorm:"size:50;not null;default:'pending'" json:"status"` // pending, completed, failed
+	Status     string         `gorm:"size:50;not null;default:'pending'" json:"status"` // pending, running, completed, failed, cancelled
 	ErrorMsg   string         `gorm:"type:text" json:"error_msg,omitempty"`
 	UserAgent  string         `gorm:"size:255" json:"user_agent,omitempty"`
+	KeyID      string         `gorm:"size:32;index" json:"key_id,omitempty"` // Master key ID wrapping the data key (empty: legacy)
+	WrappedKey string         `gorm:"type:text" json:"-"`                    // Per-backup data key encrypted with KeyID (base64, empty: derived user key)
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
@@ -23,3 +25,6 @@ type Backup struct {
 	Database   Database       `gorm:"foreignKey:DatabaseId" json:"-"`
 	Restores   []Restore      `gorm:"foreignKey:BackupId;constraint:OnDelete:CASCADE;" json:"restores,omitempty"`
 }
+
+// BackupStatusCancelled is the status of a backup job cancelled by an administrator
+const BackupStatusCancelled = "cancelled"
@@ -21,6 +21,8 @@ type DatabaseCreateRequest struct {
 	Password string `json:"password,omitempty"`
 	DbName   string `json:"db_name,omitempty"`
 	URL      string `json:"url,omitempty"` // Alternative: full connection URL
+
+	TeamId *uint `json:"team_id,omitempty"` // Équipe propriétaire (par défaut : espace personnel)
 }
 
 // DatabaseUpdateRequest is used for JSON binding during updates
@@ -36,23 +38,37 @@ type DatabaseUpdateRequest struct {
 }
 
 type Database struct {
-	Id        uint           `gorm:"primaryKey" json:"id"`
-	Name      string         `gorm:"size:100;not null" json:"name"`
-	Type      string         `gorm:"size:50;not null" json:"type"` // mysql, postgres
-	Host      string         `gorm:"size:255;not null" json:"host"`
-	Port      string         `gorm:"size:10;not null" json:"port"`
-	Username  string         `gorm:"size:100;not null" json:"username"`
-	Password  string         `gorm:"size:255;not null" json:"-"` // Ne pas exposer le mot de passe dans le JSON
-	DbName    string         `gorm:"size:100;not null" json:"db_name"`
-	URL       string         `gorm:"size:500" json:"url,omitempty"` // Full connection URL (optional)
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-	UserId    uint           `gorm:"index;not null" json:"user_id"`
-	User      User           `gorm:"foreignKey:UserId" json:"-"`
-	Backups   []Backup       `gorm:"foreignKey:DatabaseId" json:"backups,omitempty"`   // Soft delete instead of CASCADE
-	Restores  []Restore      `gorm:"foreignKey:DatabaseId" json:"restores,omitempty"`  // Soft delete instead of CASCADE
-	Schedules []Schedule     `gorm:"foreignKey:DatabaseId" json:"schedules,omitempty"` // Soft delete instead of CASCADE
+	Id                   uint           `gorm:"primaryKey" json:"id"`
+	Name                 string         `gorm:"size:100;not null" json:"name"`
+	Type                 string         `gorm:"size:50;not null" json:"type"` // mysql, postgres
+	Host                 string         `gorm:"size:255;not null" json:"host"`
+	Port                 string         `gorm:"size:10;not null" json:"port"`
+	Username             string         `gorm:"size:100;not null" json:"username"`
+	Password             string         `gorm:"size:255;not null" json:"-"` // Ne pas exposer le mot de passe dans le JSON
+	DbName               string         `gorm:"size:100;not null" json:"db_name"`
+	URL                  string         `gorm:"size:500" json:"url,omitempty"`                      // Full connection URL (optional)
+	PassphraseProtected  bool           `gorm:"not null;default:false" json:"passphrase_protected"` // Zero-knowledge mode for new backups
+	PassphraseKDF        string         `gorm:"size:255" json:"-"`                                  // Argon2id parameters, salt and key-check value
+	PassphrasePublicKey  string         `gorm:"size:64" json:"-"`                                   // base64 X25519 public key backups are sealed to
+	PassphrasePrivateKey string         `gorm:"type:text" json:"-"`                                 // base64 private key sealed with the passphrase
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `gorm:"index" json:"-"`
+	UserId               uint           `gorm:"index;not null" json:"user_id"`
+	User                 User           `gorm:"foreignKey:UserId" json:"-"`
+	Backups              []Backup       `gorm:"foreignKey:DatabaseId" json:"backups,omitempty"`   // Soft delete instead of CASCADE
+	Restores             []Restore      `gorm:"foreignKey:DatabaseId" json:"restores,omitempty"`  // Soft delete instead of CASCADE
+	Schedules            []Schedule     `gorm:"foreignKey:DatabaseId" json:"schedules,omitempty"` // Soft delete instead of CASCADE
+
+	// Team the database belongs to: its members share the database, its backups, schedules and restores
+	// (UserId remains the creator). Nil only for data created before teams were introduced.
+	TeamId *uint `gorm:"index" json:"team_id"`
+
+	// Protected databases require a second user to approve every restore (four-eyes principle)
+	Protected bool `gorm:"not null;default:false" json:"protected"`
+	// Disabling the protection is confirmed by a second user, like the restores it guards
+	UnprotectRequestedBy *uint      `json:"unprotect_requested_by,omitempty"`
+	UnprotectRequestedAt *time.Time `json:"unprotect_requested_at,omitempty"`
 }
 
 // ParseDatabaseURL parses a database connection URL and extracts components
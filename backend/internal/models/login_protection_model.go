@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginProtectionConfig holds the brute-force protection thresholds of the login (0 disables a check)
+type LoginProtectionConfig struct {
+	MaxAttempts     int           // Consecutive failures before the account is locked
+	LockoutDuration time.Duration // First lockout, doubled on every further failure
+	MaxLockout      time.Duration // Upper bound of the lockout duration
+	IPMaxAttempts   int           // Failures per IP address within IPWindow before the address is throttled
+	IPWindow        time.Duration
+	NotifyUser      bool // Email the user when the account is locked or used from a new IP address
+}
+
+// DefaultLoginProtectionConfig returns the thresholds used unless the environment overrides them
+func DefaultLoginProtectionConfig() LoginProtectionConfig {
+	return LoginProtectionConfig{
+		MaxAttempts:     5,
+		LockoutDuration: 15 * time.Minute,
+		MaxLockout:      24 * time.Hour,
+		IPMaxAttempts:   20,
+		IPWindow:        15 * time.Minute,
+	}
+}
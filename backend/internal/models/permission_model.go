@@ -0,0 +1,92 @@
+package models
+
+// Permission est une action autorisée, attribuée aux rôles (table de liaison role_permissions)
+type Permission struct {
+	Id          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Description string `gorm:"size:255" json:"description"`
+}
+
+// Permissions known by the application (checked by AuthMiddleware.RequirePermission)
+const (
+	PermDatabaseRead   = "database.read"
+	PermDatabaseCreate = "database.create"
+	PermDatabaseUpdate = "database.update"
+	PermDatabaseDelete = "database.delete"
+	PermBackupRead     = "backup.read"
+	PermBackupCreate   = "backup.create"
+	PermBackupDownload = "backup.download"
+	PermBackupDelete   = "backup.delete"
+	PermRestoreRead    = "restore.read"
+	PermRestoreExecute = "restore.execute"
+	PermRestoreApprove = "restore.approve" // Second approval of restores into protected databases
+	PermScheduleRead   = "schedule.read"
+	PermScheduleManage = "schedule.manage"
+	PermHistoryRead    = "history.read" // Own action history
+	PermAuditRead      = "audit.read"   // Action history of every user
+	PermUserManage     = "user.manage"
+	PermRoleManage     = "role.manage"
+	PermSettingsManage = "settings.manage"
+	PermKeysManage     = "keys.manage"
+	PermAdminResources = "admin.resources" // Databases, backups, restores and schedules of every user
+)
+
+// PermissionCatalog lists every permission with its description (seeded at startup)
+var PermissionCatalog = []Permission{
+	{Name: PermDatabaseRead, Description: "Consulter ses bases de données"},
+	{Name: PermDatabaseCreate, Description: "Ajouter une base de données"},
+	{Name: PermDatabaseUpdate, Description: "Modifier une base de données (connexion, phrase secrète)"},
+	{Name: PermDatabaseDelete, Description: "Supprimer une base de données"},
+	{Name: PermBackupRead, Description: "Consulter les sauvegardes"},
+	{Name: PermBackupCreate, Description: "Lancer une sauvegarde"},
+	{Name: PermBackupDownload, Description: "Télécharger une sauvegarde"},
+	{Name: PermBackupDelete, Description: "Supprimer une sauvegarde"},
+	{Name: PermRestoreRead, Description: "Consulter les restaurations"},
+	{Name: PermRestoreExecute, Description: "Restaurer une sauvegarde"},
+	{Name: PermRestoreApprove, Description: "Approuver ou rejeter les restaurations vers une base protégée"},
+	{Name: PermScheduleRead, Description: "Consulter les planifications"},
+	{Name: PermScheduleManage, Description: "Créer, modifier et supprimer des planifications"},
+	{Name: PermHistoryRead, Description: "Consulter son historique d'actions"},
+	{Name: PermAuditRead, Description: "Consulter l'historique de tous les utilisateurs"},
+	{Name: PermUserManage, Description: "Gérer les utilisateurs (rôles, activation, sessions, verrouillages)"},
+	{Name: PermRoleManage, Description: "Gérer les rôles et leurs permissions"},
+	{Name: PermSettingsManage, Description: "Modifier les paramètres d'authentification"},
+	{Name: PermKeysManage, Description: "Gérer les clés de chiffrement (rotation)"},
+	{Name: PermAdminResources, Description: "Consulter les bases, sauvegardes, restaurations et planifications de tous les utilisateurs, lancer ou annuler leurs travaux"},
+}
+
+// BuiltInRoles gives the permissions of the roles created at startup. Their permissions are reset on
+// every start and cannot be edited: custom roles are created for other needs.
+var BuiltInRoles = map[string][]string{
+	"admin": permissionNames(PermissionCatalog),
+	"user": {
+		PermDatabaseRead, PermDatabaseCreate, PermDatabaseUpdate, PermDatabaseDelete,
+		PermBackupRead, PermBackupCreate, PermBackupDownload, PermBackupDelete,
+		PermRestoreRead, PermRestoreExecute, PermRestoreApprove, PermScheduleRead, PermScheduleManage, PermHistoryRead,
+	},
+	// Exploitation : sauvegarde et restauration, sans gestion des bases ni des utilisateurs
+	"operator": {
+		PermDatabaseRead, PermBackupRead, PermBackupCreate, PermBackupDownload,
+		PermRestoreRead, PermRestoreExecute, PermRestoreApprove, PermScheduleRead, PermScheduleManage, PermHistoryRead,
+	},
+	// Audit : lecture seule de l'historique
+	"auditor": {PermHistoryRead, PermAuditRead},
+}
+
+// IsKnownPermission reports whether a permission exists in the catalog
+func IsKnownPermission(name string) bool {
+	for _, permission := range PermissionCatalog {
+		if permission.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func permissionNames(permissions []Permission) []string {
+	names := make([]string, len(permissions))
+	for i, permission := range permissions {
+		names[i] = permission.Name
+	}
+	return names
+}
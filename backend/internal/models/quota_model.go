@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// QuotaLimits are the limits applied to a user or a team. 0 means unlimited.
+type QuotaLimits struct {
+	MaxDatabases               int   `json:"max_databases"`
+	MaxSchedules               int   `json:"max_schedules"`
+	MinScheduleIntervalMinutes int   `json:"min_schedule_interval_minutes"` // Plus petit écart autorisé entre deux exécutions
+	MaxBackupBytes             int64 `json:"max_backup_bytes"`              // Taille totale des sauvegardes terminées
+}
+
+// QuotaOverride holds the limits an administrator set for a user or a team (nil keeps the default)
+type QuotaOverride struct {
+	MaxDatabases               *int   `json:"max_databases"`
+	MaxSchedules               *int   `json:"max_schedules"`
+	MinScheduleIntervalMinutes *int   `json:"min_schedule_interval_minutes"`
+	MaxBackupBytes             *int64 `json:"max_backup_bytes"`
+}
+
+// Apply returns the limits with the overridden values replaced
+func (o QuotaOverride) Apply(limits QuotaLimits) QuotaLimits {
+	if o.MaxDatabases != nil {
+		limits.MaxDatabases = *o.MaxDatabases
+	}
+	if o.MaxSchedules != nil {
+		limits.MaxSchedules = *o.MaxSchedules
+	}
+	if o.MinScheduleIntervalMinutes != nil {
+		limits.MinScheduleIntervalMinutes = *o.MinScheduleIntervalMinutes
+	}
+	if o.MaxBackupBytes != nil {
+		limits.MaxBackupBytes = *o.MaxBackupBytes
+	}
+	return limits
+}
+
+// Quota est la surcharge des limites par défaut pour un utilisateur ou une équipe (exactement l'un des deux)
+type Quota struct {
+	Id            uint  `gorm:"primaryKey" json:"id"`
+	UserId        *uint `gorm:"uniqueIndex" json:"user_id,omitempty"`
+	TeamId        *uint `gorm:"uniqueIndex" json:"team_id,omitempty"`
+	QuotaOverride `gorm:"embedded"`
+	UpdatedBy     uint      `json:"updated_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// QuotaUsage is what a user or a team currently consumes
+type QuotaUsage struct {
+	Databases   int64 `json:"databases"`
+	Schedules   int64 `json:"schedules"`
+	BackupBytes int64 `json:"backup_bytes"`
+}
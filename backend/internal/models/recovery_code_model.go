@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode est un code de secours à usage unique pour la double authentification (stocké haché)
+type RecoveryCode struct {
+	Id        uint       `gorm:"primaryKey" json:"id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"` // Hash SHA-256 du code normalisé
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserId    uint       `gorm:"index;not null" json:"user_id"`
+	User      User       `gorm:"foreignKey:UserId" json:"-"`
+}
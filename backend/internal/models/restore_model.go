@@ -8,7 +8,7 @@ import (
 
 type Restore struct {
 	Id         uint           `gorm:"primaryKey" json:"id"`
-	Status     string         `gorm:"size:50;not null" json:"status"` // pending, success, failed
+	Status     string         `gorm:"size:50;not null" json:"status"` // awaiting_approval, pending, running, success, failed, rejected, expired, cancelled
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
@@ -18,4 +18,18 @@ type Restore struct {
 	Backup     Backup         `gorm:"foreignKey:BackupId" json:"-"`
 	DatabaseId uint           `gorm:"index;not null" json:"database_id"`
 	Database   Database       `gorm:"foreignKey:DatabaseId" json:"-"`
+
+	// Four-eyes approval of restores into protected databases
+	ApprovalExpiresAt *time.Time `json:"approval_expires_at,omitempty"`
+	ReviewerId        *uint      `gorm:"index" json:"reviewer_id,omitempty"` // User who approved or rejected the restore
+	ReviewedAt        *time.Time `json:"reviewed_at,omitempty"`
+	RejectionReason   string     `gorm:"size:500" json:"rejection_reason,omitempty"`
 }
+
+// Restore statuses of the approval workflow (the execution uses pending, success and failed)
+const (
+	RestoreStatusAwaitingApproval = "awaiting_approval"
+	RestoreStatusRejected         = "rejected"
+	RestoreStatusExpired          = "expired"
+	RestoreStatusCancelled        = "cancelled" // Cancelled by an administrator before it started
+)
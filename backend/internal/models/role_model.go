@@ -13,4 +13,13 @@ type Role struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"` // if deleted, User.RoleID will be set to null
 	Users     []User         `gorm:"constraint:OnDelete:SET NULL;" json:"users,omitempty"`
+
+	// RequireTwoFactor forces the users of the role to enrol TOTP before they get a session
+	RequireTwoFactor bool `gorm:"default:false" json:"require_two_factor"`
+
+	// Permissions granted to the users of the role; built-in roles (admin, user, operator, auditor) are
+	// seeded at startup and cannot be edited or deleted
+	Description string       `gorm:"size:255" json:"description"`
+	BuiltIn     bool         `gorm:"default:false" json:"built_in"`
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
 }
@@ -4,19 +4,37 @@ import (
 	"time"
 )
 
-// Session représente une session utilisateur avec token JWT et fonctionnalités futures
+// Session représente une session utilisateur (un appareil) : le token d'accès JWT courant et
+// la famille de refresh tokens qui permet de le renouveler.
+// Une demande de réinitialisation de mot de passe est une ligne sans token d'accès valide
+// qui ne porte que ResetToken/ResetExpiresAt.
 // IMPORTANT: Pas de DeletedAt = suppression physique directe des lignes
 type Session struct {
 	Id               uint      `gorm:"primaryKey" json:"id"`
-	Token            string    `gorm:"type:text;not null;uniqueIndex" json:"-"` // Token JWT principal
-	ExpiresAt        time.Time `gorm:"not null" json:"expires_at"`
-	RefreshToken     string    `gorm:"type:text" json:"-"` // Pour refresh automatique (futur)
-	RefreshExpiresAt time.Time `json:"refresh_expires_at"` // Expiration refresh token
-	ResetToken       string    `gorm:"type:text" json:"-"` // Pour reset password (futur)
-	ResetExpiresAt   time.Time `json:"reset_expires_at"`   // Expiration reset token
+	Token            string    `gorm:"type:text;not null;uniqueIndex" json:"-"` // Token d'accès JWT courant (courte durée)
+	ExpiresAt        time.Time `gorm:"not null" json:"expires_at"`              // Expiration du token d'accès
+	FamilyID         string    `gorm:"size:64;index" json:"-"`                  // Famille de refresh tokens (préfixe du refresh token)
+	RefreshToken     string    `gorm:"type:text" json:"-"`                      // Hash SHA-256 du refresh token courant
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`                      // Expiration de la session
+	DeviceName       string    `gorm:"size:100" json:"device_name"`             // Appareil déclaré ou déduit du user agent
+	IPAddress        string    `gorm:"size:45" json:"ip_address"`
+	UserAgent        string    `gorm:"type:text" json:"user_agent"`
+	LastUsedAt       time.Time `json:"last_used_at"`       // Dernière connexion ou rotation
+	ResetToken       string    `gorm:"type:text" json:"-"` // Hash SHA-256 du token de réinitialisation (usage unique)
+	ResetExpiresAt   time.Time `json:"reset_expires_at"`   // Expiration du token de réinitialisation
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 	// DeletedAt supprimé pour forcer la suppression physique
 	UserId uint `gorm:"index;not null" json:"user_id"`
 	User   User `gorm:"foreignKey:UserId" json:"-"`
 }
+
+// ConsumedRefreshToken garde le hash de chaque refresh token déjà échangé d'une famille : le présenter
+// de nouveau, même plusieurs rotations plus tard, est une réutilisation qui révoque toute la famille.
+// Les lignes disparaissent avec leur famille.
+type ConsumedRefreshToken struct {
+	Id        uint      `gorm:"primaryKey" json:"id"`
+	FamilyID  string    `gorm:"size:64;not null;index" json:"-"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex" json:"-"` // Hash SHA-256 du refresh token remplacé
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+// Setting est un paramètre de l'application modifiable à chaud par un administrateur (clé/valeur)
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:100" json:"key"`
+	Value     string    `gorm:"type:text;not null" json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Team roles, from the most to the least privileged
+const (
+	TeamRoleOwner  = "owner"  // Tout, y compris supprimer l'équipe et nommer d'autres propriétaires
+	TeamRoleAdmin  = "admin"  // Gérer les bases de données et les membres
+	TeamRoleMember = "member" // Sauvegarder, restaurer, planifier
+	TeamRoleViewer = "viewer" // Lecture seule
+)
+
+// Team regroupe des utilisateurs qui partagent des bases de données (et leurs sauvegardes, planifications
+// et restaurations). Chaque utilisateur a un espace personnel (Personal) dont il est le seul membre.
+type Team struct {
+	Id        uint         `gorm:"primaryKey" json:"id"`
+	Name      string       `gorm:"size:100;not null" json:"name"`
+	Personal  bool         `gorm:"default:false" json:"personal"`
+	OwnerId   *uint        `gorm:"index" json:"owner_id,omitempty"` // Utilisateur de l'espace personnel
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	Members   []TeamMember `gorm:"constraint:OnDelete:CASCADE;" json:"members,omitempty"`
+
+	// Rôle de l'utilisateur courant dans l'équipe (non stocké)
+	MyRole string `gorm:"-" json:"my_role,omitempty"`
+}
+
+// TeamMember est l'appartenance d'un utilisateur à une équipe, avec son rôle dans l'équipe
+type TeamMember struct {
+	Id        uint      `gorm:"primaryKey" json:"id"`
+	TeamId    uint      `gorm:"uniqueIndex:idx_team_member;not null" json:"team_id"`
+	UserId    uint      `gorm:"uniqueIndex:idx_team_member;index;not null" json:"user_id"`
+	Role      string    `gorm:"size:20;not null" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	Team      Team      `gorm:"foreignKey:TeamId" json:"-"`
+	User      User      `gorm:"foreignKey:UserId" json:"user,omitempty"`
+}
+
+// TeamRoleRank orders team roles (0 = unknown)
+func TeamRoleRank(role string) int {
+	switch role {
+	case TeamRoleOwner:
+		return 4
+	case TeamRoleAdmin:
+		return 3
+	case TeamRoleMember:
+		return 2
+	case TeamRoleViewer:
+		return 1
+	}
+	return 0
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Structure of the user model in the database
@@ -22,4 +24,23 @@ type User struct {
 	Restores  []Restore  `gorm:"constraint:OnDelete:CASCADE;" json:"restores,omitempty"`
 	Alerts    []Alert    `gorm:"constraint:OnDelete:CASCADE;" json:"alerts,omitempty"`
 	Databases []Database `gorm:"constraint:OnDelete:CASCADE;" json:"databases,omitempty"`
+
+	// Two-factor authentication (TOTP): the secret is encrypted with the keyring and set as soon as
+	// enrolment starts, TwoFactorEnabled only once a first code has been confirmed
+	TOTPSecret       string `gorm:"type:text" json:"-"`
+	TwoFactorEnabled bool   `gorm:"default:false" json:"two_factor_enabled"`
+	TOTPLastStep     int64  `gorm:"default:0" json:"-"` // Last accepted time step (a code cannot be replayed)
+
+	// Login protection: consecutive failed logins and temporary lockout (see AuthService.LoginWithMetadata)
+	FailedLoginAttempts int        `gorm:"default:0" json:"failed_login_attempts"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+
+	// Single sign-on: subject ("sub") of the account at the OpenID Connect provider, set on first SSO login
+	OIDCSubject *string `gorm:"column:oidc_subject;size:255;uniqueIndex" json:"-"`
+
+	// Deleted accounts are scrubbed then soft deleted, so their action history keeps referring to them
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Erasure requested by the user (GDPR): the account is erased at this date unless the request is cancelled
+	ErasureScheduledAt *time.Time `gorm:"index" json:"erasure_scheduled_at,omitempty"`
 }
@@ -1,13 +1,33 @@
 package repositories
 
 import (
+	"sync"
+	"time"
+
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"gorm.io/gorm"
 )
 
+// historyChainMu serializes the inserts of this process so each row is chained to the previous one. It is the
+// only lock on SQLite; on PostgreSQL lockHistoryChain also serializes the other processes.
+var historyChainMu sync.Mutex
+
+// historyChainLockKey identifies the PostgreSQL advisory lock of the chain ("sbchain")
+const historyChainLockKey int64 = 0x7362636861696e
+
+// lockHistoryChain takes the chain lock of the database until the end of the transaction, so processes sharing
+// the database never chain two rows to the same previous hash
+func lockHistoryChain(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", historyChainLockKey).Error
+}
+
 // ActionHistoryRepository manages action history records
 type ActionHistoryRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	listener func(actionHistory *models.ActionHistory)
 }
 
 // NewActionHistoryRepository creates a new ActionHistoryRepository
@@ -15,9 +35,127 @@ func NewActionHistoryRepository(db *gorm.DB) *ActionHistoryRepository {
 	return &ActionHistoryRepository{db: db}
 }
 
-// Create creates a new action history record
+// SetListener registers a function called with every record created through the repository, in chain order.
+// It runs while inserts are serialized and must not block.
+func (r *ActionHistoryRepository) SetListener(listener func(actionHistory *models.ActionHistory)) {
+	historyChainMu.Lock()
+	defer historyChainMu.Unlock()
+	r.listener = listener
+}
+
+// Create creates a new action history record chained to the last one (see models.ActionHistory.ComputeHash)
 func (r *ActionHistoryRepository) Create(actionHistory *models.ActionHistory) error {
-	return r.db.Create(actionHistory).Error
+	historyChainMu.Lock()
+	defer historyChainMu.Unlock()
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockHistoryChain(tx); err != nil {
+			return err
+		}
+		var last models.ActionHistory
+		err := tx.Select("id", "hash").Where("hash <> ''").Order("id DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+		if actionHistory.CreatedAt.IsZero() {
+			actionHistory.CreatedAt = time.Now()
+		}
+		// PostgreSQL keeps microseconds: the hash must be computed on the stored value
+		actionHistory.CreatedAt = actionHistory.CreatedAt.Truncate(time.Microsecond)
+		actionHistory.PrevHash = last.Hash
+		actionHistory.PrivacyDigest = actionHistory.ComputePrivacyDigest()
+		actionHistory.Hash = actionHistory.ComputeHash()
+		return tx.Create(actionHistory).Error
+	})
+	if err == nil && r.listener != nil {
+		r.listener(actionHistory)
+	}
+	return err
+}
+
+// SealLegacyRows chains the rows recorded before hash chaining existed. It only runs while no row is chained yet.
+func (r *ActionHistoryRepository) SealLegacyRows() (int64, error) {
+	historyChainMu.Lock()
+	defer historyChainMu.Unlock()
+
+	var sealed int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockHistoryChain(tx); err != nil {
+			return err
+		}
+		var chained int64
+		if err := tx.Model(&models.ActionHistory{}).Where("hash <> ''").Count(&chained).Error; err != nil {
+			return err
+		}
+		if chained > 0 {
+			return nil
+		}
+
+		prevHash := ""
+		var rows []models.ActionHistory
+		return tx.Order("id").FindInBatches(&rows, 500, func(batch *gorm.DB, _ int) error {
+			for i := range rows {
+				row := &rows[i]
+				row.PrevHash = prevHash
+				row.PrivacyDigest = row.ComputePrivacyDigest()
+				row.Hash = row.ComputeHash()
+				if err := tx.Model(&models.ActionHistory{}).Where("id = ?", row.Id).UpdateColumns(map[string]interface{}{
+					"prev_hash":      row.PrevHash,
+					"privacy_digest": row.PrivacyDigest,
+					"hash":           row.Hash,
+				}).Error; err != nil {
+					return err
+				}
+				prevHash = row.Hash
+				sealed++
+			}
+			return nil
+		}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sealed, nil
+}
+
+// WalkChain calls fn with the action history rows in chain order, by batches
+func (r *ActionHistoryRepository) WalkChain(batchSize int, fn func(rows []models.ActionHistory) error) error {
+	var rows []models.ActionHistory
+	return r.db.Order("id").FindInBatches(&rows, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(rows)
+	}).Error
+}
+
+// GetChainHead returns the last chained row and the number of chained rows
+func (r *ActionHistoryRepository) GetChainHead() (*models.ActionHistory, int64, error) {
+	var head models.ActionHistory
+	if err := r.db.Where("hash <> ''").Order("id DESC").First(&head).Error; err != nil {
+		return nil, 0, err
+	}
+	var count int64
+	if err := r.db.Model(&models.ActionHistory{}).Where("hash <> ''").Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	return &head, count, nil
+}
+
+// GetErasedUserIDs returns the users whose account was erased on their request (their IP addresses are blank)
+func (r *ActionHistoryRepository) GetErasedUserIDs() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.ActionHistory{}).Distinct("user_id").Where("action = ?", "account_erased").Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// CreateAnchor records a chain head copied into backup storage
+func (r *ActionHistoryRepository) CreateAnchor(anchor *models.AuditAnchor) error {
+	return r.db.Create(anchor).Error
+}
+
+// GetAnchors returns the anchors of the chain, most recent first
+func (r *ActionHistoryRepository) GetAnchors(limit int) ([]models.AuditAnchor, error) {
+	var anchors []models.AuditAnchor
+	err := r.db.Order("id DESC").Limit(limit).Find(&anchors).Error
+	return anchors, err
 }
 
 // GetByID gets an action history record by ID
@@ -121,6 +259,77 @@ func (r *ActionHistoryRepository) GetRecentCount() (int64, error) {
 	return count, err
 }
 
+// preloadUser loads the author of the rows, including deleted accounts
+func preloadUser(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// Search gets the action history records matching a filter, most recent first. With a cursor the records
+// older than the cursor are returned, otherwise the requested page.
+func (r *ActionHistoryRepository) Search(filter HistoryFilter) ([]models.ActionHistory, error) {
+	var actionHistories []models.ActionHistory
+	query := filter.apply(r.db.Preload("User", preloadUser))
+	if filter.Cursor != 0 {
+		query = query.Where("id < ?", filter.Cursor)
+	} else if filter.Page > 1 {
+		query = query.Offset((filter.Page - 1) * filter.Limit)
+	}
+	err := query.Order("id DESC").Limit(filter.Limit).Find(&actionHistories).Error
+	return actionHistories, err
+}
+
+// Count gets the total count of action history records matching a filter, its cursor ignored
+func (r *ActionHistoryRepository) Count(filter HistoryFilter) (int64, error) {
+	var count int64
+	err := filter.apply(r.db.Model(&models.ActionHistory{})).Count(&count).Error
+	return count, err
+}
+
+// Stream calls fn with every action history record matching a filter, most recent first, by batches. Each batch
+// starts after the last row of the previous one, so rows logged during the walk do not shift it.
+func (r *ActionHistoryRepository) Stream(filter HistoryFilter, batchSize int, fn func(rows []models.ActionHistory) error) error {
+	cursor := filter.Cursor
+	for {
+		var rows []models.ActionHistory
+		query := filter.apply(r.db.Preload("User", preloadUser))
+		if cursor != 0 {
+			query = query.Where("id < ?", cursor)
+		}
+		if err := query.Order("id DESC").Limit(batchSize).Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := fn(rows); err != nil {
+			return err
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+		cursor = rows[len(rows)-1].Id
+	}
+}
+
+// ExistsForUserActionAndIP reports whether the user already performed an action from an IP address
+func (r *ActionHistoryRepository) ExistsForUserActionAndIP(userID uint, action, ipAddress string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.ActionHistory{}).
+		Where("user_id = ? AND action = ? AND ip_address = ?", userID, action, ipAddress).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CountForUserAction counts the records of an action for a user
+func (r *ActionHistoryRepository) CountForUserAction(userID uint, action string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ActionHistory{}).
+		Where("user_id = ? AND action = ?", userID, action).
+		Count(&count).Error
+	return count, err
+}
+
 // GetDB returns the database connection
 func (r *ActionHistoryRepository) GetDB() *gorm.DB {
 	return r.db
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"strings"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"gorm.io/gorm"
+)
+
+// AdminFilter filters the admin-wide listings of databases, backups, restores and schedules (zero values are ignored)
+type AdminFilter struct {
+	UserId     uint // Propriétaire (créateur) de la ressource
+	TeamId     uint // Équipe de la base de données
+	DatabaseId uint
+	Status     string // Statut d'une sauvegarde ou d'une restauration ; active/inactive pour une planification
+	Search     string // Nom de la base de données
+	Page       int
+	Limit      int
+}
+
+// apply adds the owner, team and database conditions shared by every listing
+func (f AdminFilter) apply(db *gorm.DB, query *gorm.DB, databaseColumn string) *gorm.DB {
+	if f.UserId != 0 {
+		query = query.Where("user_id = ?", f.UserId)
+	}
+	if f.DatabaseId != 0 {
+		query = query.Where(databaseColumn+" = ?", f.DatabaseId)
+	}
+	if f.TeamId != 0 || f.Search != "" {
+		databases := db.Unscoped().Model(&models.Database{}).Select("id")
+		if f.TeamId != 0 {
+			databases = databases.Where("team_id = ?", f.TeamId)
+		}
+		if f.Search != "" {
+			databases = databases.Where("LOWER(name) LIKE ?", "%"+strings.ToLower(f.Search)+"%")
+		}
+		query = query.Where(databaseColumn+" IN (?)", databases)
+	}
+	return query
+}
+
+// paginate counts the matching rows and loads the requested page, most recent first
+func (f AdminFilter) paginate(query *gorm.DB, dest interface{}) (int64, error) {
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, err
+	}
+	err := query.Order("created_at DESC").Limit(f.Limit).Offset((f.Page - 1) * f.Limit).Find(dest).Error
+	return total, err
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"gorm.io/gorm"
+)
+
+// APITokenRepository gère les tokens d'API personnels
+type APITokenRepository struct {
+	db *gorm.DB
+}
+
+// NewAPITokenRepository constructeur
+func NewAPITokenRepository(db *gorm.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+// Create enregistre un nouveau token
+func (r *APITokenRepository) Create(token *models.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetByHash retourne un token actif (non révoqué, non expiré) par son hash, avec son utilisateur et son rôle
+func (r *APITokenRepository) GetByHash(tokenHash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.Preload("User").Preload("User.Role").
+		Where("token_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", tokenHash, time.Now()).
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetByUser retourne les tokens d'un utilisateur, révoqués compris (du plus récent au plus ancien)
+func (r *APITokenRepository) GetByUser(userId uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.Where("user_id = ?", userId).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// CountActiveForUser compte les tokens utilisables d'un utilisateur
+func (r *APITokenRepository) CountActiveForUser(userId uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userId, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+// Revoke révoque un token de l'utilisateur. Retourne false s'il n'existe pas ou est déjà révoqué.
+func (r *APITokenRepository) Revoke(id, userId uint) (bool, error) {
+	result := r.db.Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userId).
+		UpdateColumn("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeAllForUser révoque tous les tokens d'un utilisateur
+func (r *APITokenRepository) RevokeAllForUser(userId uint) (int64, error) {
+	result := r.db.Model(&models.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		UpdateColumn("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// TouchLastUsed enregistre la dernière utilisation d'un token
+func (r *APITokenRepository) TouchLastUsed(id uint, usedAt time.Time, ipAddress string) error {
+	return r.db.Model(&models.APIToken{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ipAddress}).Error
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"time"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
@@ -39,6 +40,13 @@ func (r *BackupRepository) GetByUserID(userID uint) ([]models.Backup, error) {
 	return backups, err
 }
 
+// GetAccessibleByUser returns the backups of the databases a user can access
+func (r *BackupRepository) GetAccessibleByUser(userID uint) ([]models.Backup, error) {
+	var backups []models.Backup
+	err := r.db.Preload("Database").Where("database_id IN (?)", accessibleDatabaseIDs(r.db, userID)).Find(&backups).Error
+	return backups, err
+}
+
 // Get all backups for a database
 func (r *BackupRepository) GetByDatabaseID(databaseID uint) ([]models.Backup, error) {
 	var backups []models.Backup
@@ -62,6 +70,49 @@ func (r *BackupRepository) UpdateFileInfo(id uint, filepath string, size int64)
 	}).Error
 }
 
+// UpdateEncryptionKey records the wrapped data key of a backup and the master key ID wrapping it
+func (r *BackupRepository) UpdateEncryptionKey(id uint, keyID string, wrappedKey string) error {
+	return r.db.Unscoped().Model(&models.Backup{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"key_id":      keyID,
+		"wrapped_key": wrappedKey,
+	}).Error
+}
+
+// UpdateObjectEncryption points a backup to a re-encrypted object and records its wrapped data key
+func (r *BackupRepository) UpdateObjectEncryption(id uint, filepath string, keyID string, wrappedKey string) error {
+	return r.db.Unscoped().Model(&models.Backup{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"filepath":    filepath,
+		"key_id":      keyID,
+		"wrapped_key": wrappedKey,
+	}).Error
+}
+
+// GetAllForKeyRotation returns every backup with an encrypted object, soft deleted ones included
+func (r *BackupRepository) GetAllForKeyRotation() ([]models.Backup, error) {
+	var backups []models.Backup
+	err := r.db.Unscoped().Where("status = ?", "completed").Order("id").Find(&backups).Error
+	return backups, err
+}
+
+// CountByKeyID returns the number of completed backups per master key ID ("" for legacy backups)
+func (r *BackupRepository) CountByKeyID() (map[string]int64, error) {
+	var rows []struct {
+		KeyID string
+		Count int64
+	}
+	err := r.db.Unscoped().Model(&models.Backup{}).Select("key_id, count(*) as count").
+		Where("status = ?", "completed").Group("key_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.KeyID] = row.Count
+	}
+	return counts, nil
+}
+
 // Get old backups for cleanup (older than specified days)
 func (r *BackupRepository) GetOldBackups(days int) ([]models.Backup, error) {
 	cutoffDate := time.Now().AddDate(0, 0, -days)
@@ -75,6 +126,11 @@ func (r *BackupRepository) GetDB() *gorm.DB {
 	return r.db
 }
 
+// WithContext returns a copy of the repository whose queries carry ctx (trace span, cancellation)
+func (r *BackupRepository) WithContext(ctx context.Context) *BackupRepository {
+	return &BackupRepository{db: r.db.WithContext(ctx)}
+}
+
 // Delete backup
 func (r *BackupRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Backup{}, id).Error
@@ -84,3 +140,21 @@ func (r *BackupRepository) Delete(id uint) error {
 func (r *BackupRepository) SoftDeleteByDatabaseID(databaseID uint) error {
 	return r.db.Where("database_id = ?", databaseID).Delete(&models.Backup{}).Error
 }
+
+// ListForAdmin returns the backups of every user matching the filter, with their creator and database
+func (r *BackupRepository) ListForAdmin(filter AdminFilter) ([]models.Backup, int64, error) {
+	var backups []models.Backup
+	query := filter.apply(r.db, r.db.Model(&models.Backup{}), "database_id").
+		Preload("User").Preload("Database", func(db *gorm.DB) *gorm.DB { return db.Unscoped() })
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	total, err := filter.paginate(query, &backups)
+	return backups, total, err
+}
+
+// TransitionStatus changes the status of a backup only if it is currently one of the given statuses
+func (r *BackupRepository) TransitionStatus(id uint, from []string, to string) (bool, error) {
+	result := r.db.Model(&models.Backup{}).Where("id = ? AND status IN ?", id, from).Update("status", to)
+	return result.RowsAffected == 1, result.Error
+}
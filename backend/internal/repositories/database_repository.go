@@ -1,7 +1,7 @@
 package repositories
 
 import (
-	"fmt"
+	"time"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"gorm.io/gorm"
@@ -24,14 +24,11 @@ func (r *DatabaseRepository) Create(database *models.Database) error {
 
 // Get database by ID
 func (r *DatabaseRepository) GetByID(id uint) (*models.Database, error) {
-	fmt.Printf("[DEBUG] DatabaseRepository.GetByID: Querying database ID %d\n", id)
 	var database models.Database
 	err := r.db.Preload("User").First(&database, id).Error
 	if err != nil {
-		fmt.Printf("[DEBUG] DatabaseRepository.GetByID: Database ID %d not found in DB - error: %v\n", id, err)
 		return nil, err
 	}
-	fmt.Printf("[DEBUG] DatabaseRepository.GetByID: Found database ID %d, name '%s'\n", database.Id, database.Name)
 	return &database, nil
 }
 
@@ -42,6 +39,25 @@ func (r *DatabaseRepository) GetByUserID(userID uint) ([]models.Database, error)
 	return databases, err
 }
 
+// GetAccessibleByUser returns the databases of the teams of a user (and their own databases without a team)
+func (r *DatabaseRepository) GetAccessibleByUser(userID uint) ([]models.Database, error) {
+	var databases []models.Database
+	err := r.db.Where("team_id IN (?) OR (team_id IS NULL AND user_id = ?)", userTeamIDs(r.db, userID), userID).Find(&databases).Error
+	return databases, err
+}
+
+// userTeamIDs is the subquery of the teams a user belongs to
+func userTeamIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.TeamMember{}).Select("team_id").Where("user_id = ?", userID)
+}
+
+// accessibleDatabaseIDs is the subquery of the databases a user can access, soft deleted ones included
+// (their backups, schedules and restores remain visible)
+func accessibleDatabaseIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Unscoped().Model(&models.Database{}).Select("id").
+		Where("team_id IN (?) OR (team_id IS NULL AND user_id = ?)", userTeamIDs(db, userID), userID)
+}
+
 // Update database
 func (r *DatabaseRepository) Update(database *models.Database) error {
 	return r.db.Save(database).Error
@@ -49,14 +65,50 @@ func (r *DatabaseRepository) Update(database *models.Database) error {
 
 // UpdateDatabaseName updates only the name field of a database
 func (r *DatabaseRepository) UpdateDatabaseName(id uint, name string) error {
-	fmt.Printf("[DEBUG] DatabaseRepository.UpdateDatabaseName: Updating database ID %d with name '%s'\n", id, name)
-	err := r.db.Model(&models.Database{}).Where("id = ?", id).Update("name", name).Error
-	if err != nil {
-		fmt.Printf("[DEBUG] DatabaseRepository.UpdateDatabaseName: Update failed - error: %v\n", err)
-		return err
-	}
-	fmt.Printf("[DEBUG] DatabaseRepository.UpdateDatabaseName: Update succeeded for database ID %d\n", id)
-	return nil
+	return r.db.Model(&models.Database{}).Where("id = ?", id).Update("name", name).Error
+}
+
+// GetAllForKeyRotation returns every database with its encrypted credentials, soft deleted ones included
+func (r *DatabaseRepository) GetAllForKeyRotation() ([]models.Database, error) {
+	var databases []models.Database
+	err := r.db.Unscoped().Order("id").Find(&databases).Error
+	return databases, err
+}
+
+// UpdateCredentials replaces the encrypted password and URL columns without touching other fields
+func (r *DatabaseRepository) UpdateCredentials(id uint, password, url string) error {
+	return r.db.Unscoped().Model(&models.Database{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password": password,
+		"url":      url,
+	}).Error
+}
+
+// UpdatePassphraseKey stores the zero-knowledge key pair of a database and whether new backups use it
+func (r *DatabaseRepository) UpdatePassphraseKey(id uint, protected bool, kdf, publicKey, privateKey string) error {
+	return r.db.Model(&models.Database{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"passphrase_protected":   protected,
+		"passphrase_kdf":         kdf,
+		"passphrase_public_key":  publicKey,
+		"passphrase_private_key": privateKey,
+	}).Error
+}
+
+// UpdateProtected enables or disables the approval of restores into a database and clears any
+// pending request to disable it
+func (r *DatabaseRepository) UpdateProtected(id uint, protected bool) error {
+	return r.db.Model(&models.Database{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"protected":              protected,
+		"unprotect_requested_by": nil,
+		"unprotect_requested_at": nil,
+	}).Error
+}
+
+// RequestUnprotect records the user asking to disable the protection of a database
+func (r *DatabaseRepository) RequestUnprotect(id, userID uint, requestedAt time.Time) error {
+	return r.db.Model(&models.Database{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"unprotect_requested_by": userID,
+		"unprotect_requested_at": requestedAt,
+	}).Error
 }
 
 // Delete database
@@ -68,3 +120,11 @@ func (r *DatabaseRepository) Delete(id uint) error {
 func (r *DatabaseRepository) SoftDelete(id uint) error {
 	return r.db.Delete(&models.Database{}, id).Error // GORM automatically does soft delete with DeletedAt field
 }
+
+// ListForAdmin returns the databases of every user matching the filter, with their creator
+func (r *DatabaseRepository) ListForAdmin(filter AdminFilter) ([]models.Database, int64, error) {
+	var databases []models.Database
+	query := filter.apply(r.db, r.db.Model(&models.Database{}), "id").Preload("User")
+	total, err := filter.paginate(query, &databases)
+	return databases, total, err
+}
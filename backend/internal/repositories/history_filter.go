@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// historyMetadataKeyPattern restricts the metadata keys usable in a filter, they are written into the query
+var historyMetadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// HistoryFilter filters the action history (zero values are ignored)
+type HistoryFilter struct {
+	UserId       uint
+	Actions      []string
+	ResourceType string
+	ResourceId   uint
+	IpAddress    string
+	Search       string            // Texte contenu dans la description
+	From         *time.Time        // Actions effectuées à partir de cette date
+	To           *time.Time        // Actions effectuées jusqu'à cette date incluse
+	Metadata     map[string]string // Valeurs attendues des métadonnées, par exemple database_id=3
+	AdminAction  *bool
+	Cursor       uint // ID de la dernière ligne de la page précédente : seules les lignes plus anciennes sont retournées
+	Page         int  // Ignorée lorsqu'un curseur est fourni
+	Limit        int
+}
+
+// IsValidHistoryMetadataKey reports whether a metadata key can be used in a filter
+func IsValidHistoryMetadataKey(key string) bool {
+	return historyMetadataKeyPattern.MatchString(key)
+}
+
+// apply adds the conditions of the filter, the cursor and the pagination excepted
+func (f HistoryFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.UserId != 0 {
+		query = query.Where("user_id = ?", f.UserId)
+	}
+	if len(f.Actions) == 1 {
+		query = query.Where("action = ?", f.Actions[0])
+	} else if len(f.Actions) > 1 {
+		query = query.Where("action IN ?", f.Actions)
+	}
+	if f.ResourceType != "" {
+		query = query.Where("resource_type = ?", f.ResourceType)
+	}
+	if f.ResourceId != 0 {
+		query = query.Where("resource_id = ?", f.ResourceId)
+	}
+	if f.IpAddress != "" {
+		query = query.Where("ip_address = ?", f.IpAddress)
+	}
+	if f.Search != "" {
+		query = query.Where(`LOWER(description) LIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(strings.ToLower(f.Search))+"%")
+	}
+	if f.From != nil {
+		query = query.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("created_at <= ?", *f.To)
+	}
+	if f.AdminAction != nil {
+		query = query.Where("admin_action = ?", *f.AdminAction)
+	}
+	for key, value := range f.Metadata {
+		if !IsValidHistoryMetadataKey(key) {
+			// Never reached through the handlers, which reject such keys: match nothing rather than everything
+			query = query.Where("1 = 0")
+			continue
+		}
+		query = query.Where(metadataField(query, key)+" = ?", value)
+	}
+	return query
+}
+
+// metadataField returns the expression reading a metadata key as text. The metadata column is jsonb on
+// PostgreSQL and plain text with SQLite (tests), where rows without metadata hold an empty string.
+func metadataField(query *gorm.DB, key string) string {
+	if query.Dialector.Name() == "postgres" {
+		return "metadata->>'" + key + "'"
+	}
+	return "CAST(json_extract(CASE WHEN json_valid(metadata) THEN metadata END, '$." + key + "') AS TEXT)"
+}
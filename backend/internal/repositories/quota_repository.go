@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"gorm.io/gorm"
+)
+
+// QuotaRepository gère les surcharges de quotas et le calcul de la consommation
+type QuotaRepository struct {
+	db *gorm.DB
+}
+
+// NewQuotaRepository constructeur
+func NewQuotaRepository(db *gorm.DB) *QuotaRepository {
+	return &QuotaRepository{db: db}
+}
+
+// GetByUser returns the quota override of a user (nil when the defaults apply)
+func (r *QuotaRepository) GetByUser(userID uint) (*models.Quota, error) {
+	return r.first(r.db.Where("user_id = ?", userID))
+}
+
+// GetByTeam returns the quota override of a team (nil when the team is not limited)
+func (r *QuotaRepository) GetByTeam(teamID uint) (*models.Quota, error) {
+	return r.first(r.db.Where("team_id = ?", teamID))
+}
+
+func (r *QuotaRepository) first(query *gorm.DB) (*models.Quota, error) {
+	var quota models.Quota
+	err := query.First(&quota).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// GetLimitedTeamsOfUser returns the overrides of the teams a user belongs to
+func (r *QuotaRepository) GetLimitedTeamsOfUser(userID uint) ([]models.Quota, error) {
+	var quotas []models.Quota
+	err := r.db.Where("team_id IN (?)", userTeamIDs(r.db, userID)).Order("team_id").Find(&quotas).Error
+	return quotas, err
+}
+
+// Save creates or replaces an override
+func (r *QuotaRepository) Save(quota *models.Quota) error {
+	return r.db.Save(quota).Error
+}
+
+// Delete removes an override, the defaults apply again
+func (r *QuotaRepository) Delete(quota *models.Quota) error {
+	return r.db.Delete(quota).Error
+}
+
+// GetUserUsage returns the databases, schedules and completed backups created by a user
+func (r *QuotaRepository) GetUserUsage(userID uint) (models.QuotaUsage, error) {
+	var usage models.QuotaUsage
+	if err := r.db.Model(&models.Database{}).Where("user_id = ?", userID).Count(&usage.Databases).Error; err != nil {
+		return usage, err
+	}
+	if err := r.db.Model(&models.Schedule{}).Where("user_id = ?", userID).Count(&usage.Schedules).Error; err != nil {
+		return usage, err
+	}
+	err := r.db.Model(&models.Backup{}).Select("COALESCE(SUM(size), 0)").
+		Where("user_id = ? AND status = ?", userID, "completed").Scan(&usage.BackupBytes).Error
+	return usage, err
+}
+
+// GetTeamUsage returns the databases of a team with their schedules and completed backups
+func (r *QuotaRepository) GetTeamUsage(teamID uint) (models.QuotaUsage, error) {
+	var usage models.QuotaUsage
+	if err := r.db.Model(&models.Database{}).Where("team_id = ?", teamID).Count(&usage.Databases).Error; err != nil {
+		return usage, err
+	}
+	teamDatabases := r.db.Unscoped().Model(&models.Database{}).Select("id").Where("team_id = ?", teamID)
+	if err := r.db.Model(&models.Schedule{}).Where("database_id IN (?)", teamDatabases).Count(&usage.Schedules).Error; err != nil {
+		return usage, err
+	}
+	err := r.db.Model(&models.Backup{}).Select("COALESCE(SUM(size), 0)").
+		Where("database_id IN (?) AND status = ?", teamDatabases, "completed").Scan(&usage.BackupBytes).Error
+	return usage, err
+}
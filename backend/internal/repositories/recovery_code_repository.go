@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepository gère les codes de secours de la double authentification
+type RecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository constructeur
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+// ReplaceForUser remplace tous les codes de secours d'un utilisateur par de nouveaux hashes
+func (r *RecoveryCodeRepository) ReplaceForUser(userId uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.RecoveryCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = models.RecoveryCode{UserId: userId, CodeHash: hash}
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseCode marque un code de secours comme utilisé. Retourne false s'il n'existe pas ou a déjà servi.
+func (r *RecoveryCodeRepository) UseCode(userId uint, codeHash string) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountRemaining retourne le nombre de codes de secours encore utilisables
+func (r *RecoveryCodeRepository) CountRemaining(userId uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userId).Count(&count).Error
+	return count, err
+}
+
+// DeleteForUser supprime tous les codes de secours d'un utilisateur
+func (r *RecoveryCodeRepository) DeleteForUser(userId uint) error {
+	return r.db.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error
+}
@@ -1,6 +1,9 @@
 package repositories
 
 import (
+	"context"
+	"time"
+
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"gorm.io/gorm"
 )
@@ -37,6 +40,14 @@ func (r *RestoreRepository) GetByUserID(userID uint) ([]models.Restore, error) {
 	return restores, err
 }
 
+// GetAccessibleByUser returns the restores of the databases a user can access
+func (r *RestoreRepository) GetAccessibleByUser(userID uint) ([]models.Restore, error) {
+	var restores []models.Restore
+	err := r.db.Preload("Database").Preload("User").Preload("Backup").
+		Where("database_id IN (?)", accessibleDatabaseIDs(r.db, userID)).Find(&restores).Error
+	return restores, err
+}
+
 // Get all restores for a database
 func (r *RestoreRepository) GetByDatabaseID(databaseID uint) ([]models.Restore, error) {
 	var restores []models.Restore
@@ -58,11 +69,53 @@ func (r *RestoreRepository) UpdateStatus(id uint, status string) error {
 	}).Error
 }
 
+// GetAwaitingApproval returns the restores waiting for a second approval, oldest first
+func (r *RestoreRepository) GetAwaitingApproval() ([]models.Restore, error) {
+	var restores []models.Restore
+	err := r.db.Preload("Database").Preload("User").Preload("Backup").
+		Where("status = ?", models.RestoreStatusAwaitingApproval).Order("created_at").Find(&restores).Error
+	return restores, err
+}
+
+// CountAwaitingApprovalForDatabase counts the restores into a database waiting for a second approval
+func (r *RestoreRepository) CountAwaitingApprovalForDatabase(databaseID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Restore{}).
+		Where("database_id = ? AND status = ?", databaseID, models.RestoreStatusAwaitingApproval).Count(&count).Error
+	return count, err
+}
+
+// Review moves a restore out of awaiting_approval; it reports false when another reviewer was faster
+func (r *RestoreRepository) Review(id uint, status string, reviewerID *uint, reviewedAt time.Time, reason string) (bool, error) {
+	result := r.db.Model(&models.Restore{}).
+		Where("id = ? AND status = ?", id, models.RestoreStatusAwaitingApproval).
+		Updates(map[string]interface{}{
+			"status":           status,
+			"reviewer_id":      reviewerID,
+			"reviewed_at":      reviewedAt,
+			"rejection_reason": reason,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetExpiredApprovals returns the restores whose approval window is over
+func (r *RestoreRepository) GetExpiredApprovals(now time.Time) ([]models.Restore, error) {
+	var restores []models.Restore
+	err := r.db.Preload("Database").Preload("User").Preload("Backup").
+		Where("status = ? AND approval_expires_at <= ?", models.RestoreStatusAwaitingApproval, now).Find(&restores).Error
+	return restores, err
+}
+
 // GetDB returns the database connection (for advanced queries)
 func (r *RestoreRepository) GetDB() *gorm.DB {
 	return r.db
 }
 
+// WithContext returns a copy of the repository whose queries carry ctx (trace span, cancellation)
+func (r *RestoreRepository) WithContext(ctx context.Context) *RestoreRepository {
+	return &RestoreRepository{db: r.db.WithContext(ctx)}
+}
+
 // Delete restore
 func (r *RestoreRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Restore{}, id).Error
@@ -72,3 +125,22 @@ func (r *RestoreRepository) Delete(id uint) error {
 func (r *RestoreRepository) SoftDeleteByDatabaseID(databaseID uint) error {
 	return r.db.Where("database_id = ?", databaseID).Delete(&models.Restore{}).Error
 }
+
+// ListForAdmin returns the restores of every user matching the filter, with their creator, backup and database
+func (r *RestoreRepository) ListForAdmin(filter AdminFilter) ([]models.Restore, int64, error) {
+	var restores []models.Restore
+	query := filter.apply(r.db, r.db.Model(&models.Restore{}), "database_id").
+		Preload("User").Preload("Backup", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Database", func(db *gorm.DB) *gorm.DB { return db.Unscoped() })
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	total, err := filter.paginate(query, &restores)
+	return restores, total, err
+}
+
+// TransitionStatus changes the status of a restore only if it is currently one of the given statuses
+func (r *RestoreRepository) TransitionStatus(id uint, from []string, to string) (bool, error) {
+	result := r.db.Model(&models.Restore{}).Where("id = ? AND status IN ?", id, from).Update("status", to)
+	return result.RowsAffected == 1, result.Error
+}
@@ -19,7 +19,7 @@ func (r *RoleRepository) Create(role *models.Role) error {
 
 func (r *RoleRepository) GetAll() ([]models.Role, error) {
 	var roles []models.Role
-	if err := r.db.Find(&roles).Error; err != nil {
+	if err := r.db.Preload("Permissions").Order("id").Find(&roles).Error; err != nil {
 		return nil, err
 	}
 	return roles, nil
@@ -27,7 +27,7 @@ func (r *RoleRepository) GetAll() ([]models.Role, error) {
 
 func (r *RoleRepository) GetByID(id uint) (*models.Role, error) {
 	var role models.Role
-	if err := r.db.First(&role, id).Error; err != nil {
+	if err := r.db.Preload("Permissions").First(&role, id).Error; err != nil {
 		return nil, err
 	}
 	return &role, nil
@@ -39,3 +39,86 @@ func (r *UserRepository) UpdateUserRole(userID uint, newRoleID uint) error {
 		Update("role_id", newRoleID).
 		Error
 }
+
+// SetRequireTwoFactor active ou désactive la double authentification obligatoire pour un rôle
+func (r *RoleRepository) SetRequireTwoFactor(id uint, required bool) error {
+	return r.db.Model(&models.Role{}).Where("id = ?", id).Update("require_two_factor", required).Error
+}
+
+// GetPermissionNamesByRole retourne les noms des permissions d'un rôle
+func (r *RoleRepository) GetPermissionNamesByRole(roleName string) ([]string, error) {
+	var names []string
+	err := r.db.Table("permissions").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id AND roles.deleted_at IS NULL").
+		Where("roles.name = ?", roleName).
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
+
+// GetAllPermissions retourne le catalogue des permissions
+func (r *RoleRepository) GetAllPermissions() ([]models.Permission, error) {
+	var permissions []models.Permission
+	if err := r.db.Order("id").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// GetPermissionsByNames retourne les permissions correspondant à une liste de noms
+func (r *RoleRepository) GetPermissionsByNames(names []string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	if err := r.db.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// ReplacePermissions remplace les permissions d'un rôle
+func (r *RoleRepository) ReplacePermissions(role *models.Role, permissions []models.Permission) error {
+	return r.db.Model(role).Association("Permissions").Replace(permissions)
+}
+
+// CountUsers compte les utilisateurs d'un rôle
+func (r *RoleRepository) CountUsers(roleID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("role_id = ?", roleID).Count(&count).Error
+	return count, err
+}
+
+// Delete supprime définitivement un rôle et ses liaisons de permissions
+func (r *RoleRepository) Delete(role *models.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(role).Error // Libère le nom (index unique)
+	})
+}
+
+// EnsurePermission crée une permission si elle n'existe pas et met sa description à jour
+func (r *RoleRepository) EnsurePermission(definition models.Permission) error {
+	permission := models.Permission{Name: definition.Name}
+	if err := r.db.Where("name = ?", definition.Name).Attrs(models.Permission{Description: definition.Description}).
+		FirstOrCreate(&permission).Error; err != nil {
+		return err
+	}
+	if permission.Description == definition.Description {
+		return nil
+	}
+	return r.db.Model(&permission).Update("description", definition.Description).Error
+}
+
+// EnsureBuiltInRole crée un rôle intégré s'il n'existe pas et le marque comme intégré
+func (r *RoleRepository) EnsureBuiltInRole(name string) (*models.Role, error) {
+	role := models.Role{Name: name}
+	if err := r.db.Where("name = ?", name).FirstOrCreate(&role).Error; err != nil {
+		return nil, err
+	}
+	if !role.BuiltIn {
+		if err := r.db.Model(&role).Update("built_in", true).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &role, nil
+}
@@ -51,6 +51,13 @@ func (r *ScheduleRepository) GetByUserID(userID uint) ([]models.Schedule, error)
 	return schedules, err
 }
 
+// GetAccessibleByUser returns the schedules of the databases a user can access
+func (r *ScheduleRepository) GetAccessibleByUser(userID uint) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.db.Preload("Database").Where("database_id IN (?)", accessibleDatabaseIDs(r.db, userID)).Find(&schedules).Error
+	return schedules, err
+}
+
 // Get all schedules for a databse
 func (r *ScheduleRepository) GetByDatabaseID(databaseID uint) ([]models.Schedule, error) {
 	var schedules []models.Schedule
@@ -82,3 +89,18 @@ func (r *ScheduleRepository) Delete(id uint) error {
 func (r *ScheduleRepository) SoftDeleteByDatabaseID(databaseID uint) error {
 	return r.db.Where("database_id = ?", databaseID).Delete(&models.Schedule{}).Error
 }
+
+// ListForAdmin returns the schedules of every user matching the filter (status: active or inactive)
+func (r *ScheduleRepository) ListForAdmin(filter AdminFilter) ([]models.Schedule, int64, error) {
+	var schedules []models.Schedule
+	query := filter.apply(r.db, r.db.Model(&models.Schedule{}), "database_id").
+		Preload("User").Preload("Database", func(db *gorm.DB) *gorm.DB { return db.Unscoped() })
+	switch filter.Status {
+	case "active":
+		query = query.Where("active = ?", true)
+	case "inactive":
+		query = query.Where("active = ?", false)
+	}
+	total, err := filter.paginate(query, &schedules)
+	return schedules, total, err
+}
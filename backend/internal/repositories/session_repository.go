@@ -60,21 +60,161 @@ func (r *SessionRepository) DeleteByUserId(userId uint) error {
 	return nil
 }
 
-// DeleteExpiredSessions supprime physiquement toutes les sessions expirées (à appeler périodiquement)
+// GetSessionByID récupère une session par son identifiant
+func (r *SessionRepository) GetSessionByID(id uint) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// DeleteByID supprime physiquement une session par son identifiant
+func (r *SessionRepository) DeleteByID(id uint) error {
+	return r.db.Unscoped().Delete(&models.Session{}, id).Error
+}
+
+// DeleteOtherSessions supprime physiquement les sessions d'un utilisateur sauf celle indiquée
+func (r *SessionRepository) DeleteOtherSessions(userId uint, keepID uint) (int64, error) {
+	result := r.db.Unscoped().Where("user_id = ? AND id <> ?", userId, keepID).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteAllForUser supprime physiquement toutes les sessions d'un utilisateur et retourne leur nombre
+func (r *SessionRepository) DeleteAllForUser(userId uint) (int64, error) {
+	result := r.db.Unscoped().Where("user_id = ?", userId).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// TouchSession met à jour la date de dernière activité d'une session
+func (r *SessionRepository) TouchSession(id uint, at time.Time) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
+
+// GetByFamilyID récupère une session par sa famille de refresh tokens (sans vérifier l'expiration)
+func (r *SessionRepository) GetByFamilyID(familyID string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Where("family_id = ?", familyID).
+		Preload("User").
+		First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateTokens remplace le token d'accès et le refresh token d'une session si le refresh token
+// présenté est toujours le courant, et garde le hash de ce dernier pour détecter sa réutilisation.
+// Retourne false si un autre appel l'a déjà consommé.
+func (r *SessionRepository) RotateTokens(session *models.Session, previousRefreshHash string) (bool, error) {
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Session{}).
+			Where("id = ? AND refresh_token = ?", session.Id, previousRefreshHash).
+			Updates(map[string]interface{}{
+				"token":              session.Token,
+				"expires_at":         session.ExpiresAt,
+				"refresh_token":      session.RefreshToken,
+				"refresh_expires_at": session.RefreshExpiresAt,
+				"ip_address":         session.IPAddress,
+				"user_agent":         session.UserAgent,
+				"last_used_at":       session.LastUsedAt,
+			})
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		rotated = true
+		return tx.Create(&models.ConsumedRefreshToken{FamilyID: session.FamilyID, TokenHash: previousRefreshHash}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return rotated, nil
+}
+
+// IsConsumedRefreshToken indique si un refresh token de la famille a déjà été échangé
+func (r *SessionRepository) IsConsumedRefreshToken(familyID, tokenHash string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.ConsumedRefreshToken{}).
+		Where("family_id = ? AND token_hash = ?", familyID, tokenHash).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteByFamilyID supprime physiquement une session et sa famille de refresh tokens
+func (r *SessionRepository) DeleteByFamilyID(familyID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("family_id = ?", familyID).Delete(&models.ConsumedRefreshToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("family_id = ?", familyID).Delete(&models.Session{}).Error
+	})
+}
+
+// GetByResetToken récupère la demande de réinitialisation correspondant au hash d'un reset token non expiré
+func (r *SessionRepository) GetByResetToken(resetTokenHash string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Where("reset_token = ? AND reset_expires_at > ?", resetTokenHash, time.Now()).
+		First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ConsumeResetToken supprime une demande de réinitialisation si son reset token est toujours le même.
+// Retourne false si un autre appel l'a déjà utilisé (usage unique).
+func (r *SessionRepository) ConsumeResetToken(id uint, resetTokenHash string) (bool, error) {
+	result := r.db.Unscoped().Where("id = ? AND reset_token = ?", id, resetTokenHash).Delete(&models.Session{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteResetTokensForUser supprime les demandes de réinitialisation en attente d'un utilisateur
+func (r *SessionRepository) DeleteResetTokensForUser(userId uint) error {
+	return r.db.Unscoped().Where("user_id = ? AND reset_token <> ''", userId).Delete(&models.Session{}).Error
+}
+
+// DeleteOldestForUser supprime les sessions les moins récemment utilisées au-delà de keep sessions
+func (r *SessionRepository) DeleteOldestForUser(userId uint, keep int) error {
+	var ids []uint
+	if err := r.db.Model(&models.Session{}).
+		Where("user_id = ? AND (reset_token IS NULL OR reset_token = '')", userId).
+		Order("last_used_at DESC, id DESC").
+		Offset(keep).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Unscoped().Where("id IN ?", ids).Delete(&models.Session{}).Error
+}
+
+// DeleteExpiredSessions supprime physiquement toutes les sessions expirées (à appeler périodiquement).
+// Une session dont le token d'accès a expiré reste valable tant que son refresh token ne l'est pas,
+// et une demande de réinitialisation de mot de passe tant que son reset token ne l'est pas.
 func (r *SessionRepository) DeleteExpiredSessions() error {
 	// Suppression physique directe (plus de soft delete)
-	result := r.db.Unscoped().Where("expires_at < ?", time.Now()).Delete(&models.Session{})
+	now := time.Now()
+	result := r.db.Unscoped().
+		Where("expires_at < ? AND refresh_expires_at < ? AND (reset_expires_at IS NULL OR reset_expires_at < ?)", now, now, now).
+		Delete(&models.Session{})
 	if result.Error != nil {
 		return result.Error
 	}
 	log.Printf("Sessions expirées supprimées physiquement: %d", result.RowsAffected)
-	return nil
+
+	// Les refresh tokens consommés des familles fermées (expiration, déconnexion, révocation) ne servent plus
+	return r.db.Where("family_id NOT IN (?)", r.db.Model(&models.Session{}).Where("family_id <> ''").Select("family_id")).
+		Delete(&models.ConsumedRefreshToken{}).Error
 }
 
 // GetActiveSessionsForUser récupère toutes les sessions actives d'un utilisateur
 func (r *SessionRepository) GetActiveSessionsForUser(userId uint) ([]models.Session, error) {
 	var sessions []models.Session
-	if err := r.db.Where("user_id = ? AND expires_at > ?", userId, time.Now()).
+	now := time.Now()
+	if err := r.db.Where("user_id = ? AND (expires_at > ? OR refresh_expires_at > ?)", userId, now, now).
+		Order("last_used_at DESC").
 		Find(&sessions).Error; err != nil {
 		return nil, err
 	}
@@ -89,8 +229,9 @@ func (r *SessionRepository) FindByToken(token string) (*models.Session, error) {
 // GetActiveSessionsCount retourne le nombre de sessions actives
 func (r *SessionRepository) GetActiveSessionsCount() (int64, error) {
 	var count int64
+	now := time.Now()
 	if err := r.db.Model(&models.Session{}).
-		Where("expires_at > ?", time.Now()).
+		Where("expires_at > ? OR refresh_expires_at > ?", now, now).
 		Count(&count).Error; err != nil {
 		return 0, err
 	}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SettingRepository gère les paramètres de l'application
+type SettingRepository struct {
+	db *gorm.DB
+}
+
+// NewSettingRepository constructeur
+func NewSettingRepository(db *gorm.DB) *SettingRepository {
+	return &SettingRepository{db: db}
+}
+
+// Get retourne la valeur d'un paramètre ; found vaut false s'il n'a jamais été défini
+func (r *SettingRepository) Get(key string) (value string, found bool, err error) {
+	var setting models.Setting
+	err = r.db.Where("key = ?", key).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return setting.Value, true, nil
+}
+
+// Set crée ou remplace la valeur d'un paramètre
+func (r *SettingRepository) Set(key, value string) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&models.Setting{Key: key, Value: value}).Error
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"gorm.io/gorm"
+)
+
+// TeamRepository gère les équipes et leurs membres
+type TeamRepository struct {
+	db *gorm.DB
+}
+
+// NewTeamRepository constructeur
+func NewTeamRepository(db *gorm.DB) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+// CreateWithOwner crée une équipe et son premier membre (propriétaire)
+func (r *TeamRepository) CreateWithOwner(team *models.Team, ownerID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(team).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.TeamMember{TeamId: team.Id, UserId: ownerID, Role: models.TeamRoleOwner}).Error
+	})
+}
+
+// GetByID retourne une équipe avec ses membres
+func (r *TeamRepository) GetByID(id uint) (*models.Team, error) {
+	var team models.Team
+	if err := r.db.Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Preload("Members.User").
+		First(&team, id).Error; err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// GetPersonalTeam retourne l'espace personnel d'un utilisateur
+func (r *TeamRepository) GetPersonalTeam(userID uint) (*models.Team, error) {
+	var team models.Team
+	if err := r.db.Where("personal = ? AND owner_id = ?", true, userID).First(&team).Error; err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// GetTeamsForUser retourne les équipes d'un utilisateur
+func (r *TeamRepository) GetTeamsForUser(userID uint) ([]models.Team, error) {
+	var teams []models.Team
+	err := r.db.Joins("JOIN team_members ON team_members.team_id = teams.id").
+		Where("team_members.user_id = ?", userID).
+		Order("teams.personal DESC, teams.name").
+		Find(&teams).Error
+	return teams, err
+}
+
+// GetMemberships retourne les appartenances d'un utilisateur (équipe → rôle)
+func (r *TeamRepository) GetMemberships(userID uint) ([]models.TeamMember, error) {
+	var members []models.TeamMember
+	err := r.db.Where("user_id = ?", userID).Find(&members).Error
+	return members, err
+}
+
+// GetMembership retourne l'appartenance d'un utilisateur à une équipe
+func (r *TeamRepository) GetMembership(teamID, userID uint) (*models.TeamMember, error) {
+	var member models.TeamMember
+	if err := r.db.Where("team_id = ? AND user_id = ?", teamID, userID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetMembersWithRoles retourne les membres d'une équipe ayant l'un des rôles donnés
+func (r *TeamRepository) GetMembersWithRoles(teamID uint, roles []string) ([]models.TeamMember, error) {
+	var members []models.TeamMember
+	err := r.db.Preload("User").Where("team_id = ? AND role IN ?", teamID, roles).Find(&members).Error
+	return members, err
+}
+
+// AddMember ajoute un membre à une équipe
+func (r *TeamRepository) AddMember(member *models.TeamMember) error {
+	return r.db.Create(member).Error
+}
+
+// UpdateMemberRole change le rôle d'un membre
+func (r *TeamRepository) UpdateMemberRole(teamID, userID uint, role string) error {
+	return r.db.Model(&models.TeamMember{}).Where("team_id = ? AND user_id = ?", teamID, userID).Update("role", role).Error
+}
+
+// RemoveMember retire un membre d'une équipe
+func (r *TeamRepository) RemoveMember(teamID, userID uint) error {
+	return r.db.Where("team_id = ? AND user_id = ?", teamID, userID).Delete(&models.TeamMember{}).Error
+}
+
+// CountOwners compte les propriétaires d'une équipe
+func (r *TeamRepository) CountOwners(teamID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.TeamMember{}).Where("team_id = ? AND role = ?", teamID, models.TeamRoleOwner).Count(&count).Error
+	return count, err
+}
+
+// CountDatabases compte les bases de données (non supprimées) d'une équipe
+func (r *TeamRepository) CountDatabases(teamID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Database{}).Where("team_id = ?", teamID).Count(&count).Error
+	return count, err
+}
+
+// UpdateName met à jour le nom d'une équipe
+func (r *TeamRepository) UpdateName(id uint, name string) error {
+	return r.db.Model(&models.Team{}).Where("id = ?", id).Update("name", name).Error
+}
+
+// Delete supprime une équipe et ses membres
+func (r *TeamRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", id).Delete(&models.TeamMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Team{}, id).Error
+	})
+}
+
+// GetUserIDsWithoutTeamDatabases retourne les utilisateurs possédant des bases de données sans équipe
+// (données antérieures aux équipes, y compris les bases supprimées)
+func (r *TeamRepository) GetUserIDsWithoutTeamDatabases() ([]uint, error) {
+	var userIDs []uint
+	err := r.db.Unscoped().Model(&models.Database{}).Where("team_id IS NULL").Distinct().Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// AssignUserDatabasesToTeam rattache à une équipe les bases sans équipe d'un utilisateur
+func (r *TeamRepository) AssignUserDatabasesToTeam(userID, teamID uint) (int64, error) {
+	result := r.db.Unscoped().Model(&models.Database{}).Where("user_id = ? AND team_id IS NULL", userID).Update("team_id", teamID)
+	return result.RowsAffected, result.Error
+}
+
+// MoveDatabase rattache une base de données à une autre équipe
+func (r *TeamRepository) MoveDatabase(databaseID, teamID uint) error {
+	return r.db.Model(&models.Database{}).Where("id = ?", databaseID).Update("team_id", teamID).Error
+}
+
+// GetDatabaseOwnership retourne le créateur et l'équipe d'une base de données, même supprimée
+func (r *TeamRepository) GetDatabaseOwnership(databaseID uint) (*models.Database, error) {
+	var database models.Database
+	if err := r.db.Unscoped().Select("id", "user_id", "team_id").First(&database, databaseID).Error; err != nil {
+		return nil, err
+	}
+	return &database, nil
+}
@@ -0,0 +1,296 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"gorm.io/gorm"
+)
+
+// UserDeletionRepository gathers the resources of a user being deleted and removes their account data
+type UserDeletionRepository struct {
+	db *gorm.DB
+}
+
+// NewUserDeletionRepository constructeur
+func NewUserDeletionRepository(db *gorm.DB) *UserDeletionRepository {
+	return &UserDeletionRepository{db: db}
+}
+
+// ownedDatabaseIDs selects the databases created by a user, including soft deleted ones
+func (r *UserDeletionRepository) ownedDatabaseIDs(userID uint) *gorm.DB {
+	return r.db.Unscoped().Model(&models.Database{}).Select("id").Where("user_id = ?", userID)
+}
+
+// personalDatabases selects the databases of the personal workspace of a user and those they created without a
+// team, including soft deleted ones. The databases of a team belong to the team, not to the user who created them.
+func (r *UserDeletionRepository) personalDatabases(userID, personalTeamID uint) *gorm.DB {
+	return r.db.Unscoped().Model(&models.Database{}).Where("(team_id IS NULL AND user_id = ?) OR team_id = ?", userID, personalTeamID)
+}
+
+// GetOwnedDatabases returns the databases created by a user, including soft deleted ones
+func (r *UserDeletionRepository) GetOwnedDatabases(userID uint) ([]models.Database, error) {
+	var databases []models.Database
+	err := r.db.Unscoped().Where("user_id = ?", userID).Order("id").Find(&databases).Error
+	return databases, err
+}
+
+// GetPersonalDatabases returns the databases of the personal workspace of a user (see personalDatabases)
+func (r *UserDeletionRepository) GetPersonalDatabases(userID, personalTeamID uint) ([]models.Database, error) {
+	var databases []models.Database
+	err := r.personalDatabases(userID, personalTeamID).Order("id").Find(&databases).Error
+	return databases, err
+}
+
+// GetBackupsToPurge returns the backups of the personal databases of a user, including soft deleted ones
+func (r *UserDeletionRepository) GetBackupsToPurge(userID, personalTeamID uint) ([]models.Backup, error) {
+	var backups []models.Backup
+	err := r.db.Unscoped().Where("database_id IN (?)", r.personalDatabases(userID, personalTeamID).Select("id")).Order("id").Find(&backups).Error
+	return backups, err
+}
+
+// GetSchedulesToPurge returns the schedules of the personal databases of a user
+func (r *UserDeletionRepository) GetSchedulesToPurge(userID, personalTeamID uint) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.db.Unscoped().Where("database_id IN (?)", r.personalDatabases(userID, personalTeamID).Select("id")).Order("id").Find(&schedules).Error
+	return schedules, err
+}
+
+// teamDatabaseIDs selects the databases of a team, including soft deleted ones
+func (r *UserDeletionRepository) teamDatabaseIDs(teamID uint) *gorm.DB {
+	return r.db.Unscoped().Model(&models.Database{}).Select("id").Where("team_id = ?", teamID)
+}
+
+// GetTeamsWithUserResources returns the teams, personal workspace excluded, whose databases were created by a user
+// or hold their schedules, backups or restores
+func (r *UserDeletionRepository) GetTeamsWithUserResources(userID, personalTeamID uint) ([]uint, error) {
+	var teamIDs []uint
+	err := r.db.Unscoped().Model(&models.Database{}).
+		Where("team_id IS NOT NULL AND team_id <> ?", personalTeamID).
+		Where("user_id = ? OR id IN (?) OR id IN (?) OR id IN (?)", userID,
+			r.db.Unscoped().Model(&models.Schedule{}).Select("database_id").Where("user_id = ?", userID),
+			r.db.Unscoped().Model(&models.Backup{}).Select("database_id").Where("user_id = ?", userID),
+			r.db.Unscoped().Model(&models.Restore{}).Select("database_id").Where("user_id = ?", userID)).
+		Distinct().Order("team_id").Pluck("team_id", &teamIDs).Error
+	return teamIDs, err
+}
+
+// GetUserSchedulesOnTeam returns the schedules a user created on the databases of a team
+func (r *UserDeletionRepository) GetUserSchedulesOnTeam(userID, teamID uint) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.db.Where("user_id = ? AND database_id IN (?)", userID, r.teamDatabaseIDs(teamID)).Order("id").Find(&schedules).Error
+	return schedules, err
+}
+
+// HandOverTeamResources gives the databases a user created in a team, and their schedules, backups and restores
+// on the databases of the team, to another member of the team
+func (r *UserDeletionRepository) HandOverTeamResources(userID, teamID, newOwnerID uint) (UserReassignment, error) {
+	var counts UserReassignment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.Database{}).Where("user_id = ? AND team_id = ?", userID, teamID).Update("user_id", newOwnerID)
+		if result.Error != nil {
+			return result.Error
+		}
+		counts.Databases = result.RowsAffected
+		for _, target := range []struct {
+			model interface{}
+			count *int64
+		}{
+			{&models.Schedule{}, &counts.Schedules},
+			{&models.Backup{}, &counts.Backups},
+			{&models.Restore{}, &counts.Restores},
+		} {
+			result := tx.Unscoped().Model(target.model).Where("user_id = ? AND database_id IN (?)", userID, r.teamDatabaseIDs(teamID)).
+				Update("user_id", newOwnerID)
+			if result.Error != nil {
+				return result.Error
+			}
+			*target.count = result.RowsAffected
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// GetSchedulesOfUser returns the schedules created by a user or running on their databases
+func (r *UserDeletionRepository) GetSchedulesOfUser(userID uint) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.db.Where("user_id = ? OR database_id IN (?)", userID, r.ownedDatabaseIDs(userID)).Order("id").Find(&schedules).Error
+	return schedules, err
+}
+
+// PurgeBackup permanently removes a backup and its restores
+func (r *UserDeletionRepository) PurgeBackup(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("backup_id = ?", id).Delete(&models.Restore{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Backup{}, id).Error
+	})
+}
+
+// PurgeSchedule permanently removes a schedule
+func (r *UserDeletionRepository) PurgeSchedule(id uint) error {
+	return r.db.Unscoped().Delete(&models.Schedule{}, id).Error
+}
+
+// PurgeDatabase permanently removes a database whose backups and schedules are already purged
+func (r *UserDeletionRepository) PurgeDatabase(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var remaining int64
+		if err := tx.Unscoped().Model(&models.Backup{}).Where("database_id = ?", id).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return fmt.Errorf("%d sauvegarde(s) restante(s)", remaining)
+		}
+		if err := tx.Unscoped().Where("database_id = ?", id).Delete(&models.Restore{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("database_id = ?", id).Delete(&models.Schedule{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Database{}, id).Error
+	})
+}
+
+// UserReassignment counts the records moved to another user
+type UserReassignment struct {
+	Databases int64 `json:"databases"`
+	Schedules int64 `json:"schedules"`
+	Backups   int64 `json:"backups"`
+	Restores  int64 `json:"restores"`
+}
+
+// ReassignResources gives the databases, schedules, backups and restores of a user to another user. Databases of
+// the personal workspace of the user move to the workspace of the new owner (fromTeamID and toTeamID, 0 to skip).
+func (r *UserDeletionRepository) ReassignResources(userID, targetID, fromTeamID, toTeamID uint) (UserReassignment, error) {
+	var counts UserReassignment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if fromTeamID != 0 && toTeamID != 0 {
+			if err := tx.Unscoped().Model(&models.Database{}).Where("team_id = ?", fromTeamID).Update("team_id", toTeamID).Error; err != nil {
+				return err
+			}
+		}
+		for _, target := range []struct {
+			model interface{}
+			count *int64
+		}{
+			{&models.Database{}, &counts.Databases},
+			{&models.Schedule{}, &counts.Schedules},
+			{&models.Backup{}, &counts.Backups},
+			{&models.Restore{}, &counts.Restores},
+		} {
+			result := tx.Unscoped().Model(target.model).Where("user_id = ?", userID).Update("user_id", targetID)
+			if result.Error != nil {
+				return result.Error
+			}
+			*target.count = result.RowsAffected
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// DeleteAccount removes the sessions, API tokens, recovery codes, team memberships, personal workspace and quota
+// of a user, then scrubs and soft deletes the account. The row is kept so the action history still refers to it.
+func (r *UserDeletionRepository) DeleteAccount(userID uint, personalTeamID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Removing the membership of the last owner would leave the team without an owner
+		var soleOwned int64
+		err := tx.Model(&models.TeamMember{}).Where("user_id = ? AND role = ? AND team_id <> ?", userID, models.TeamRoleOwner, personalTeamID).
+			Where("NOT EXISTS (SELECT 1 FROM team_members other WHERE other.team_id = team_members.team_id AND other.role = ? AND other.user_id <> ?)",
+				models.TeamRoleOwner, userID).
+			Count(&soleOwned).Error
+		if err != nil {
+			return err
+		}
+		if soleOwned > 0 {
+			return errors.New("le compte est le dernier propriétaire d'une équipe")
+		}
+
+		for _, model := range []interface{}{&models.Session{}, &models.APIToken{}, &models.RecoveryCode{}, &models.TeamMember{}, &models.Quota{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if personalTeamID != 0 {
+			if err := tx.Where("team_id = ?", personalTeamID).Delete(&models.TeamMember{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("team_id = ?", personalTeamID).Delete(&models.Quota{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&models.Team{}, personalTeamID).Error; err != nil {
+				return err
+			}
+		}
+		scrubbed := map[string]interface{}{
+			"firstname":            "Utilisateur",
+			"lastname":             "supprimé",
+			"email":                fmt.Sprintf("deleted-%d@deleted.invalid", userID),
+			"password":             "",
+			"active":               false,
+			"totp_secret":          "",
+			"two_factor_enabled":   false,
+			"oidc_subject":         nil,
+			"erasure_scheduled_at": nil,
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(scrubbed).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, userID).Error
+	})
+}
+
+// RevokeAccess deactivates an account and removes its sessions and API tokens
+func (r *UserDeletionRepository) RevokeAccess(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("active", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.APIToken{}).Error
+	})
+}
+
+// AnonymizeActionHistory clears the IP addresses, user agents and privacy metadata (see models.PrivacyMetadataKeys)
+// of the actions of a user, the actions are kept
+func (r *UserDeletionRepository) AnonymizeActionHistory(userID uint) (int64, error) {
+	var anonymized int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ActionHistory{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"ip_address": "", "user_agent": ""})
+		if result.Error != nil {
+			return result.Error
+		}
+		anonymized = result.RowsAffected
+
+		var rows []models.ActionHistory
+		return tx.Select("id", "metadata").Where("user_id = ?", userID).Order("id").
+			FindInBatches(&rows, 500, func(batch *gorm.DB, _ int) error {
+				for _, row := range rows {
+					metadata, removed := models.RemovePrivacyMetadata(row.Metadata)
+					if !removed {
+						continue
+					}
+					if err := tx.Model(&models.ActionHistory{}).Where("id = ?", row.Id).Update("metadata", metadata).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+	})
+	return anonymized, err
+}
+
+// GetDueErasures returns the users whose erasure grace period has ended
+func (r *UserDeletionRepository) GetDueErasures(now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("erasure_scheduled_at IS NOT NULL AND erasure_scheduled_at <= ?", now).Order("id").Find(&users).Error
+	return users, err
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"gorm.io/gorm"
 )
@@ -61,13 +63,13 @@ func (r *UserRepository) ActivateUser(id uint) error {
 
 // Hard delete user (use with caution)
 func (r *UserRepository) DeleteUser(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+	return r.db.Unscoped().Delete(&models.User{}, id).Error
 }
 
 // Get user by id
 func (r *UserRepository) GetUserById(id uint) (*models.User, error) {
 	var user models.User
-	if err := r.db.Preload("Role").First(&user, id).Error; err != nil {
+	if err := r.db.Preload("Role.Permissions").First(&user, id).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -91,3 +93,65 @@ func (r *UserRepository) UpdateUserById(id uint, updates map[string]interface{})
 		Updates(updates).
 		Error
 }
+
+// AdvanceTOTPStep enregistre le dernier pas de temps TOTP accepté s'il est plus récent que le précédent.
+// Retourne false si le code a déjà été utilisé (rejeu, y compris par une requête concurrente).
+func (r *UserRepository) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		UpdateColumn("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// IncrementFailedLogins ajoute un échec de connexion au compteur de l'utilisateur et retourne sa nouvelle valeur.
+// L'incrément est fait en SQL pour ne perdre aucun échec entre requêtes concurrentes.
+func (r *UserRepository) IncrementFailedLogins(id uint) (int, error) {
+	if err := r.db.Model(&models.User{}).
+		Where("id = ?", id).
+		UpdateColumn("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).Error; err != nil {
+		return 0, err
+	}
+	var user models.User
+	if err := r.db.Select("failed_login_attempts").First(&user, id).Error; err != nil {
+		return 0, err
+	}
+	return user.FailedLoginAttempts, nil
+}
+
+// LockUntil verrouille le compte jusqu'à la date donnée
+func (r *UserRepository) LockUntil(id uint, until time.Time) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ?", id).
+		UpdateColumn("locked_until", until).Error
+}
+
+// ResetFailedLogins remet le compteur d'échecs à zéro et lève le verrouillage
+func (r *UserRepository) ResetFailedLogins(id uint) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"failed_login_attempts": 0, "locked_until": nil}).Error
+}
+
+// GetUserByOIDCSubject retourne l'utilisateur lié à un compte du fournisseur d'identité
+func (r *UserRepository) GetUserByOIDCSubject(subject string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("oidc_subject = ?", subject).Preload("Role").First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// LinkOIDCSubject lie un utilisateur existant à son compte du fournisseur d'identité.
+// Retourne false si l'utilisateur est déjà lié à un autre compte.
+func (r *UserRepository) LinkOIDCSubject(id uint, subject string) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND (oidc_subject IS NULL OR oidc_subject = ?)", id, subject).
+		UpdateColumn("oidc_subject", subject)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
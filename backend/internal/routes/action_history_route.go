@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
 	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,15 +14,22 @@ func SetupActionHistoryRoutes(router *gin.Engine, actionHistoryHandler *handlers
 	historyRoutes.Use(authMiddleware.RequireAuth())
 	{
 		// User action history
-		historyRoutes.GET("", actionHistoryHandler.GetUserActionHistory)
+		historyRoutes.GET("", authMiddleware.RequirePermission(models.PermHistoryRead), actionHistoryHandler.GetUserActionHistory)
+
+		// Export of the user action history (csv, json, ndjson)
+		historyRoutes.GET("/export", authMiddleware.RequirePermission(models.PermHistoryRead), actionHistoryHandler.ExportUserActionHistory)
 
 		// Action history by resource type
-		historyRoutes.GET("/type/:type", actionHistoryHandler.GetActionHistoryByType)
+		historyRoutes.GET("/type/:type", authMiddleware.RequirePermission(models.PermHistoryRead), actionHistoryHandler.GetActionHistoryByType)
 
 		// Action history for specific resource
-		historyRoutes.GET("/resource/:type/:id", actionHistoryHandler.GetResourceActionHistory)
+		historyRoutes.GET("/resource/:type/:id", authMiddleware.RequirePermission(models.PermHistoryRead), actionHistoryHandler.GetResourceActionHistory)
+
+		// Recent action history of every user (audit)
+		historyRoutes.GET("/recent", authMiddleware.RequirePermission(models.PermAuditRead), actionHistoryHandler.GetRecentActionHistory)
 
-		// Recent action history (admin only)
-		historyRoutes.GET("/recent", actionHistoryHandler.GetRecentActionHistory)
+		// Search and export of the action history of every user (audit)
+		historyRoutes.GET("/audit", authMiddleware.RequirePermission(models.PermAuditRead), actionHistoryHandler.SearchAuditHistory)
+		historyRoutes.GET("/audit/export", authMiddleware.RequirePermission(models.PermAuditRead), actionHistoryHandler.ExportAuditHistory)
 	}
 }
\ No newline at end of file
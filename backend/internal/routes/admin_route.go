@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAdminRoutes configures the admin-wide views of databases, backups, restores and schedules
+func SetupAdminRoutes(router *gin.Engine, adminHandler *handlers.AdminHandler, authMiddleware *middlewares.AuthMiddleware) {
+	admin := router.Group("/api/admin")
+	admin.Use(authMiddleware.RequireAuth())
+	admin.Use(authMiddleware.RequirePermission(models.PermAdminResources))
+	{
+		// Filtres : user_id, team_id, database_id, status, search, page, limit
+		admin.GET("/databases", adminHandler.ListDatabases)
+		admin.GET("/backups", adminHandler.ListBackups)
+		admin.GET("/restores", adminHandler.ListRestores)
+		admin.GET("/schedules", adminHandler.ListSchedules)
+
+		// Travaux lancés ou annulés pour le compte des utilisateurs
+		admin.POST("/databases/:id/backup", adminHandler.TriggerBackup)
+		admin.POST("/schedules/:id/run", adminHandler.RunSchedule)
+		admin.POST("/backups/:id/cancel", adminHandler.CancelBackup)
+		admin.POST("/restores/:id/cancel", adminHandler.CancelRestore)
+	}
+}
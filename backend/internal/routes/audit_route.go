@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAuditRoutes configures the verification and anchoring of the tamper-evident action history
+func SetupAuditRoutes(router *gin.Engine, auditHandler *handlers.AuditHandler, authMiddleware *middlewares.AuthMiddleware) {
+	audit := router.Group("/api/admin/audit")
+	audit.Use(authMiddleware.RequireAuth())
+	{
+		audit.GET("/verify", authMiddleware.RequirePermission(models.PermAuditRead), auditHandler.VerifyChain)
+		audit.GET("/anchors", authMiddleware.RequirePermission(models.PermAuditRead), auditHandler.GetAnchors)
+		// Ancrage immédiat, en plus de l'ancrage périodique (AUDIT_ANCHOR_INTERVAL)
+		audit.POST("/anchor", authMiddleware.RequirePermission(models.PermSettingsManage), auditHandler.AnchorHead)
+	}
+}
@@ -10,7 +10,15 @@ func AuthRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, jwtSecret
 	{
 		auth.POST("/register", authHandler.Register)
 		auth.POST("/login", authHandler.Login)
+		auth.POST("/refresh", authHandler.Refresh) // Rotation du refresh token
 		auth.POST("/logout", authHandler.Logout)
+		auth.POST("/2fa/setup", authHandler.SetupTwoFactorChallenge) // Enrôlement imposé par le rôle (token partiel)
+		auth.POST("/2fa/verify", authHandler.VerifyTwoFactor)        // Second facteur : ouvre la session
+		auth.POST("/forgot-password", authHandler.ForgotPassword)    // Envoi d'un lien de réinitialisation par email
+		auth.POST("/reset-password", authHandler.ResetPassword)      // Nouveau mot de passe avec le token reçu
+		auth.GET("/oidc/config", authHandler.GetLoginOptions)        // SSO disponible, connexion par mot de passe autorisée
+		auth.GET("/oidc/login", authHandler.OIDCLogin)               // Redirection vers le fournisseur d'identité
+		auth.GET("/oidc/callback", authHandler.OIDCCallback)         // Retour du fournisseur : ouvre la session
 		auth.GET("/me", authHandler.GetCurrentUser)
 		auth.GET("/sessions/stats", authHandler.GetSessionsStats) // Monitoring
 	}
@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
 	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,13 +14,13 @@ func SetupBackupRoutes(router *gin.Engine, backupHandler *handlers.BackupHandler
 	backupRoutes.Use(authMiddleware.RequireAuth())
 	{
 		// General backup routes
-		backupRoutes.GET("", backupHandler.GetBackups)
-		backupRoutes.GET("/:id", backupHandler.GetBackup)
-		backupRoutes.DELETE("/:id", backupHandler.DeleteBackup)
-		backupRoutes.GET("/:id/download", backupHandler.DownloadBackup)
+		backupRoutes.GET("", authMiddleware.RequirePermission(models.PermBackupRead), backupHandler.GetBackups)
+		backupRoutes.GET("/:id", authMiddleware.RequirePermission(models.PermBackupRead), backupHandler.GetBackup)
+		backupRoutes.DELETE("/:id", authMiddleware.RequirePermission(models.PermBackupDelete), backupHandler.DeleteBackup)
+		backupRoutes.GET("/:id/download", authMiddleware.RequirePermission(models.PermBackupDownload), backupHandler.DownloadBackup)
 
 		// Database-specific backup routes
-		backupRoutes.POST("/database/:database_id", backupHandler.CreateBackup)
-		backupRoutes.GET("/database/:database_id", backupHandler.GetBackupsByDatabase)
+		backupRoutes.POST("/database/:database_id", authMiddleware.RequirePermission(models.PermBackupCreate), backupHandler.CreateBackup)
+		backupRoutes.GET("/database/:database_id", authMiddleware.RequirePermission(models.PermBackupRead), backupHandler.GetBackupsByDatabase)
 	}
 }
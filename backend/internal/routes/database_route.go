@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
 	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,12 +13,15 @@ func SetupDatabaseRoutes(router *gin.Engine, databaseHandler *handlers.DatabaseH
 	databaseRoutes := router.Group("/api/databases")
 	databaseRoutes.Use(authMiddleware.RequireAuth())
 	{
-		databaseRoutes.POST("", databaseHandler.CreateDatabase)
-		databaseRoutes.GET("", databaseHandler.GetDatabases)
-		databaseRoutes.GET("/:id", databaseHandler.GetDatabase)
-		databaseRoutes.GET("/:id/details", databaseHandler.GetDatabaseWithBackupCount) // Get database with backup count
-		databaseRoutes.PUT("/:id", databaseHandler.UpdateDatabase)
-		databaseRoutes.PUT("/:id/partial", databaseHandler.UpdateDatabasePartial) // Secure partial update
-		databaseRoutes.DELETE("/:id", databaseHandler.DeleteDatabase)
+		databaseRoutes.POST("", authMiddleware.RequirePermission(models.PermDatabaseCreate), databaseHandler.CreateDatabase)
+		databaseRoutes.GET("", authMiddleware.RequirePermission(models.PermDatabaseRead), databaseHandler.GetDatabases)
+		databaseRoutes.GET("/:id", authMiddleware.RequirePermission(models.PermDatabaseRead), databaseHandler.GetDatabase)
+		databaseRoutes.GET("/:id/details", authMiddleware.RequirePermission(models.PermDatabaseRead), databaseHandler.GetDatabaseWithBackupCount) // Get database with backup count
+		databaseRoutes.PUT("/:id", authMiddleware.RequirePermission(models.PermDatabaseUpdate), databaseHandler.UpdateDatabase)
+		databaseRoutes.PUT("/:id/partial", authMiddleware.RequirePermission(models.PermDatabaseUpdate), databaseHandler.UpdateDatabasePartial) // Secure partial update
+		databaseRoutes.DELETE("/:id", authMiddleware.RequirePermission(models.PermDatabaseDelete), databaseHandler.DeleteDatabase)
+		databaseRoutes.PUT("/:id/passphrase", authMiddleware.RequirePermission(models.PermDatabaseUpdate), databaseHandler.SetBackupPassphrase)        // Zero-knowledge backups
+		databaseRoutes.DELETE("/:id/passphrase", authMiddleware.RequirePermission(models.PermDatabaseUpdate), databaseHandler.DisableBackupPassphrase) // Back to operator-managed keys
+		databaseRoutes.PUT("/:id/protection", authMiddleware.RequirePermission(models.PermDatabaseUpdate), databaseHandler.SetProtection)              // Four-eyes restores
 	}
 }
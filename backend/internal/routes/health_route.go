@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupHealthRoutes configures the public health probes (monitoring, Docker, Kubernetes)
+func SetupHealthRoutes(router *gin.Engine, healthHandler *handlers.HealthHandler) {
+	health := router.Group("/health")
+	{
+		health.GET("", healthHandler.Health)
+		health.GET("/live", healthHandler.Live)
+		health.GET("/ready", healthHandler.Ready)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupKeyRotationRoutes configures the master key administration routes (keys.manage permission)
+func SetupKeyRotationRoutes(router *gin.Engine, keyRotationHandler *handlers.KeyRotationHandler, authMiddleware *middlewares.AuthMiddleware) {
+	admin := router.Group("/api/admin/keys")
+	admin.Use(authMiddleware.RequireAuth())
+	admin.Use(authMiddleware.RequirePermission(models.PermKeysManage))
+	{
+		admin.GET("", keyRotationHandler.GetKeys)
+		admin.POST("/rotate", keyRotationHandler.StartRotation)
+		admin.POST("/migrate-objects", keyRotationHandler.StartObjectMigration)
+		admin.GET("/rotations/:id", keyRotationHandler.GetRotation)
+	}
+}
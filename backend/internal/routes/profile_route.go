@@ -14,6 +14,20 @@ func ProfileRoutes(router *gin.Engine, profileHandler *handlers.ProfileHandler,
 		profile.GET("", profileHandler.GetProfile)
 		profile.PUT("", profileHandler.UpdateProfile)
 		profile.PUT("/password", profileHandler.ChangePassword)
+		profile.GET("/sessions", profileHandler.GetSessions)
+		profile.DELETE("/sessions", profileHandler.RevokeOtherSessions) // Déconnecte tous les autres appareils
+		profile.DELETE("/sessions/:id", profileHandler.RevokeSession)
+		profile.GET("/2fa", profileHandler.GetTwoFactorStatus)
+		profile.POST("/2fa/setup", profileHandler.SetupTwoFactor)     // Secret + URI otpauth:// (QR code)
+		profile.POST("/2fa/confirm", profileHandler.ConfirmTwoFactor) // Active la 2FA, retourne les codes de secours
+		profile.POST("/2fa/recovery-codes", profileHandler.RegenerateRecoveryCodes)
+		profile.DELETE("/2fa", profileHandler.DisableTwoFactor)
+		profile.GET("/tokens", profileHandler.GetAPITokens)
+		profile.POST("/tokens", profileHandler.CreateAPIToken) // Le token en clair n'est affiché qu'une fois
+		profile.DELETE("/tokens/:id", profileHandler.RevokeAPIToken)
+		profile.GET("/export", profileHandler.ExportData) // Archive ZIP des données personnelles
+		profile.DELETE("", profileHandler.RequestErasure) // Effacement du compte après le délai de grâce
+		profile.POST("/erasure/cancel", profileHandler.CancelErasure)
 	}
 }
 
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupQuotaRoutes configures the usage report of the profile and the admin quota overrides
+func SetupQuotaRoutes(router *gin.Engine, quotaHandler *handlers.QuotaHandler, authMiddleware *middlewares.AuthMiddleware) {
+	router.GET("/api/profile/usage", authMiddleware.RequireAuth(), quotaHandler.GetMyUsage)
+
+	admin := router.Group("/api/admin")
+	admin.Use(authMiddleware.RequireAuth())
+	admin.Use(authMiddleware.RequirePermission(models.PermUserManage))
+	{
+		admin.GET("/users/:id/quota", quotaHandler.GetUserQuota)
+		admin.PUT("/users/:id/quota", quotaHandler.SetUserQuota)      // null = valeur par défaut, 0 = illimité
+		admin.DELETE("/users/:id/quota", quotaHandler.ResetUserQuota) // Retour aux valeurs par défaut
+		admin.GET("/teams/:id/quota", quotaHandler.GetTeamQuota)
+		admin.PUT("/teams/:id/quota", quotaHandler.SetTeamQuota)
+		admin.DELETE("/teams/:id/quota", quotaHandler.ResetTeamQuota)
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
 	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,14 +14,19 @@ func SetupRestoreRoutes(router *gin.Engine, restoreHandler *handlers.RestoreHand
 	restoreRoutes.Use(authMiddleware.RequireAuth())
 	{
 		// General restore routes
-		restoreRoutes.GET("", restoreHandler.GetRestores)
-		restoreRoutes.GET("/:id", restoreHandler.GetRestore)
+		restoreRoutes.GET("", authMiddleware.RequirePermission(models.PermRestoreRead), restoreHandler.GetRestores)
+		restoreRoutes.GET("/approvals", authMiddleware.RequirePermission(models.PermRestoreApprove), restoreHandler.GetPendingApprovals)
+		restoreRoutes.GET("/:id", authMiddleware.RequirePermission(models.PermRestoreRead), restoreHandler.GetRestore)
 
 		// Database-specific restore routes
-		restoreRoutes.GET("/database/:database_id", restoreHandler.GetRestoresByDatabase)
+		restoreRoutes.GET("/database/:database_id", authMiddleware.RequirePermission(models.PermRestoreRead), restoreHandler.GetRestoresByDatabase)
 
 		// Backup-specific restore routes
-		restoreRoutes.GET("/backup/:backup_id", restoreHandler.GetRestoresByBackup)
-		restoreRoutes.POST("/backup/:backup_id/database/:database_id", restoreHandler.CreateRestore)
+		restoreRoutes.GET("/backup/:backup_id", authMiddleware.RequirePermission(models.PermRestoreRead), restoreHandler.GetRestoresByBackup)
+		restoreRoutes.POST("/backup/:backup_id/database/:database_id", authMiddleware.RequirePermission(models.PermRestoreExecute), restoreHandler.CreateRestore)
+
+		// Four-eyes approval of restores into protected databases
+		restoreRoutes.POST("/:id/approve", authMiddleware.RequirePermission(models.PermRestoreApprove), restoreHandler.ApproveRestore)
+		restoreRoutes.POST("/:id/reject", authMiddleware.RequirePermission(models.PermRestoreApprove), restoreHandler.RejectRestore)
 	}
 }
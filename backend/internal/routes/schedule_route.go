@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
 	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,10 +13,10 @@ func SetupScheduleRoutes(router *gin.Engine, scheduleHandler *handlers.ScheduleH
 	scheduleRoutes := router.Group("/api/schedules")
 	scheduleRoutes.Use(authMiddleware.RequireAuth())
 	{
-		scheduleRoutes.POST("", scheduleHandler.CreateSchedule)
-		scheduleRoutes.GET("", scheduleHandler.GetSchedules)
-		scheduleRoutes.GET("/:id", scheduleHandler.GetSchedule)
-		scheduleRoutes.PUT("/:id", scheduleHandler.UpdateSchedule)
-		scheduleRoutes.DELETE("/:id", scheduleHandler.DeleteSchedule)
+		scheduleRoutes.POST("", authMiddleware.RequirePermission(models.PermScheduleManage), scheduleHandler.CreateSchedule)
+		scheduleRoutes.GET("", authMiddleware.RequirePermission(models.PermScheduleRead), scheduleHandler.GetSchedules)
+		scheduleRoutes.GET("/:id", authMiddleware.RequirePermission(models.PermScheduleRead), scheduleHandler.GetSchedule)
+		scheduleRoutes.PUT("/:id", authMiddleware.RequirePermission(models.PermScheduleManage), scheduleHandler.UpdateSchedule)
+		scheduleRoutes.DELETE("/:id", authMiddleware.RequirePermission(models.PermScheduleManage), scheduleHandler.DeleteSchedule)
 	}
 }
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupTeamRoutes configures the team routes (the team role of the user is checked by the service)
+func SetupTeamRoutes(router *gin.Engine, teamHandler *handlers.TeamHandler, authMiddleware *middlewares.AuthMiddleware) {
+	teamRoutes := router.Group("/api/teams")
+	teamRoutes.Use(authMiddleware.RequireAuth())
+	{
+		teamRoutes.GET("", authMiddleware.RequirePermission(models.PermDatabaseRead), teamHandler.GetTeams)
+		teamRoutes.POST("", authMiddleware.RequirePermission(models.PermDatabaseCreate), teamHandler.CreateTeam)
+		teamRoutes.GET("/:id", authMiddleware.RequirePermission(models.PermDatabaseRead), teamHandler.GetTeam)
+		teamRoutes.PUT("/:id", authMiddleware.RequirePermission(models.PermDatabaseUpdate), teamHandler.RenameTeam)
+		teamRoutes.DELETE("/:id", authMiddleware.RequirePermission(models.PermDatabaseDelete), teamHandler.DeleteTeam)
+		teamRoutes.POST("/:id/members", authMiddleware.RequirePermission(models.PermDatabaseUpdate), teamHandler.AddMember)
+		teamRoutes.PUT("/:id/members/:user_id", authMiddleware.RequirePermission(models.PermDatabaseUpdate), teamHandler.UpdateMemberRole)
+		teamRoutes.DELETE("/:id/members/:user_id", authMiddleware.RequirePermission(models.PermDatabaseRead), teamHandler.RemoveMember) // Also used to leave a team
+	}
+
+	// Move a database (with its backups, schedules and restores) to another team
+	router.PUT("/api/databases/:id/team", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(models.PermDatabaseUpdate), teamHandler.MoveDatabase)
+}
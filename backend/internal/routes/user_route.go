@@ -3,21 +3,51 @@ package routes
 import (
 	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
 	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
 func UserRoutes(router *gin.Engine, userHandler *handlers.UserHandler, authMiddleware *middlewares.AuthMiddleware) {
-	// Admin routes - require admin role
+	// Admin routes - require the user.manage permission
 	admin := router.Group("/api/admin/users")
 	admin.Use(authMiddleware.RequireAuth())
-	admin.Use(authMiddleware.RequireRole("admin"))
+	admin.Use(authMiddleware.RequirePermission(models.PermUserManage))
 	{
 		admin.GET("", userHandler.GetAllUsers)
+		admin.POST("", userHandler.CreateUser) // Sans mot de passe : invitation par email
 		admin.GET("/active", userHandler.GetAllActiveUsers)
 		admin.GET("/:id", userHandler.GetUser)
 		admin.PUT("/:id", userHandler.UpdateUser)
 		admin.PUT("/:id/role", userHandler.ChangeUserRole)
 		admin.PUT("/:id/deactivate", userHandler.DeactivateUser)
 		admin.PUT("/:id/activate", userHandler.ActivateUser)
+		admin.DELETE("/:id/sessions", userHandler.RevokeUserSessions)
+		admin.DELETE("/:id/2fa", userHandler.ResetUserTwoFactor) // Appareil perdu : l'utilisateur se réenrôle
+		admin.POST("/:id/unlock", userHandler.UnlockUser)        // Lève le verrouillage après échecs de connexion
+		admin.POST("/:id/invite", userHandler.InviteUser)        // Nouveau lien de choix du mot de passe
+		admin.DELETE("/:id", userHandler.DeleteUser)             // Réattribution ou purge des ressources en arrière-plan
+	}
+	router.GET("/api/admin/user-deletions/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(models.PermUserManage), userHandler.GetUserDeletion)
+
+	// Admin role routes - custom roles, permissions and two-factor policy per role
+	roles := router.Group("/api/admin/roles")
+	roles.Use(authMiddleware.RequireAuth())
+	roles.Use(authMiddleware.RequirePermission(models.PermRoleManage))
+	{
+		roles.GET("", userHandler.GetAllRoles)
+		roles.GET("/permissions", userHandler.GetPermissions) // Catalogue des permissions
+		roles.POST("", userHandler.CreateRole)
+		roles.PUT("/:id/permissions", userHandler.UpdateRolePermissions) // Rôles personnalisés uniquement
+		roles.DELETE("/:id", userHandler.DeleteRole)                     // Rôle personnalisé sans utilisateur
+		roles.PUT("/:id/two-factor", userHandler.SetRoleTwoFactor)
+	}
+
+	// Admin authentication settings - password login switch when SSO is configured
+	authSettings := router.Group("/api/admin/auth")
+	authSettings.Use(authMiddleware.RequireAuth())
+	authSettings.Use(authMiddleware.RequirePermission(models.PermSettingsManage))
+	{
+		authSettings.GET("/settings", userHandler.GetAuthSettings)
+		authSettings.PUT("/settings", userHandler.UpdateAuthSettings)
 	}
 }
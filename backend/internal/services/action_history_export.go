@@ -0,0 +1,193 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+)
+
+// Formats of an action history export
+const (
+	HistoryExportCSV    = "csv"
+	HistoryExportJSON   = "json"
+	HistoryExportNDJSON = "ndjson"
+)
+
+// historyExportBatchSize is the number of action history rows loaded at once during an export
+const historyExportBatchSize = 500
+
+// ErrInvalidExportFormat is returned for an export format other than csv, json or ndjson
+var ErrInvalidExportFormat = errors.New("format d'export invalide (csv, json ou ndjson)")
+
+// historyExportColumns are the CSV columns, in the order of historyExportRow
+var historyExportColumns = []string{"id", "created_at", "user_id", "user_email", "action", "resource_type", "resource_id",
+	"description", "metadata", "ip_address", "user_agent", "admin_action", "hash"}
+
+// historyExportRow is an action history row as exported for a compliance review. The hash lets reviewers
+// compare the export with the chain (see AuditService.VerifyChain).
+type historyExportRow struct {
+	Id           uint            `json:"id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UserId       uint            `json:"user_id"`
+	UserEmail    string          `json:"user_email"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceId   uint            `json:"resource_id"`
+	Description  string          `json:"description"`
+	Metadata     json.RawMessage `json:"metadata"`
+	IpAddress    string          `json:"ip_address"`
+	UserAgent    string          `json:"user_agent"`
+	AdminAction  bool            `json:"admin_action"`
+	Hash         string          `json:"hash"`
+}
+
+// newHistoryExportRow converts an action history row for an export
+func newHistoryExportRow(history *models.ActionHistory) historyExportRow {
+	row := historyExportRow{
+		Id:           history.Id,
+		CreatedAt:    history.CreatedAt,
+		UserId:       history.UserId,
+		UserEmail:    history.User.Email,
+		Action:       history.Action,
+		ResourceType: history.ResourceType,
+		ResourceId:   history.ResourceId,
+		Description:  history.Description,
+		IpAddress:    history.IpAddress,
+		UserAgent:    history.UserAgent,
+		AdminAction:  history.AdminAction,
+		Hash:         history.Hash,
+	}
+	if history.Metadata != "" && json.Valid([]byte(history.Metadata)) {
+		row.Metadata = json.RawMessage(history.Metadata)
+	}
+	return row
+}
+
+// csvRecord returns the CSV cells of the row
+func (r historyExportRow) csvRecord() []string {
+	return []string{
+		strconv.FormatUint(uint64(r.Id), 10),
+		r.CreatedAt.UTC().Format(time.RFC3339Nano),
+		strconv.FormatUint(uint64(r.UserId), 10),
+		csvSafe(r.UserEmail),
+		csvSafe(r.Action),
+		csvSafe(r.ResourceType),
+		strconv.FormatUint(uint64(r.ResourceId), 10),
+		csvSafe(r.Description),
+		csvSafe(string(r.Metadata)),
+		csvSafe(r.IpAddress),
+		csvSafe(r.UserAgent),
+		strconv.FormatBool(r.AdminAction),
+		r.Hash,
+	}
+}
+
+// csvSafe prevents a cell from being read as a formula by a spreadsheet
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// IsValidHistoryExportFormat reports whether an action history export format is supported
+func IsValidHistoryExportFormat(format string) bool {
+	return format == HistoryExportCSV || format == HistoryExportJSON || format == HistoryExportNDJSON
+}
+
+// ExportActionHistory writes every action history row matching a filter into w, most recent first, by batches
+// so that large exports are never held in memory. The writer is flushed after each batch when it supports it.
+// The export itself is logged for the user who requested it.
+func (s *ActionHistoryService) ExportActionHistory(w io.Writer, format string, filter repositories.HistoryFilter, userID uint, ipAddress, userAgent string) (int64, error) {
+	if !IsValidHistoryExportFormat(format) {
+		return 0, ErrInvalidExportFormat
+	}
+	flush := func() {}
+	if flusher, ok := w.(interface{ Flush() }); ok {
+		flush = flusher.Flush
+	}
+
+	var csvWriter *csv.Writer
+	switch format {
+	case HistoryExportCSV:
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(historyExportColumns); err != nil {
+			return 0, err
+		}
+	case HistoryExportJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return 0, err
+		}
+	}
+
+	var rows int64
+	err := s.actionHistoryRepo.Stream(filter, historyExportBatchSize, func(histories []models.ActionHistory) error {
+		for i := range histories {
+			row := newHistoryExportRow(&histories[i])
+			switch format {
+			case HistoryExportCSV:
+				if err := csvWriter.Write(row.csvRecord()); err != nil {
+					return err
+				}
+			default:
+				data, err := json.Marshal(row)
+				if err != nil {
+					return err
+				}
+				separator := "\n"
+				if format == HistoryExportJSON {
+					separator = ","
+					if rows == 0 {
+						separator = ""
+					}
+					data = append([]byte(separator), data...)
+				} else {
+					data = append(data, separator...)
+				}
+				if _, err := w.Write(data); err != nil {
+					return err
+				}
+			}
+			rows++
+		}
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		flush()
+		return nil
+	})
+	if err != nil {
+		return rows, fmt.Errorf("export interrompu après %d lignes: %w", rows, err)
+	}
+	if format == HistoryExportJSON {
+		if _, err := io.WriteString(w, "]\n"); err != nil {
+			return rows, err
+		}
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+	}
+	flush()
+
+	metadata := map[string]interface{}{"format": format, "rows": rows}
+	if filter.UserId != 0 {
+		metadata["user_id"] = filter.UserId
+	}
+	if err := s.LogAction(userID, "history_exported", "history", 0, fmt.Sprintf("Export de l'historique (%s, %d lignes)", format, rows),
+		metadata, ipAddress, userAgent); err != nil {
+		slog.Warn("failed to log history export", "user_id", userID, "error", err)
+	}
+	return rows, nil
+}
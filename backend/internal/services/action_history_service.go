@@ -3,10 +3,12 @@ package services
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/auditlog"
 )
 
 // ActionHistoryService manages action history operations
@@ -21,6 +23,41 @@ func NewActionHistoryService(actionHistoryRepo *repositories.ActionHistoryReposi
 	}
 }
 
+// SetForwarder forwards every recorded action to external collectors (syslog, SIEM, files). The forwarder is
+// registered on the repository, so the services recording actions through it directly are forwarded too.
+func (s *ActionHistoryService) SetForwarder(forwarder *auditlog.Forwarder) {
+	if forwarder == nil {
+		s.actionHistoryRepo.SetListener(nil)
+		return
+	}
+	s.actionHistoryRepo.SetListener(func(history *models.ActionHistory) {
+		forwarder.Publish(newAuditEvent(history))
+	})
+}
+
+// newAuditEvent converts an action history row for the forwarder
+func newAuditEvent(history *models.ActionHistory) auditlog.Event {
+	event := auditlog.Event{
+		ID:           history.Id,
+		Time:         history.CreatedAt,
+		UserID:       history.UserId,
+		Action:       history.Action,
+		ResourceType: history.ResourceType,
+		ResourceID:   history.ResourceId,
+		Description:  history.Description,
+		IPAddress:    history.IpAddress,
+		UserAgent:    history.UserAgent,
+		AdminAction:  history.AdminAction,
+		Hash:         history.Hash,
+	}
+	if history.Metadata != "" {
+		if err := json.Unmarshal([]byte(history.Metadata), &event.Metadata); err != nil {
+			slog.Warn("failed to parse action metadata", "history_id", history.Id, "error", err)
+		}
+	}
+	return event
+}
+
 // LogAction logs a user action with metadata
 func (s *ActionHistoryService) LogAction(userID uint, action, resourceType string, resourceID uint, description string, metadata map[string]interface{}, ipAddress, userAgent string) error {
 	var metadataJSON string
@@ -28,7 +65,7 @@ func (s *ActionHistoryService) LogAction(userID uint, action, resourceType strin
 		jsonBytes, err := json.Marshal(metadata)
 		if err != nil {
 			// Log the error but continue without metadata
-			fmt.Printf("Error marshaling metadata: %v\n", err)
+			slog.Warn("failed to marshal action metadata", "action", action, "resource_type", resourceType, "error", err)
 		} else {
 			metadataJSON = string(jsonBytes)
 		}
@@ -49,6 +86,31 @@ func (s *ActionHistoryService) LogAction(userID uint, action, resourceType strin
 	return s.actionHistoryRepo.Create(actionHistory)
 }
 
+// LogAdminAction logs an action an administrator performed on the resources of another user
+func (s *ActionHistoryService) LogAdminAction(adminID, ownerID uint, action, resourceType string, resourceID uint, description string, metadata map[string]interface{}, ipAddress, userAgent string) error {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["on_behalf_of"] = ownerID
+	jsonBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal admin action metadata: %w", err)
+	}
+
+	return s.actionHistoryRepo.Create(&models.ActionHistory{
+		UserId:       adminID,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceId:   resourceID,
+		Description:  description,
+		Metadata:     string(jsonBytes),
+		IpAddress:    ipAddress,
+		UserAgent:    userAgent,
+		CreatedAt:    time.Now(),
+		AdminAction:  true,
+	})
+}
+
 // ActionHistoryResponse represents the response format for action history
 type ActionHistoryResponse struct {
 	Id           uint                   `json:"id"`
@@ -62,6 +124,7 @@ type ActionHistoryResponse struct {
 	IpAddress    string                 `json:"ip_address,omitempty"`
 	UserAgent    string                 `json:"user_agent,omitempty"`
 	CreatedAt    time.Time              `json:"created_at"`
+	AdminAction  bool                   `json:"admin_action"`
 }
 
 // convertToResponse converts ActionHistory model to response format
@@ -77,13 +140,14 @@ func (s *ActionHistoryService) convertToResponse(history models.ActionHistory) A
 		IpAddress:    history.IpAddress,
 		UserAgent:    history.UserAgent,
 		CreatedAt:    history.CreatedAt,
+		AdminAction:  history.AdminAction,
 	}
 
 	// Parse metadata JSON string to map
 	if history.Metadata != "" {
 		var metadata map[string]interface{}
 		if err := json.Unmarshal([]byte(history.Metadata), &metadata); err != nil {
-			fmt.Printf("Error parsing metadata JSON for history ID %d: %v\n", history.Id, err)
+			slog.Warn("failed to parse action metadata", "history_id", history.Id, "error", err)
 		} else {
 			response.Metadata = metadata
 		}
@@ -92,6 +156,16 @@ func (s *ActionHistoryService) convertToResponse(history models.ActionHistory) A
 	return response
 }
 
+// HasUserActionFromIP reports whether the user already performed an action from an IP address
+func (s *ActionHistoryService) HasUserActionFromIP(userID uint, action, ipAddress string) (bool, error) {
+	return s.actionHistoryRepo.ExistsForUserActionAndIP(userID, action, ipAddress)
+}
+
+// CountUserAction counts the records of an action for a user
+func (s *ActionHistoryService) CountUserAction(userID uint, action string) (int64, error) {
+	return s.actionHistoryRepo.CountForUserAction(userID, action)
+}
+
 // GetUserActionHistory gets action history for a user with pagination
 func (s *ActionHistoryService) GetUserActionHistory(userID uint, page, limit int) ([]ActionHistoryResponse, int64, error) {
 	if page < 1 {
@@ -228,6 +302,43 @@ func (s *ActionHistoryService) GetRecentActionHistory(page, limit int) ([]Action
 	return responses, total, nil
 }
 
+// SearchActionHistory gets a page of action history matching a filter and the cursor of the next page (0 on the last page)
+func (s *ActionHistoryService) SearchActionHistory(filter repositories.HistoryFilter) ([]ActionHistoryResponse, uint, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	// One more row tells whether a next page exists
+	pageSize := filter.Limit
+	filter.Limit++
+	histories, err := s.actionHistoryRepo.Search(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var nextCursor uint
+	if len(histories) > pageSize {
+		histories = histories[:pageSize]
+		nextCursor = histories[pageSize-1].Id
+	}
+
+	// Convert to response format
+	responses := make([]ActionHistoryResponse, len(histories))
+	for i, history := range histories {
+		responses[i] = s.convertToResponse(history)
+	}
+
+	return responses, nextCursor, nil
+}
+
+// CountActionHistory gets the total count of action history records matching a filter
+func (s *ActionHistoryService) CountActionHistory(filter repositories.HistoryFilter) (int64, error) {
+	return s.actionHistoryRepo.Count(filter)
+}
+
 // Helper methods for common actions
 
 // LogDatabaseAction logs database-related actions
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+)
+
+// ErrJobNotCancellable is returned when a backup or a restore is already finished (or, for a restore, already running)
+var ErrJobNotCancellable = errors.New("cette tâche ne peut plus être annulée")
+
+// AdminService gives administrators a view of the databases, backups, restores and schedules of every user
+// and lets them start or cancel jobs on their behalf. Every job action is logged as an admin action.
+type AdminService struct {
+	databaseRepo         *repositories.DatabaseRepository
+	backupRepo           *repositories.BackupRepository
+	restoreRepo          *repositories.RestoreRepository
+	scheduleRepo         *repositories.ScheduleRepository
+	backupService        *BackupService
+	restoreService       *RestoreService
+	actionHistoryService *ActionHistoryService
+}
+
+// ResourceOwner is the user who created a resource
+type ResourceOwner struct {
+	Id        uint   `json:"id"`
+	Email     string `json:"email"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+}
+
+// AdminDatabase is a database with its owner
+type AdminDatabase struct {
+	models.Database
+	Owner ResourceOwner `json:"owner"`
+}
+
+// AdminBackup is a backup with its owner and the name of its database
+type AdminBackup struct {
+	models.Backup
+	DatabaseName string        `json:"database_name"`
+	Owner        ResourceOwner `json:"owner"`
+}
+
+// AdminRestore is a restore with its owner and the names of its backup and database
+type AdminRestore struct {
+	models.Restore
+	BackupFilename string        `json:"backup_filename"`
+	DatabaseName   string        `json:"database_name"`
+	Owner          ResourceOwner `json:"owner"`
+}
+
+// AdminSchedule is a schedule with its owner
+type AdminSchedule struct {
+	models.Schedule
+	Owner ResourceOwner `json:"owner"`
+}
+
+// NewAdminService constructor
+func NewAdminService(databaseRepo *repositories.DatabaseRepository, backupRepo *repositories.BackupRepository, restoreRepo *repositories.RestoreRepository, scheduleRepo *repositories.ScheduleRepository, backupService *BackupService, restoreService *RestoreService) *AdminService {
+	return &AdminService{
+		databaseRepo:   databaseRepo,
+		backupRepo:     backupRepo,
+		restoreRepo:    restoreRepo,
+		scheduleRepo:   scheduleRepo,
+		backupService:  backupService,
+		restoreService: restoreService,
+	}
+}
+
+// SetActionHistoryService sets the action history service for logging
+func (s *AdminService) SetActionHistoryService(actionHistoryService *ActionHistoryService) {
+	s.actionHistoryService = actionHistoryService
+}
+
+func ownerOf(user models.User) ResourceOwner {
+	return ResourceOwner{Id: user.Id, Email: user.Email, Firstname: user.Firstname, Lastname: user.Lastname}
+}
+
+// ListDatabases returns the databases of every user matching the filter
+func (s *AdminService) ListDatabases(filter repositories.AdminFilter) ([]AdminDatabase, int64, error) {
+	databases, total, err := s.databaseRepo.ListForAdmin(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]AdminDatabase, 0, len(databases))
+	for _, database := range databases {
+		result = append(result, AdminDatabase{Database: database, Owner: ownerOf(database.User)})
+	}
+	return result, total, nil
+}
+
+// ListBackups returns the backups of every user matching the filter
+func (s *AdminService) ListBackups(filter repositories.AdminFilter) ([]AdminBackup, int64, error) {
+	backups, total, err := s.backupRepo.ListForAdmin(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]AdminBackup, 0, len(backups))
+	for _, backup := range backups {
+		result = append(result, AdminBackup{Backup: backup, DatabaseName: backup.Database.Name, Owner: ownerOf(backup.User)})
+	}
+	return result, total, nil
+}
+
+// ListRestores returns the restores of every user matching the filter
+func (s *AdminService) ListRestores(filter repositories.AdminFilter) ([]AdminRestore, int64, error) {
+	restores, total, err := s.restoreRepo.ListForAdmin(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]AdminRestore, 0, len(restores))
+	for _, restore := range restores {
+		result = append(result, AdminRestore{
+			Restore:        restore,
+			BackupFilename: restore.Backup.Filename,
+			DatabaseName:   restore.Database.Name,
+			Owner:          ownerOf(restore.User),
+		})
+	}
+	return result, total, nil
+}
+
+// ListSchedules returns the schedules of every user matching the filter
+func (s *AdminService) ListSchedules(filter repositories.AdminFilter) ([]AdminSchedule, int64, error) {
+	schedules, total, err := s.scheduleRepo.ListForAdmin(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]AdminSchedule, 0, len(schedules))
+	for _, schedule := range schedules {
+		result = append(result, AdminSchedule{Schedule: schedule, Owner: ownerOf(schedule.User)})
+	}
+	return result, total, nil
+}
+
+// TriggerBackup starts a backup of a database on behalf of its owner
+func (s *AdminService) TriggerBackup(ctx context.Context, adminID, databaseID uint, ipAddress, userAgent string) (*models.Backup, error) {
+	backup, database, err := s.backupService.StartBackupForOwner(ctx, databaseID, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{
+		"backup_id":     backup.Id,
+		"database_id":   database.Id,
+		"database_name": database.Name,
+		"filename":      backup.Filename,
+	}
+	description := fmt.Sprintf("Sauvegarde '%s' lancée par un administrateur (Base de données: %s)", backup.Filename, database.Name)
+	s.logAdminAction(adminID, database.UserId, "admin_backup", "backup", backup.Id, description, metadata, ipAddress, userAgent)
+	return backup, nil
+}
+
+// RunSchedule runs a schedule immediately, even when it is inactive
+func (s *AdminService) RunSchedule(ctx context.Context, adminID, scheduleID uint, ipAddress, userAgent string) (*models.Backup, error) {
+	schedule, err := s.scheduleRepo.GetByID(scheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("planification introuvable: %v", err)
+	}
+	backup, database, err := s.backupService.StartBackupForOwner(ctx, schedule.DatabaseId, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{
+		"schedule_id":   schedule.Id,
+		"schedule_name": schedule.Name,
+		"backup_id":     backup.Id,
+		"database_id":   database.Id,
+		"database_name": database.Name,
+	}
+	description := fmt.Sprintf("Planification '%s' exécutée par un administrateur (Base de données: %s)", schedule.Name, database.Name)
+	s.logAdminAction(adminID, schedule.UserId, "admin_schedule_run", "schedule", schedule.Id, description, metadata, ipAddress, userAgent)
+	return backup, nil
+}
+
+// CancelBackup cancels a pending or running backup
+func (s *AdminService) CancelBackup(adminID, backupID uint, ipAddress, userAgent string) (*models.Backup, error) {
+	backup, err := s.backupService.CancelBackup(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{
+		"backup_id":   backup.Id,
+		"database_id": backup.DatabaseId,
+		"filename":    backup.Filename,
+	}
+	description := fmt.Sprintf("Sauvegarde '%s' annulée par un administrateur", backup.Filename)
+	s.logAdminAction(adminID, backup.UserId, "admin_backup_cancelled", "backup", backup.Id, description, metadata, ipAddress, userAgent)
+	return backup, nil
+}
+
+// CancelRestore cancels a restore that has not started yet
+func (s *AdminService) CancelRestore(adminID, restoreID uint, ipAddress, userAgent string) (*models.Restore, error) {
+	restore, err := s.restoreService.CancelRestore(restoreID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{
+		"restore_id":  restore.Id,
+		"backup_id":   restore.BackupId,
+		"database_id": restore.DatabaseId,
+	}
+	description := fmt.Sprintf("Restauration #%d annulée par un administrateur", restore.Id)
+	s.logAdminAction(adminID, restore.UserId, "admin_restore_cancelled", "restore", restore.Id, description, metadata, ipAddress, userAgent)
+	return restore, nil
+}
+
+func (s *AdminService) logAdminAction(adminID, ownerID uint, action, resourceType string, resourceID uint, description string, metadata map[string]interface{}, ipAddress, userAgent string) {
+	if s.actionHistoryService == nil {
+		return
+	}
+	_ = s.actionHistoryService.LogAdminAction(adminID, ownerID, action, resourceType, resourceID, description, metadata, ipAddress, userAgent)
+}
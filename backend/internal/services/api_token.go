@@ -0,0 +1,223 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+)
+
+// APITokenPrefix starts every personal API token, so the middleware can tell them apart from session JWTs
+// (and secret scanners can recognise a leaked token)
+const APITokenPrefix = "sb_"
+
+// maxAPITokensPerUser caps the number of usable tokens of a user
+const maxAPITokensPerUser = 20
+
+// Scopes an API token can be granted. Anything not covered by a scope (profile, admin, database
+// configuration, deletions...) stays reserved to interactive sessions.
+const (
+	ScopeDatabasesRead   = "databases:read"
+	ScopeBackupsRead     = "backups:read"
+	ScopeBackupsCreate   = "backups:create"
+	ScopeBackupsDownload = "backups:download"
+)
+
+// APITokenScopes lists the valid scopes
+var APITokenScopes = []string{ScopeDatabasesRead, ScopeBackupsRead, ScopeBackupsCreate, ScopeBackupsDownload}
+
+// ErrInvalidAPIToken is returned for unknown, revoked or expired API tokens
+var ErrInvalidAPIToken = errors.New("invalid, revoked or expired API token")
+
+// CreateAPITokenInput describes a token to create
+type CreateAPITokenInput struct {
+	Name        string
+	Scopes      []string
+	DatabaseIDs []uint     // Empty: every database of the user
+	ExpiresAt   *time.Time // Nil: no expiry
+}
+
+// SetAPITokenRepository enables personal API tokens
+func (s *AuthService) SetAPITokenRepository(apiTokenRepo *repositories.APITokenRepository) {
+	s.apiTokenRepo = apiTokenRepo
+}
+
+// SetAuthorizationService lets API tokens be restricted to the databases of the user's teams
+func (s *AuthService) SetAuthorizationService(authz *AuthorizationService) {
+	s.authz = authz
+}
+
+// CreateAPIToken creates a token for the user and returns it in clear text: it is only shown once,
+// the database keeps its SHA-256 hash
+func (s *AuthService) CreateAPIToken(userID uint, input CreateAPITokenInput, ipAddress, userAgent string) (string, *models.APIToken, error) {
+	if s.apiTokenRepo == nil {
+		return "", nil, errors.New("les tokens d'API ne sont pas activés")
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return "", nil, errors.New("le nom du token est requis (100 caractères maximum)")
+	}
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return "", nil, errors.New("la date d'expiration doit être dans le futur")
+	}
+	databaseIDs, err := s.ownedDatabaseIDs(userID, input.DatabaseIDs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	count, err := s.apiTokenRepo.CountActiveForUser(userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("erreur lors de la création du token: %w", err)
+	}
+	if count >= maxAPITokensPerUser {
+		return "", nil, fmt.Errorf("nombre maximal de tokens d'API atteint (%d), révoquez-en un d'abord", maxAPITokensPerUser)
+	}
+
+	secret, err := security.RandomToken(32)
+	if err != nil {
+		return "", nil, fmt.Errorf("erreur lors de la génération du token: %w", err)
+	}
+	plaintext := APITokenPrefix + secret
+
+	token := &models.APIToken{
+		Name:        name,
+		Prefix:      plaintext[:len(APITokenPrefix)+6],
+		TokenHash:   security.HashToken(plaintext),
+		Scopes:      strings.Join(scopes, ","),
+		DatabaseIDs: joinIDs(databaseIDs),
+		ExpiresAt:   input.ExpiresAt,
+		UserId:      userID,
+	}
+	if err := s.apiTokenRepo.Create(token); err != nil {
+		return "", nil, fmt.Errorf("erreur lors de la création du token: %w", err)
+	}
+
+	s.logSessionAction(userID, "api_token_created", "api_token", token.Id,
+		fmt.Sprintf("Token d'API créé (%s)", token.Name),
+		map[string]interface{}{"token_id": token.Id, "name": token.Name, "scopes": scopes, "database_ids": databaseIDs, "expires_at": token.ExpiresAt},
+		ipAddress, userAgent)
+	return plaintext, token, nil
+}
+
+// ListAPITokens returns the tokens of a user (without their secret), most recent first
+func (s *AuthService) ListAPITokens(userID uint) ([]models.APIToken, error) {
+	if s.apiTokenRepo == nil {
+		return []models.APIToken{}, nil
+	}
+	return s.apiTokenRepo.GetByUser(userID)
+}
+
+// RevokeAPIToken revokes a token of the user; it is refused from the next request on
+func (s *AuthService) RevokeAPIToken(userID, tokenID uint, ipAddress, userAgent string) error {
+	if s.apiTokenRepo == nil {
+		return errors.New("token introuvable")
+	}
+	revoked, err := s.apiTokenRepo.Revoke(tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la révocation du token: %w", err)
+	}
+	if !revoked {
+		return errors.New("token introuvable")
+	}
+
+	s.logSessionAction(userID, "api_token_revoked", "api_token", tokenID,
+		fmt.Sprintf("Token d'API %d révoqué", tokenID),
+		map[string]interface{}{"token_id": tokenID},
+		ipAddress, userAgent)
+	return nil
+}
+
+// ValidateAPIToken checks a token presented in the Authorization header and records its use.
+// It is called by the auth middleware, the returned token carries its user and role.
+func (s *AuthService) ValidateAPIToken(plaintext, ipAddress string) (*models.APIToken, error) {
+	if s.apiTokenRepo == nil || !strings.HasPrefix(plaintext, APITokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+	token, err := s.apiTokenRepo.GetByHash(security.HashToken(plaintext))
+	if err != nil || !token.User.Active {
+		return nil, ErrInvalidAPIToken
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > sessionTouchInterval || token.LastUsedIP != ipAddress {
+		if err := s.apiTokenRepo.TouchLastUsed(token.Id, now, ipAddress); err != nil {
+			slog.Warn("failed to update API token usage", "token_id", token.Id, "error", err)
+		}
+	}
+	return token, nil
+}
+
+// normalizeScopes checks the requested scopes and removes duplicates
+func normalizeScopes(requested []string) ([]string, error) {
+	scopes := []string{}
+	seen := map[string]bool{}
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		valid := false
+		for _, known := range APITokenScopes {
+			if scope == known {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("scope inconnu: %q (valeurs possibles: %s)", scope, strings.Join(APITokenScopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("au moins un scope est requis")
+	}
+	return scopes, nil
+}
+
+// ownedDatabaseIDs checks that the databases a token is restricted to are accessible to the user
+func (s *AuthService) ownedDatabaseIDs(userID uint, requested []uint) ([]uint, error) {
+	ids := []uint{}
+	seen := map[uint]bool{}
+	for _, id := range requested {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	var databases []models.Database
+	if err := s.userRepo.GetDB().Where("id IN ?", ids).Find(&databases).Error; err != nil {
+		return nil, fmt.Errorf("erreur lors de la vérification des bases de données: %w", err)
+	}
+	if len(databases) != len(ids) {
+		return nil, errors.New("base de données introuvable")
+	}
+	for i := range databases {
+		if !s.authz.CanAccessDatabase(userID, &databases[i], TeamActionView) {
+			return nil, errors.New("base de données introuvable")
+		}
+	}
+	return ids, nil
+}
+
+// joinIDs serialises database IDs for storage
+func joinIDs(ids []uint) string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(values, ",")
+}
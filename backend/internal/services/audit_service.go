@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/tracing"
+	"gorm.io/gorm"
+)
+
+// auditAnchorDir is the folder of the chain anchors in backup storage
+const auditAnchorDir = "audit-anchors"
+
+// auditVerifyBatchSize is the number of action history rows loaded at once during a verification
+const auditVerifyBatchSize = 1000
+
+// AuditHead identifies the last row of the action history chain
+type AuditHead struct {
+	HistoryId uint   `json:"history_id"`
+	Hash      string `json:"hash"`
+	Rows      int64  `json:"rows"`
+}
+
+// AuditVerification is the result of a walk of the action history chain
+type AuditVerification struct {
+	Valid          bool       `json:"valid"`
+	Checked        int64      `json:"checked"`                 // Chained rows verified
+	Unsealed       int64      `json:"unsealed"`                // Rows recorded before chaining, not covered
+	Anonymized     int64      `json:"anonymized"`              // Rows whose IP address and user agent were erased
+	AnchorsChecked int        `json:"anchors_checked"`         // Anchors found in the chain
+	BrokenAt       *uint      `json:"broken_at,omitempty"`     // First row that breaks the chain
+	Reason         string     `json:"reason,omitempty"`        // Why the chain is broken
+	Head           *AuditHead `json:"head,omitempty"`          // Last verified row
+	LatestAnchor   *uint      `json:"latest_anchor,omitempty"` // ID of the most recent anchor
+	RemoteChecked  bool       `json:"remote_checked"`          // Latest anchor compared with its copy in backup storage
+	VerifiedAt     time.Time  `json:"verified_at"`
+}
+
+// AuditService verifies the hash chain of the action history and anchors its head into backup storage, so that
+// rows edited, inserted or deleted directly in the database are detected
+type AuditService struct {
+	historyRepo   *repositories.ActionHistoryRepository
+	backupService *BackupService
+}
+
+// NewAuditService constructor
+func NewAuditService(historyRepo *repositories.ActionHistoryRepository, backupService *BackupService) *AuditService {
+	return &AuditService{
+		historyRepo:   historyRepo,
+		backupService: backupService,
+	}
+}
+
+// storage returns the backup storage the anchors are written to
+func (s *AuditService) storage() CloudStorageService {
+	if s.backupService == nil {
+		return nil
+	}
+	return s.backupService.cloudStorage
+}
+
+// VerifyChain walks the action history in order and reports the first broken link: a row whose content no
+// longer matches its hash, a row that does not follow the previous one (deleted or inserted row), or an
+// anchor whose row was rewritten or deleted
+func (s *AuditService) VerifyChain(ctx context.Context) (*AuditVerification, error) {
+	_, span := tracing.Start(ctx, "audit.verify")
+	var runErr error
+	defer func() { tracing.End(span, runErr) }()
+
+	result := &AuditVerification{Valid: true}
+
+	// Rows of erased accounts legitimately lost their IP address, user agent and privacy metadata
+	erasedIDs, err := s.historyRepo.GetErasedUserIDs()
+	if err != nil {
+		runErr = err
+		return nil, err
+	}
+	erased := make(map[uint]bool, len(erasedIDs))
+	for _, id := range erasedIDs {
+		erased[id] = true
+	}
+	blankDigest := (&models.ActionHistory{}).ComputePrivacyDigest()
+
+	anchors, err := s.historyRepo.GetAnchors(-1)
+	if err != nil {
+		runErr = err
+		return nil, err
+	}
+	anchorsByRow := make(map[uint][]models.AuditAnchor, len(anchors))
+	for _, anchor := range anchors {
+		anchorsByRow[anchor.HistoryId] = append(anchorsByRow[anchor.HistoryId], anchor)
+	}
+
+	errBroken := errors.New("chain broken")
+	broken := func(row *models.ActionHistory, reason string) error {
+		id := row.Id
+		result.Valid = false
+		result.BrokenAt = &id
+		result.Reason = reason
+		return errBroken
+	}
+
+	prevHash := ""
+	started := false
+	err = s.historyRepo.WalkChain(auditVerifyBatchSize, func(rows []models.ActionHistory) error {
+		for i := range rows {
+			row := &rows[i]
+			if row.Hash == "" {
+				if !started {
+					result.Unsealed++
+					continue
+				}
+				return broken(row, "ligne non chaînée insérée dans l'historique")
+			}
+			started = true
+
+			if row.PrevHash != prevHash {
+				return broken(row, "la ligne ne suit pas la précédente (ligne supprimée, insérée ou réécrite)")
+			}
+			if row.HasPrivacyData() {
+				if row.ComputePrivacyDigest() != row.PrivacyDigest {
+					return broken(row, "adresse IP ou user agent modifié")
+				}
+			} else if row.PrivacyDigest != blankDigest {
+				if !erased[row.UserId] {
+					return broken(row, "adresse IP ou user agent effacé sans effacement du compte")
+				}
+				result.Anonymized++
+			}
+			if row.ComputeHash() != row.Hash {
+				return broken(row, "contenu de la ligne modifié")
+			}
+			for _, anchor := range anchorsByRow[row.Id] {
+				if anchor.Hash != row.Hash {
+					return broken(row, fmt.Sprintf("la ligne ne correspond plus à l'ancrage %d", anchor.Id))
+				}
+				result.AnchorsChecked++
+			}
+
+			prevHash = row.Hash
+			result.Checked++
+			result.Head = &AuditHead{HistoryId: row.Id, Hash: row.Hash, Rows: result.Checked}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBroken) {
+		runErr = err
+		return nil, err
+	}
+
+	// An anchor whose row was never reached means the end of the chain was deleted
+	if result.Valid && result.AnchorsChecked < len(anchors) {
+		for _, anchor := range anchors {
+			if result.Head == nil || anchor.HistoryId > result.Head.HistoryId {
+				id := anchor.HistoryId
+				result.Valid = false
+				result.BrokenAt = &id
+				result.Reason = fmt.Sprintf("ligne %d de l'ancrage %d supprimée", anchor.HistoryId, anchor.Id)
+				break
+			}
+		}
+	}
+
+	// The anchors table itself may have been rewritten: compare the latest anchor with its copy
+	if len(anchors) > 0 {
+		latest := anchors[0]
+		result.LatestAnchor = &latest.Id
+		if storage := s.storage(); storage != nil && result.Valid {
+			if err := s.checkRemoteAnchor(storage, &latest); err != nil {
+				id := latest.HistoryId
+				result.Valid = false
+				result.BrokenAt = &id
+				result.Reason = err.Error()
+			} else {
+				result.RemoteChecked = true
+			}
+		}
+	}
+
+	result.VerifiedAt = time.Now()
+	if !result.Valid {
+		slog.WarnContext(ctx, "audit chain verification failed", "broken_at", *result.BrokenAt, "reason", result.Reason)
+	}
+	return result, nil
+}
+
+// auditAnchorFile is the content of an anchor in backup storage
+type auditAnchorFile struct {
+	HistoryId  uint      `json:"history_id"`
+	Hash       string    `json:"hash"`
+	Rows       int64     `json:"rows"`
+	AnchoredAt time.Time `json:"anchored_at"`
+}
+
+// checkRemoteAnchor compares an anchor with its copy in backup storage
+func (s *AuditService) checkRemoteAnchor(storage CloudStorageService, anchor *models.AuditAnchor) error {
+	data, err := storage.DownloadFile(anchor.RemotePath)
+	if err != nil {
+		return fmt.Errorf("copie de l'ancrage %d introuvable dans le stockage: %v", anchor.Id, err)
+	}
+	var remote auditAnchorFile
+	if err := json.Unmarshal(data, &remote); err != nil {
+		return fmt.Errorf("copie de l'ancrage %d illisible: %v", anchor.Id, err)
+	}
+	if remote.HistoryId != anchor.HistoryId || remote.Hash != anchor.Hash {
+		return fmt.Errorf("l'ancrage %d ne correspond plus à sa copie dans le stockage", anchor.Id)
+	}
+	return nil
+}
+
+// AnchorHead copies the head of the chain into backup storage. Nothing is written when the head is already anchored.
+func (s *AuditService) AnchorHead(ctx context.Context) (*models.AuditAnchor, bool, error) {
+	_, span := tracing.Start(ctx, "audit.anchor")
+	var runErr error
+	defer func() { tracing.End(span, runErr) }()
+
+	storage := s.storage()
+	if storage == nil {
+		runErr = errors.New("service de stockage cloud non disponible")
+		return nil, false, runErr
+	}
+	head, rows, err := s.historyRepo.GetChainHead()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			runErr = errors.New("l'historique ne contient aucune ligne chaînée")
+		} else {
+			runErr = err
+		}
+		return nil, false, runErr
+	}
+	if latest, err := s.historyRepo.GetAnchors(1); err == nil && len(latest) == 1 && latest[0].HistoryId == head.Id && latest[0].Hash == head.Hash {
+		return &latest[0], false, nil
+	}
+
+	now := time.Now()
+	data, err := json.MarshalIndent(auditAnchorFile{HistoryId: head.Id, Hash: head.Hash, Rows: rows, AnchoredAt: now}, "", "  ")
+	if err != nil {
+		runErr = err
+		return nil, false, err
+	}
+	filename := fmt.Sprintf("anchor_%d_%s.json", head.Id, now.UTC().Format("2006-01-02_15-04-05"))
+	localPath := filepath.Join(os.TempDir(), filename)
+	if err := os.WriteFile(localPath, data, 0600); err != nil {
+		runErr = err
+		return nil, false, err
+	}
+	defer os.Remove(localPath)
+
+	remotePath := auditAnchorDir + "/" + filename
+	if err := storage.UploadFile(localPath, remotePath); err != nil {
+		runErr = fmt.Errorf("erreur lors de l'envoi de l'ancrage: %v", err)
+		return nil, false, runErr
+	}
+
+	anchor := &models.AuditAnchor{HistoryId: head.Id, Hash: head.Hash, Rows: rows, RemotePath: remotePath, CreatedAt: now}
+	if err := s.historyRepo.CreateAnchor(anchor); err != nil {
+		runErr = err
+		return nil, false, err
+	}
+	slog.InfoContext(ctx, "audit chain anchored", "history_id", head.Id, "rows", rows, "remote_path", remotePath)
+	return anchor, true, nil
+}
+
+// GetAnchors returns the most recent anchors of the chain
+func (s *AuditService) GetAnchors(limit int) ([]models.AuditAnchor, error) {
+	return s.historyRepo.GetAnchors(limit)
+}
@@ -1,36 +1,106 @@
 package services
 
 import (
+	"crypto/subtle"
 	"errors"
 	"log"
+	"log/slog"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/mailer"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/oidc"
 	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// DefaultRefreshTokenTTL is the lifetime of a session when SetRefreshTokenTTL is not called
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+// maxSessionsPerUser caps concurrent sessions: the least recently used ones are removed beyond it
+const maxSessionsPerUser = 10
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, malformed or expired refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again:
+	// the whole session is revoked because the token has probably been stolen
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+)
+
 // AuthService manages everything related to authentication
 type AuthService struct {
-	userRepo    *repositories.UserRepository
-	sessionRepo *repositories.SessionRepository
-	jwtSecret   string
-	tokenTTL    time.Duration
+	userRepo             *repositories.UserRepository
+	sessionRepo          *repositories.SessionRepository
+	actionHistoryService *ActionHistoryService
+	jwtSecret            string
+	tokenTTL             time.Duration
+	refreshTokenTTL      time.Duration
+	passwordResetTTL     time.Duration
+	invitationTTL        time.Duration
+	mailer               mailer.Mailer
+	resetURL             string
+	recoveryCodeRepo     *repositories.RecoveryCodeRepository
+	attempts             challengeAttempts // wrong second factor codes per partial token
+	loginProtection      models.LoginProtectionConfig
+	ipAttempts           ipLoginAttempts // failed logins per IP address
+	oidcProvider         *oidc.Provider  // nil when single sign-on is not configured
+	oidcGroupRoles       []OIDCGroupRole
+	oidcFlows            oidcFlows // pending authorization requests
+	settingRepo          *repositories.SettingRepository
+	apiTokenRepo         *repositories.APITokenRepository // nil when personal API tokens are disabled
+	authz                *AuthorizationService            // nil: API tokens can only be restricted to the user's own databases
 }
 
-// NewAuthService constructor
+// SessionMetadata describes the device a session is opened from
+type SessionMetadata struct {
+	DeviceName string
+	IPAddress  string
+	UserAgent  string
+}
+
+// TokenPair is returned on login and refresh
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+	Session          *models.Session
+}
+
+// NewAuthService constructor; tokenTTL is the lifetime of access tokens
 func NewAuthService(userRepo *repositories.UserRepository, sessionRepo *repositories.SessionRepository, jwtSecret string, tokenTTL time.Duration) *AuthService {
 	return &AuthService{
-		userRepo:    userRepo,
-		sessionRepo: sessionRepo,
-		jwtSecret:   jwtSecret,
-		tokenTTL:    tokenTTL,
+		userRepo:         userRepo,
+		sessionRepo:      sessionRepo,
+		jwtSecret:        jwtSecret,
+		tokenTTL:         tokenTTL,
+		refreshTokenTTL:  DefaultRefreshTokenTTL,
+		passwordResetTTL: DefaultPasswordResetTTL,
+		invitationTTL:    DefaultInvitationTTL,
+		loginProtection:  models.DefaultLoginProtectionConfig(),
 	}
 }
 
+// SetRefreshTokenTTL sets the lifetime of refresh tokens (how long a device stays signed in without activity)
+func (s *AuthService) SetRefreshTokenTTL(ttl time.Duration) {
+	s.refreshTokenTTL = ttl
+}
+
+// SetActionHistoryService sets the action history service used to record security events
+func (s *AuthService) SetActionHistoryService(actionHistoryService *ActionHistoryService) {
+	s.actionHistoryService = actionHistoryService
+}
+
+// AccessTokenTTL returns the lifetime of access tokens
+func (s *AuthService) AccessTokenTTL() time.Duration {
+	return s.tokenTTL
+}
+
 // ValidatePassword check the password according to the rules
 func ValidatePassword(password string) error {
 	if len(password) < 10 {
@@ -49,6 +119,11 @@ func ValidatePassword(password string) error {
 
 // Register create a new user and assign the "user" role by default
 func (s *AuthService) Register(user *models.User) error {
+	// Comptes créés par le fournisseur d'identité uniquement quand le SSO est obligatoire
+	if !s.LocalLoginEnabled() {
+		return ErrLocalLoginDisabled
+	}
+
 	// Check if the email already exists
 	existingUser, _ := s.userRepo.GetUserByEmail(user.Email)
 	if existingUser != nil {
@@ -89,52 +164,294 @@ func (s *AuthService) Register(user *models.User) error {
 
 // Login check the credentials and create a session with a JWT token
 func (s *AuthService) Login(email, password string) (string, error) {
+	pair, err := s.LoginWithMetadata(email, password, SessionMetadata{})
+	if err != nil {
+		return "", err
+	}
+	return pair.AccessToken, nil
+}
+
+// LoginWithMetadata checks the credentials and opens a new session for the device.
+// Other sessions of the user are kept: each device has its own refresh token family.
+// When a second factor is needed, the returned error is a *TwoFactorChallenge; during a lockout
+// (too many failures for the account or the IP address) it is a *LoginLockedError.
+func (s *AuthService) LoginWithMetadata(email, password string, metadata SessionMetadata) (*TokenPair, error) {
 	user, err := s.userRepo.GetUserByEmail(email)
 	if err != nil {
-		return "", errors.New("invalid email or password")
+		user = nil
+	}
+	if err := s.checkLoginAllowed(user, metadata); err != nil {
+		return nil, err
+	}
+	if user == nil {
+		s.recordLoginFailure(nil, "unknown_account", metadata)
+		return nil, errors.New("invalid email or password")
 	}
 
 	// Check password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.New("invalid email or password")
+		s.recordLoginFailure(user, "invalid_password", metadata)
+		return nil, errors.New("invalid email or password")
+	}
+	// The local login switch only applies once the password is known to be right: checking it earlier
+	// would tell unknown and administrator emails (break-glass login) apart from the others
+	if !s.localLoginAllowed(user) {
+		return nil, ErrLocalLoginDisabled
 	}
 
 	// Check if user is active
 	if !user.Active {
-		return "", errors.New("account is disabled")
+		s.logSessionAction(user.Id, "login_failed", "user", user.Id,
+			"Échec de connexion : compte désactivé",
+			map[string]interface{}{"reason": "account_disabled"},
+			metadata.IPAddress, metadata.UserAgent)
+		return nil, errors.New("account is disabled")
+	}
+
+	// Second factor: no session until the TOTP or recovery code is verified (see VerifyTwoFactor).
+	// The failure counter is only reset once the login is complete.
+	challenge, err := s.twoFactorChallenge(user)
+	if err != nil {
+		return nil, err
 	}
+	if challenge != nil {
+		return nil, challenge
+	}
+
+	pair, err := s.startSession(user, metadata)
+	if err != nil {
+		return nil, err
+	}
+	s.recordLoginSuccess(user, "password", metadata)
+	return pair, nil
+}
 
-	// OPTIMISATION 1: Nettoyer les sessions expirées avant de créer une nouvelle
+// startSession opens the session of an authenticated user on a new device
+func (s *AuthService) startSession(user *models.User, metadata SessionMetadata) (*TokenPair, error) {
+	// Nettoyer les sessions expirées avant de créer une nouvelle
 	if err := s.sessionRepo.DeleteExpiredSessions(); err != nil {
 		log.Printf("Avertissement: Impossible de nettoyer les sessions expirées: %v", err)
 	}
 
-	// OPTIMISATION 2: Supprimer les anciennes sessions de cet utilisateur (une seule session active par utilisateur)
-	if err := s.sessionRepo.DeleteByUserId(user.Id); err != nil {
-		log.Printf("Avertissement: Impossible de supprimer les anciennes sessions: %v", err)
+	pair, err := s.openSession(user, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	// Limiter le nombre de sessions simultanées (les moins récemment utilisées sont fermées)
+	if err := s.sessionRepo.DeleteOldestForUser(user.Id, maxSessionsPerUser); err != nil {
+		slog.Warn("failed to limit sessions of user", "user_id", user.Id, "error", err)
 	}
 
-	// Generate JWT token
-	token, err := security.GenerateJWT(s.jwtSecret, user.Id, user.Email, user.Role.Name, s.tokenTTL)
+	slog.Info("session created", "user_id", user.Id, "device", metadata.DeviceName)
+	return pair, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token (rotation).
+// Presenting a refresh token that was already rotated revokes the whole session.
+func (s *AuthService) Refresh(refreshToken string, metadata SessionMetadata) (*TokenPair, error) {
+	familyID, _, ok := strings.Cut(refreshToken, ".")
+	if !ok || familyID == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	session, err := s.sessionRepo.GetByFamilyID(familyID)
 	if err != nil {
-		return "", err
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// The family ID is public (it is also the sid of the access token): only a refresh token this family already
+	// issued and rotated, however long ago, proves a replay. Anything else is an invalid token and must not log
+	// the owner out.
+	presentedHash := security.HashToken(refreshToken)
+	if subtle.ConstantTimeCompare([]byte(presentedHash), []byte(session.RefreshToken)) != 1 {
+		consumed, err := s.sessionRepo.IsConsumedRefreshToken(familyID, presentedHash)
+		if err != nil {
+			return nil, err
+		}
+		if consumed {
+			s.revokeReusedFamily(session, metadata)
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if time.Now().After(session.RefreshExpiresAt) {
+		if err := s.sessionRepo.DeleteByFamilyID(familyID); err != nil {
+			slog.Warn("failed to delete expired session", "session_id", session.Id, "error", err)
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// The role may have changed and the account may have been disabled since the last rotation
+	user, err := s.userRepo.GetUserById(session.UserId)
+	if err != nil || !user.Active {
+		if err := s.sessionRepo.DeleteByFamilyID(familyID); err != nil {
+			slog.Warn("failed to delete session of disabled account", "session_id", session.Id, "user_id", session.UserId, "error", err)
+		}
+		return nil, errors.New("account is disabled")
+	}
+
+	pair, err := s.issueTokens(user, familyID)
+	if err != nil {
+		return nil, err
+	}
+	session.Token = pair.AccessToken
+	session.ExpiresAt = pair.AccessExpiresAt
+	session.RefreshToken = security.HashToken(pair.RefreshToken)
+	session.RefreshExpiresAt = pair.RefreshExpiresAt
+	session.LastUsedAt = time.Now()
+	if metadata.IPAddress != "" {
+		session.IPAddress = metadata.IPAddress
+	}
+	if metadata.UserAgent != "" {
+		session.UserAgent = metadata.UserAgent
+	}
+
+	rotated, err := s.sessionRepo.RotateTokens(session, presentedHash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request consumed the same refresh token first
+		s.revokeReusedFamily(session, metadata)
+		return nil, ErrRefreshTokenReused
+	}
+	pair.Session = session
+	return pair, nil
+}
+
+// RevokeRefreshToken closes the session a refresh token belongs to (logout with an expired access token)
+func (s *AuthService) RevokeRefreshToken(refreshToken string) error {
+	familyID, _, ok := strings.Cut(refreshToken, ".")
+	if !ok || familyID == "" {
+		return ErrInvalidRefreshToken
+	}
+	session, err := s.sessionRepo.GetByFamilyID(familyID)
+	if err != nil {
+		return errors.New("session not found")
+	}
+	// Knowing the public family ID is not enough to close the session
+	if subtle.ConstantTimeCompare([]byte(security.HashToken(refreshToken)), []byte(session.RefreshToken)) != 1 {
+		return ErrInvalidRefreshToken
+	}
+	if err := s.sessionRepo.DeleteByFamilyID(familyID); err != nil {
+		return err
+	}
+	slog.Info("session revoked with refresh token", "user_id", session.UserId, "session_id", session.Id)
+	return nil
+}
+
+// openSession creates the session row of a new device and its first token pair
+func (s *AuthService) openSession(user *models.User, metadata SessionMetadata) (*TokenPair, error) {
+	familyID, err := security.RandomID(16)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := s.issueTokens(user, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceName := metadata.DeviceName
+	if deviceName == "" {
+		deviceName = DeviceFromUserAgent(metadata.UserAgent)
+	}
+	if len(deviceName) > 100 {
+		deviceName = deviceName[:100]
 	}
 
-	// Create session in DB
 	session := &models.Session{
-		UserId:    user.Id,
-		Token:     token,
-		ExpiresAt: time.Now().Add(s.tokenTTL),
+		UserId:           user.Id,
+		Token:            pair.AccessToken,
+		ExpiresAt:        pair.AccessExpiresAt,
+		FamilyID:         familyID,
+		RefreshToken:     security.HashToken(pair.RefreshToken),
+		RefreshExpiresAt: pair.RefreshExpiresAt,
+		DeviceName:       deviceName,
+		IPAddress:        metadata.IPAddress,
+		UserAgent:        metadata.UserAgent,
+		LastUsedAt:       time.Now(),
 	}
 	if err := s.sessionRepo.CreateSession(session); err != nil {
-		return "", err
+		return nil, err
+	}
+	pair.Session = session
+	return pair, nil
+}
+
+// issueTokens generates an access token and a refresh token "<familyID>.<secret>" for a session
+func (s *AuthService) issueTokens(user *models.User, familyID string) (*TokenPair, error) {
+	now := time.Now()
+	accessToken, err := security.GenerateSessionJWT(s.jwtSecret, user.Id, user.Email, user.Role.Name, familyID, s.tokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := security.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  now.Add(s.tokenTTL),
+		RefreshToken:     familyID + "." + secret,
+		RefreshExpiresAt: now.Add(s.refreshTokenTTL),
+	}, nil
+}
+
+// revokeReusedFamily deletes a session whose refresh token was replayed and records the event
+func (s *AuthService) revokeReusedFamily(session *models.Session, metadata SessionMetadata) {
+	if err := s.sessionRepo.DeleteByFamilyID(session.FamilyID); err != nil {
+		slog.Error("failed to revoke reused refresh token family", "session_id", session.Id, "error", err)
+	}
+	slog.Warn("refresh token reuse detected, session revoked", "session_id", session.Id, "user_id", session.UserId)
+
+	s.logSessionAction(session.UserId, "refresh_token_reused", "session", session.Id,
+		"Réutilisation d'un refresh token détectée : session révoquée",
+		map[string]interface{}{"session_id": session.Id, "device_name": session.DeviceName, "session_ip": session.IPAddress},
+		metadata.IPAddress, metadata.UserAgent)
+}
+
+// DeviceFromUserAgent returns a short label such as "Firefox sur Linux" for the session list
+func DeviceFromUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Appareil inconnu"
 	}
 
-	log.Printf("Session créée pour l'utilisateur %d, token: %s...", user.Id, token[:10])
-	return token, nil
+	browser := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"},
+		{"Safari/", "Safari"}, {"curl/", "curl"}, {"PostmanRuntime", "Postman"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+	system := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			system = candidate.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " sur " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	if len(userAgent) > 100 {
+		return userAgent[:100]
+	}
+	return userAgent
 }
 
-// GetUserFromToken get a user from a JWT token
+// GetUserFromToken get a user from a JWT token whose session is still open
 func (s *AuthService) GetUserFromToken(tokenString string) (*models.User, error) {
 	// Parse and validate the token
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -151,6 +468,16 @@ func (s *AuthService) GetUserFromToken(tokenString string) (*models.User, error)
 		return nil, errors.New("invalid token claims")
 	}
 
+	// Restricted tokens (pending second factor) do not authenticate the user
+	if purpose, _ := claims["purpose"].(string); purpose != "" {
+		return nil, errors.New("invalid token")
+	}
+
+	// A logged out or revoked session no longer authenticates the user, even though its JWT has not expired
+	if _, err := s.ValidateSession(tokenString); err != nil {
+		return nil, err
+	}
+
 	// Get the user ID from the claims
 	userIDFloat, ok := claims["user_id"].(float64)
 	if !ok {
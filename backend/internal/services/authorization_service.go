@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// Team actions, checked against the role of the user in the team owning a database
+const (
+	TeamActionView    = "view"    // Lire la base, ses sauvegardes, planifications et restaurations
+	TeamActionOperate = "operate" // Sauvegarder, télécharger, restaurer, planifier
+	TeamActionManage  = "manage"  // Modifier, supprimer ou déplacer la base, gérer la phrase secrète
+)
+
+// teamActionMinimumRole is the least privileged team role allowed to perform an action
+var teamActionMinimumRole = map[string]string{
+	TeamActionView:    models.TeamRoleViewer,
+	TeamActionOperate: models.TeamRoleMember,
+	TeamActionManage:  models.TeamRoleAdmin,
+}
+
+// AuthorizationService answers "may this user do this to this database?" for every service and handler.
+// Resources (backups, schedules, restores) are authorized through their database, which belongs to a team.
+// A nil *AuthorizationService keeps the historical rule: only the user who created a resource may access it.
+type AuthorizationService struct {
+	teamRepo *repositories.TeamRepository
+}
+
+// NewAuthorizationService constructor
+func NewAuthorizationService(teamRepo *repositories.TeamRepository) *AuthorizationService {
+	return &AuthorizationService{teamRepo: teamRepo}
+}
+
+// Enabled reports whether team based authorization is active
+func (a *AuthorizationService) Enabled() bool {
+	return a != nil
+}
+
+// TeamRole returns the role of a user in a team ("" when not a member)
+func (a *AuthorizationService) TeamRole(teamID, userID uint) string {
+	if a == nil {
+		return ""
+	}
+	member, err := a.teamRepo.GetMembership(teamID, userID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Warn("failed to read team membership", "team_id", teamID, "user_id", userID, "error", err)
+		}
+		return ""
+	}
+	return member.Role
+}
+
+// CanInTeam reports whether a user may perform an action on the resources of a team
+func (a *AuthorizationService) CanInTeam(userID, teamID uint, action string) bool {
+	minimum, known := teamActionMinimumRole[action]
+	if !known {
+		return false
+	}
+	return models.TeamRoleRank(a.TeamRole(teamID, userID)) >= models.TeamRoleRank(minimum)
+}
+
+// CanAccessDatabase reports whether a user may perform an action on a database
+func (a *AuthorizationService) CanAccessDatabase(userID uint, database *models.Database, action string) bool {
+	if database == nil {
+		return false
+	}
+	// Bases antérieures aux équipes (pas encore migrées) : seul leur créateur y a accès
+	if a == nil || database.TeamId == nil {
+		return database.UserId == userID
+	}
+	return a.CanInTeam(userID, *database.TeamId, action)
+}
+
+// CanAccessResource reports whether a user may perform an action on a backup, schedule or restore,
+// given the user who created it and the database it belongs to
+func (a *AuthorizationService) CanAccessResource(userID, ownerID, databaseID uint, action string) bool {
+	if a == nil {
+		return ownerID == userID
+	}
+	// The database may be soft deleted: its backups stay readable by the team
+	database, err := a.teamRepo.GetDatabaseOwnership(databaseID)
+	if err != nil {
+		slog.Debug("database of resource not found", "database_id", databaseID, "error", err)
+		return false
+	}
+	return a.CanAccessDatabase(userID, database, action)
+}
+
+// UsersAllowed returns the active users who may perform an action on a database (e.g. to notify them)
+func (a *AuthorizationService) UsersAllowed(database *models.Database, action string) ([]models.User, error) {
+	if a == nil || database.TeamId == nil {
+		if database.User.Id == 0 || !database.User.Active {
+			return []models.User{}, nil
+		}
+		return []models.User{database.User}, nil
+	}
+	minimum := models.TeamRoleRank(teamActionMinimumRole[action])
+	roles := []string{}
+	for _, role := range []string{models.TeamRoleOwner, models.TeamRoleAdmin, models.TeamRoleMember, models.TeamRoleViewer} {
+		if minimum > 0 && models.TeamRoleRank(role) >= minimum {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		return []models.User{}, nil
+	}
+	members, err := a.teamRepo.GetMembersWithRoles(*database.TeamId, roles)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]models.User, 0, len(members))
+	for _, member := range members {
+		if member.User.Active {
+			users = append(users, member.User)
+		}
+	}
+	return users, nil
+}
+
+// AuthorizeDatabase returns an error when a user may not perform an action on a database
+func (a *AuthorizationService) AuthorizeDatabase(userID uint, database *models.Database, action string) error {
+	if !a.CanAccessDatabase(userID, database, action) {
+		return fmt.Errorf("accès non autorisé à cette base de données")
+	}
+	return nil
+}
+
+// PersonalTeam returns the personal workspace of a user, creating it on first use
+func (a *AuthorizationService) PersonalTeam(userID uint) (*models.Team, error) {
+	team, err := a.teamRepo.GetPersonalTeam(userID)
+	if err == nil {
+		return team, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	owner := userID
+	team = &models.Team{Name: "Espace personnel", Personal: true, OwnerId: &owner}
+	if err := a.teamRepo.CreateWithOwner(team, userID); err != nil {
+		return nil, fmt.Errorf("erreur lors de la création de l'espace personnel: %w", err)
+	}
+	return team, nil
+}
+
+// TeamForNewDatabase resolves the team of a database being created: the requested team
+// (the user must be allowed to manage its databases) or the personal workspace of the user
+func (a *AuthorizationService) TeamForNewDatabase(userID uint, requested *uint) (*uint, error) {
+	if a == nil {
+		return nil, nil
+	}
+	if requested != nil && *requested != 0 {
+		if !a.CanInTeam(userID, *requested, TeamActionManage) {
+			return nil, fmt.Errorf("accès non autorisé à cette équipe")
+		}
+		teamID := *requested
+		return &teamID, nil
+	}
+	team, err := a.PersonalTeam(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &team.Id, nil
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/container"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+)
+
+// backupContainerHeader describes a backup object so it can be decrypted offline with
+// cmd/safebase-decrypt: the wrapped data key and its key ID travel with the object.
+// backup.KeyID and backup.WrappedKey must be set; database may be nil when unknown.
+func backupContainerHeader(backup *models.Backup, database *models.Database) container.Header {
+	header := container.Header{
+		KeyID:      backup.KeyID,
+		WrappedKey: backup.WrappedKey,
+		Filename:   backup.Filename,
+		UserID:     backup.UserId,
+		BackupID:   backup.Id,
+	}
+	if database == nil || database.Id == 0 {
+		return header
+	}
+
+	header.DBType = database.Type
+	header.DatabaseName = database.Name
+	if backup.KeyID == security.PassphraseKeyID {
+		// The private key is only stored encrypted with the passphrase: the object stays zero-knowledge
+		header.Passphrase = &container.PassphraseKey{
+			KDF:               database.PassphraseKDF,
+			WrappedPrivateKey: database.PassphrasePrivateKey,
+		}
+	}
+	return header
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// migratedObjectSuffix marks objects re-encrypted with a per-backup data key
+const migratedObjectSuffix = ".env"
+
+// NeedsObjectMigration reports whether a backup object is still encrypted with a key derived from the user ID
+func NeedsObjectMigration(backup *models.Backup) bool {
+	return backup.Status == "completed" && backup.WrappedKey == ""
+}
+
+// MigrateBackupObjectContext re-encrypts a backup written with a derived user key under a random data key
+// wrapped by the primary master key, in the container format. The new object is uploaded next to the old one and read back before
+// the record points to it, so a failure at any step leaves the backup readable.
+func (s *BackupService) MigrateBackupObjectContext(ctx context.Context, backup *models.Backup) error {
+	if !NeedsObjectMigration(backup) {
+		return nil
+	}
+	if s.cloudStorage == nil {
+		return fmt.Errorf("service de stockage cloud non disponible")
+	}
+
+	ctx, span := tracing.Start(ctx, "backup.migrate_object", attribute.Int("backup.id", int(backup.Id)))
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	// Read the object with the old derived key
+	legacyEncryption, err := s.userEncryption(backup.UserId, backup.KeyID)
+	if err != nil {
+		return fmt.Errorf("clé de chiffrement indisponible: %v", err)
+	}
+	encryptedData, err := downloadFileTraced(ctx, s.cloudStorage, backup.Filepath)
+	if err != nil {
+		return fmt.Errorf("fichier non trouvé dans le stockage cloud: %v", err)
+	}
+	plainData, err := legacyEncryption.DecryptData(encryptedData)
+	if err != nil {
+		return fmt.Errorf("erreur lors du déchiffrement: %v", err)
+	}
+
+	// Re-encrypt with a fresh data key
+	keyring, err := s.getKeyring()
+	if err != nil {
+		return err
+	}
+	dataKey, err := security.GenerateDataKey()
+	if err != nil {
+		return err
+	}
+	keyID, wrappedKey, err := keyring.WrapDataKey(dataKey)
+	if err != nil {
+		return err
+	}
+	dataEncryption, err := NewEncryptionServiceWithKey(dataKey)
+	if err != nil {
+		return err
+	}
+	migrated := *backup
+	migrated.KeyID = keyID
+	migrated.WrappedKey = base64.StdEncoding.EncodeToString(wrappedKey)
+	sealed, err := dataEncryption.EncryptDataToContainer(plainData, backupContainerHeader(&migrated, &backup.Database))
+	if err != nil {
+		return fmt.Errorf("erreur lors du chiffrement: %v", err)
+	}
+
+	// Storage backends upload from a local file
+	tmpFile, err := os.CreateTemp(s.backupDir, "migrate-*.encrypted")
+	if err != nil {
+		return fmt.Errorf("erreur lors de la création du fichier temporaire: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.Write(sealed); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("erreur lors de l'écriture du fichier temporaire: %v", err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+
+	newPath := migratedObjectPath(backup.Filepath)
+	if err = uploadFileTraced(ctx, s.cloudStorage, tmpFile.Name(), newPath); err != nil {
+		return fmt.Errorf("erreur lors de l'upload vers le cloud: %v", err)
+	}
+
+	// Read the new object back before switching the record to it
+	if err = s.verifyMigratedObject(ctx, newPath, dataEncryption, plainData); err != nil {
+		if deleteErr := deleteFileTraced(ctx, s.cloudStorage, newPath); deleteErr != nil {
+			slog.WarnContext(ctx, "failed to delete unverified migrated object", "backup_id", backup.Id, "remote_path", newPath, "error", deleteErr)
+		}
+		return err
+	}
+
+	if err = s.backupRepo.WithContext(ctx).UpdateObjectEncryption(backup.Id, newPath, keyID, migrated.WrappedKey); err != nil {
+		return fmt.Errorf("erreur lors de la mise à jour de la sauvegarde: %v", err)
+	}
+
+	// The record no longer references the old object: a failed delete only leaves an orphan behind
+	if deleteErr := deleteFileTraced(ctx, s.cloudStorage, backup.Filepath); deleteErr != nil {
+		slog.WarnContext(ctx, "failed to delete object encrypted with derived key", "backup_id", backup.Id, "remote_path", backup.Filepath, "error", deleteErr)
+	}
+
+	slog.InfoContext(ctx, "backup object re-encrypted with data key", "backup_id", backup.Id, "key_id", keyID, "remote_path", newPath)
+	backup.Filepath = newPath
+	backup.KeyID = keyID
+	backup.WrappedKey = migrated.WrappedKey
+	return nil
+}
+
+// verifyMigratedObject downloads a re-encrypted object and compares it with the original content
+func (s *BackupService) verifyMigratedObject(ctx context.Context, remotePath string, encryption *EncryptionService, expected []byte) error {
+	uploaded, err := downloadFileTraced(ctx, s.cloudStorage, remotePath)
+	if err != nil {
+		return fmt.Errorf("vérification impossible: %v", err)
+	}
+	decrypted, err := encryption.DecryptData(uploaded)
+	if err != nil {
+		return fmt.Errorf("vérification impossible: %v", err)
+	}
+	if !bytes.Equal(decrypted, expected) {
+		return fmt.Errorf("vérification échouée: le contenu re-chiffré diffère de l'original")
+	}
+	return nil
+}
+
+// migratedObjectPath inserts the migration suffix before the extension ("a/b.zip" -> "a/b.env.zip")
+func migratedObjectPath(remotePath string) string {
+	ext := path.Ext(remotePath)
+	return strings.TrimSuffix(remotePath, ext) + migratedObjectSuffix + ext
+}
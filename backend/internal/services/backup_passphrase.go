@@ -0,0 +1,183 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+)
+
+// IsPassphraseError reports whether err is caused by a missing or wrong backup passphrase
+func IsPassphraseError(err error) bool {
+	return errors.Is(err, security.ErrPassphraseRequired) || errors.Is(err, security.ErrWrongPassphrase)
+}
+
+// passphraseKeyOf decodes the zero-knowledge key pair of a database (nil when never enabled)
+func passphraseKeyOf(database *models.Database) (*security.PassphraseKey, error) {
+	if database.PassphraseKDF == "" {
+		return nil, nil
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(database.PassphrasePublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("clé publique invalide: %v", err)
+	}
+	privateKey, err := base64.StdEncoding.DecodeString(database.PassphrasePrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("clé privée invalide: %v", err)
+	}
+	return &security.PassphraseKey{
+		KDF:               database.PassphraseKDF,
+		PublicKey:         publicKey,
+		WrappedPrivateKey: privateKey,
+	}, nil
+}
+
+// sealToDatabasePassphrase encrypts a backup data key to the public key of a protected database
+func sealToDatabasePassphrase(database *models.Database, dataKey []byte) ([]byte, error) {
+	key, err := passphraseKeyOf(database)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("phrase secrète non configurée pour cette base de données")
+	}
+	return security.SealToPublicKey(key.PublicKey, dataKey)
+}
+
+// unlockBackupDataKey opens the data key of a zero-knowledge backup with the passphrase
+func unlockBackupDataKey(backup *models.Backup, passphrase string) ([]byte, error) {
+	if backup.Database.Id == 0 {
+		return nil, fmt.Errorf("base de données de la sauvegarde introuvable")
+	}
+	key, err := passphraseKeyOf(&backup.Database)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("phrase secrète non configurée pour cette base de données")
+	}
+
+	privateKey, err := key.Unlock(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(backup.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("clé de données invalide: %v", err)
+	}
+	return security.OpenWithPrivateKey(privateKey, sealed)
+}
+
+// passphraseEncryption returns the encryption service of a zero-knowledge backup
+func passphraseEncryption(backup *models.Backup, passphrase string) (*EncryptionService, error) {
+	dataKey, err := unlockBackupDataKey(backup, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewEncryptionServiceWithKey(dataKey)
+}
+
+// IsPassphraseProtected reports whether a backup can only be decrypted with its database passphrase
+func IsPassphraseProtected(backup *models.Backup) bool {
+	return backup.KeyID == security.PassphraseKeyID
+}
+
+// CheckBackupPassphrase validates the passphrase of a zero-knowledge backup without downloading it
+func (s *BackupService) CheckBackupPassphrase(backup *models.Backup, passphrase string) error {
+	if !IsPassphraseProtected(backup) {
+		return nil
+	}
+	_, err := unlockBackupDataKey(backup, passphrase)
+	return err
+}
+
+// SetBackupPassphrase enables zero-knowledge backups for a database. When a key pair already exists
+// the current passphrase is required and the key pair is re-wrapped, so older backups stay readable.
+func (s *DatabaseService) SetBackupPassphrase(databaseID, userID uint, passphrase, currentPassphrase string, ipAddress, userAgent string) error {
+	database, err := s.databaseRepo.GetByID(databaseID)
+	if err != nil {
+		return fmt.Errorf("base de données introuvable: %v", err)
+	}
+	if err := s.authz.AuthorizeDatabase(userID, database, TeamActionManage); err != nil {
+		return err
+	}
+
+	existing, err := passphraseKeyOf(database)
+	if err != nil {
+		return err
+	}
+
+	var key *security.PassphraseKey
+	action := "passphrase_enabled"
+	if existing == nil {
+		key, err = security.NewPassphraseKey(passphrase)
+	} else {
+		key, err = existing.ChangePassphrase(currentPassphrase, passphrase)
+		action = "passphrase_changed"
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.databaseRepo.UpdatePassphraseKey(databaseID, true, key.KDF,
+		base64.StdEncoding.EncodeToString(key.PublicKey), base64.StdEncoding.EncodeToString(key.WrappedPrivateKey)); err != nil {
+		return err
+	}
+
+	s.logPassphraseAction(userID, action, database, ipAddress, userAgent)
+	return nil
+}
+
+// DisableBackupPassphrase stops protecting new backups with the passphrase. The key pair is kept:
+// existing zero-knowledge backups still require the passphrase.
+func (s *DatabaseService) DisableBackupPassphrase(databaseID, userID uint, currentPassphrase string, ipAddress, userAgent string) error {
+	database, err := s.databaseRepo.GetByID(databaseID)
+	if err != nil {
+		return fmt.Errorf("base de données introuvable: %v", err)
+	}
+	if err := s.authz.AuthorizeDatabase(userID, database, TeamActionManage); err != nil {
+		return err
+	}
+
+	key, err := passphraseKeyOf(database)
+	if err != nil {
+		return err
+	}
+	if key == nil || !database.PassphraseProtected {
+		return fmt.Errorf("phrase secrète non configurée pour cette base de données")
+	}
+	if _, err := key.Unlock(currentPassphrase); err != nil {
+		return err
+	}
+
+	if err := s.databaseRepo.UpdatePassphraseKey(databaseID, false, database.PassphraseKDF, database.PassphrasePublicKey, database.PassphrasePrivateKey); err != nil {
+		return err
+	}
+
+	s.logPassphraseAction(userID, "passphrase_disabled", database, ipAddress, userAgent)
+	return nil
+}
+
+// logPassphraseAction records a zero-knowledge mode change (the passphrase itself is never logged)
+func (s *DatabaseService) logPassphraseAction(userID uint, action string, database *models.Database, ipAddress, userAgent string) {
+	if s.actionHistoryService == nil {
+		return
+	}
+
+	descriptions := map[string]string{
+		"passphrase_enabled":  "Phrase secrète activée pour la base de données '%s'",
+		"passphrase_changed":  "Phrase secrète modifiée pour la base de données '%s'",
+		"passphrase_disabled": "Phrase secrète désactivée pour les nouvelles sauvegardes de '%s'",
+	}
+	metadata := map[string]interface{}{
+		"database_id":   database.Id,
+		"database_name": database.Name,
+	}
+	if err := s.actionHistoryService.LogAction(userID, action, "database", database.Id, fmt.Sprintf(descriptions[action], database.Name), metadata, ipAddress, userAgent); err != nil {
+		slog.Warn("failed to log passphrase change", "database_id", database.Id, "error", err)
+	}
+}
@@ -4,17 +4,27 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/logger"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WorkerPool interface for background tasks
@@ -29,8 +39,12 @@ type BackupService struct {
 	backupDir            string
 	workerPool           WorkerPoolInterface
 	cloudStorage         CloudStorageService // Generic cloud storage interface
-	encryption           *EncryptionService  // Encryption service for files
+	keyring              *security.Keyring   // Master keys wrapping the per-backup data keys
 	actionHistoryService *ActionHistoryService
+	authz                *AuthorizationService
+	quotas               *QuotaService
+	jobsMu               sync.Mutex
+	jobCancels           map[uint]context.CancelFunc // Running backup jobs, stopped by CancelBackup
 }
 
 // Constructor for BackupService
@@ -40,6 +54,7 @@ func NewBackupService(backupRepo *repositories.BackupRepository, databaseService
 		databaseService: databaseService,
 		userService:     userService,
 		backupDir:       backupDir,
+		jobCancels:      make(map[uint]context.CancelFunc),
 	}
 }
 
@@ -48,6 +63,16 @@ func (s *BackupService) SetActionHistoryService(actionHistoryService *ActionHist
 	s.actionHistoryService = actionHistoryService
 }
 
+// SetAuthorizationService enables team based access checks (nil keeps per-user ownership)
+func (s *BackupService) SetAuthorizationService(authz *AuthorizationService) {
+	s.authz = authz
+}
+
+// SetQuotaService enables the quotas (nil enforces no limit)
+func (s *BackupService) SetQuotaService(quotas *QuotaService) {
+	s.quotas = quotas
+}
+
 // generateBackupFilename generates a consistent filename for backups
 func (s *BackupService) generateBackupFilename(database *models.Database) string {
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
@@ -102,9 +127,65 @@ func (s *BackupService) SetCloudStorage(cloudStorage CloudStorageService) {
 	s.cloudStorage = cloudStorage
 }
 
-// SetEncryptionService sets the encryption service
-func (s *BackupService) SetEncryptionService(encryption *EncryptionService) {
-	s.encryption = encryption
+// SetKeyring sets the keyring used for backup encryption (defaults to security.DefaultKeyring)
+func (s *BackupService) SetKeyring(keyring *security.Keyring) {
+	s.keyring = keyring
+}
+
+// getKeyring returns the configured keyring or the process-wide default one
+func (s *BackupService) getKeyring() (*security.Keyring, error) {
+	if s.keyring != nil {
+		return s.keyring, nil
+	}
+	return security.DefaultKeyring()
+}
+
+// backupEncryption returns the encryption service able to decrypt the object of a backup.
+// The passphrase is only used (and required) for zero-knowledge backups.
+func (s *BackupService) backupEncryption(backup *models.Backup, passphrase string) (*EncryptionService, error) {
+	if backup.WrappedKey == "" {
+		return s.userEncryption(backup.UserId, backup.KeyID)
+	}
+	if backup.KeyID == security.PassphraseKeyID {
+		return passphraseEncryption(backup, passphrase)
+	}
+
+	keyring, err := s.getKeyring()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(backup.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("clé de données invalide: %v", err)
+	}
+	dataKey, err := keyring.UnwrapDataKey(backup.KeyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return NewEncryptionServiceWithKey(dataKey)
+}
+
+// userEncryption returns the per-user derived encryption service used before envelope encryption.
+// An empty key ID designates backups written before key IDs were recorded.
+func (s *BackupService) userEncryption(userID uint, keyID string) (*EncryptionService, error) {
+	keyring, err := s.getKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	if keyID == "" {
+		salt := keyring.LegacyBackupSalt()
+		if salt == "" {
+			return nil, fmt.Errorf("sauvegarde antérieure aux identifiants de clé: LEGACY_BACKUP_SALT non configuré")
+		}
+		return NewEncryptionService(GenerateUserKey(userID, salt)), nil
+	}
+
+	masterKey, err := keyring.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return NewEncryptionService(GenerateUserKey(userID, hex.EncodeToString(masterKey))), nil
 }
 
 // getMySQLDumpPaths returns possible mysqldump paths based on OS
@@ -195,7 +276,7 @@ func (s *BackupService) getPostgreSQLDumpPaths() []string {
 }
 
 // findExecutable tries to find a working executable from a list of paths
-func (s *BackupService) findExecutable(paths []string) (string, error) {
+func findExecutable(paths []string) (string, error) {
 	for _, path := range paths {
 		if _, err := exec.LookPath(path); err == nil {
 			return path, nil
@@ -205,72 +286,96 @@ func (s *BackupService) findExecutable(paths []string) (string, error) {
 }
 
 // executeBackupAsync executes the backup process asynchronously
-func (s *BackupService) executeBackupAsync(backup *models.Backup, database *models.Database) {
+// Each phase (dump, compress, encrypt, upload) gets its own span under the "backup.job" span
+func (s *BackupService) executeBackupAsync(ctx context.Context, backup *models.Backup, database *models.Database) {
+	ctx, span := tracing.Start(ctx, "backup.job",
+		attribute.Int64("backup.id", int64(backup.Id)),
+		attribute.Int64("database.id", int64(database.Id)),
+		attribute.String("database.type", database.Type),
+	)
+	defer span.End()
+
+	// An administrator may cancel the job: the dump commands are killed with the context
+	ctx, cancel := context.WithCancel(ctx)
+	s.trackJob(backup.Id, cancel)
+	defer s.untrackJob(backup.Id)
+	backupRepo := s.backupRepo.WithContext(ctx)
+
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Printf("[BACKUP] Panic in backup process for ID %d: %v\n", backup.Id, r)
-			s.updateBackupError(backup.Id, fmt.Sprintf("Erreur critique: %v", r))
+			slog.ErrorContext(ctx, "panic in backup process", "backup_id", backup.Id, "panic", fmt.Sprint(r))
+			s.updateBackupError(ctx, backup.Id, fmt.Sprintf("Erreur critique: %v", r))
 		}
 	}()
 
-	fmt.Printf("[BACKUP] Starting asynchronous backup process for ID: %d\n", backup.Id)
+	slog.InfoContext(ctx, "starting asynchronous backup process", "backup_id", backup.Id, "database_id", database.Id)
 
-	// Update status to running
-	if err := s.backupRepo.UpdateStatus(backup.Id, "running", ""); err != nil {
-		fmt.Printf("[BACKUP] Failed to update status to running: %v\n", err)
+	// Update status to running, unless the job was cancelled while waiting in the queue
+	started, err := backupRepo.TransitionStatus(backup.Id, []string{"pending"}, "running")
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to update backup status to running", "backup_id", backup.Id, "error", err)
+		return
+	}
+	if !started {
+		slog.InfoContext(ctx, "backup cancelled before it started", "backup_id", backup.Id)
 		return
 	}
 
 	// Get user information for MinIO path
 	user, err := s.userService.GetUserByID(database.UserId)
 	if err != nil {
-		fmt.Printf("[BACKUP] Failed to get user information: %v\n", err)
-		s.updateBackupError(backup.Id, fmt.Sprintf("Erreur lors de la récupération des informations utilisateur: %v", err))
+		slog.ErrorContext(ctx, "failed to get user information", "backup_id", backup.Id, "user_id", database.UserId, "error", err)
+		s.updateBackupError(ctx, backup.Id, fmt.Sprintf("Erreur lors de la récupération des informations utilisateur: %v", err))
 		return
 	}
 
 	// Create backup directory if it doesn't exist (temporary for dump creation)
 	if err := os.MkdirAll(s.backupDir, 0755); err != nil {
-		fmt.Printf("[BACKUP] Failed to create backup directory: %v\n", err)
-		s.updateBackupError(backup.Id, fmt.Sprintf("Erreur lors de la création du répertoire: %v", err))
+		slog.ErrorContext(ctx, "failed to create backup directory", "backup_id", backup.Id, "error", err)
+		s.updateBackupError(ctx, backup.Id, fmt.Sprintf("Erreur lors de la création du répertoire: %v", err))
 		return
 	}
 
 	var backupFilepath string
 
 	// Execute backup based on database type
+	dumpCtx, dumpSpan := tracing.Start(ctx, "backup.dump", attribute.String("database.type", database.Type))
 	switch dbType := database.Type; dbType {
 	case "mysql":
 		sqlFilename := strings.TrimSuffix(backup.Filename, ".zip") + ".sql"
 		dbTypeDir := filepath.Join(s.backupDir, "mysql")
-		backupFilepath, err = s.dumpMySQL(database, dbTypeDir, sqlFilename)
+		backupFilepath, err = s.dumpMySQL(dumpCtx, database, dbTypeDir, sqlFilename)
 	case "postgresql":
 		sqlFilename := strings.TrimSuffix(backup.Filename, ".zip") + ".sql"
 		dbTypeDir := filepath.Join(s.backupDir, "postgresql")
-		backupFilepath, err = s.dumpPostgreSQL(database, dbTypeDir, sqlFilename)
+		backupFilepath, err = s.dumpPostgreSQL(dumpCtx, database, dbTypeDir, sqlFilename)
 	default:
 		err = fmt.Errorf("type de base de données non supporté: %s", dbType)
 	}
+	tracing.End(dumpSpan, err)
 
 	if err != nil {
-		fmt.Printf("[BACKUP] Backup failed for ID %d: %v\n", backup.Id, err)
-		s.updateBackupError(backup.Id, err.Error())
+		slog.ErrorContext(ctx, "backup dump failed", "backup_id", backup.Id, "error", err)
+		s.updateBackupError(ctx, backup.Id, err.Error())
 		return
 	}
 
 	// Compress the SQL file to ZIP
 	zipFilePath := strings.TrimSuffix(backupFilepath, ".sql") + ".zip"
-	fmt.Printf("[BACKUP] Compressing SQL file to ZIP: %s -> %s\n", backupFilepath, zipFilePath)
+	slog.DebugContext(ctx, "compressing SQL file to ZIP", "backup_id", backup.Id, "source", backupFilepath, "target", zipFilePath)
 
-	if err := s.zipFile(backupFilepath, zipFilePath); err != nil {
-		fmt.Printf("[BACKUP] Failed to compress backup file: %v\n", err)
-		s.updateBackupError(backup.Id, fmt.Sprintf("Erreur lors de la compression: %v", err))
+	_, zipSpan := tracing.Start(ctx, "backup.compress")
+	err = s.zipFile(backupFilepath, zipFilePath)
+	tracing.End(zipSpan, err)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to compress backup file", "backup_id", backup.Id, "error", err)
+		s.updateBackupError(ctx, backup.Id, fmt.Sprintf("Erreur lors de la compression: %v", err))
 		return
 	}
 
 	// Remove the original SQL file after successful compression
 	if err := os.Remove(backupFilepath); err != nil {
-		fmt.Printf("[BACKUP] Warning: failed to remove original SQL file: %v\n", err)
+		slog.WarnContext(ctx, "failed to remove original SQL file", "backup_id", backup.Id, "error", err)
 		// Don't fail the backup for this
 	}
 
@@ -280,33 +385,56 @@ func (s *BackupService) executeBackupAsync(backup *models.Backup, database *mode
 	// Get file size
 	fileInfo, err := os.Stat(backupFilepath)
 	if err != nil {
-		fmt.Printf("[BACKUP] Failed to get file info: %v\n", err)
-		s.updateBackupError(backup.Id, fmt.Sprintf("Erreur lors de la récupération des informations du fichier: %v", err))
+		slog.ErrorContext(ctx, "failed to get backup file info", "backup_id", backup.Id, "error", err)
+		s.updateBackupError(ctx, backup.Id, fmt.Sprintf("Erreur lors de la récupération des informations du fichier: %v", err))
 		return
 	}
 
 	// Upload to cloud storage (required for cloud storage)
 	var remotePath string
-	if s.cloudStorage != nil && s.encryption != nil {
-		// Generate user-specific encryption key
-		userKey := GenerateUserKey(database.UserId, "SafeBaseBackupSalt2025!")
-		userEncryption := NewEncryptionService(userKey)
-
-		// Encrypt the file before upload
-		fmt.Printf("[BACKUP] Encrypting backup file before upload...\n")
-		encryptedData, err := userEncryption.EncryptFile(backupFilepath)
+	keyring, keyringErr := s.getKeyring()
+	if s.cloudStorage != nil && keyringErr == nil {
+		// Envelope encryption: a random data key per backup, wrapped with the primary master key
+		dataKey, err := security.GenerateDataKey()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to generate backup data key", "backup_id", backup.Id, "error", err)
+			s.updateBackupError(ctx, backup.Id, fmt.Sprintf("Erreur lors de la génération de la clé: %v", err))
+			return
+		}
+		var keyID string
+		var wrappedKey []byte
+		if database.PassphraseProtected {
+			// Zero-knowledge mode: only the holder of the passphrase can open the data key
+			keyID = security.PassphraseKeyID
+			wrappedKey, err = sealToDatabasePassphrase(database, dataKey)
+		} else {
+			keyID, wrappedKey, err = keyring.WrapDataKey(dataKey)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to wrap backup data key", "backup_id", backup.Id, "error", err)
+			s.updateBackupError(ctx, backup.Id, fmt.Sprintf("Erreur lors du chiffrement de la clé: %v", err))
+			return
+		}
+		backup.KeyID = keyID
+		backup.WrappedKey = base64.StdEncoding.EncodeToString(wrappedKey)
+
+		dataEncryption, err := NewEncryptionServiceWithKey(dataKey)
 		if err != nil {
-			fmt.Printf("[BACKUP] Failed to encrypt backup file: %v\n", err)
-			s.updateBackupError(backup.Id, fmt.Sprintf("Erreur lors du chiffrement: %v", err))
+			slog.ErrorContext(ctx, "failed to initialize backup encryption", "backup_id", backup.Id, "error", err)
+			s.updateBackupError(ctx, backup.Id, fmt.Sprintf("Erreur lors de l'initialisation du chiffrement: %v", err))
 			return
 		}
 
-		// Create temporary encrypted file
+		// Encrypt the file before upload, in the self-describing container format
+		slog.DebugContext(ctx, "encrypting backup file before upload", "backup_id", backup.Id)
 		encryptedFilePath := backupFilepath + ".encrypted"
-		err = os.WriteFile(encryptedFilePath, encryptedData, 0644)
+		_, encryptSpan := tracing.Start(ctx, "backup.encrypt", attribute.Int64("backup.size", fileInfo.Size()))
+		err = dataEncryption.EncryptFileToContainer(backupFilepath, encryptedFilePath, backupContainerHeader(backup, database))
+		tracing.End(encryptSpan, err)
 		if err != nil {
-			fmt.Printf("[BACKUP] Failed to write encrypted file: %v\n", err)
-			s.updateBackupError(backup.Id, fmt.Sprintf("Erreur lors de l'écriture du fichier chiffré: %v", err))
+			slog.ErrorContext(ctx, "failed to encrypt backup file", "backup_id", backup.Id, "error", err)
+			s.updateBackupError(ctx, backup.Id, fmt.Sprintf("Erreur lors du chiffrement: %v", err))
+			os.Remove(encryptedFilePath) // Clean up
 			return
 		}
 
@@ -314,26 +442,26 @@ func (s *BackupService) executeBackupAsync(backup *models.Backup, database *mode
 		remotePath = s.cloudStorage.GenerateRemotePath(fmt.Sprintf("%s %s", user.Firstname, user.Lastname), database.Type, backup.Filename)
 
 		// Upload encrypted file
-		if err := s.cloudStorage.UploadFile(encryptedFilePath, remotePath); err != nil {
-			fmt.Printf("[BACKUP] Failed to upload to cloud storage: %v\n", err)
-			s.updateBackupError(backup.Id, fmt.Sprintf("Erreur lors de l'upload vers le cloud: %v", err))
+		if err := uploadFileTraced(ctx, s.cloudStorage, encryptedFilePath, remotePath); err != nil {
+			slog.ErrorContext(ctx, "failed to upload backup to cloud storage", "backup_id", backup.Id, "remote_path", remotePath, "error", err)
+			s.updateBackupError(ctx, backup.Id, fmt.Sprintf("Erreur lors de l'upload vers le cloud: %v", err))
 			os.Remove(encryptedFilePath) // Clean up
 			return
 		} else {
-			fmt.Printf("[BACKUP] Successfully uploaded encrypted backup to cloud: %s\n", remotePath)
+			slog.InfoContext(ctx, "uploaded encrypted backup to cloud storage", "backup_id", backup.Id, "remote_path", remotePath)
 		}
 
 		// Clean up encrypted temporary file
 		os.Remove(encryptedFilePath)
 	} else {
-		fmt.Printf("[BACKUP] Cloud storage or encryption service not available - backup failed\n")
-		s.updateBackupError(backup.Id, "Service de stockage cloud ou de chiffrement non disponible")
+		slog.ErrorContext(ctx, "cloud storage or encryption service not available", "backup_id", backup.Id)
+		s.updateBackupError(ctx, backup.Id, "Service de stockage cloud ou de chiffrement non disponible")
 		return
 	}
 
 	// Clean up local temporary file after successful upload
 	if err := os.Remove(backupFilepath); err != nil {
-		fmt.Printf("[BACKUP] Warning: failed to remove temporary local file: %v\n", err)
+		slog.WarnContext(ctx, "failed to remove temporary local file", "backup_id", backup.Id, "error", err)
 		// Don't fail the backup for this
 	}
 
@@ -342,28 +470,82 @@ func (s *BackupService) executeBackupAsync(backup *models.Backup, database *mode
 	backup.Filepath = remotePath // Store cloud path
 	backup.Size = fileInfo.Size()
 
-	// Update status and filepath
-	if err := s.backupRepo.UpdateStatus(backup.Id, "completed", ""); err != nil {
-		fmt.Printf("[BACKUP] Failed to update backup status: %v\n", err)
+	// Update status and filepath, unless the job was cancelled while uploading
+	completed, err := s.backupRepo.WithContext(context.WithoutCancel(ctx)).TransitionStatus(backup.Id, []string{"running"}, "completed")
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to update backup status", "backup_id", backup.Id, "error", err)
+		return
+	}
+	if !completed {
+		slog.InfoContext(ctx, "backup cancelled during upload, removing it", "backup_id", backup.Id)
+		if err := deleteFileTraced(context.WithoutCancel(ctx), s.cloudStorage, remotePath); err != nil {
+			slog.ErrorContext(ctx, "failed to delete cancelled backup from cloud storage", "backup_id", backup.Id, "error", err)
+		}
 		return
 	}
 
 	// Update filepath and size
-	if err := s.backupRepo.UpdateFileInfo(backup.Id, remotePath, fileInfo.Size()); err != nil {
-		fmt.Printf("[BACKUP] Failed to update file info: %v\n", err)
+	if err := backupRepo.UpdateFileInfo(backup.Id, remotePath, fileInfo.Size()); err != nil {
+		slog.ErrorContext(ctx, "failed to update backup file info", "backup_id", backup.Id, "error", err)
+		return
+	}
+
+	// Record the wrapped data key and its master key ID so the object can be decrypted after a key rotation
+	if err := backupRepo.UpdateEncryptionKey(backup.Id, backup.KeyID, backup.WrappedKey); err != nil {
+		slog.ErrorContext(ctx, "failed to update backup encryption key", "backup_id", backup.Id, "error", err)
+		return
+	}
+
+	if s.enforceStorageQuota(ctx, backup, database) {
 		return
 	}
 
-	fmt.Printf("[BACKUP] Backup process completed successfully for ID: %d\n", backup.Id)
+	slog.InfoContext(ctx, "backup process completed", "backup_id", backup.Id, "size", fileInfo.Size())
+}
+
+// enforceStorageQuota removes a completed backup that pushed its user or its team over the storage quota.
+// It reports whether the backup was removed.
+func (s *BackupService) enforceStorageQuota(ctx context.Context, backup *models.Backup, database *models.Database) bool {
+	quotaErr := s.quotas.CheckBackupStorage(backup.UserId, database.TeamId)
+	if quotaErr == nil {
+		return false
+	}
+	if !errors.Is(quotaErr, ErrQuotaExceeded) {
+		slog.WarnContext(ctx, "failed to check backup storage quota", "backup_id", backup.Id, "error", quotaErr)
+		return false
+	}
+
+	slog.WarnContext(ctx, "backup exceeds storage quota, removing it", "backup_id", backup.Id, "size", backup.Size)
+	if err := deleteFileTraced(ctx, s.cloudStorage, backup.Filepath); err != nil {
+		slog.ErrorContext(ctx, "failed to delete backup over quota from cloud storage", "backup_id", backup.Id, "error", err)
+	}
+	s.updateBackupError(ctx, backup.Id, "Sauvegarde supprimée, "+quotaErr.Error())
+	backup.Status = "failed"
+
+	if s.actionHistoryService != nil {
+		metadata := map[string]interface{}{
+			"backup_id":     backup.Id,
+			"database_id":   database.Id,
+			"database_name": database.Name,
+			"size":          backup.Size,
+		}
+		description := fmt.Sprintf("Sauvegarde '%s' supprimée : quota de stockage dépassé (Base de données: %s)", backup.Filename, database.Name)
+		_ = s.actionHistoryService.LogAction(backup.UserId, "quota_exceeded", "backup", backup.Id, description, metadata, "", "")
+	}
+	return true
+}
+
+// isRemoteHost reports whether a database host is outside the local/private network
+func isRemoteHost(host string) bool {
+	return host != "localhost" && host != "127.0.0.1" && !strings.HasPrefix(host, "192.168.") && !strings.HasPrefix(host, "10.")
 }
 
 // dumpMySQL creates a MySQL dump
-func (s *BackupService) dumpMySQL(database *models.Database, outputDir string, filename string) (string, error) {
+func (s *BackupService) dumpMySQL(ctx context.Context, database *models.Database, outputDir string, filename string) (string, error) {
 	dumpFile := filepath.Join(outputDir, filename)
 
-	// Log the start of backup process
-	fmt.Printf("[BACKUP] Starting MySQL dump for database %s (host: %s, port: %s, user: %s, password_length: %d)\n",
-		database.DbName, database.Host, database.Port, database.Username, len(database.Password))
+	// Log the start of backup process (connection details stay out of the logs)
+	slog.InfoContext(ctx, "starting MySQL dump", "database_id", database.Id, "db_name", database.DbName)
 
 	// Validate that password is not empty
 	if len(database.Password) == 0 {
@@ -371,19 +553,19 @@ func (s *BackupService) dumpMySQL(database *models.Database, outputDir string, f
 	}
 
 	// Determine if this is a remote connection
-	isRemote := database.Host != "localhost" && database.Host != "127.0.0.1" && !strings.HasPrefix(database.Host, "192.168.") && !strings.HasPrefix(database.Host, "10.")
-	fmt.Printf("[BACKUP] Connection type analysis: host='%s', remote=%t\n", database.Host, isRemote)
+	isRemote := isRemoteHost(database.Host)
+	slog.DebugContext(ctx, "connection type analysis", "remote", isRemote)
 
 	// Skip connectivity test for now - let mysqldump handle connection errors
 	// This prevents false negatives with complex network configurations
-	fmt.Printf("[BACKUP] Skipping connectivity test - will test during dump execution\n")
 
 	// Find mysqldump executable
-	mysqldumpPath, err := s.findExecutable(s.getMySQLDumpPaths())
+	mysqldumpPath, err := findExecutable(s.getMySQLDumpPaths())
 	if err != nil {
 		return "", fmt.Errorf("mysqldump non trouvé: %v", err)
 	}
-	fmt.Printf("[BACKUP] Using mysqldump at: %s\n", mysqldumpPath)
+	slog.DebugContext(ctx, "using mysqldump", "path", mysqldumpPath)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("dump.tool", mysqldumpPath))
 
 	// Check if we're using MAMP (detect by path)
 	isMAMP := strings.Contains(mysqldumpPath, "/Applications/MAMP/")
@@ -402,7 +584,6 @@ func (s *BackupService) dumpMySQL(database *models.Database, outputDir string, f
 
 	// Connection and SSL configuration
 	if isRemote {
-		fmt.Printf("[BACKUP] Configuring for remote MySQL connection\n")
 		args = append(args, "-h", database.Host, "-P", database.Port)
 
 		// For remote connections, try SSL but don't require it
@@ -420,21 +601,20 @@ func (s *BackupService) dumpMySQL(database *models.Database, outputDir string, f
 		for _, caPath := range sslCaPaths {
 			if _, err := os.Stat(caPath); err == nil {
 				args = append(args, "--ssl-ca="+caPath)
-				fmt.Printf("[BACKUP] Using SSL CA: %s\n", caPath)
+				slog.DebugContext(ctx, "using SSL CA bundle", "path", caPath)
 				break
 			}
 		}
 
 	} else if isMAMP {
-		fmt.Printf("[BACKUP] Detected MAMP installation, using socket connection (recommended for MAMP)\n")
 		// For MAMP, use socket connection instead of TCP
 		socketPath := "/Applications/MAMP/tmp/mysql/mysql.sock"
 		if _, err := os.Stat(socketPath); err == nil {
 			args = append(args, "--socket="+socketPath)
-			fmt.Printf("[BACKUP] Using MAMP socket: %s\n", socketPath)
+			slog.DebugContext(ctx, "using MAMP socket connection", "socket", socketPath)
 		} else {
 			// Fallback to TCP if socket not found
-			fmt.Printf("[BACKUP] MAMP socket not found at %s, falling back to TCP connection\n", socketPath)
+			slog.DebugContext(ctx, "MAMP socket not found, falling back to TCP", "socket", socketPath)
 			args = append(args, "-h", database.Host, "-P", database.Port)
 		}
 	} else {
@@ -445,12 +625,11 @@ func (s *BackupService) dumpMySQL(database *models.Database, outputDir string, f
 	args = append(args, database.DbName)
 
 	// Set timeout for the command (30 minutes max for large databases)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	dumpCtx, cancel := context.WithTimeout(ctx, 30*time.Minute)
 	defer cancel()
 
 	// Try with SSL options first
-	fmt.Printf("[BACKUP] Command args: %v\n", args)
-	cmd := exec.CommandContext(ctx, mysqldumpPath, args...)
+	cmd := exec.CommandContext(dumpCtx, mysqldumpPath, args...)
 	cmd.Env = env
 
 	output, err := os.Create(dumpFile)
@@ -464,13 +643,11 @@ func (s *BackupService) dumpMySQL(database *models.Database, outputDir string, f
 	cmd.Stdout = output
 	cmd.Stderr = &stderr
 
-	fmt.Printf("[BACKUP] Starting mysqldump execution...\n")
 	err = cmd.Run()
 
 	if err != nil {
 		stderrStr := stderr.String()
-		fmt.Printf("[BACKUP] mysqldump failed: %v\n", err)
-		fmt.Printf("[BACKUP] stderr: %s\n", stderrStr)
+		slog.WarnContext(ctx, "mysqldump failed", "error", err, "stderr", stderrStr)
 
 		// Build base args for retry attempts (without SSL options)
 		buildBaseArgs := func() []string {
@@ -503,12 +680,12 @@ func (s *BackupService) dumpMySQL(database *models.Database, outputDir string, f
 				strings.Contains(stderrStr, "connect_timeout") ||
 				strings.Contains(stderrStr, "ssl-ca")) {
 
-			fmt.Printf("[BACKUP] SSL options not supported, retrying with basic options...\n")
+			slog.InfoContext(ctx, "SSL options not supported by mysqldump, retrying with basic options")
 
 			// Retry with basic args (no SSL options)
 			baseArgs := buildBaseArgs()
 
-			cmd = exec.CommandContext(context.Background(), mysqldumpPath, baseArgs...)
+			cmd = exec.CommandContext(ctx, mysqldumpPath, baseArgs...)
 			cmd.Env = env
 			output2, err := os.Create(dumpFile)
 			if err != nil {
@@ -520,11 +697,9 @@ func (s *BackupService) dumpMySQL(database *models.Database, outputDir string, f
 			cmd.Stdout = output2
 			cmd.Stderr = &stderr2
 
-			fmt.Printf("[BACKUP] Retry command args: %v\n", baseArgs)
 			if err2 := cmd.Run(); err2 != nil {
 				stderr2Str := stderr2.String()
-				fmt.Printf("[BACKUP] mysqldump retry failed: %v\n", err2)
-				fmt.Printf("[BACKUP] stderr: %s\n", stderr2Str)
+				slog.ErrorContext(ctx, "mysqldump retry failed", "error", err2, "stderr", stderr2Str)
 				os.Remove(dumpFile)
 				return "", fmt.Errorf("erreur lors de l'exécution de mysqldump (même sans SSL): %v, stderr: %s", err2, stderr2Str)
 			}
@@ -539,18 +714,17 @@ func (s *BackupService) dumpMySQL(database *models.Database, outputDir string, f
 		}
 	}
 
-	fmt.Printf("[BACKUP] mysqldump completed successfully\n")
+	slog.InfoContext(ctx, "mysqldump completed", "database_id", database.Id)
 
 	return dumpFile, nil
 }
 
 // dumpPostgreSQL creates a PostgreSQL dump
-func (s *BackupService) dumpPostgreSQL(database *models.Database, outputDir string, filename string) (string, error) {
+func (s *BackupService) dumpPostgreSQL(ctx context.Context, database *models.Database, outputDir string, filename string) (string, error) {
 	dumpFile := filepath.Join(outputDir, filename)
 
-	// Log the start of backup process
-	fmt.Printf("[BACKUP] Starting PostgreSQL dump for database %s (host: %s, port: %s, user: %s, password_length: %d)\n",
-		database.DbName, database.Host, database.Port, database.Username, len(database.Password))
+	// Log the start of backup process (connection details stay out of the logs)
+	slog.InfoContext(ctx, "starting PostgreSQL dump", "database_id", database.Id, "db_name", database.DbName)
 
 	// Validate that password is not empty
 	if len(database.Password) == 0 {
@@ -558,34 +732,32 @@ func (s *BackupService) dumpPostgreSQL(database *models.Database, outputDir stri
 	}
 
 	// Determine if this is a remote connection
-	isRemote := database.Host != "localhost" && database.Host != "127.0.0.1" && !strings.HasPrefix(database.Host, "192.168.") && !strings.HasPrefix(database.Host, "10.")
-	fmt.Printf("[BACKUP] Connection type analysis: host='%s', remote=%t\n", database.Host, isRemote)
+	isRemote := isRemoteHost(database.Host)
+	slog.DebugContext(ctx, "connection type analysis", "remote", isRemote)
 
 	// Skip connectivity test for now - let pg_dump handle connection errors
 	// This prevents false negatives with complex network configurations
-	fmt.Printf("[BACKUP] Skipping connectivity test - will test during dump execution\n")
 
 	// Find pg_dump executable
-	pgDumpPath, err := s.findExecutable(s.getPostgreSQLDumpPaths())
+	pgDumpPath, err := findExecutable(s.getPostgreSQLDumpPaths())
 	if err != nil {
 		return "", fmt.Errorf("pg_dump non trouvé: %v", err)
 	}
-	fmt.Printf("[BACKUP] Using pg_dump at: %s\n", pgDumpPath)
+	slog.DebugContext(ctx, "using pg_dump", "path", pgDumpPath)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("dump.tool", pgDumpPath))
 
 	// Set environment variables for password and SSL mode
 	env := os.Environ()
 	env = append(env, fmt.Sprintf("PGPASSWORD=%s", database.Password))
 
 	// Configure SSL mode via environment variable (not command line option)
+	sslMode := "prefer"
 	if isRemote {
 		// For remote connections (Neon, Supabase, etc.), require SSL
-		fmt.Printf("[BACKUP] Configuring SSL mode 'require' for remote PostgreSQL connection\n")
-		env = append(env, "PGSSLMODE=require")
-	} else {
-		// For local connections, prefer SSL but allow fallback
-		fmt.Printf("[BACKUP] Configuring SSL mode 'prefer' for local PostgreSQL connection\n")
-		env = append(env, "PGSSLMODE=prefer")
+		sslMode = "require"
 	}
+	// For local connections, prefer SSL but allow fallback
+	env = append(env, "PGSSLMODE="+sslMode)
 
 	// Build pg_dump arguments (no SSL options in args, use env vars instead)
 	args := []string{
@@ -602,9 +774,9 @@ func (s *BackupService) dumpPostgreSQL(database *models.Database, outputDir stri
 	}
 
 	// Set timeout for the command (30 minutes max for large databases)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	dumpCtx, cancel := context.WithTimeout(ctx, 30*time.Minute)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, pgDumpPath, args...)
+	cmd := exec.CommandContext(dumpCtx, pgDumpPath, args...)
 
 	cmd.Env = env
 
@@ -612,41 +784,31 @@ func (s *BackupService) dumpPostgreSQL(database *models.Database, outputDir stri
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
-	fmt.Printf("[BACKUP] Command args: %v\n", args)
-	fmt.Printf("[BACKUP] Environment: PGSSLMODE=%s\n", func() string {
-		if isRemote {
-			return "require"
-		}
-		return "prefer"
-	}())
-	fmt.Printf("[BACKUP] Starting pg_dump execution...\n")
+	slog.DebugContext(ctx, "starting pg_dump execution", "ssl_mode", sslMode)
 
 	err = cmd.Run()
 	if err != nil {
 		stderrStr := stderr.String()
-		fmt.Printf("[BACKUP] pg_dump failed: %v\n", err)
-		fmt.Printf("[BACKUP] stderr: %s\n", stderrStr)
+		slog.WarnContext(ctx, "pg_dump failed", "error", err, "stderr", stderrStr)
 
 		// If SSL connection failed for local connections, try without SSL
 		if !isRemote && (strings.Contains(stderrStr, "SSL") || strings.Contains(stderrStr, "ssl")) {
-			fmt.Printf("[BACKUP] SSL connection failed for local connection, retrying with PGSSLMODE=disable...\n")
+			slog.InfoContext(ctx, "SSL connection failed for local connection, retrying with PGSSLMODE=disable")
 
 			// Retry without SSL for local connections
 			envNoSSL := os.Environ()
 			envNoSSL = append(envNoSSL, fmt.Sprintf("PGPASSWORD=%s", database.Password))
 			envNoSSL = append(envNoSSL, "PGSSLMODE=disable")
 
-			cmd = exec.CommandContext(context.Background(), pgDumpPath, args...)
+			cmd = exec.CommandContext(ctx, pgDumpPath, args...)
 			cmd.Env = envNoSSL
 
 			var stderr2 bytes.Buffer
 			cmd.Stderr = &stderr2
 
-			fmt.Printf("[BACKUP] Retry with PGSSLMODE=disable\n")
 			err2 := cmd.Run()
 			if err2 != nil {
-				fmt.Printf("[BACKUP] pg_dump retry failed: %v\n", err2)
-				fmt.Printf("[BACKUP] stderr: %s\n", stderr2.String())
+				slog.ErrorContext(ctx, "pg_dump retry failed", "error", err2, "stderr", stderr2.String())
 				os.Remove(dumpFile)
 				return "", fmt.Errorf("erreur lors de l'exécution de pg_dump (même sans SSL): %v, stderr: %s", err2, stderr2.String())
 			}
@@ -656,21 +818,30 @@ func (s *BackupService) dumpPostgreSQL(database *models.Database, outputDir stri
 		}
 	}
 
-	fmt.Printf("[BACKUP] pg_dump completed successfully\n")
+	slog.InfoContext(ctx, "pg_dump completed", "database_id", database.Id)
 
 	return dumpFile, nil
 }
 
-// updateBackupError updates backup status to failed with error message
-func (s *BackupService) updateBackupError(backupID uint, errorMsg string) {
-	if err := s.backupRepo.UpdateStatus(backupID, "failed", errorMsg); err != nil {
+// updateBackupError updates backup status to failed with error message (and fails the job span)
+func (s *BackupService) updateBackupError(ctx context.Context, backupID uint, errorMsg string) {
+	tracing.Fail(ctx, errorMsg)
+	// A cancelled job already has its final status
+	if errors.Is(ctx.Err(), context.Canceled) {
+		slog.InfoContext(context.WithoutCancel(ctx), "backup job cancelled", "backup_id", backupID)
+		return
+	}
+	if err := s.backupRepo.WithContext(ctx).UpdateStatus(backupID, "failed", errorMsg); err != nil {
 		// Log the error but don't fail the operation
-		fmt.Printf("Warning: failed to update backup status: %v\n", err)
+		slog.WarnContext(ctx, "failed to update backup status", "backup_id", backupID, "error", err)
 	}
 }
 
-// GetBackupsByUser returns all backups for a user
+// GetBackupsByUser returns all backups a user can access
 func (s *BackupService) GetBackupsByUser(userID uint) ([]models.Backup, error) {
+	if s.authz.Enabled() {
+		return s.backupRepo.GetAccessibleByUser(userID)
+	}
 	return s.backupRepo.GetByUserID(userID)
 }
 
@@ -686,33 +857,48 @@ func (s *BackupService) GetBackupByID(id uint) (*models.Backup, error) {
 
 // DownloadBackup downloads a backup file from cloud storage with decryption
 func (s *BackupService) DownloadBackup(id uint, userID uint) ([]byte, error) {
-	backup, err := s.backupRepo.GetByID(id)
+	return s.DownloadBackupContext(context.Background(), id, userID)
+}
+
+// DownloadBackupContext downloads and decrypts a backup, tracing the storage call under ctx
+func (s *BackupService) DownloadBackupContext(ctx context.Context, id uint, userID uint) ([]byte, error) {
+	return s.DownloadBackupWithPassphraseContext(ctx, id, userID, "")
+}
+
+// DownloadBackupWithPassphraseContext downloads and decrypts a backup; passphrase protected backups
+// are rejected with security.ErrPassphraseRequired or security.ErrWrongPassphrase before any download
+func (s *BackupService) DownloadBackupWithPassphraseContext(ctx context.Context, id uint, userID uint, passphrase string) ([]byte, error) {
+	backup, err := s.backupRepo.WithContext(ctx).GetByID(id)
 	if err != nil {
 		return nil, fmt.Errorf("sauvegarde introuvable: %v", err)
 	}
 
-	// Verify user ownership
-	if backup.UserId != userID {
+	// Verify that the user may download the backup
+	if !s.authz.CanAccessResource(userID, backup.UserId, backup.DatabaseId, TeamActionOperate) {
 		return nil, fmt.Errorf("accès non autorisé à cette sauvegarde")
 	}
 
-	// Check if cloud storage and encryption services are available
-	if s.cloudStorage == nil || s.encryption == nil {
+	// Check if cloud storage is available
+	if s.cloudStorage == nil {
 		return nil, fmt.Errorf("services de stockage cloud ou de chiffrement non disponibles")
 	}
 
-	// Generate user-specific encryption key
-	userKey := GenerateUserKey(userID, "SafeBaseBackupSalt2025!")
-	userEncryption := NewEncryptionService(userKey)
+	// Unwrap the data key of the backup (or derive the key of backups written before envelope encryption)
+	backupEncryption, err := s.backupEncryption(backup, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("clé de chiffrement indisponible: %w", err)
+	}
 
 	// Download encrypted file from cloud
-	encryptedData, err := s.cloudStorage.DownloadFile(backup.Filepath)
+	encryptedData, err := downloadFileTraced(ctx, s.cloudStorage, backup.Filepath)
 	if err != nil {
 		return nil, fmt.Errorf("fichier non trouvé dans le stockage cloud: %v", err)
 	}
 
 	// Decrypt the file
-	decryptedData, err := userEncryption.DecryptData(encryptedData)
+	_, decryptSpan := tracing.Start(ctx, "backup.decrypt", attribute.Int("backup.size", len(encryptedData)))
+	decryptedData, err := backupEncryption.DecryptData(encryptedData)
+	tracing.End(decryptSpan, err)
 	if err != nil {
 		return nil, fmt.Errorf("erreur lors du déchiffrement: %v", err)
 	}
@@ -727,15 +913,15 @@ func (s *BackupService) DeleteBackup(id uint, userID uint) error {
 		return fmt.Errorf("sauvegarde introuvable: %v", err)
 	}
 
-	// Verify user ownership
-	if backup.UserId != userID {
+	// Verify that the user may delete the backup
+	if !s.authz.CanAccessResource(userID, backup.UserId, backup.DatabaseId, TeamActionManage) {
 		return fmt.Errorf("accès non autorisé à cette sauvegarde")
 	}
 
 	// Delete from cloud storage
 	if s.cloudStorage != nil {
-		if err := s.cloudStorage.DeleteFile(backup.Filepath); err != nil {
-			fmt.Printf("[BACKUP] Warning: failed to delete from cloud storage: %v\n", err)
+		if err := deleteFileTraced(context.Background(), s.cloudStorage, backup.Filepath); err != nil {
+			slog.Warn("failed to delete backup from cloud storage", "backup_id", backup.Id, "error", err)
 			return fmt.Errorf("erreur lors de la suppression du fichier cloud: %v", err)
 		}
 	} else {
@@ -750,38 +936,26 @@ func (s *BackupService) DeleteBackup(id uint, userID uint) error {
 
 // CreateBackup creates a backup and logs the action
 func (s *BackupService) CreateBackup(databaseID uint, userID uint, ipAddress string, userAgent string) (*models.Backup, error) {
+	return s.CreateBackupContext(context.Background(), databaseID, userID, ipAddress, userAgent)
+}
+
+// CreateBackupContext creates a backup and logs the action, propagating the request
+// correlation ID from ctx into the asynchronous backup job
+func (s *BackupService) CreateBackupContext(ctx context.Context, databaseID uint, userID uint, ipAddress string, userAgent string) (*models.Backup, error) {
 	// Get database info with decrypted password
 	database, err := s.databaseService.GetDatabaseByID(databaseID)
 	if err != nil {
 		return nil, fmt.Errorf("database not found: %v", err)
 	}
 
-	// Verify that the database belongs to the user
-	if database.UserId != userID {
+	// Verify that the user may back up the database
+	if !s.authz.CanAccessDatabase(userID, database, TeamActionOperate) {
 		return nil, fmt.Errorf("unauthorized: database does not belong to user")
 	}
 
-	// Create backup record with pending status
-	backup := &models.Backup{
-		UserId:     userID,
-		DatabaseId: databaseID,
-		Status:     "pending",
-		Filename:   s.generateBackupFilename(database),
-		Filepath:   "", // Will be set when backup is completed
-		UserAgent:  userAgent,
-	}
-
-	if err := s.backupRepo.Create(backup); err != nil {
-		return nil, fmt.Errorf("failed to create backup record: %v", err)
-	}
-
-	// Execute backup asynchronously using worker pool
-	if s.workerPool != nil {
-		s.workerPool.Submit(func() {
-			s.executeBackupAsync(backup, database)
-		})
-	} else {
-		go s.executeBackupAsync(backup, database)
+	backup, err := s.startBackup(ctx, database, userID, userAgent)
+	if err != nil {
+		return nil, err
 	}
 
 	// Log the action
@@ -808,8 +982,8 @@ func (s *BackupService) DeleteBackupWithLogging(id uint, userID uint, ipAddress
 		return fmt.Errorf("sauvegarde introuvable: %v", err)
 	}
 
-	// Verify user ownership
-	if backup.UserId != userID {
+	// Verify that the user may delete the backup
+	if !s.authz.CanAccessResource(userID, backup.UserId, backup.DatabaseId, TeamActionManage) {
 		return fmt.Errorf("accès non autorisé à cette sauvegarde")
 	}
 
@@ -821,8 +995,8 @@ func (s *BackupService) DeleteBackupWithLogging(id uint, userID uint, ipAddress
 
 	// Delete from cloud storage
 	if s.cloudStorage != nil {
-		if err := s.cloudStorage.DeleteFile(backup.Filepath); err != nil {
-			fmt.Printf("[BACKUP] Warning: failed to delete from cloud storage: %v\n", err)
+		if err := deleteFileTraced(context.Background(), s.cloudStorage, backup.Filepath); err != nil {
+			slog.Warn("failed to delete backup from cloud storage", "backup_id", backup.Id, "error", err)
 			return fmt.Errorf("erreur lors de la suppression du fichier cloud: %v", err)
 		}
 	} else {
@@ -854,7 +1028,12 @@ func (s *BackupService) DeleteBackupWithLogging(id uint, userID uint, ipAddress
 
 // DownloadBackupWithLogging downloads a backup and logs the action
 func (s *BackupService) DownloadBackupWithLogging(id uint, userID uint, ipAddress string, userAgent string) ([]byte, error) {
-	data, err := s.DownloadBackup(id, userID)
+	return s.DownloadBackupWithPassphraseLogging(context.Background(), id, userID, "", ipAddress, userAgent)
+}
+
+// DownloadBackupWithPassphraseLogging downloads a backup, unlocking zero-knowledge backups with the passphrase, and logs the action
+func (s *BackupService) DownloadBackupWithPassphraseLogging(ctx context.Context, id uint, userID uint, passphrase string, ipAddress string, userAgent string) ([]byte, error) {
+	data, err := s.DownloadBackupWithPassphraseContext(ctx, id, userID, passphrase)
 	if err != nil {
 		return nil, err
 	}
@@ -892,3 +1071,93 @@ func (s *BackupService) DownloadBackupWithLogging(id uint, userID uint, ipAddres
 
 	return data, nil
 }
+
+// startBackup checks the backup quota, creates the pending backup record and submits the job
+func (s *BackupService) startBackup(ctx context.Context, database *models.Database, userID uint, userAgent string) (*models.Backup, error) {
+	if err := s.quotas.CheckBackupStart(userID, database.TeamId); err != nil {
+		return nil, err
+	}
+
+	// Create backup record with pending status
+	backup := &models.Backup{
+		UserId:     userID,
+		DatabaseId: database.Id,
+		Status:     "pending",
+		Filename:   s.generateBackupFilename(database),
+		Filepath:   "", // Will be set when backup is completed
+		UserAgent:  userAgent,
+	}
+
+	if err := s.backupRepo.WithContext(ctx).Create(backup); err != nil {
+		return nil, fmt.Errorf("failed to create backup record: %v", err)
+	}
+
+	// The job outlives the HTTP request: keep its values (request ID) but not its cancellation
+	jobCtx := logger.WithJobID(context.WithoutCancel(ctx), fmt.Sprintf("backup-%d", backup.Id))
+
+	// Execute backup asynchronously using worker pool
+	if s.workerPool != nil {
+		s.workerPool.Submit(func() {
+			s.executeBackupAsync(jobCtx, backup, database)
+		})
+	} else {
+		go s.executeBackupAsync(jobCtx, backup, database)
+	}
+
+	return backup, nil
+}
+
+// trackJob registers the cancel function of a running backup job
+func (s *BackupService) trackJob(backupID uint, cancel context.CancelFunc) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	s.jobCancels[backupID] = cancel
+}
+
+// untrackJob releases the context of a finished backup job
+func (s *BackupService) untrackJob(backupID uint) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	if cancel, exists := s.jobCancels[backupID]; exists {
+		cancel()
+		delete(s.jobCancels, backupID)
+	}
+}
+
+// CancelBackup cancels a pending or running backup job (the authorization is checked by the caller)
+func (s *BackupService) CancelBackup(backupID uint) (*models.Backup, error) {
+	backup, err := s.backupRepo.GetByID(backupID)
+	if err != nil {
+		return nil, fmt.Errorf("sauvegarde introuvable: %v", err)
+	}
+	cancelled, err := s.backupRepo.TransitionStatus(backupID, []string{"pending", "running"}, models.BackupStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, ErrJobNotCancellable
+	}
+
+	s.jobsMu.Lock()
+	if cancel, exists := s.jobCancels[backupID]; exists {
+		cancel()
+	}
+	s.jobsMu.Unlock()
+
+	backup.Status = models.BackupStatusCancelled
+	return backup, nil
+}
+
+// StartBackupForOwner starts a backup of a database on behalf of its creator (scheduled or administrator
+// triggered backups). Authorization and logging are left to the caller.
+func (s *BackupService) StartBackupForOwner(ctx context.Context, databaseID uint, userAgent string) (*models.Backup, *models.Database, error) {
+	database, err := s.databaseService.GetDatabaseByID(databaseID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("base de données introuvable: %v", err)
+	}
+	backup, err := s.startBackup(ctx, database, database.UserId, userAgent)
+	if err != nil {
+		return nil, nil, err
+	}
+	return backup, database, nil
+}
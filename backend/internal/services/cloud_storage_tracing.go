@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RyanLadmia/plateforme-safebase/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The CloudStorageService interface has no context parameter, so storage calls are
+// traced at the call site with these helpers (one client span per operation)
+
+func startStorageSpan(ctx context.Context, storage CloudStorageService, operation, remotePath string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "storage."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("storage.backend", fmt.Sprintf("%T", storage)),
+			attribute.String("storage.remote_path", remotePath),
+		),
+	)
+}
+
+// uploadFileTraced uploads a file to cloud storage inside a span
+func uploadFileTraced(ctx context.Context, storage CloudStorageService, localPath, remotePath string) error {
+	_, span := startStorageSpan(ctx, storage, "upload", remotePath)
+	err := storage.UploadFile(localPath, remotePath)
+	tracing.End(span, err)
+	return err
+}
+
+// downloadFileTraced downloads a file from cloud storage inside a span
+func downloadFileTraced(ctx context.Context, storage CloudStorageService, remotePath string) ([]byte, error) {
+	_, span := startStorageSpan(ctx, storage, "download", remotePath)
+	data, err := storage.DownloadFile(remotePath)
+	span.SetAttributes(attribute.Int("storage.size", len(data)))
+	tracing.End(span, err)
+	return data, err
+}
+
+// deleteFileTraced deletes a file from cloud storage inside a span
+func deleteFileTraced(ctx context.Context, storage CloudStorageService, remotePath string) error {
+	_, span := startStorageSpan(ctx, storage, "delete", remotePath)
+	err := storage.DeleteFile(remotePath)
+	tracing.End(span, err)
+	return err
+}
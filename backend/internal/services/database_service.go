@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"log/slog"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
@@ -15,6 +16,8 @@ type DatabaseService struct {
 	scheduleRepo         *repositories.ScheduleRepository
 	backupService        *BackupService
 	actionHistoryService *ActionHistoryService
+	authz                *AuthorizationService
+	quotas               *QuotaService
 }
 
 // Constructor for DatabaseService
@@ -38,6 +41,16 @@ func (s *DatabaseService) SetActionHistoryService(actionHistoryService *ActionHi
 	s.actionHistoryService = actionHistoryService
 }
 
+// SetAuthorizationService enables team based access checks (nil keeps per-user ownership)
+func (s *DatabaseService) SetAuthorizationService(authz *AuthorizationService) {
+	s.authz = authz
+}
+
+// SetQuotaService enables the quotas (nil enforces no limit)
+func (s *DatabaseService) SetQuotaService(quotas *QuotaService) {
+	s.quotas = quotas
+}
+
 // CreateDatabase creates a new database record with action logging
 func (s *DatabaseService) CreateDatabase(database *models.Database, userID uint, ipAddress, userAgent string) error {
 	// Validate database type
@@ -50,6 +63,17 @@ func (s *DatabaseService) CreateDatabase(database *models.Database, userID uint,
 		database.Type = "postgresql"
 	}
 
+	// Rattacher la base à l'équipe demandée ou à l'espace personnel
+	teamID, err := s.authz.TeamForNewDatabase(userID, database.TeamId)
+	if err != nil {
+		return err
+	}
+	database.TeamId = teamID
+
+	if err := s.quotas.CheckDatabaseCreation(userID, database.TeamId); err != nil {
+		return err
+	}
+
 	// Encrypt the database password before storing
 	if database.Password != "" {
 		encryptedPassword, err := security.EncryptDatabasePassword(database.Password)
@@ -68,7 +92,7 @@ func (s *DatabaseService) CreateDatabase(database *models.Database, userID uint,
 		database.URL = encryptedURL
 	}
 
-	err := s.databaseRepo.Create(database)
+	err = s.databaseRepo.Create(database)
 	if err != nil {
 		return err
 	}
@@ -83,7 +107,7 @@ func (s *DatabaseService) logDatabaseAction(userID uint, action string, database
 	if s.actionHistoryService != nil {
 		err := s.actionHistoryService.LogDatabaseAction(userID, action, databaseID, databaseName, databaseType, ipAddress, userAgent)
 		if err != nil {
-			fmt.Printf("[HISTORY] Failed to log database action %s for database %d: %v\n", action, databaseID, err)
+			slog.Warn("failed to log database action", "action", action, "database_id", databaseID, "error", err)
 		}
 	}
 }
@@ -107,13 +131,16 @@ func (s *DatabaseService) logDatabaseNameChange(userID uint, databaseID uint, ol
 
 		err := s.actionHistoryService.LogAction(userID, "updated", "database", databaseID, description, metadata, ipAddress, userAgent)
 		if err != nil {
-			fmt.Printf("[HISTORY] Failed to log database name change for database %d: %v\n", databaseID, err)
+			slog.Warn("failed to log database name change", "database_id", databaseID, "error", err)
 		}
 	}
 }
 
-// GetDatabasesByUser returns all databases for a user (without decrypted passwords for security)
+// GetDatabasesByUser returns all databases a user can access (without decrypted passwords for security)
 func (s *DatabaseService) GetDatabasesByUser(userID uint) ([]models.Database, error) {
+	if s.authz.Enabled() {
+		return s.databaseRepo.GetAccessibleByUser(userID)
+	}
 	return s.databaseRepo.GetByUserID(userID)
 }
 
@@ -124,13 +151,11 @@ func (s *DatabaseService) GetBackupsByDatabase(databaseID uint) ([]models.Backup
 
 // GetDatabaseByID returns a database by ID
 func (s *DatabaseService) GetDatabaseByID(id uint) (*models.Database, error) {
-	fmt.Printf("[DEBUG] GetDatabaseByID: Looking for database ID %d\n", id)
 	database, err := s.databaseRepo.GetByID(id)
 	if err != nil {
-		fmt.Printf("[DEBUG] GetDatabaseByID: Database ID %d not found - error: %v\n", id, err)
+		slog.Debug("database not found", "database_id", id, "error", err)
 		return nil, err
 	}
-	fmt.Printf("[DEBUG] GetDatabaseByID: Found database ID %d, name '%s', user_id %d\n", database.Id, database.Name, database.UserId)
 
 	// Decrypt the database password
 	if database.Password != "" {
@@ -146,14 +171,13 @@ func (s *DatabaseService) GetDatabaseByID(id uint) (*models.Database, error) {
 		decryptedURL, err := security.DecryptDatabasePassword(database.URL)
 		if err != nil {
 			// Log the error but don't fail - URL decryption failure shouldn't prevent database access
-			fmt.Printf("[DEBUG] GetDatabaseByID: Failed to decrypt URL for database ID %d - error: %v\n", database.Id, err)
+			slog.Warn("failed to decrypt database URL", "database_id", database.Id, "error", err)
 			// Keep the encrypted URL as-is or set to empty to avoid issues
 			database.URL = ""
 		} else {
 			database.URL = decryptedURL
 		}
 	}
-	fmt.Print("ddddd", database, "ddddd")
 	return database, nil
 }
 
@@ -236,39 +260,32 @@ func (s *DatabaseService) DeleteDatabase(id uint, userID uint, ipAddress, userAg
 		return fmt.Errorf("base de données introuvable: %v", err)
 	}
 
-	// Verify user ownership
-	if database.UserId != userID {
-		return fmt.Errorf("accès non autorisé à cette base de données")
+	// Verify that the user may manage the database
+	if err := s.authz.AuthorizeDatabase(userID, database, TeamActionManage); err != nil {
+		return err
 	}
 
-	fmt.Printf("[DELETE] Soft deleting database %d and all associated records\n", id)
+	slog.Info("soft deleting database and associated records", "database_id", id)
 
 	// Soft delete all associated backups (keep files in Mega for storage optimization)
 	if err := s.backupRepo.SoftDeleteByDatabaseID(id); err != nil {
-		fmt.Printf("[DELETE] Warning: failed to soft delete backups for database %d: %v\n", id, err)
+		slog.Warn("failed to soft delete backups", "database_id", id, "error", err)
 		// Continue even if soft delete fails
-	} else {
-		fmt.Printf("[DELETE] Soft deleted backups for database %d\n", id)
 	}
 
 	// Soft delete all associated schedules
 	if err := s.scheduleRepo.SoftDeleteByDatabaseID(id); err != nil {
-		fmt.Printf("[DELETE] Warning: failed to soft delete schedules for database %d: %v\n", id, err)
+		slog.Warn("failed to soft delete schedules", "database_id", id, "error", err)
 		// Continue even if soft delete fails
-	} else {
-		fmt.Printf("[DELETE] Soft deleted schedules for database %d\n", id)
 	}
 
 	// Soft delete all associated restores
 	if err := s.restoreRepo.SoftDeleteByDatabaseID(id); err != nil {
-		fmt.Printf("[DELETE] Warning: failed to soft delete restores for database %d: %v\n", id, err)
+		slog.Warn("failed to soft delete restores", "database_id", id, "error", err)
 		// Continue even if soft delete fails
-	} else {
-		fmt.Printf("[DELETE] Soft deleted restores for database %d\n", id)
 	}
 
 	// Finally, soft delete the database record
-	fmt.Printf("[DELETE] Soft deleting database record ID %d\n", id)
 	err = s.databaseRepo.SoftDelete(id)
 	if err != nil {
 		return err
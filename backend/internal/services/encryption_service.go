@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -9,6 +10,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	"github.com/RyanLadmia/plateforme-safebase/pkg/container"
 )
 
 // EncryptionService handles AES-256-GCM encryption/decryption of files
@@ -25,6 +28,14 @@ func NewEncryptionService(masterKey string) *EncryptionService {
 	}
 }
 
+// NewEncryptionServiceWithKey creates an encryption service using a raw 32-byte key as is
+func NewEncryptionServiceWithKey(key []byte) (*EncryptionService, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	return &EncryptionService{key: append([]byte(nil), key...)}, nil
+}
+
 // EncryptFile encrypts a file and returns the encrypted data
 func (e *EncryptionService) EncryptFile(filePath string) ([]byte, error) {
 	// Read the original file
@@ -42,8 +53,63 @@ func (e *EncryptionService) EncryptFile(filePath string) ([]byte, error) {
 	return encryptedData, nil
 }
 
-// DecryptData decrypts encrypted data and returns the original data
+// EncryptData encrypts data in memory and returns nonce||ciphertext
+func (e *EncryptionService) EncryptData(plainData []byte) ([]byte, error) {
+	return e.encryptData(plainData)
+}
+
+// EncryptFileToContainer encrypts a file into the portable container format (see pkg/container).
+// The checksum and size of the header are computed from the file.
+func (e *EncryptionService) EncryptFileToContainer(srcPath, dstPath string, header container.Header) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to read file for encryption: %v", err)
+	}
+	defer src.Close()
+
+	if header.Checksum, header.PlaintextSize, err = container.Checksum(src); err != nil {
+		return fmt.Errorf("failed to compute checksum: %v", err)
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create encrypted file: %v", err)
+	}
+	if err := container.Encrypt(dst, src, header, e.key); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to encrypt data: %v", err)
+	}
+	return dst.Close()
+}
+
+// EncryptDataToContainer encrypts data in memory into the portable container format
+func (e *EncryptionService) EncryptDataToContainer(plainData []byte, header container.Header) ([]byte, error) {
+	var err error
+	if header.Checksum, header.PlaintextSize, err = container.Checksum(bytes.NewReader(plainData)); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := container.Encrypt(&out, bytes.NewReader(plainData), header, e.key); err != nil {
+		return nil, fmt.Errorf("failed to encrypt data: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// DecryptData decrypts encrypted data and returns the original data.
+// Both the container format and the older raw nonce||ciphertext format are accepted.
 func (e *EncryptionService) DecryptData(encryptedData []byte) ([]byte, error) {
+	if container.IsContainer(encryptedData) {
+		var out bytes.Buffer
+		if _, err := container.Decrypt(&out, bytes.NewReader(encryptedData), func(*container.Header) ([]byte, error) {
+			return e.key, nil
+		}); err != nil {
+			return nil, fmt.Errorf("decryption failed: %v", err)
+		}
+		return out.Bytes(), nil
+	}
 	return e.decryptData(encryptedData)
 }
 
@@ -97,7 +163,10 @@ func (e *EncryptionService) decryptData(encryptedData []byte) ([]byte, error) {
 }
 
 // GenerateUserKey generates a unique encryption key for a user
-// This creates a deterministic key based on user ID and a master salt
+// This creates a deterministic key based on user ID and a master salt.
+//
+// Deprecated: only used to read backups written before envelope encryption (see
+// BackupService.MigrateBackupObjectContext); new backups use a random data key.
 func GenerateUserKey(userID uint, masterSalt string) string {
 	data := fmt.Sprintf("%d:%s", userID, masterSalt)
 	hash := sha256.Sum256([]byte(data))
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/pkg/version"
+	"gorm.io/gorm"
+)
+
+// Health statuses reported for each check and for the whole service
+const (
+	HealthUp       = "up"
+	HealthDown     = "down"
+	HealthDegraded = "degraded"
+	HealthDisabled = "disabled"
+)
+
+// HealthSentinelPath is the object probed (FileExists) to check that the storage backend answers
+const HealthSentinelPath = ".safebase/healthcheck"
+
+const (
+	healthCheckTimeout = 5 * time.Second
+	toolCheckTTL       = 5 * time.Minute // dump tools rarely change, avoid spawning processes on every probe
+)
+
+// WorkerPoolStats is implemented by worker pools able to report their load
+type WorkerPoolStats interface {
+	Workers() int
+	ActiveWorkers() int
+	QueueLength() int
+	QueueCapacity() int
+}
+
+// HealthCheck is the result of a single dependency check
+type HealthCheck struct {
+	Status    string                 `json:"status"`
+	Critical  bool                   `json:"critical"`
+	LatencyMs int64                  `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// HealthReport is returned by the readiness probe
+type HealthReport struct {
+	Status    string                 `json:"status"`
+	Version   version.Info           `json:"version"`
+	Uptime    string                 `json:"uptime"`
+	CheckedAt time.Time              `json:"checked_at"`
+	Checks    map[string]HealthCheck `json:"checks"`
+}
+
+// Ready reports whether every critical check is up
+func (r *HealthReport) Ready() bool {
+	return r.Status != HealthDown
+}
+
+type HealthService struct {
+	db              *gorm.DB
+	backupService   *BackupService
+	restoreService  *RestoreService
+	scheduleService *ScheduleService
+	workerPool      WorkerPoolStats
+	startedAt       time.Time
+
+	toolsMu       sync.Mutex
+	toolsCache    map[string]HealthCheck
+	toolsCachedAt time.Time
+}
+
+// Constructor for HealthService
+func NewHealthService(db *gorm.DB, backupService *BackupService, restoreService *RestoreService, scheduleService *ScheduleService) *HealthService {
+	return &HealthService{
+		db:              db,
+		backupService:   backupService,
+		restoreService:  restoreService,
+		scheduleService: scheduleService,
+		startedAt:       time.Now(),
+	}
+}
+
+// SetWorkerPool sets the worker pool whose saturation is reported
+func (s *HealthService) SetWorkerPool(workerPool WorkerPoolStats) {
+	s.workerPool = workerPool
+}
+
+// Uptime returns the time elapsed since the service was created
+func (s *HealthService) Uptime() time.Duration {
+	return time.Since(s.startedAt).Truncate(time.Second)
+}
+
+// CheckReadiness runs every dependency check and aggregates them.
+// A critical check down makes the service not ready; any other problem only degrades it.
+func (s *HealthService) CheckReadiness(ctx context.Context) *HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	checks := make(map[string]HealthCheck)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	run := func(name string, check func(context.Context) HealthCheck) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			result := check(ctx)
+			result.LatencyMs = time.Since(start).Milliseconds()
+			mu.Lock()
+			checks[name] = result
+			mu.Unlock()
+		}()
+	}
+
+	run("database", s.checkDatabase)
+	run("storage", s.checkStorage)
+	run("worker_pool", func(context.Context) HealthCheck { return s.checkWorkerPool() })
+	run("scheduler", func(context.Context) HealthCheck { return s.checkScheduler() })
+	wg.Wait()
+
+	for name, check := range s.checkTools(ctx) {
+		checks[name] = check
+	}
+
+	status := HealthUp
+	for _, check := range checks {
+		switch {
+		case check.Status == HealthDown && check.Critical:
+			status = HealthDown
+		case check.Status != HealthUp && status == HealthUp:
+			status = HealthDegraded
+		}
+	}
+
+	return &HealthReport{
+		Status:    status,
+		Version:   version.Get(),
+		Uptime:    s.Uptime().String(),
+		CheckedAt: time.Now(),
+		Checks:    checks,
+	}
+}
+
+// checkDatabase pings the metadata database
+func (s *HealthService) checkDatabase(ctx context.Context) HealthCheck {
+	check := HealthCheck{Critical: true}
+	if s.db == nil {
+		check.Status = HealthDown
+		check.Error = "database not configured"
+		return check
+	}
+
+	sqlDB, err := s.db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		check.Status = HealthDown
+		check.Error = err.Error()
+		return check
+	}
+
+	stats := sqlDB.Stats()
+	check.Status = HealthUp
+	check.Details = map[string]interface{}{
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+	}
+	return check
+}
+
+// checkStorage probes the sentinel object to make sure the storage backend answers
+func (s *HealthService) checkStorage(ctx context.Context) HealthCheck {
+	check := HealthCheck{Critical: true}
+	if s.backupService == nil || s.backupService.cloudStorage == nil {
+		// Without storage backups cannot be uploaded, but the API itself still works
+		check.Status = HealthDisabled
+		check.Critical = false
+		check.Error = "cloud storage not configured"
+		return check
+	}
+
+	type result struct {
+		exists bool
+		err    error
+	}
+	// FileExists has no context parameter: give up waiting when the probe times out
+	done := make(chan result, 1)
+	go func() {
+		exists, err := s.backupService.cloudStorage.FileExists(HealthSentinelPath)
+		done <- result{exists, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		check.Status = HealthDown
+		check.Error = "storage check timed out"
+	case res := <-done:
+		if res.err != nil {
+			check.Status = HealthDown
+			check.Error = res.err.Error()
+			return check
+		}
+		check.Status = HealthUp
+		check.Details = map[string]interface{}{
+			"backend":         fmt.Sprintf("%T", s.backupService.cloudStorage),
+			"sentinel":        HealthSentinelPath,
+			"sentinel_exists": res.exists,
+		}
+	}
+	return check
+}
+
+// checkWorkerPool reports the pool load: a full queue means new jobs run synchronously in the request
+func (s *HealthService) checkWorkerPool() HealthCheck {
+	check := HealthCheck{Critical: true}
+	if s.workerPool == nil {
+		check.Status = HealthDown
+		check.Error = "worker pool not started"
+		return check
+	}
+
+	workers := s.workerPool.Workers()
+	active := s.workerPool.ActiveWorkers()
+	queued := s.workerPool.QueueLength()
+	capacity := s.workerPool.QueueCapacity()
+	check.Details = map[string]interface{}{
+		"workers":        workers,
+		"active_workers": active,
+		"queued":         queued,
+		"queue_capacity": capacity,
+	}
+
+	switch {
+	case capacity > 0 && queued >= capacity:
+		check.Status = HealthDown
+		check.Error = "worker pool saturated: task queue is full"
+	case active >= workers && queued > 0:
+		check.Status = HealthDegraded
+		check.Error = "all workers busy, tasks are waiting"
+	default:
+		check.Status = HealthUp
+	}
+	return check
+}
+
+// checkScheduler verifies that the cron scheduler is running
+func (s *HealthService) checkScheduler() HealthCheck {
+	check := HealthCheck{Critical: true}
+	if s.scheduleService == nil || !s.scheduleService.IsSchedulerRunning() {
+		check.Status = HealthDown
+		check.Error = "scheduler not running"
+		return check
+	}
+
+	check.Status = HealthUp
+	check.Details = map[string]interface{}{
+		"scheduled_jobs": s.scheduleService.ScheduledJobsCount(),
+	}
+	return check
+}
+
+// checkTools looks up the dump/restore clients with the same paths the jobs use.
+// Results are cached since running "--version" on every probe is wasteful.
+func (s *HealthService) checkTools(ctx context.Context) map[string]HealthCheck {
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+
+	if s.toolsCache != nil && time.Since(s.toolsCachedAt) < toolCheckTTL {
+		return s.toolsCache
+	}
+
+	tools := map[string][]string{}
+	if s.backupService != nil {
+		tools["pg_dump"] = s.backupService.getPostgreSQLDumpPaths()
+		tools["mysqldump"] = s.backupService.getMySQLDumpPaths()
+	}
+	if s.restoreService != nil {
+		tools["psql"] = s.restoreService.getPostgreSQLClientPaths()
+		tools["mysql"] = s.restoreService.getMySQLClientPaths()
+	}
+
+	checks := make(map[string]HealthCheck, len(tools))
+	for name, paths := range tools {
+		start := time.Now()
+		check := checkTool(ctx, paths)
+		check.LatencyMs = time.Since(start).Milliseconds()
+		checks[name] = check
+	}
+
+	s.toolsCache = checks
+	s.toolsCachedAt = time.Now()
+	return checks
+}
+
+// checkTool finds an executable and reads its version (missing tools only degrade the service)
+func checkTool(ctx context.Context, paths []string) HealthCheck {
+	path, err := findExecutable(paths)
+	if err != nil {
+		return HealthCheck{Status: HealthDown, Error: err.Error()}
+	}
+
+	check := HealthCheck{Status: HealthUp, Details: map[string]interface{}{"path": path}}
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		check.Status = HealthDegraded
+		check.Error = fmt.Sprintf("unable to read version: %v", err)
+		return check
+	}
+
+	firstLine, _, _ := strings.Cut(string(output), "\n")
+	check.Details["version"] = strings.TrimSpace(firstLine)
+	return check
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/mailer"
+)
+
+// ErrTooManyLoginAttempts is returned while an account or an IP address is locked out after failed logins
+var ErrTooManyLoginAttempts = errors.New("too many failed login attempts, please try again later")
+
+// LoginLockedError is returned by LoginWithMetadata during a lockout; it wraps ErrTooManyLoginAttempts
+type LoginLockedError struct {
+	Until time.Time
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// SetLoginProtection sets the lockout thresholds of the login (models.DefaultLoginProtectionConfig otherwise)
+func (s *AuthService) SetLoginProtection(config models.LoginProtectionConfig) {
+	s.loginProtection = config
+}
+
+// lockoutDuration doubles the lockout for every failure beyond the threshold
+func lockoutDuration(config models.LoginProtectionConfig, failures int) time.Duration {
+	duration := config.LockoutDuration
+	for i := config.MaxAttempts; i < failures && duration < config.MaxLockout; i++ {
+		duration *= 2
+	}
+	if config.MaxLockout > 0 && duration > config.MaxLockout {
+		duration = config.MaxLockout
+	}
+	return duration
+}
+
+// checkLoginAllowed refuses the login of a locked account or from a throttled IP address
+func (s *AuthService) checkLoginAllowed(user *models.User, metadata SessionMetadata) error {
+	if until, blocked := s.ipAttempts.blockedUntil(metadata.IPAddress); blocked {
+		return &LoginLockedError{Until: until}
+	}
+	if user != nil && user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		s.logSessionAction(user.Id, "login_blocked", "user", user.Id,
+			"Connexion refusée : compte temporairement verrouillé",
+			map[string]interface{}{"locked_until": user.LockedUntil},
+			metadata.IPAddress, metadata.UserAgent)
+		return &LoginLockedError{Until: *user.LockedUntil}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login against the IP address and the account (when known),
+// locks the account beyond the threshold and records the security event
+func (s *AuthService) recordLoginFailure(user *models.User, reason string, metadata SessionMetadata) {
+	config := s.loginProtection
+	s.ipAttempts.fail(metadata.IPAddress, config)
+
+	if user == nil {
+		// Pas de ligne d'historique sans utilisateur : l'échec est seulement journalisé
+		slog.Warn("login failed for unknown account", "reason", reason, "ip", metadata.IPAddress)
+		return
+	}
+
+	failures, err := s.userRepo.IncrementFailedLogins(user.Id)
+	if err != nil {
+		slog.Error("failed to record login failure", "user_id", user.Id, "error", err)
+		return
+	}
+	s.logSessionAction(user.Id, "login_failed", "user", user.Id,
+		"Échec de connexion",
+		map[string]interface{}{"reason": reason, "failed_attempts": failures},
+		metadata.IPAddress, metadata.UserAgent)
+
+	if config.MaxAttempts <= 0 || failures < config.MaxAttempts {
+		return
+	}
+	until := time.Now().Add(lockoutDuration(config, failures))
+	if err := s.userRepo.LockUntil(user.Id, until); err != nil {
+		slog.Error("failed to lock account", "user_id", user.Id, "error", err)
+		return
+	}
+	s.logSessionAction(user.Id, "account_locked", "user", user.Id,
+		fmt.Sprintf("Compte verrouillé après %d échecs de connexion", failures),
+		map[string]interface{}{"failed_attempts": failures, "locked_until": until},
+		metadata.IPAddress, metadata.UserAgent)
+
+	// Un seul email au premier verrouillage, pas à chaque prolongation
+	if config.NotifyUser && failures == config.MaxAttempts {
+		go s.sendMail(mailer.Message{
+			To:      user.Email,
+			Subject: "Votre compte SafeBase a été temporairement verrouillé",
+			Body: fmt.Sprintf("Bonjour %s,\n\n"+
+				"Après %d tentatives de connexion échouées (dernière depuis l'adresse %s), votre compte SafeBase "+
+				"est verrouillé jusqu'au %s.\n\n"+
+				"Si vous n'êtes pas à l'origine de ces tentatives, réinitialisez votre mot de passe dès que possible "+
+				"et prévenez un administrateur.\n",
+				user.Firstname, failures, metadata.IPAddress, until.Format("02/01/2006 15:04")),
+		})
+	}
+}
+
+// recordLoginSuccess clears the failure counter, records the login and warns the user about a new IP address
+func (s *AuthService) recordLoginSuccess(user *models.User, method string, metadata SessionMetadata) {
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.ResetFailedLogins(user.Id); err != nil {
+			slog.Error("failed to reset failed login counter", "user_id", user.Id, "error", err)
+		}
+	}
+
+	if s.loginProtection.NotifyUser && s.isNewLoginIP(user.Id, metadata.IPAddress) {
+		go s.sendMail(mailer.Message{
+			To:      user.Email,
+			Subject: "Nouvelle connexion à votre compte SafeBase",
+			Body: fmt.Sprintf("Bonjour %s,\n\n"+
+				"Une connexion à votre compte SafeBase a eu lieu depuis une adresse inhabituelle :\n\n"+
+				"Adresse IP : %s\nAppareil : %s\nDate : %s\n\n"+
+				"Si vous n'êtes pas à l'origine de cette connexion, changez votre mot de passe et fermez "+
+				"les sessions inconnues depuis votre profil.\n",
+				user.Firstname, metadata.IPAddress, DeviceFromUserAgent(metadata.UserAgent), time.Now().Format("02/01/2006 15:04")),
+		})
+	}
+
+	s.logSessionAction(user.Id, "login_succeeded", "user", user.Id,
+		"Connexion réussie",
+		map[string]interface{}{"method": method, "device_name": DeviceFromUserAgent(metadata.UserAgent)},
+		metadata.IPAddress, metadata.UserAgent)
+}
+
+// isNewLoginIP reports whether the user already logged in before, but never from this IP address
+func (s *AuthService) isNewLoginIP(userID uint, ipAddress string) bool {
+	if s.actionHistoryService == nil || ipAddress == "" {
+		return false
+	}
+	previous, err := s.actionHistoryService.CountUserAction(userID, "login_succeeded")
+	if err != nil || previous == 0 {
+		return false
+	}
+	known, err := s.actionHistoryService.HasUserActionFromIP(userID, "login_succeeded", ipAddress)
+	return err == nil && !known
+}
+
+// UnlockAccount lifts the lockout of an account on behalf of an administrator
+func (s *AuthService) UnlockAccount(adminID, userID uint, ipAddress, userAgent string) error {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return errors.New("utilisateur introuvable")
+	}
+	if err := s.userRepo.ResetFailedLogins(user.Id); err != nil {
+		return fmt.Errorf("erreur lors du déverrouillage du compte: %w", err)
+	}
+
+	s.logSessionAction(adminID, "account_unlocked", "user", user.Id,
+		fmt.Sprintf("Compte de %s déverrouillé par un administrateur", user.Email),
+		map[string]interface{}{"target_user_id": user.Id, "failed_attempts": user.FailedLoginAttempts},
+		ipAddress, userAgent)
+	return nil
+}
+
+// ipLoginAttempts tracks failed logins per IP address in memory (sliding window with exponential backoff)
+type ipLoginAttempts struct {
+	mu      sync.Mutex
+	entries map[string]*ipLoginAttempt
+}
+
+type ipLoginAttempt struct {
+	failures     int
+	windowStart  time.Time
+	blockedUntil time.Time
+}
+
+// fail records a failed login from an address and throttles it beyond the threshold
+func (a *ipLoginAttempts) fail(ip string, config models.LoginProtectionConfig) {
+	if ip == "" || config.IPMaxAttempts <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	a.prune(now, config.IPWindow)
+
+	entry, ok := a.entries[ip]
+	if !ok {
+		entry = &ipLoginAttempt{windowStart: now}
+		a.entries[ip] = entry
+	}
+	entry.failures++
+	if entry.failures >= config.IPMaxAttempts {
+		backoff := config
+		backoff.MaxAttempts = config.IPMaxAttempts
+		entry.blockedUntil = now.Add(lockoutDuration(backoff, entry.failures))
+	}
+}
+
+// blockedUntil reports whether an address is throttled and until when
+func (a *ipLoginAttempts) blockedUntil(ip string) (time.Time, bool) {
+	if ip == "" {
+		return time.Time{}, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.entries[ip]
+	if !ok || !time.Now().Before(entry.blockedUntil) {
+		return time.Time{}, false
+	}
+	return entry.blockedUntil, true
+}
+
+// prune forgets addresses whose window and block are over
+func (a *ipLoginAttempts) prune(now time.Time, window time.Duration) {
+	if a.entries == nil {
+		a.entries = make(map[string]*ipLoginAttempt)
+	}
+	for ip, entry := range a.entries {
+		if now.After(entry.windowStart.Add(window)) && now.After(entry.blockedUntil) {
+			delete(a.entries, ip)
+		}
+	}
+}
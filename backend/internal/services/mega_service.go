@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 
@@ -35,7 +36,7 @@ func NewMegaService(config MegaConfig) (*MegaService, error) {
 		return nil, fmt.Errorf("failed to login to Mega: %v", err)
 	}
 
-	fmt.Printf("[MEGA] Successfully logged in as %s\n", config.Email)
+	slog.Info("logged in to Mega")
 	return service, nil
 }
 
@@ -58,7 +59,7 @@ func (m *MegaService) UploadFile(localPath, remotePath string) error {
 		return fmt.Errorf("failed to upload file to Mega: %v", err)
 	}
 
-	fmt.Printf("[MEGA] Successfully uploaded file: %s\n", remotePath)
+	slog.Debug("uploaded file to Mega", "remote_path", remotePath)
 	return nil
 }
 
@@ -90,7 +91,7 @@ func (m *MegaService) DownloadFile(remotePath string) ([]byte, error) {
 		return nil, fmt.Errorf("failed to read downloaded file: %v", err)
 	}
 
-	fmt.Printf("[MEGA] Successfully downloaded file: %s\n", remotePath)
+	slog.Debug("downloaded file from Mega", "remote_path", remotePath)
 	return data, nil
 }
 
@@ -106,7 +107,7 @@ func (m *MegaService) DeleteFile(remotePath string) error {
 		return fmt.Errorf("failed to delete file from Mega: %v", err)
 	}
 
-	fmt.Printf("[MEGA] Successfully permanently deleted file: %s\n", remotePath)
+	slog.Debug("permanently deleted file from Mega", "remote_path", remotePath)
 	return nil
 }
 
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/oidc"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// oidcFlowTTL is how long the user has to authenticate at the identity provider
+	oidcFlowTTL = 10 * time.Minute
+	// localLoginSetting is the setting key of the password login switch
+	localLoginSetting = "auth.local_login_enabled"
+)
+
+var (
+	// ErrOIDCNotConfigured is returned when single sign-on is used without an identity provider
+	ErrOIDCNotConfigured = errors.New("single sign-on is not configured")
+	// ErrOIDCStateInvalid is returned for an unknown, expired or already used authorization request
+	ErrOIDCStateInvalid = errors.New("invalid or expired single sign-on request, please try again")
+	// ErrOIDCAccountConflict is returned when the email belongs to an account linked to another identity
+	ErrOIDCAccountConflict = errors.New("this email is already linked to another single sign-on account")
+	// ErrLocalLoginDisabled is returned for password logins while an administrator requires single sign-on
+	ErrLocalLoginDisabled = errors.New("password login is disabled, please use single sign-on")
+)
+
+// OIDCGroupRole maps a group of the identity provider to a SafeBase role
+type OIDCGroupRole struct {
+	Group string
+	Role  string
+}
+
+// AuthSettings are the authentication settings administrators can change at runtime
+type AuthSettings struct {
+	OIDCEnabled       bool `json:"oidc_enabled"`
+	LocalLoginEnabled bool `json:"local_login_enabled"`
+}
+
+// SetOIDCProvider enables single sign-on; groupRoles are checked in order and the first match wins
+func (s *AuthService) SetOIDCProvider(provider *oidc.Provider, groupRoles []OIDCGroupRole) {
+	s.oidcProvider = provider
+	s.oidcGroupRoles = groupRoles
+}
+
+// SetSettingRepository sets the repository of the runtime settings (local login switch)
+func (s *AuthService) SetSettingRepository(settingRepo *repositories.SettingRepository) {
+	s.settingRepo = settingRepo
+}
+
+// OIDCEnabled reports whether single sign-on is configured
+func (s *AuthService) OIDCEnabled() bool {
+	return s.oidcProvider != nil
+}
+
+// GetAuthSettings returns the current authentication settings
+func (s *AuthService) GetAuthSettings() AuthSettings {
+	return AuthSettings{
+		OIDCEnabled:       s.OIDCEnabled(),
+		LocalLoginEnabled: s.LocalLoginEnabled(),
+	}
+}
+
+// LocalLoginEnabled reports whether users may log in with a password (enabled unless turned off by an admin)
+func (s *AuthService) LocalLoginEnabled() bool {
+	if s.settingRepo == nil {
+		return true
+	}
+	value, found, err := s.settingRepo.Get(localLoginSetting)
+	if err != nil {
+		slog.Warn("failed to read setting", "setting", localLoginSetting, "error", err)
+		return true
+	}
+	if !found {
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	return err != nil || enabled
+}
+
+// SetLocalLoginEnabled turns password login on or off. It can only be turned off when single sign-on works,
+// and administrators keep their password login as a break-glass access if the identity provider is down.
+func (s *AuthService) SetLocalLoginEnabled(adminID uint, enabled bool, ipAddress, userAgent string) error {
+	if s.settingRepo == nil {
+		return errors.New("settings are not available")
+	}
+	if !enabled && !s.OIDCEnabled() {
+		return ErrOIDCNotConfigured
+	}
+	if err := s.settingRepo.Set(localLoginSetting, strconv.FormatBool(enabled)); err != nil {
+		return err
+	}
+
+	action, description := "local_login_enabled", "Connexion par mot de passe réactivée"
+	if !enabled {
+		action, description = "local_login_disabled", "Connexion par mot de passe désactivée (SSO obligatoire)"
+	}
+	s.logSessionAction(adminID, action, "settings", 0, description,
+		map[string]interface{}{"local_login_enabled": enabled}, ipAddress, userAgent)
+	return nil
+}
+
+// localLoginAllowed applies the local login switch; administrators are never locked out
+func (s *AuthService) localLoginAllowed(user *models.User) bool {
+	if user != nil && user.Role != nil && user.Role.Name == "admin" {
+		return true
+	}
+	return s.LocalLoginEnabled()
+}
+
+// BeginOIDCLogin starts an authorization code flow with PKCE and returns the provider URL and the state
+// the browser must present on the callback
+func (s *AuthService) BeginOIDCLogin() (authURL, state string, err error) {
+	if s.oidcProvider == nil {
+		return "", "", ErrOIDCNotConfigured
+	}
+	if state, err = security.RandomToken(32); err != nil {
+		return "", "", err
+	}
+	nonce, err := security.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+	s.oidcFlows.put(state, oidcFlow{nonce: nonce, verifier: verifier, expiresAt: time.Now().Add(oidcFlowTTL)})
+	return s.oidcProvider.AuthCodeURL(state, nonce, challenge), state, nil
+}
+
+// CompleteOIDCLogin exchanges the authorization code, verifies the ID token and opens a session for the
+// linked user. The account is linked by verified email or created on first login, and its role follows
+// the provider groups. The identity provider is responsible for the second factor.
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, state, code string, metadata SessionMetadata) (*TokenPair, error) {
+	if s.oidcProvider == nil {
+		return nil, ErrOIDCNotConfigured
+	}
+	flow, ok := s.oidcFlows.take(state)
+	if !ok || code == "" {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	token, err := s.oidcProvider.Exchange(ctx, code, flow.verifier)
+	if err != nil {
+		slog.ErrorContext(ctx, "SSO code exchange failed", "error", err)
+		return nil, errors.New("single sign-on failed")
+	}
+	claims, err := s.oidcProvider.VerifyIDToken(ctx, token.IDToken, flow.nonce)
+	if err != nil {
+		slog.ErrorContext(ctx, "SSO ID token verification failed", "error", err)
+		return nil, errors.New("single sign-on failed")
+	}
+
+	user, err := s.oidcUser(claims, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		s.logSessionAction(user.Id, "login_failed", "user", user.Id,
+			"Échec de connexion SSO : compte désactivé",
+			map[string]interface{}{"reason": "account_disabled", "method": "oidc"},
+			metadata.IPAddress, metadata.UserAgent)
+		return nil, errors.New("account is disabled")
+	}
+	s.syncOIDCRole(user, claims.Groups, metadata)
+
+	pair, err := s.startSession(user, metadata)
+	if err != nil {
+		return nil, err
+	}
+	s.recordLoginSuccess(user, "oidc", metadata)
+	return pair, nil
+}
+
+// oidcUser finds the user of an identity: by subject, then by verified email (link), else a new account
+func (s *AuthService) oidcUser(claims *oidc.Claims, metadata SessionMetadata) (*models.User, error) {
+	if user, err := s.userRepo.GetUserByOIDCSubject(claims.Subject); err == nil {
+		return user, nil
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !claims.EmailVerified {
+		return nil, errors.New("the identity provider did not return a verified email")
+	}
+
+	if user, err := s.userRepo.GetUserByEmail(email); err == nil {
+		linked, err := s.userRepo.LinkOIDCSubject(user.Id, claims.Subject)
+		if err != nil {
+			return nil, err
+		}
+		if !linked {
+			return nil, ErrOIDCAccountConflict
+		}
+		s.logSessionAction(user.Id, "oidc_account_linked", "user", user.Id,
+			"Compte lié au fournisseur d'identité (SSO)",
+			map[string]interface{}{"subject": claims.Subject},
+			metadata.IPAddress, metadata.UserAgent)
+		return user, nil
+	}
+
+	// Premier login : mot de passe aléatoire inconnu de tous, l'utilisateur ne se connecte que par SSO
+	// (ou après une réinitialisation du mot de passe si la connexion locale est autorisée)
+	randomPassword, err := security.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	firstname, lastname := claims.GivenName, claims.FamilyName
+	if firstname == "" && lastname == "" {
+		firstname = claims.Name
+	}
+	if firstname == "" {
+		firstname = strings.SplitN(email, "@", 2)[0]
+	}
+	subject := claims.Subject
+	user := &models.User{
+		Firstname:   firstname,
+		Lastname:    lastname,
+		Email:       email,
+		Password:    string(hashedPassword),
+		Active:      true,
+		OIDCSubject: &subject,
+	}
+	roleName := s.oidcRoleName(claims.Groups)
+	if roleName == "" {
+		roleName = "user"
+	}
+	role, err := s.roleByName(roleName)
+	if err != nil {
+		return nil, err
+	}
+	user.RoleID = &role.Id
+	if err := s.userRepo.CreateUser(user); err != nil {
+		return nil, err
+	}
+	user.Role = role
+
+	s.logSessionAction(user.Id, "oidc_user_created", "user", user.Id,
+		fmt.Sprintf("Compte créé à la première connexion SSO (rôle %s)", role.Name),
+		map[string]interface{}{"subject": claims.Subject, "groups": claims.Groups},
+		metadata.IPAddress, metadata.UserAgent)
+	return user, nil
+}
+
+// syncOIDCRole applies the role mapped from the provider groups; without matching group the role is kept
+func (s *AuthService) syncOIDCRole(user *models.User, groups []string, metadata SessionMetadata) {
+	roleName := s.oidcRoleName(groups)
+	if roleName == "" || (user.Role != nil && user.Role.Name == roleName) {
+		return
+	}
+	role, err := s.roleByName(roleName)
+	if err != nil {
+		slog.Warn("SSO role not found", "role", roleName, "error", err)
+		return
+	}
+	if err := s.userRepo.UpdateUserRole(user.Id, role.Id); err != nil {
+		slog.Error("failed to update SSO role of user", "user_id", user.Id, "role", roleName, "error", err)
+		return
+	}
+	previous := ""
+	if user.Role != nil {
+		previous = user.Role.Name
+	}
+	user.RoleID, user.Role = &role.Id, role
+
+	s.logSessionAction(user.Id, "role_synced", "user", user.Id,
+		fmt.Sprintf("Rôle mis à jour depuis les groupes SSO : %s", role.Name),
+		map[string]interface{}{"previous_role": previous, "new_role": role.Name, "groups": groups},
+		metadata.IPAddress, metadata.UserAgent)
+}
+
+// oidcRoleName returns the role of the first mapping whose group the user belongs to
+func (s *AuthService) oidcRoleName(groups []string) string {
+	for _, mapping := range s.oidcGroupRoles {
+		for _, group := range groups {
+			if group == mapping.Group {
+				return mapping.Role
+			}
+		}
+	}
+	return ""
+}
+
+// roleByName loads a role by name
+func (s *AuthService) roleByName(name string) (*models.Role, error) {
+	var role models.Role
+	if err := s.userRepo.GetDB().Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, fmt.Errorf("role %q not found", name)
+	}
+	return &role, nil
+}
+
+// oidcFlows keeps the pending authorization requests in memory, keyed by state
+type oidcFlows struct {
+	mu      sync.Mutex
+	entries map[string]oidcFlow
+}
+
+type oidcFlow struct {
+	nonce     string
+	verifier  string
+	expiresAt time.Time
+}
+
+func (f *oidcFlows) put(state string, flow oidcFlow) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prune()
+	f.entries[state] = flow
+}
+
+// take returns a pending request and forgets it: a state can only be used once
+func (f *oidcFlows) take(state string) (oidcFlow, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prune()
+	flow, ok := f.entries[state]
+	delete(f.entries, state)
+	return flow, ok
+}
+
+// prune forgets expired requests
+func (f *oidcFlows) prune() {
+	if f.entries == nil {
+		f.entries = make(map[string]oidcFlow)
+	}
+	now := time.Now()
+	for state, flow := range f.entries {
+		if now.After(flow.expiresAt) {
+			delete(f.entries, state)
+		}
+	}
+}
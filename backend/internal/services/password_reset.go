@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/mailer"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultPasswordResetTTL is the lifetime of a reset link when SetPasswordResetTTL is not called
+const DefaultPasswordResetTTL = time.Hour
+
+// ErrInvalidResetToken is returned for unknown, already used or expired reset tokens
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// SetMailer sets the mailer delivering reset links and the frontend page they point to
+// (the token is appended as the "token" query parameter)
+func (s *AuthService) SetMailer(m mailer.Mailer, resetURL string) {
+	s.mailer = m
+	s.resetURL = resetURL
+}
+
+// SetPasswordResetTTL sets how long a reset link stays valid
+func (s *AuthService) SetPasswordResetTTL(ttl time.Duration) {
+	s.passwordResetTTL = ttl
+}
+
+// RequestPasswordReset emails a single-use reset link to the account. It never reports whether the
+// account exists: the lookup, the token issuance and the email all run in the background, so neither
+// the response nor its timing depends on the account, and unknown or disabled accounts are ignored.
+func (s *AuthService) RequestPasswordReset(email, ipAddress, userAgent string) {
+	go s.sendPasswordReset(strings.TrimSpace(email), ipAddress, userAgent)
+}
+
+// sendPasswordReset issues a reset token for an active account and emails its link
+func (s *AuthService) sendPasswordReset(email, ipAddress, userAgent string) {
+	user, err := s.userRepo.GetUserByEmail(email)
+	if err != nil || !user.Active || !s.localLoginAllowed(user) {
+		return
+	}
+
+	ttl := s.passwordResetTTL
+	if ttl <= 0 {
+		ttl = DefaultPasswordResetTTL
+	}
+	token, expiresAt, err := s.issueResetToken(user.Id, ttl, ipAddress, userAgent)
+	if err != nil {
+		slog.Error("failed to issue password reset token", "user_id", user.Id, "error", err)
+		return
+	}
+
+	s.logSessionAction(user.Id, "password_reset_requested", "password", user.Id,
+		"Demande de réinitialisation du mot de passe",
+		map[string]interface{}{"expires_at": expiresAt},
+		ipAddress, userAgent)
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Réinitialisation de votre mot de passe SafeBase",
+		Body: fmt.Sprintf("Bonjour %s,\n\n"+
+			"Une réinitialisation du mot de passe de votre compte SafeBase a été demandée.\n"+
+			"Pour choisir un nouveau mot de passe, ouvrez le lien suivant (valable %s, utilisable une seule fois) :\n\n"+
+			"%s\n\n"+
+			"Si vous n'êtes pas à l'origine de cette demande, ignorez ce message : votre mot de passe reste inchangé.\n",
+			user.Firstname, ttl, s.resetLink(token)),
+	}
+	s.sendMail(msg)
+}
+
+// issueResetToken stores a single-use token setting the password of a user; only the latest token is valid
+func (s *AuthService) issueResetToken(userID uint, ttl time.Duration, ipAddress, userAgent string) (string, time.Time, error) {
+	token, err := security.RandomToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	if err := s.sessionRepo.DeleteResetTokensForUser(userID); err != nil {
+		return "", time.Time{}, err
+	}
+	placeholder, err := security.RandomID(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	request := &models.Session{
+		UserId:         userID,
+		Token:          "reset:" + placeholder, // Jamais un JWT valide : la ligne n'ouvre aucune session
+		ResetToken:     security.HashToken(token),
+		ResetExpiresAt: expiresAt,
+		IPAddress:      ipAddress,
+		UserAgent:      userAgent,
+	}
+	if err := s.sessionRepo.CreateSession(request); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ResetPassword sets a new password with a reset token. The token can only be used once and every
+// session of the user is closed, so a stolen session does not survive the reset.
+func (s *AuthService) ResetPassword(token, newPassword, ipAddress, userAgent string) error {
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+	request, err := s.sessionRepo.GetByResetToken(security.HashToken(token))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	if err := ValidatePassword(newPassword); err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetUserById(request.UserId)
+	if err != nil || !user.Active {
+		return ErrInvalidResetToken
+	}
+
+	consumed, err := s.sessionRepo.ConsumeResetToken(request.Id, request.ResetToken)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdateUserById(user.Id, map[string]interface{}{"password": string(hashedPassword)}); err != nil {
+		return err
+	}
+
+	revoked, err := s.sessionRepo.DeleteAllForUser(user.Id)
+	if err != nil {
+		slog.Error("failed to revoke sessions after password reset", "user_id", user.Id, "error", err)
+	}
+
+	s.logSessionAction(user.Id, "password_reset", "password", user.Id,
+		fmt.Sprintf("Mot de passe réinitialisé, %d session(s) fermée(s)", revoked),
+		map[string]interface{}{"revoked_count": revoked},
+		ipAddress, userAgent)
+	return nil
+}
+
+// resetLink builds the frontend link carrying a reset token
+func (s *AuthService) resetLink(token string) string {
+	separator := "?"
+	if strings.Contains(s.resetURL, "?") {
+		separator = "&"
+	}
+	return s.resetURL + separator + "token=" + url.QueryEscape(token)
+}
+
+// sendMail delivers an email, logging failures (the caller has already answered the request)
+func (s *AuthService) sendMail(msg mailer.Message) {
+	if s.mailer == nil {
+		slog.Warn("no mailer configured, email not sent", "subject", msg.Subject)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		slog.Error("failed to send email", "subject", msg.Subject, "error", err)
+	}
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultErasureGracePeriod is the delay before an account is erased when SetErasureGracePeriod is not called
+const DefaultErasureGracePeriod = 30 * 24 * time.Hour
+
+// ErrErasureNotScheduled is returned when cancelling an erasure that was not requested
+var ErrErasureNotScheduled = errors.New("aucun effacement du compte n'est programmé")
+
+// PrivacyService exports the personal data of a user and erases their account on request (GDPR).
+// An erasure runs after a grace period during which the user can cancel it; their resources are then purged
+// and their action history is kept for the audit trail, without IP addresses and user agents.
+type PrivacyService struct {
+	userRepo             *repositories.UserRepository
+	databaseRepo         *repositories.DatabaseRepository
+	scheduleRepo         *repositories.ScheduleRepository
+	backupRepo           *repositories.BackupRepository
+	actionHistoryRepo    *repositories.ActionHistoryRepository
+	deletionRepo         *repositories.UserDeletionRepository
+	deletionService      *UserDeletionService
+	actionHistoryService *ActionHistoryService
+	gracePeriod          time.Duration
+}
+
+// NewPrivacyService constructor
+func NewPrivacyService(userRepo *repositories.UserRepository, databaseRepo *repositories.DatabaseRepository, scheduleRepo *repositories.ScheduleRepository, backupRepo *repositories.BackupRepository, actionHistoryRepo *repositories.ActionHistoryRepository, deletionRepo *repositories.UserDeletionRepository, deletionService *UserDeletionService) *PrivacyService {
+	return &PrivacyService{
+		userRepo:          userRepo,
+		databaseRepo:      databaseRepo,
+		scheduleRepo:      scheduleRepo,
+		backupRepo:        backupRepo,
+		actionHistoryRepo: actionHistoryRepo,
+		deletionRepo:      deletionRepo,
+		deletionService:   deletionService,
+		gracePeriod:       DefaultErasureGracePeriod,
+	}
+}
+
+// SetActionHistoryService sets the action history service for logging exports and erasure requests
+func (s *PrivacyService) SetActionHistoryService(actionHistoryService *ActionHistoryService) {
+	s.actionHistoryService = actionHistoryService
+}
+
+// SetErasureGracePeriod sets the delay between an erasure request and the erasure of the account
+func (s *PrivacyService) SetErasureGracePeriod(gracePeriod time.Duration) {
+	if gracePeriod >= 0 {
+		s.gracePeriod = gracePeriod
+	}
+}
+
+// Export formats: only what the user entered or what was recorded about them, never credentials or keys
+
+type exportProfile struct {
+	Id                 uint       `json:"id"`
+	Firstname          string     `json:"firstname"`
+	Lastname           string     `json:"lastname"`
+	Email              string     `json:"email"`
+	Role               string     `json:"role,omitempty"`
+	Active             bool       `json:"active"`
+	TwoFactorEnabled   bool       `json:"two_factor_enabled"`
+	SSOLinked          bool       `json:"sso_linked"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	ErasureScheduledAt *time.Time `json:"erasure_scheduled_at,omitempty"`
+}
+
+type exportDatabase struct {
+	Id                  uint      `json:"id"`
+	Name                string    `json:"name"`
+	Type                string    `json:"type"`
+	Host                string    `json:"host"`
+	Port                string    `json:"port"`
+	Username            string    `json:"username"`
+	DbName              string    `json:"db_name"`
+	TeamId              *uint     `json:"team_id,omitempty"`
+	PassphraseProtected bool      `json:"passphrase_protected"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+type exportSchedule struct {
+	Id             uint      `json:"id"`
+	Name           string    `json:"name"`
+	CronExpression string    `json:"cron_expression"`
+	Active         bool      `json:"active"`
+	DatabaseId     uint      `json:"database_id"`
+	DatabaseName   string    `json:"database_name,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type exportBackup struct {
+	Id           uint      `json:"id"`
+	Filename     string    `json:"filename"`
+	Size         int64     `json:"size"`
+	Status       string    `json:"status"`
+	ErrorMsg     string    `json:"error_msg,omitempty"`
+	DatabaseId   uint      `json:"database_id"`
+	DatabaseName string    `json:"database_name,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type exportAction struct {
+	Id           uint      `json:"id"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resource_type"`
+	ResourceId   uint      `json:"resource_id"`
+	Description  string    `json:"description"`
+	Metadata     string    `json:"metadata,omitempty"`
+	IpAddress    string    `json:"ip_address,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	AdminAction  bool      `json:"admin_action"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ExportUserData builds a ZIP archive with the profile, databases (without secrets), schedules, backup catalog
+// and action history of a user, one JSON file each. It returns the archive and its file name.
+func (s *PrivacyService) ExportUserData(userID uint, ipAddress, userAgent string) ([]byte, string, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, "", errors.New("utilisateur introuvable")
+	}
+
+	profile := exportProfile{
+		Id:                 user.Id,
+		Firstname:          user.Firstname,
+		Lastname:           user.Lastname,
+		Email:              user.Email,
+		Active:             user.Active,
+		TwoFactorEnabled:   user.TwoFactorEnabled,
+		SSOLinked:          user.OIDCSubject != nil,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
+		ErasureScheduledAt: user.ErasureScheduledAt,
+	}
+	if user.Role != nil {
+		profile.Role = user.Role.Name
+	}
+
+	databases, err := s.databaseRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("erreur lors de la récupération des bases de données: %v", err)
+	}
+	exportedDatabases := make([]exportDatabase, 0, len(databases))
+	for _, database := range databases {
+		exportedDatabases = append(exportedDatabases, exportDatabase{
+			Id:                  database.Id,
+			Name:                database.Name,
+			Type:                database.Type,
+			Host:                database.Host,
+			Port:                database.Port,
+			Username:            database.Username,
+			DbName:              database.DbName,
+			TeamId:              database.TeamId,
+			PassphraseProtected: database.PassphraseProtected,
+			CreatedAt:           database.CreatedAt,
+			UpdatedAt:           database.UpdatedAt,
+		})
+	}
+
+	schedules, err := s.scheduleRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("erreur lors de la récupération des planifications: %v", err)
+	}
+	exportedSchedules := make([]exportSchedule, 0, len(schedules))
+	for _, schedule := range schedules {
+		exportedSchedules = append(exportedSchedules, exportSchedule{
+			Id:             schedule.Id,
+			Name:           schedule.Name,
+			CronExpression: schedule.CronExpression,
+			Active:         schedule.Active,
+			DatabaseId:     schedule.DatabaseId,
+			DatabaseName:   schedule.Database.Name,
+			CreatedAt:      schedule.CreatedAt,
+			UpdatedAt:      schedule.UpdatedAt,
+		})
+	}
+
+	backups, err := s.backupRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("erreur lors de la récupération des sauvegardes: %v", err)
+	}
+	exportedBackups := make([]exportBackup, 0, len(backups))
+	for _, backup := range backups {
+		exportedBackups = append(exportedBackups, exportBackup{
+			Id:           backup.Id,
+			Filename:     backup.Filename,
+			Size:         backup.Size,
+			Status:       backup.Status,
+			ErrorMsg:     backup.ErrorMsg,
+			DatabaseId:   backup.DatabaseId,
+			DatabaseName: backup.Database.Name,
+			CreatedAt:    backup.CreatedAt,
+		})
+	}
+
+	// -1 removes the limit and the offset
+	actions, err := s.actionHistoryRepo.GetByUserID(userID, -1, -1)
+	if err != nil {
+		return nil, "", fmt.Errorf("erreur lors de la récupération de l'historique: %v", err)
+	}
+	exportedActions := make([]exportAction, 0, len(actions))
+	for _, action := range actions {
+		exportedActions = append(exportedActions, exportAction{
+			Id:           action.Id,
+			Action:       action.Action,
+			ResourceType: action.ResourceType,
+			ResourceId:   action.ResourceId,
+			Description:  action.Description,
+			Metadata:     action.Metadata,
+			IpAddress:    action.IpAddress,
+			UserAgent:    action.UserAgent,
+			AdminAction:  action.AdminAction,
+			CreatedAt:    action.CreatedAt,
+		})
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for _, file := range []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", profile},
+		{"databases.json", exportedDatabases},
+		{"schedules.json", exportedSchedules},
+		{"backups.json", exportedBackups},
+		{"action_history.json", exportedActions},
+	} {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return nil, "", err
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, "", fmt.Errorf("erreur lors de l'écriture de %s: %v", file.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, "", err
+	}
+
+	s.logAction(userID, "data_exported", "Export des données personnelles",
+		map[string]interface{}{"databases": len(databases), "schedules": len(schedules), "backups": len(backups), "actions": len(actions)},
+		ipAddress, userAgent)
+
+	filename := fmt.Sprintf("safebase_export_%d_%s.zip", userID, time.Now().Format("2006-01-02_15-04-05"))
+	return buffer.Bytes(), filename, nil
+}
+
+// RequestErasure schedules the erasure of an account at the end of the grace period. Accounts with a password
+// must confirm it; accounts linked to the identity provider are already authenticated by their session.
+func (s *PrivacyService) RequestErasure(userID uint, password, ipAddress, userAgent string) (time.Time, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return time.Time{}, errors.New("utilisateur introuvable")
+	}
+	if user.ErasureScheduledAt != nil {
+		return time.Time{}, fmt.Errorf("l'effacement du compte est déjà programmé le %s", user.ErasureScheduledAt.Format("02/01/2006 15:04"))
+	}
+	if user.OIDCSubject == nil {
+		if password == "" || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+			return time.Time{}, errors.New("mot de passe incorrect")
+		}
+	}
+
+	if s.deletionService != nil {
+		if err := s.deletionService.CheckErasure(userID); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	scheduledAt := time.Now().Add(s.gracePeriod)
+	if err := s.userRepo.UpdateUserById(userID, map[string]interface{}{"erasure_scheduled_at": scheduledAt}); err != nil {
+		return time.Time{}, fmt.Errorf("erreur lors de la programmation de l'effacement: %v", err)
+	}
+
+	s.logAction(userID, "account_erasure_requested", fmt.Sprintf("Effacement du compte programmé le %s", scheduledAt.Format("02/01/2006 15:04")),
+		map[string]interface{}{"scheduled_at": scheduledAt}, ipAddress, userAgent)
+	return scheduledAt, nil
+}
+
+// CancelErasure cancels a pending erasure request
+func (s *PrivacyService) CancelErasure(userID uint, ipAddress, userAgent string) error {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return errors.New("utilisateur introuvable")
+	}
+	if user.ErasureScheduledAt == nil {
+		return ErrErasureNotScheduled
+	}
+	if err := s.userRepo.UpdateUserById(userID, map[string]interface{}{"erasure_scheduled_at": nil}); err != nil {
+		return fmt.Errorf("erreur lors de l'annulation de l'effacement: %v", err)
+	}
+
+	s.logAction(userID, "account_erasure_cancelled", "Effacement du compte annulé", nil, ipAddress, userAgent)
+	return nil
+}
+
+// ProcessDueErasures starts the erasure of the accounts whose grace period has ended. A failed erasure leaves
+// the account deactivated and is started again on the next run.
+func (s *PrivacyService) ProcessDueErasures() (int, error) {
+	users, err := s.deletionRepo.GetDueErasures(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	started := 0
+	for _, user := range users {
+		if _, err := s.deletionService.StartErasure(user.Id); err != nil {
+			slog.Warn("failed to start account erasure", "user_id", user.Id, "error", err)
+			continue
+		}
+		started++
+	}
+	return started, nil
+}
+
+// logAction records a privacy action of the user
+func (s *PrivacyService) logAction(userID uint, action, description string, metadata map[string]interface{}, ipAddress, userAgent string) {
+	if s.actionHistoryService == nil {
+		return
+	}
+	if err := s.actionHistoryService.LogAction(userID, action, "user", userID, description, metadata, ipAddress, userAgent); err != nil {
+		slog.Warn("failed to log privacy action", "user_id", userID, "action", action, "error", err)
+	}
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/robfig/cron/v3"
+)
+
+// ErrQuotaExceeded is returned when an operation would exceed the quota of the user or of the team
+var ErrQuotaExceeded = errors.New("quota dépassé")
+
+// QuotaService enforces the limits on databases, schedules and backup storage. Users get the configured
+// defaults, which an administrator may override per user; teams are only limited when an administrator sets
+// a quota on them. A nil *QuotaService enforces nothing.
+type QuotaService struct {
+	quotaRepo            *repositories.QuotaRepository
+	userRepo             *repositories.UserRepository
+	teamRepo             *repositories.TeamRepository
+	defaults             models.QuotaLimits
+	actionHistoryService *ActionHistoryService
+}
+
+// QuotaReport is the limits and the usage of a user or a team
+type QuotaReport struct {
+	Limits   models.QuotaLimits `json:"limits"`
+	Usage    models.QuotaUsage  `json:"usage"`
+	Override *models.Quota      `json:"override,omitempty"` // Surcharge définie par un administrateur
+}
+
+// TeamQuotaReport is the report of a team with a quota
+type TeamQuotaReport struct {
+	TeamId   uint   `json:"team_id"`
+	TeamName string `json:"team_name"`
+	QuotaReport
+}
+
+// UsageReport is what a user sees in their profile: their own quota and those of their limited teams
+type UsageReport struct {
+	QuotaReport
+	Teams []TeamQuotaReport `json:"teams"`
+}
+
+// quotaSubject is a user or a team whose limits apply to an operation
+type quotaSubject struct {
+	label  string
+	limits models.QuotaLimits
+	usage  models.QuotaUsage
+}
+
+// NewQuotaService constructor
+func NewQuotaService(quotaRepo *repositories.QuotaRepository, userRepo *repositories.UserRepository, teamRepo *repositories.TeamRepository, defaults models.QuotaLimits) *QuotaService {
+	return &QuotaService{
+		quotaRepo: quotaRepo,
+		userRepo:  userRepo,
+		teamRepo:  teamRepo,
+		defaults:  defaults,
+	}
+}
+
+// SetActionHistoryService sets the action history service reference for logging
+func (s *QuotaService) SetActionHistoryService(actionHistoryService *ActionHistoryService) {
+	s.actionHistoryService = actionHistoryService
+}
+
+// DefaultLimits returns the limits of users without an override
+func (s *QuotaService) DefaultLimits() models.QuotaLimits {
+	return s.defaults
+}
+
+// GetUserReport returns the limits and the usage of a user
+func (s *QuotaService) GetUserReport(userID uint) (*QuotaReport, error) {
+	override, err := s.quotaRepo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := s.quotaRepo.GetUserUsage(userID)
+	if err != nil {
+		return nil, err
+	}
+	limits := s.defaults
+	if override != nil {
+		limits = override.QuotaOverride.Apply(limits)
+	}
+	return &QuotaReport{Limits: limits, Usage: usage, Override: override}, nil
+}
+
+// GetTeamReport returns the limits and the usage of a team (no limit without an override)
+func (s *QuotaService) GetTeamReport(teamID uint) (*QuotaReport, error) {
+	override, err := s.quotaRepo.GetByTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := s.quotaRepo.GetTeamUsage(teamID)
+	if err != nil {
+		return nil, err
+	}
+	limits := models.QuotaLimits{}
+	if override != nil {
+		limits = override.QuotaOverride.Apply(limits)
+	}
+	return &QuotaReport{Limits: limits, Usage: usage, Override: override}, nil
+}
+
+// GetUsage returns the quota of a user and of the limited teams they belong to
+func (s *QuotaService) GetUsage(userID uint) (*UsageReport, error) {
+	report, err := s.GetUserReport(userID)
+	if err != nil {
+		return nil, err
+	}
+	quotas, err := s.quotaRepo.GetLimitedTeamsOfUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	usage := &UsageReport{QuotaReport: *report, Teams: []TeamQuotaReport{}}
+	for _, quota := range quotas {
+		teamReport, err := s.GetTeamReport(*quota.TeamId)
+		if err != nil {
+			return nil, err
+		}
+		team, err := s.teamRepo.GetByID(*quota.TeamId)
+		if err != nil {
+			continue // Équipe supprimée
+		}
+		usage.Teams = append(usage.Teams, TeamQuotaReport{TeamId: team.Id, TeamName: team.Name, QuotaReport: *teamReport})
+	}
+	return usage, nil
+}
+
+// subjects returns the user and, when it has a quota, the team an operation counts against
+func (s *QuotaService) subjects(userID uint, teamID *uint) ([]quotaSubject, error) {
+	report, err := s.GetUserReport(userID)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la vérification des quotas: %v", err)
+	}
+	subjects := []quotaSubject{{label: "votre quota", limits: report.Limits, usage: report.Usage}}
+	if teamID != nil {
+		teamReport, err := s.GetTeamReport(*teamID)
+		if err != nil {
+			return nil, fmt.Errorf("erreur lors de la vérification des quotas: %v", err)
+		}
+		if teamReport.Override != nil {
+			subjects = append(subjects, quotaSubject{label: "le quota de l'équipe", limits: teamReport.Limits, usage: teamReport.Usage})
+		}
+	}
+	return subjects, nil
+}
+
+// CheckDatabaseCreation returns an error when a user may not add a database (to a team)
+func (s *QuotaService) CheckDatabaseCreation(userID uint, teamID *uint) error {
+	if s == nil {
+		return nil
+	}
+	subjects, err := s.subjects(userID, teamID)
+	if err != nil {
+		return err
+	}
+	for _, subject := range subjects {
+		if max := subject.limits.MaxDatabases; max > 0 && subject.usage.Databases >= int64(max) {
+			return fmt.Errorf("%w : %s est limité à %d base(s) de données", ErrQuotaExceeded, subject.label, max)
+		}
+	}
+	return nil
+}
+
+// CheckScheduleCreation returns an error when a user may not add a schedule to a database of a team
+func (s *QuotaService) CheckScheduleCreation(userID uint, teamID *uint, cronExpression string) error {
+	if s == nil {
+		return nil
+	}
+	subjects, err := s.subjects(userID, teamID)
+	if err != nil {
+		return err
+	}
+	for _, subject := range subjects {
+		if max := subject.limits.MaxSchedules; max > 0 && subject.usage.Schedules >= int64(max) {
+			return fmt.Errorf("%w : %s est limité à %d planification(s)", ErrQuotaExceeded, subject.label, max)
+		}
+	}
+	return checkScheduleInterval(subjects, cronExpression)
+}
+
+// CheckScheduleInterval returns an error when a cron expression runs more often than allowed
+func (s *QuotaService) CheckScheduleInterval(userID uint, teamID *uint, cronExpression string) error {
+	if s == nil {
+		return nil
+	}
+	subjects, err := s.subjects(userID, teamID)
+	if err != nil {
+		return err
+	}
+	return checkScheduleInterval(subjects, cronExpression)
+}
+
+func checkScheduleInterval(subjects []quotaSubject, cronExpression string) error {
+	for _, subject := range subjects {
+		minimum := time.Duration(subject.limits.MinScheduleIntervalMinutes) * time.Minute
+		if minimum <= 0 {
+			continue
+		}
+		interval, err := MinCronInterval(cronExpression)
+		if err != nil {
+			return fmt.Errorf("expression cron invalide: %v", err)
+		}
+		if interval < minimum {
+			return fmt.Errorf("%w : %s impose au moins %d minute(s) entre deux sauvegardes planifiées", ErrQuotaExceeded, subject.label, subject.limits.MinScheduleIntervalMinutes)
+		}
+	}
+	return nil
+}
+
+// CheckBackupStart returns an error when the backup storage of the user or of the team is already full
+func (s *QuotaService) CheckBackupStart(userID uint, teamID *uint) error {
+	return s.checkBackupStorage(userID, teamID, true)
+}
+
+// CheckBackupStorage returns an error when completed backups exceed the storage quota of the user or of the team
+func (s *QuotaService) CheckBackupStorage(userID uint, teamID *uint) error {
+	return s.checkBackupStorage(userID, teamID, false)
+}
+
+func (s *QuotaService) checkBackupStorage(userID uint, teamID *uint, full bool) error {
+	if s == nil {
+		return nil
+	}
+	subjects, err := s.subjects(userID, teamID)
+	if err != nil {
+		return err
+	}
+	for _, subject := range subjects {
+		max := subject.limits.MaxBackupBytes
+		if max <= 0 {
+			continue
+		}
+		if subject.usage.BackupBytes > max || (full && subject.usage.BackupBytes == max) {
+			return fmt.Errorf("%w : %s est limité à %s de sauvegardes (%s utilisés)", ErrQuotaExceeded, subject.label,
+				formatQuotaBytes(max), formatQuotaBytes(subject.usage.BackupBytes))
+		}
+	}
+	return nil
+}
+
+// SetUserQuota overrides the default limits of a user (nil values keep the default)
+func (s *QuotaService) SetUserQuota(adminID, userID uint, override models.QuotaOverride, ipAddress, userAgent string) (*QuotaReport, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, fmt.Errorf("utilisateur introuvable")
+	}
+	quota, err := s.quotaRepo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if quota == nil {
+		quota = &models.Quota{UserId: &user.Id}
+	}
+	if err := s.saveOverride(quota, override, adminID); err != nil {
+		return nil, err
+	}
+	s.logQuotaChange(adminID, "quota_updated", "user", user.Id, fmt.Sprintf("Quota de l'utilisateur '%s' modifié", user.Email), override, ipAddress, userAgent)
+	return s.GetUserReport(userID)
+}
+
+// ResetUserQuota removes the override of a user, the defaults apply again
+func (s *QuotaService) ResetUserQuota(adminID, userID uint, ipAddress, userAgent string) error {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return fmt.Errorf("utilisateur introuvable")
+	}
+	quota, err := s.quotaRepo.GetByUser(userID)
+	if err != nil || quota == nil {
+		return err
+	}
+	if err := s.quotaRepo.Delete(quota); err != nil {
+		return err
+	}
+	s.logQuotaChange(adminID, "quota_reset", "user", user.Id, fmt.Sprintf("Quota de l'utilisateur '%s' réinitialisé aux valeurs par défaut", user.Email), models.QuotaOverride{}, ipAddress, userAgent)
+	return nil
+}
+
+// SetTeamQuota limits a team (nil values leave the corresponding resource unlimited)
+func (s *QuotaService) SetTeamQuota(adminID, teamID uint, override models.QuotaOverride, ipAddress, userAgent string) (*QuotaReport, error) {
+	team, err := s.teamRepo.GetByID(teamID)
+	if err != nil {
+		return nil, fmt.Errorf("équipe introuvable")
+	}
+	quota, err := s.quotaRepo.GetByTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	if quota == nil {
+		quota = &models.Quota{TeamId: &team.Id}
+	}
+	if err := s.saveOverride(quota, override, adminID); err != nil {
+		return nil, err
+	}
+	s.logQuotaChange(adminID, "quota_updated", "team", team.Id, fmt.Sprintf("Quota de l'équipe '%s' modifié", team.Name), override, ipAddress, userAgent)
+	return s.GetTeamReport(teamID)
+}
+
+// ResetTeamQuota removes the quota of a team
+func (s *QuotaService) ResetTeamQuota(adminID, teamID uint, ipAddress, userAgent string) error {
+	team, err := s.teamRepo.GetByID(teamID)
+	if err != nil {
+		return fmt.Errorf("équipe introuvable")
+	}
+	quota, err := s.quotaRepo.GetByTeam(teamID)
+	if err != nil || quota == nil {
+		return err
+	}
+	if err := s.quotaRepo.Delete(quota); err != nil {
+		return err
+	}
+	s.logQuotaChange(adminID, "quota_reset", "team", team.Id, fmt.Sprintf("Quota de l'équipe '%s' supprimé", team.Name), models.QuotaOverride{}, ipAddress, userAgent)
+	return nil
+}
+
+// saveOverride validates and stores the limits of an override
+func (s *QuotaService) saveOverride(quota *models.Quota, override models.QuotaOverride, adminID uint) error {
+	for _, value := range []*int{override.MaxDatabases, override.MaxSchedules, override.MinScheduleIntervalMinutes} {
+		if value != nil && *value < 0 {
+			return errors.New("les limites doivent être positives (0 = illimité)")
+		}
+	}
+	if override.MaxBackupBytes != nil && *override.MaxBackupBytes < 0 {
+		return errors.New("les limites doivent être positives (0 = illimité)")
+	}
+	quota.QuotaOverride = override
+	quota.UpdatedBy = adminID
+	return s.quotaRepo.Save(quota)
+}
+
+func (s *QuotaService) logQuotaChange(adminID uint, action, resourceType string, resourceID uint, description string, override models.QuotaOverride, ipAddress, userAgent string) {
+	if s.actionHistoryService == nil {
+		return
+	}
+	metadata := map[string]interface{}{
+		"max_databases":                 override.MaxDatabases,
+		"max_schedules":                 override.MaxSchedules,
+		"min_schedule_interval_minutes": override.MinScheduleIntervalMinutes,
+		"max_backup_bytes":              override.MaxBackupBytes,
+	}
+	_ = s.actionHistoryService.LogAction(adminID, action, resourceType, resourceID, description, metadata, ipAddress, userAgent)
+}
+
+// MinCronInterval returns the smallest gap between two runs of a standard cron expression
+func MinCronInterval(cronExpression string) (time.Duration, error) {
+	schedule, err := cron.ParseStandard(cronExpression)
+	if err != nil {
+		return 0, err
+	}
+	minimum := time.Duration(math.MaxInt64)
+	previous := schedule.Next(time.Now())
+	// Enough runs to cover the irregular gaps of usual expressions (hours, week days, month days)
+	for i := 0; i < 1000 && !previous.IsZero(); i++ {
+		next := schedule.Next(previous)
+		if next.IsZero() {
+			break
+		}
+		if gap := next.Sub(previous); gap < minimum {
+			minimum = gap
+		}
+		previous = next
+	}
+	return minimum, nil
+}
+
+// formatQuotaBytes formats a size for the quota messages
+func formatQuotaBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d o", size)
+	}
+	value, suffix := float64(size)/unit, "Ko"
+	for _, next := range []string{"Mo", "Go", "To"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
@@ -0,0 +1,373 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/logger"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/mailer"
+)
+
+// DefaultRestoreApprovalTTL is how long a restore into a protected database waits for its approval
+const DefaultRestoreApprovalTTL = 24 * time.Hour
+
+var (
+	// ErrRestoreNotAwaitingApproval is returned when a restore was already approved, rejected or expired
+	ErrRestoreNotAwaitingApproval = errors.New("cette restauration n'est pas en attente d'approbation")
+	// ErrRestoreApprovalExpired is returned when the approval window of a restore is over
+	ErrRestoreApprovalExpired = errors.New("la demande de restauration a expiré")
+	// ErrSelfApproval is returned when the requester of a restore tries to review it
+	ErrSelfApproval = errors.New("une restauration doit être approuvée par un autre utilisateur que le demandeur")
+	// ErrRestoreReviewForbidden is returned when the reviewer may not manage the target database
+	ErrRestoreReviewForbidden = errors.New("vous ne pouvez pas approuver les restaurations de cette base de données")
+	// ErrUnprotectPendingRestores is returned when disabling the protection while restores await their approval
+	ErrUnprotectPendingRestores = errors.New("des restaurations attendent une approbation : traitez-les avant de désactiver la protection")
+	// ErrSelfUnprotect is returned when the user who asked to disable the protection tries to confirm it
+	ErrSelfUnprotect = errors.New("la désactivation de la protection doit être confirmée par un autre utilisateur que le demandeur")
+)
+
+// SetMailer sets the mailer notifying approvers and requesters, and the frontend page linked in the emails
+func (s *RestoreService) SetMailer(m mailer.Mailer, approvalsURL string) {
+	s.mailer = m
+	s.approvalsURL = approvalsURL
+}
+
+// SetApprovalTTL sets how long a restore into a protected database waits for its approval
+func (s *RestoreService) SetApprovalTTL(ttl time.Duration) {
+	s.approvalTTL = ttl
+}
+
+// requestRestoreApproval records a restore into a protected database without executing it and
+// notifies the users who may approve it
+func (s *RestoreService) requestRestoreApproval(ctx context.Context, backup *models.Backup, database *models.Database, userID uint, ipAddress, userAgent string) (*models.Restore, error) {
+	approvers, err := s.restoreApprovers(database, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(approvers) == 0 {
+		return nil, errors.New("base de données protégée : aucun autre utilisateur ne peut approuver cette restauration (partagez la base avec un administrateur d'équipe)")
+	}
+
+	ttl := s.approvalTTL
+	if ttl <= 0 {
+		ttl = DefaultRestoreApprovalTTL
+	}
+	expiresAt := time.Now().Add(ttl)
+	restore := &models.Restore{
+		UserId:            userID,
+		BackupId:          backup.Id,
+		DatabaseId:        database.Id,
+		Status:            models.RestoreStatusAwaitingApproval,
+		ApprovalExpiresAt: &expiresAt,
+	}
+	if err := s.restoreRepo.WithContext(ctx).Create(restore); err != nil {
+		return nil, fmt.Errorf("échec de la création de l'enregistrement de restauration: %v", err)
+	}
+
+	s.logApprovalAction(userID, "restore_requested", restore, backup, database,
+		fmt.Sprintf("Restauration demandée - Sauvegarde '%s' vers la base protégée '%s' (en attente d'approbation)", backup.Filename, database.Name),
+		map[string]interface{}{"expires_at": expiresAt, "approvers": len(approvers)}, ipAddress, userAgent)
+
+	for _, approver := range approvers {
+		go s.sendMail(mailer.Message{
+			To:      approver.Email,
+			Subject: fmt.Sprintf("SafeBase : restauration de %s à approuver", database.Name),
+			Body: fmt.Sprintf("Bonjour %s,\n\n"+
+				"Une restauration de la sauvegarde \"%s\" vers la base protégée \"%s\" a été demandée.\n"+
+				"Elle ne sera exécutée qu'après l'approbation d'un second utilisateur, avant le %s.\n\n"+
+				"Approuver ou rejeter la demande : %s\n",
+				approver.Firstname, backup.Filename, database.Name, expiresAt.Format("02/01/2006 15:04"), s.approvalsURL),
+		})
+	}
+	return restore, nil
+}
+
+// GetPendingApprovals returns the restores the user may approve (expired requests are closed first)
+func (s *RestoreService) GetPendingApprovals(userID uint) ([]models.Restore, error) {
+	if _, err := s.ExpireStaleApprovals(); err != nil {
+		slog.Warn("failed to expire restore approvals", "error", err)
+	}
+	restores, err := s.restoreRepo.GetAwaitingApproval()
+	if err != nil {
+		return nil, err
+	}
+	pending := []models.Restore{}
+	for i := range restores {
+		if restores[i].UserId != userID && s.authz.CanAccessDatabase(userID, &restores[i].Database, TeamActionManage) {
+			pending = append(pending, restores[i])
+		}
+	}
+	return pending, nil
+}
+
+// ApproveRestore approves a restore into a protected database and starts it. The approver provides the
+// passphrase of zero-knowledge backups: it is never stored while the request waits.
+func (s *RestoreService) ApproveRestore(ctx context.Context, restoreID, reviewerID uint, passphrase, ipAddress, userAgent string) (*models.Restore, error) {
+	restore, database, err := s.reviewableRestore(restoreID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+	backup, err := s.backupService.GetBackupByID(restore.BackupId)
+	if err != nil {
+		return nil, fmt.Errorf("sauvegarde introuvable: %v", err)
+	}
+	// The requester may have lost access since the request
+	if !s.authz.CanAccessDatabase(restore.UserId, database, TeamActionOperate) {
+		return nil, errors.New("le demandeur n'a plus accès à cette base de données")
+	}
+	if err := s.backupService.CheckBackupPassphrase(backup, passphrase); err != nil {
+		return nil, fmt.Errorf("phrase secrète invalide: %w", err)
+	}
+
+	reviewedAt := time.Now()
+	reviewed, err := s.restoreRepo.WithContext(ctx).Review(restore.Id, "pending", &reviewerID, reviewedAt, "")
+	if err != nil {
+		return nil, err
+	}
+	if !reviewed {
+		return nil, ErrRestoreNotAwaitingApproval
+	}
+	restore.Status = "pending"
+	restore.ReviewerId = &reviewerID
+	restore.ReviewedAt = &reviewedAt
+
+	jobCtx := logger.WithJobID(context.WithoutCancel(ctx), fmt.Sprintf("restore-%d", restore.Id))
+	if s.workerPool != nil {
+		s.workerPool.Submit(func() {
+			s.executeRestoreAsync(jobCtx, restore, backup, database, passphrase)
+		})
+	} else {
+		go s.executeRestoreAsync(jobCtx, restore, backup, database, passphrase)
+	}
+
+	s.logApprovalAction(reviewerID, "restore_approved", restore, backup, database,
+		fmt.Sprintf("Restauration approuvée - Sauvegarde '%s' vers la base protégée '%s'", backup.Filename, database.Name),
+		map[string]interface{}{"requested_by": restore.UserId}, ipAddress, userAgent)
+	s.notifyRequester(restore, database, "approuvée", "La restauration a démarré.")
+	return restore, nil
+}
+
+// RejectRestore rejects a restore into a protected database with a reason
+func (s *RestoreService) RejectRestore(restoreID, reviewerID uint, reason, ipAddress, userAgent string) (*models.Restore, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > 500 {
+		return nil, errors.New("le motif du rejet est requis (500 caractères maximum)")
+	}
+	restore, database, err := s.reviewableRestore(restoreID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewedAt := time.Now()
+	reviewed, err := s.restoreRepo.Review(restore.Id, models.RestoreStatusRejected, &reviewerID, reviewedAt, reason)
+	if err != nil {
+		return nil, err
+	}
+	if !reviewed {
+		return nil, ErrRestoreNotAwaitingApproval
+	}
+	restore.Status = models.RestoreStatusRejected
+	restore.ReviewerId = &reviewerID
+	restore.ReviewedAt = &reviewedAt
+	restore.RejectionReason = reason
+
+	s.logApprovalAction(reviewerID, "restore_rejected", restore, &restore.Backup, database,
+		fmt.Sprintf("Restauration rejetée - Base protégée '%s' : %s", database.Name, reason),
+		map[string]interface{}{"requested_by": restore.UserId, "reason": reason}, ipAddress, userAgent)
+	s.notifyRequester(restore, database, "rejetée", "Motif : "+reason)
+	return restore, nil
+}
+
+// ExpireStaleApprovals closes the requests whose approval window is over and notifies their requesters
+func (s *RestoreService) ExpireStaleApprovals() (int, error) {
+	restores, err := s.restoreRepo.GetExpiredApprovals(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for i := range restores {
+		restore := &restores[i]
+		reviewed, err := s.restoreRepo.Review(restore.Id, models.RestoreStatusExpired, nil, time.Now(), "")
+		if err != nil {
+			return expired, err
+		}
+		if !reviewed {
+			continue
+		}
+		expired++
+		restore.Status = models.RestoreStatusExpired
+		s.logApprovalAction(restore.UserId, "restore_expired", restore, &restore.Backup, &restore.Database,
+			fmt.Sprintf("Demande de restauration expirée sans approbation - Base protégée '%s'", restore.Database.Name),
+			nil, "", "")
+		s.notifyRequester(restore, &restore.Database, "expirée", "Aucun utilisateur ne l'a approuvée à temps : faites une nouvelle demande si besoin.")
+	}
+	return expired, nil
+}
+
+// reviewableRestore loads a restore awaiting approval and checks that the reviewer may review it
+func (s *RestoreService) reviewableRestore(restoreID, reviewerID uint) (*models.Restore, *models.Database, error) {
+	restore, err := s.restoreRepo.GetByID(restoreID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("restauration introuvable: %v", err)
+	}
+	if restore.Status != models.RestoreStatusAwaitingApproval {
+		return nil, nil, ErrRestoreNotAwaitingApproval
+	}
+	if restore.UserId == reviewerID {
+		return nil, nil, ErrSelfApproval
+	}
+	database, err := s.databaseService.GetDatabaseByID(restore.DatabaseId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("base de données introuvable: %v", err)
+	}
+	if !s.authz.CanAccessDatabase(reviewerID, database, TeamActionManage) {
+		return nil, nil, ErrRestoreReviewForbidden
+	}
+	if restore.ApprovalExpiresAt != nil && !restore.ApprovalExpiresAt.After(time.Now()) {
+		if _, err := s.ExpireStaleApprovals(); err != nil {
+			slog.Warn("failed to expire restore approvals", "error", err)
+		}
+		return nil, nil, ErrRestoreApprovalExpired
+	}
+	return restore, database, nil
+}
+
+// restoreApprovers returns the users other than the requester who may approve restores into a database
+func (s *RestoreService) restoreApprovers(database *models.Database, requesterID uint) ([]models.User, error) {
+	users, err := s.authz.UsersAllowed(database, TeamActionManage)
+	if err != nil {
+		return nil, err
+	}
+	approvers := []models.User{}
+	for _, user := range users {
+		if user.Id != requesterID {
+			approvers = append(approvers, user)
+		}
+	}
+	return approvers, nil
+}
+
+// notifyRequester emails the requester of a restore the outcome of their request
+func (s *RestoreService) notifyRequester(restore *models.Restore, database *models.Database, outcome, details string) {
+	if restore.User.Email == "" {
+		return
+	}
+	go s.sendMail(mailer.Message{
+		To:      restore.User.Email,
+		Subject: fmt.Sprintf("SafeBase : restauration de %s %s", database.Name, outcome),
+		Body: fmt.Sprintf("Bonjour %s,\n\n"+
+			"Votre demande de restauration vers la base protégée \"%s\" a été %s.\n%s\n",
+			restore.User.Firstname, database.Name, outcome, details),
+	})
+}
+
+func (s *RestoreService) sendMail(msg mailer.Message) {
+	if s.mailer == nil {
+		slog.Warn("no mailer configured, email not sent", "subject", msg.Subject)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		slog.Warn("failed to send email", "subject", msg.Subject, "error", err)
+	}
+}
+
+// logApprovalAction records a step of the approval workflow in the action history
+func (s *RestoreService) logApprovalAction(userID uint, action string, restore *models.Restore, backup *models.Backup, database *models.Database, description string, extra map[string]interface{}, ipAddress, userAgent string) {
+	if s.actionHistoryService == nil {
+		return
+	}
+	metadata := map[string]interface{}{
+		"restore_id":    restore.Id,
+		"backup_id":     restore.BackupId,
+		"backup_name":   backup.Filename,
+		"database_id":   restore.DatabaseId,
+		"database_name": database.Name,
+		"status":        restore.Status,
+	}
+	for key, value := range extra {
+		metadata[key] = value
+	}
+	_ = s.actionHistoryService.LogAction(userID, action, "restore", restore.Id, description, metadata, ipAddress, userAgent)
+}
+
+// SetDatabaseProtection enables or disables the four-eyes approval of restores into a database.
+// Disabling it follows the same rule as the restores: a first user asks for it and a second user
+// confirms it, so nobody can lift the protection alone and restore straight away. It reports true
+// while the request waits for its confirmation.
+func (s *DatabaseService) SetDatabaseProtection(databaseID, userID uint, protected bool, ipAddress, userAgent string) (bool, error) {
+	database, err := s.databaseRepo.GetByID(databaseID)
+	if err != nil {
+		return false, fmt.Errorf("base de données introuvable: %v", err)
+	}
+	if err := s.authz.AuthorizeDatabase(userID, database, TeamActionManage); err != nil {
+		return false, err
+	}
+	if database.Protected == protected {
+		return false, nil
+	}
+	metadata := map[string]interface{}{"database_id": database.Id, "database_name": database.Name}
+
+	if protected {
+		if err := s.databaseRepo.UpdateProtected(databaseID, true); err != nil {
+			return false, err
+		}
+		s.logProtectionAction(userID, "protection_enabled", database,
+			fmt.Sprintf("Base de données '%s' protégée : les restaurations exigent une seconde approbation", database.Name),
+			metadata, ipAddress, userAgent)
+		return false, nil
+	}
+
+	awaiting, err := s.restoreRepo.CountAwaitingApprovalForDatabase(databaseID)
+	if err != nil {
+		return false, err
+	}
+	if awaiting > 0 {
+		return false, ErrUnprotectPendingRestores
+	}
+
+	// First step (or a request left unconfirmed too long): record who asks for it
+	requestedBy, requestedAt := database.UnprotectRequestedBy, database.UnprotectRequestedAt
+	if requestedBy == nil || requestedAt == nil || time.Since(*requestedAt) > DefaultRestoreApprovalTTL {
+		approvers, err := s.authz.UsersAllowed(database, TeamActionManage)
+		if err != nil {
+			return false, err
+		}
+		if len(approvers) < 2 {
+			return false, errors.New("aucun autre utilisateur ne peut confirmer la désactivation de la protection (partagez la base avec un administrateur d'équipe)")
+		}
+		if err := s.databaseRepo.RequestUnprotect(databaseID, userID, time.Now()); err != nil {
+			return false, err
+		}
+		s.logProtectionAction(userID, "protection_disable_requested", database,
+			fmt.Sprintf("Désactivation de la protection de la base de données '%s' demandée (en attente de confirmation)", database.Name),
+			metadata, ipAddress, userAgent)
+		return true, nil
+	}
+	if *requestedBy == userID {
+		return false, ErrSelfUnprotect
+	}
+
+	if err := s.databaseRepo.UpdateProtected(databaseID, false); err != nil {
+		return false, err
+	}
+	metadata["requested_by"] = *requestedBy
+	s.logProtectionAction(userID, "protection_disabled", database,
+		fmt.Sprintf("Protection de la base de données '%s' désactivée", database.Name),
+		metadata, ipAddress, userAgent)
+	return false, nil
+}
+
+// logProtectionAction records a change of the protection of a database in the action history
+func (s *DatabaseService) logProtectionAction(userID uint, action string, database *models.Database, description string, metadata map[string]interface{}, ipAddress, userAgent string) {
+	if s.actionHistoryService == nil {
+		return
+	}
+	_ = s.actionHistoryService.LogAction(userID, action, "database", database.Id, description, metadata, ipAddress, userAgent)
+}
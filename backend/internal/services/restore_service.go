@@ -3,13 +3,21 @@ package services
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/logger"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/mailer"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WorkerPool interface for background tasks
@@ -24,6 +32,10 @@ type RestoreService struct {
 	userService          *UserService
 	workerPool           RestoreWorkerPoolInterface
 	actionHistoryService *ActionHistoryService
+	authz                *AuthorizationService
+	mailer               mailer.Mailer // Notifications of the restore approval workflow
+	approvalsURL         string
+	approvalTTL          time.Duration
 }
 
 // Constructor for RestoreService
@@ -41,6 +53,11 @@ func (s *RestoreService) SetActionHistoryService(actionHistoryService *ActionHis
 	s.actionHistoryService = actionHistoryService
 }
 
+// SetAuthorizationService enables team based access checks (nil keeps per-user ownership)
+func (s *RestoreService) SetAuthorizationService(authz *AuthorizationService) {
+	s.authz = authz
+}
+
 // SetWorkerPool sets the worker pool for background tasks
 func (s *RestoreService) SetWorkerPool(workerPool RestoreWorkerPoolInterface) {
 	s.workerPool = workerPool
@@ -77,69 +94,90 @@ func (s *RestoreService) unzipBackupData(zipData []byte) ([]byte, error) {
 }
 
 // executeRestoreAsync executes the restore process asynchronously
-func (s *RestoreService) executeRestoreAsync(restore *models.Restore, backup *models.Backup, database *models.Database) {
+// Each phase (download, unzip, apply) gets its own span under the "restore.job" span
+func (s *RestoreService) executeRestoreAsync(ctx context.Context, restore *models.Restore, backup *models.Backup, database *models.Database, passphrase string) {
+	ctx, span := tracing.Start(ctx, "restore.job",
+		attribute.Int64("restore.id", int64(restore.Id)),
+		attribute.Int64("backup.id", int64(backup.Id)),
+		attribute.Int64("database.id", int64(database.Id)),
+		attribute.String("database.type", database.Type),
+	)
+	defer span.End()
+	restoreRepo := s.restoreRepo.WithContext(ctx)
+
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Printf("[RESTORE] Panic in restore process for ID %d: %v\n", restore.Id, r)
-			s.updateRestoreError(restore.Id)
+			slog.ErrorContext(ctx, "panic in restore process", "restore_id", restore.Id, "panic", fmt.Sprint(r))
+			s.updateRestoreError(ctx, restore.Id)
 		}
 	}()
 
-	fmt.Printf("[RESTORE] Starting asynchronous restore process for ID: %d\n", restore.Id)
+	slog.InfoContext(ctx, "starting asynchronous restore process", "restore_id", restore.Id, "backup_id", backup.Id, "database_id", database.Id)
 
-	// Update status to running
-	if err := s.restoreRepo.UpdateStatus(restore.Id, "running"); err != nil {
-		fmt.Printf("[RESTORE] Failed to update status to running: %v\n", err)
+	// Update status to running, unless the restore was cancelled while waiting in the queue
+	started, err := restoreRepo.TransitionStatus(restore.Id, []string{"pending"}, "running")
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to update restore status to running", "restore_id", restore.Id, "error", err)
+		return
+	}
+	if !started {
+		slog.InfoContext(ctx, "restore cancelled before it started", "restore_id", restore.Id)
 		return
 	}
 
 	// Download and decrypt the backup file
-	backupData, err := s.backupService.DownloadBackup(backup.Id, backup.UserId)
+	downloadCtx, downloadSpan := tracing.Start(ctx, "restore.download")
+	backupData, err := s.backupService.DownloadBackupWithPassphraseContext(downloadCtx, backup.Id, restore.UserId, passphrase)
+	tracing.End(downloadSpan, err)
 	if err != nil {
-		fmt.Printf("[RESTORE] Failed to download backup file: %v\n", err)
-		s.updateRestoreError(restore.Id)
+		slog.ErrorContext(ctx, "failed to download backup file", "restore_id", restore.Id, "error", err)
+		s.updateRestoreError(ctx, restore.Id)
 		return
 	}
 
 	// Unzip the backup data to extract the SQL file
+	_, unzipSpan := tracing.Start(ctx, "restore.unzip")
 	sqlData, err := s.unzipBackupData(backupData)
+	tracing.End(unzipSpan, err)
 	if err != nil {
-		fmt.Printf("[RESTORE] Failed to unzip backup data: %v\n", err)
-		s.updateRestoreError(restore.Id)
+		slog.ErrorContext(ctx, "failed to unzip backup data", "restore_id", restore.Id, "error", err)
+		s.updateRestoreError(ctx, restore.Id)
 		return
 	}
 
 	// Execute restore based on database type
+	applyCtx, applySpan := tracing.Start(ctx, "restore.apply",
+		attribute.String("database.type", database.Type),
+		attribute.Int("restore.sql_size", len(sqlData)),
+	)
 	switch dbType := database.Type; dbType {
 	case "mysql":
-		err = s.restoreMySQL(database, sqlData)
+		err = s.restoreMySQL(applyCtx, database, sqlData)
 	case "postgresql":
-		err = s.restorePostgreSQL(database, sqlData)
+		err = s.restorePostgreSQL(applyCtx, database, sqlData)
 	default:
 		err = fmt.Errorf("type de base de données non supporté: %s", dbType)
 	}
+	tracing.End(applySpan, err)
 
 	if err != nil {
-		fmt.Printf("[RESTORE] Restore failed for ID %d: %v\n", restore.Id, err)
-		s.updateRestoreError(restore.Id)
+		slog.ErrorContext(ctx, "restore failed", "restore_id", restore.Id, "error", err)
+		s.updateRestoreError(ctx, restore.Id)
 		return
 	}
 
 	// Update restore record with success
-	if err := s.restoreRepo.UpdateStatus(restore.Id, "success"); err != nil {
-		fmt.Printf("[RESTORE] Failed to update restore status: %v\n", err)
+	if err := restoreRepo.UpdateStatus(restore.Id, "success"); err != nil {
+		slog.ErrorContext(ctx, "failed to update restore status", "restore_id", restore.Id, "error", err)
 		return
 	}
 
-	fmt.Printf("[RESTORE] Restore process completed successfully for ID: %d\n", restore.Id)
+	slog.InfoContext(ctx, "restore process completed", "restore_id", restore.Id)
 }
 
-// restoreMySQL restores a MySQL database from backup data
-func (s *RestoreService) restoreMySQL(database *models.Database, backupData []byte) error {
-	fmt.Printf("[RESTORE] Starting MySQL restore for database %s\n", database.DbName)
-
-	// Find mysql executable
-	mysqlPaths := []string{
+// getMySQLClientPaths returns possible mysql client paths
+func (s *RestoreService) getMySQLClientPaths() []string {
+	return []string{
 		"/Applications/MAMP/Library/bin/mysql80/bin/mysql",
 		"/Applications/MAMP/Library/bin/mysql",
 		"/usr/local/mysql/bin/mysql",
@@ -148,20 +186,32 @@ func (s *RestoreService) restoreMySQL(database *models.Database, backupData []by
 		"/usr/bin/mysql",
 		"mysql",
 	}
+}
 
-	var mysqlPath string
-	for _, path := range mysqlPaths {
-		if _, err := exec.LookPath(path); err == nil {
-			mysqlPath = path
-			break
-		}
+// getPostgreSQLClientPaths returns possible psql client paths
+func (s *RestoreService) getPostgreSQLClientPaths() []string {
+	return []string{
+		"/Applications/Postgres.app/Contents/Versions/latest/bin/psql",
+		"/usr/local/pgsql/bin/psql",
+		"/usr/local/bin/psql",
+		"/opt/homebrew/bin/psql",
+		"/usr/bin/psql",
+		"psql",
 	}
+}
+
+// restoreMySQL restores a MySQL database from backup data
+func (s *RestoreService) restoreMySQL(ctx context.Context, database *models.Database, backupData []byte) error {
+	slog.InfoContext(ctx, "starting MySQL restore", "database_id", database.Id, "db_name", database.DbName)
 
-	if mysqlPath == "" {
+	// Find mysql executable
+	mysqlPath, err := findExecutable(s.getMySQLClientPaths())
+	if err != nil {
 		return fmt.Errorf("mysql client non trouvé")
 	}
 
-	fmt.Printf("[RESTORE] Using mysql at: %s\n", mysqlPath)
+	slog.DebugContext(ctx, "using mysql client", "path", mysqlPath)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("restore.tool", mysqlPath))
 
 	// Build mysql command
 	args := []string{
@@ -175,48 +225,32 @@ func (s *RestoreService) restoreMySQL(database *models.Database, backupData []by
 	env := []string{fmt.Sprintf("MYSQL_PWD=%s", database.Password)}
 
 	// Execute mysql command with backup data as input
-	cmd := exec.Command(mysqlPath, args...)
+	cmd := exec.CommandContext(ctx, mysqlPath, args...)
 	cmd.Env = append(cmd.Env, env...)
 	cmd.Stdin = strings.NewReader(string(backupData))
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		fmt.Printf("[RESTORE] MySQL restore failed: %v\n", err)
-		fmt.Printf("[RESTORE] Output: %s\n", string(output))
+		slog.ErrorContext(ctx, "MySQL restore failed", "error", err, "output", string(output))
 		return fmt.Errorf("erreur lors de la restauration MySQL: %v, output: %s", err, string(output))
 	}
 
-	fmt.Printf("[RESTORE] MySQL restore completed successfully\n")
+	slog.InfoContext(ctx, "MySQL restore completed", "database_id", database.Id)
 	return nil
 }
 
 // restorePostgreSQL restores a PostgreSQL database from backup data
-func (s *RestoreService) restorePostgreSQL(database *models.Database, backupData []byte) error {
-	fmt.Printf("[RESTORE] Starting PostgreSQL restore for database %s\n", database.DbName)
+func (s *RestoreService) restorePostgreSQL(ctx context.Context, database *models.Database, backupData []byte) error {
+	slog.InfoContext(ctx, "starting PostgreSQL restore", "database_id", database.Id, "db_name", database.DbName)
 
 	// Find psql executable
-	psqlPaths := []string{
-		"/Applications/Postgres.app/Contents/Versions/latest/bin/psql",
-		"/usr/local/pgsql/bin/psql",
-		"/usr/local/bin/psql",
-		"/opt/homebrew/bin/psql",
-		"/usr/bin/psql",
-		"psql",
-	}
-
-	var psqlPath string
-	for _, path := range psqlPaths {
-		if _, err := exec.LookPath(path); err == nil {
-			psqlPath = path
-			break
-		}
-	}
-
-	if psqlPath == "" {
+	psqlPath, err := findExecutable(s.getPostgreSQLClientPaths())
+	if err != nil {
 		return fmt.Errorf("psql client non trouvé")
 	}
 
-	fmt.Printf("[RESTORE] Using psql at: %s\n", psqlPath)
+	slog.DebugContext(ctx, "using psql client", "path", psqlPath)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("restore.tool", psqlPath))
 
 	// Build psql command
 	args := []string{
@@ -230,33 +264,36 @@ func (s *RestoreService) restorePostgreSQL(database *models.Database, backupData
 	env := []string{fmt.Sprintf("PGPASSWORD=%s", database.Password)}
 
 	// Execute psql command with backup data as input
-	cmd := exec.Command(psqlPath, args...)
+	cmd := exec.CommandContext(ctx, psqlPath, args...)
 	cmd.Env = append(cmd.Env, env...)
 	cmd.Stdin = strings.NewReader(string(backupData))
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		fmt.Printf("[RESTORE] PostgreSQL restore failed: %v\n", err)
-		fmt.Printf("[RESTORE] Output: %s\n", string(output))
+		slog.ErrorContext(ctx, "PostgreSQL restore failed", "error", err, "output", string(output))
 		return fmt.Errorf("erreur lors de la restauration PostgreSQL: %v, output: %s", err, string(output))
 	}
 
-	fmt.Printf("[RESTORE] PostgreSQL restore completed successfully\n")
+	slog.InfoContext(ctx, "PostgreSQL restore completed", "database_id", database.Id)
 	return nil
 }
 
-// updateRestoreError updates restore status to failed
-func (s *RestoreService) updateRestoreError(restoreID uint) {
+// updateRestoreError updates restore status to failed (and fails the job span)
+func (s *RestoreService) updateRestoreError(ctx context.Context, restoreID uint) {
+	tracing.Fail(ctx, "restore failed")
 	// For now, we'll just update the status to failed
 	// In the future, we might want to add an error_msg field to the Restore model
-	if err := s.restoreRepo.UpdateStatus(restoreID, "failed"); err != nil {
+	if err := s.restoreRepo.WithContext(ctx).UpdateStatus(restoreID, "failed"); err != nil {
 		// Log the error but don't fail the operation
-		fmt.Printf("Warning: failed to update restore status: %v\n", err)
+		slog.WarnContext(ctx, "failed to update restore status", "restore_id", restoreID, "error", err)
 	}
 }
 
-// GetRestoresByUser returns all restores for a user
+// GetRestoresByUser returns all restores a user can access
 func (s *RestoreService) GetRestoresByUser(userID uint) ([]models.Restore, error) {
+	if s.authz.Enabled() {
+		return s.restoreRepo.GetAccessibleByUser(userID)
+	}
 	return s.restoreRepo.GetByUserID(userID)
 }
 
@@ -279,14 +316,26 @@ func (s *RestoreService) GetRestoreByID(id uint) (*models.Restore, error) {
 
 // CreateRestore creates a restore operation and logs the action
 func (s *RestoreService) CreateRestore(backupID uint, databaseID uint, userID uint, ipAddress string, userAgent string) (*models.Restore, error) {
+	return s.CreateRestoreContext(context.Background(), backupID, databaseID, userID, ipAddress, userAgent)
+}
+
+// CreateRestoreContext creates a restore operation and logs the action, propagating the
+// request correlation ID from ctx into the asynchronous restore job
+func (s *RestoreService) CreateRestoreContext(ctx context.Context, backupID uint, databaseID uint, userID uint, ipAddress string, userAgent string) (*models.Restore, error) {
+	return s.CreateRestoreWithPassphraseContext(ctx, backupID, databaseID, userID, "", ipAddress, userAgent)
+}
+
+// CreateRestoreWithPassphraseContext creates a restore operation; the passphrase of a zero-knowledge
+// backup is checked before the job is queued and only kept in memory for the job
+func (s *RestoreService) CreateRestoreWithPassphraseContext(ctx context.Context, backupID uint, databaseID uint, userID uint, passphrase string, ipAddress string, userAgent string) (*models.Restore, error) {
 	// Get backup info
 	backup, err := s.backupService.GetBackupByID(backupID)
 	if err != nil {
 		return nil, fmt.Errorf("sauvegarde introuvable: %v", err)
 	}
 
-	// Verify that the backup belongs to the user
-	if backup.UserId != userID {
+	// Verify that the user may restore the backup
+	if !s.authz.CanAccessResource(userID, backup.UserId, backup.DatabaseId, TeamActionOperate) {
 		return nil, fmt.Errorf("accès non autorisé à cette sauvegarde")
 	}
 
@@ -296,8 +345,8 @@ func (s *RestoreService) CreateRestore(backupID uint, databaseID uint, userID ui
 		return nil, fmt.Errorf("base de données introuvable: %v", err)
 	}
 
-	// Verify that the database belongs to the user
-	if database.UserId != userID {
+	// Verify that the user may restore into the database
+	if !s.authz.CanAccessDatabase(userID, database, TeamActionOperate) {
 		return nil, fmt.Errorf("accès non autorisé à cette base de données")
 	}
 
@@ -306,6 +355,16 @@ func (s *RestoreService) CreateRestore(backupID uint, databaseID uint, userID ui
 		return nil, fmt.Errorf("la sauvegarde n'est pas encore terminée")
 	}
 
+	// Reject a missing or wrong passphrase now rather than in a failed background job
+	if err := s.backupService.CheckBackupPassphrase(backup, passphrase); err != nil {
+		return nil, fmt.Errorf("phrase secrète invalide: %w", err)
+	}
+
+	// Protected databases: the restore waits for the approval of a second user
+	if database.Protected {
+		return s.requestRestoreApproval(ctx, backup, database, userID, ipAddress, userAgent)
+	}
+
 	// Create restore record with pending status
 	restore := &models.Restore{
 		UserId:     userID,
@@ -314,17 +373,20 @@ func (s *RestoreService) CreateRestore(backupID uint, databaseID uint, userID ui
 		Status:     "pending",
 	}
 
-	if err := s.restoreRepo.Create(restore); err != nil {
+	if err := s.restoreRepo.WithContext(ctx).Create(restore); err != nil {
 		return nil, fmt.Errorf("échec de la création de l'enregistrement de restauration: %v", err)
 	}
 
+	// The job outlives the HTTP request: keep its values (request ID) but not its cancellation
+	jobCtx := logger.WithJobID(context.WithoutCancel(ctx), fmt.Sprintf("restore-%d", restore.Id))
+
 	// Execute restore asynchronously using worker pool
 	if s.workerPool != nil {
 		s.workerPool.Submit(func() {
-			s.executeRestoreAsync(restore, backup, database)
+			s.executeRestoreAsync(jobCtx, restore, backup, database, passphrase)
 		})
 	} else {
-		go s.executeRestoreAsync(restore, backup, database)
+		go s.executeRestoreAsync(jobCtx, restore, backup, database, passphrase)
 	}
 
 	// Log the action
@@ -343,3 +405,21 @@ func (s *RestoreService) CreateRestore(backupID uint, databaseID uint, userID ui
 
 	return restore, nil
 }
+
+// CancelRestore cancels a restore that has not started yet (pending or awaiting approval). A running
+// restore is never interrupted: the target database would be left half restored.
+func (s *RestoreService) CancelRestore(restoreID uint) (*models.Restore, error) {
+	restore, err := s.restoreRepo.GetByID(restoreID)
+	if err != nil {
+		return nil, fmt.Errorf("restauration introuvable: %v", err)
+	}
+	cancelled, err := s.restoreRepo.TransitionStatus(restoreID, []string{"pending", models.RestoreStatusAwaitingApproval}, models.RestoreStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, ErrJobNotCancellable
+	}
+	restore.Status = models.RestoreStatusCancelled
+	return restore, nil
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+)
+
+// roleNamePattern restricts custom role names (they end up in JWT claims and URLs)
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+// ErrBuiltInRole is returned when trying to edit or delete a built-in role
+var ErrBuiltInRole = errors.New("built-in roles cannot be modified")
+
+// RoleService manages roles and their permissions and answers permission checks
+type RoleService struct {
+	roleRepo    *repositories.RoleRepository
+	historyRepo *repositories.ActionHistoryRepository
+
+	mu    sync.RWMutex
+	cache map[string]map[string]bool // role name → permissions, filled on demand and reset on every change
+}
+
+// NewRoleService constructor
+func NewRoleService(roleRepo *repositories.RoleRepository, historyRepo *repositories.ActionHistoryRepository) *RoleService {
+	return &RoleService{
+		roleRepo:    roleRepo,
+		historyRepo: historyRepo,
+		cache:       make(map[string]map[string]bool),
+	}
+}
+
+// builtInRoleOrder creates the built-in roles in a stable order (admin and user come from db.SeedRoles)
+var builtInRoleOrder = []string{"admin", "user", "operator", "auditor"}
+
+// SyncBuiltInRoles creates the permission catalog and the built-in roles, and resets the permissions of
+// the built-in roles to their defaults. It is called at startup, after db.SeedRoles.
+func (s *RoleService) SyncBuiltInRoles() error {
+	for _, definition := range models.PermissionCatalog {
+		if err := s.roleRepo.EnsurePermission(definition); err != nil {
+			return fmt.Errorf("failed to seed permission %s: %w", definition.Name, err)
+		}
+	}
+	for _, name := range builtInRoleOrder {
+		role, err := s.roleRepo.EnsureBuiltInRole(name)
+		if err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", name, err)
+		}
+		permissions, err := s.roleRepo.GetPermissionsByNames(models.BuiltInRoles[name])
+		if err != nil {
+			return err
+		}
+		if err := s.roleRepo.ReplacePermissions(role, permissions); err != nil {
+			return fmt.Errorf("failed to seed permissions of role %s: %w", name, err)
+		}
+	}
+	s.resetCache()
+	return nil
+}
+
+// HasPermission reports whether a role grants a permission (used by AuthMiddleware.RequirePermission)
+func (s *RoleService) HasPermission(roleName, permission string) bool {
+	permissions, err := s.permissionSet(roleName)
+	if err != nil {
+		slog.Error("failed to load role permissions", "role", roleName, "error", err)
+		return false
+	}
+	return permissions[permission]
+}
+
+// RolePermissions returns the permission names of a role
+func (s *RoleService) RolePermissions(roleName string) ([]string, error) {
+	permissions, err := s.permissionSet(roleName)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(permissions))
+	for _, definition := range models.PermissionCatalog {
+		if permissions[definition.Name] {
+			names = append(names, definition.Name)
+		}
+	}
+	return names, nil
+}
+
+// ListPermissions returns the permission catalog
+func (s *RoleService) ListPermissions() ([]models.Permission, error) {
+	return s.roleRepo.GetAllPermissions()
+}
+
+// CreateRole creates a custom role
+func (s *RoleService) CreateRole(adminID uint, name, description string, permissions []string, requireTwoFactor bool, ipAddress, userAgent string) (*models.Role, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !roleNamePattern.MatchString(name) {
+		return nil, errors.New("invalid role name (2-50 lowercase letters, digits, '-' or '_')")
+	}
+	if _, builtIn := models.BuiltInRoles[name]; builtIn {
+		return nil, ErrBuiltInRole
+	}
+	granted, err := s.resolvePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{Name: name, Description: strings.TrimSpace(description), RequireTwoFactor: requireTwoFactor}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, fmt.Errorf("role %q already exists", name)
+	}
+	if err := s.roleRepo.ReplacePermissions(role, granted); err != nil {
+		return nil, err
+	}
+	role.Permissions = granted
+	s.resetCache()
+
+	s.logRoleAction(adminID, "created", role, fmt.Sprintf("Rôle %s créé", role.Name),
+		map[string]interface{}{"permissions": permissionList(granted), "require_two_factor": requireTwoFactor},
+		ipAddress, userAgent)
+	return role, nil
+}
+
+// UpdateRolePermissions replaces the permissions of a custom role; it applies to the next request of its users
+func (s *RoleService) UpdateRolePermissions(adminID, roleID uint, permissions []string, ipAddress, userAgent string) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid role ID: %w", err)
+	}
+	if role.BuiltIn {
+		return nil, ErrBuiltInRole
+	}
+	granted, err := s.resolvePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := permissionList(role.Permissions)
+	if err := s.roleRepo.ReplacePermissions(role, granted); err != nil {
+		return nil, err
+	}
+	role.Permissions = granted
+	s.resetCache()
+
+	s.logRoleAction(adminID, "permissions_updated", role, fmt.Sprintf("Permissions du rôle %s modifiées", role.Name),
+		map[string]interface{}{"previous": previous, "permissions": permissionList(granted)},
+		ipAddress, userAgent)
+	return role, nil
+}
+
+// DeleteRole deletes a custom role that no user has anymore
+func (s *RoleService) DeleteRole(adminID, roleID uint, ipAddress, userAgent string) error {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return fmt.Errorf("invalid role ID: %w", err)
+	}
+	if role.BuiltIn {
+		return ErrBuiltInRole
+	}
+	count, err := s.roleRepo.CountUsers(role.Id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("role %s is still assigned to %d user(s)", role.Name, count)
+	}
+
+	if err := s.roleRepo.Delete(role); err != nil {
+		return err
+	}
+	s.resetCache()
+
+	s.logRoleAction(adminID, "deleted", role, fmt.Sprintf("Rôle %s supprimé", role.Name),
+		map[string]interface{}{"permissions": permissionList(role.Permissions)},
+		ipAddress, userAgent)
+	return nil
+}
+
+// permissionSet returns the permissions of a role, from the cache when possible
+func (s *RoleService) permissionSet(roleName string) (map[string]bool, error) {
+	s.mu.RLock()
+	permissions, found := s.cache[roleName]
+	s.mu.RUnlock()
+	if found {
+		return permissions, nil
+	}
+
+	names, err := s.roleRepo.GetPermissionNamesByRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+	permissions = make(map[string]bool, len(names))
+	for _, name := range names {
+		permissions[name] = true
+	}
+
+	s.mu.Lock()
+	s.cache[roleName] = permissions
+	s.mu.Unlock()
+	return permissions, nil
+}
+
+func (s *RoleService) resetCache() {
+	s.mu.Lock()
+	s.cache = make(map[string]map[string]bool)
+	s.mu.Unlock()
+}
+
+// resolvePermissions checks requested permission names against the catalog
+func (s *RoleService) resolvePermissions(names []string) ([]models.Permission, error) {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if !models.IsKnownPermission(name) {
+			return nil, fmt.Errorf("unknown permission: %q", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	if len(unique) == 0 {
+		return []models.Permission{}, nil
+	}
+	return s.roleRepo.GetPermissionsByNames(unique)
+}
+
+// logRoleAction records a role change in the action history
+func (s *RoleService) logRoleAction(adminID uint, action string, role *models.Role, description string, metadata map[string]interface{}, ipAddress, userAgent string) {
+	if s.historyRepo == nil {
+		return
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	history := &models.ActionHistory{
+		UserId:       adminID,
+		Action:       action,
+		ResourceType: "role",
+		ResourceId:   role.Id,
+		Description:  description,
+		IpAddress:    ipAddress,
+		UserAgent:    userAgent,
+		Metadata:     string(metadataJSON),
+	}
+	// Don't fail the whole operation if history logging fails
+	if err := s.historyRepo.Create(history); err != nil {
+		slog.Error("failed to record role action", "action", action, "admin_id", adminID, "error", err)
+	}
+}
+
+func permissionList(permissions []models.Permission) []string {
+	names := make([]string, len(permissions))
+	for i, permission := range permissions {
+		names[i] = permission.Name
+	}
+	return names
+}
@@ -1,11 +1,17 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"sync/atomic"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/logger"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/tracing"
 	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type ScheduleService struct {
@@ -15,6 +21,9 @@ type ScheduleService struct {
 	cronScheduler        *cron.Cron
 	jobs                 map[uint]cron.EntryID // key: schedule ID
 	actionHistoryService *ActionHistoryService
+	running              atomic.Bool // cron.Cron does not expose its state
+	authz                *AuthorizationService
+	quotas               *QuotaService
 }
 
 // Constructor of the ScheduleService
@@ -34,13 +43,43 @@ func (s *ScheduleService) SetActionHistoryService(actionHistoryService *ActionHi
 	s.actionHistoryService = actionHistoryService
 }
 
+// SetAuthorizationService enables team based access checks (nil keeps per-user ownership)
+func (s *ScheduleService) SetAuthorizationService(authz *AuthorizationService) {
+	s.authz = authz
+}
+
+// SetQuotaService enables the quotas (nil enforces no limit)
+func (s *ScheduleService) SetQuotaService(quotas *QuotaService) {
+	s.quotas = quotas
+}
+
 // Start the cron scheduler
 func (s *ScheduleService) StartScheduler() {
 	s.cronScheduler.Start()
+	s.running.Store(true)
+}
+
+// StopScheduler stops the cron scheduler (running jobs are not interrupted)
+func (s *ScheduleService) StopScheduler() {
+	s.cronScheduler.Stop()
+	s.running.Store(false)
 }
 
-// GetSchedules returns all schedules for a user
+// IsSchedulerRunning reports whether the cron scheduler has been started
+func (s *ScheduleService) IsSchedulerRunning() bool {
+	return s.running.Load()
+}
+
+// ScheduledJobsCount returns the number of cron entries currently registered
+func (s *ScheduleService) ScheduledJobsCount() int {
+	return len(s.cronScheduler.Entries())
+}
+
+// GetSchedules returns all schedules a user can access
 func (s *ScheduleService) GetSchedules(userID uint) ([]models.Schedule, error) {
+	if s.authz.Enabled() {
+		return s.scheduleRepo.GetAccessibleByUser(userID)
+	}
 	return s.scheduleRepo.GetByUserID(userID)
 }
 
@@ -50,12 +89,31 @@ func (s *ScheduleService) GetSchedule(id uint, userID uint) (*models.Schedule, e
 	if err != nil {
 		return nil, err
 	}
-	if schedule.UserId != userID {
+	if !s.authz.CanAccessResource(userID, schedule.UserId, schedule.DatabaseId, TeamActionView) {
 		return nil, fmt.Errorf("accès non autorisé")
 	}
 	return schedule, nil
 }
 
+// scheduledBackupJob returns the cron job triggering a backup for a schedule.
+// Each run gets its own job ID so the backup logs can be correlated to the schedule.
+func (s *ScheduleService) scheduledBackupJob(scheduleID uint, db *models.Database) func() {
+	return func() {
+		ctx := logger.WithJobID(context.Background(), fmt.Sprintf("schedule-%d-%s", scheduleID, logger.NewID()))
+		ctx, span := tracing.Start(ctx, "schedule.run",
+			attribute.Int64("schedule.id", int64(scheduleID)),
+			attribute.Int64("database.id", int64(db.Id)),
+		)
+		backup, err := s.backupService.CreateBackupContext(ctx, db.Id, db.UserId, "127.0.0.1", "Scheduled Task")
+		tracing.End(span, err)
+		if err != nil {
+			slog.ErrorContext(ctx, "scheduled backup failed", "schedule_id", scheduleID, "database_id", db.Id, "error", err)
+		} else {
+			slog.InfoContext(ctx, "scheduled backup created", "schedule_id", scheduleID, "database_id", db.Id, "backup_id", backup.Id)
+		}
+	}
+}
+
 // LoadActiveSchedules loads all active schedules from DB and adds them to the cron scheduler
 func (s *ScheduleService) LoadActiveSchedules() error {
 	schedules, err := s.scheduleRepo.GetActive()
@@ -66,19 +124,12 @@ func (s *ScheduleService) LoadActiveSchedules() error {
 	for _, schedule := range schedules {
 		db, err := s.databaseRepo.GetByID(schedule.DatabaseId)
 		if err != nil {
-			fmt.Printf("Warning: database %d not found for schedule %d\n", schedule.DatabaseId, schedule.Id)
+			slog.Warn("database not found for schedule", "database_id", schedule.DatabaseId, "schedule_id", schedule.Id)
 			continue
 		}
-		jobID, err := s.cronScheduler.AddFunc(schedule.CronExpression, func() {
-			_, err := s.backupService.CreateBackup(db.Id, db.UserId, "127.0.0.1", "Scheduled Task")
-			if err != nil {
-				fmt.Printf("erreur lors de la création de la sauvegarde planifiée pour la base de données %s: %v\n", db.Name, err)
-			} else {
-				fmt.Printf("sauvegarde planifiée créée avec succès pour la base de données %s\n", db.Name)
-			}
-		})
+		jobID, err := s.cronScheduler.AddFunc(schedule.CronExpression, s.scheduledBackupJob(schedule.Id, db))
 		if err != nil {
-			fmt.Printf("Warning: failed to schedule job for schedule %d: %v\n", schedule.Id, err)
+			slog.Warn("failed to schedule job", "schedule_id", schedule.Id, "error", err)
 			continue
 		}
 		s.jobs[schedule.Id] = jobID
@@ -95,7 +146,7 @@ func (s *ScheduleService) CreateSchedule(databaseID uint, userID uint, name stri
 	if err != nil {
 		return nil, fmt.Errorf("base de données introuvable: %v", err)
 	}
-	if db.UserId != userID {
+	if !s.authz.CanAccessDatabase(userID, db, TeamActionOperate) {
 		return nil, fmt.Errorf("accès non autorisé à cette base de données")
 	}
 
@@ -103,6 +154,9 @@ func (s *ScheduleService) CreateSchedule(databaseID uint, userID uint, name stri
 	if _, err := cron.ParseStandard(cronExpression); err != nil {
 		return nil, fmt.Errorf("expression cron invalide: %v", err)
 	}
+	if err := s.quotas.CheckScheduleCreation(userID, db.TeamId, cronExpression); err != nil {
+		return nil, err
+	}
 
 	// Create the schedule record
 	schedule := &models.Schedule{
@@ -123,19 +177,12 @@ func (s *ScheduleService) CreateSchedule(databaseID uint, userID uint, name stri
 	}
 
 	// Add to cron scheduler
-	jobID, err := s.cronScheduler.AddFunc(cronExpression, func() {
-		_, err := s.backupService.CreateBackup(db.Id, db.UserId, "127.0.0.1", "Scheduled Task")
-		if err != nil {
-			fmt.Printf("erreur lors de la création de la sauvegarde planifiée pour la base de données %s: %v\n", db.Name, err)
-		} else {
-			fmt.Printf("sauvegarde planifiée créée avec succès pour la base de données %s\n", db.Name)
-		}
-	})
+	jobID, err := s.cronScheduler.AddFunc(cronExpression, s.scheduledBackupJob(schedule.Id, db))
 	if err != nil {
 		// If adding to cron fails, delete the schedule from DB
 		if deleteErr := s.scheduleRepo.Delete(schedule.Id); deleteErr != nil {
 			// Log the error but don't fail the operation
-			fmt.Printf("Warning: failed to delete schedule after cron error: %v\n", deleteErr)
+			slog.Warn("failed to delete schedule after cron error", "schedule_id", schedule.Id, "error", deleteErr)
 		}
 		return nil, fmt.Errorf("erreur lors de l'ajout de la tâche cron: %v", err)
 	}
@@ -164,7 +211,7 @@ func (s *ScheduleService) UpdateSchedule(id uint, userID uint, name string, cron
 	if err != nil {
 		return nil, err
 	}
-	if schedule.UserId != userID {
+	if !s.authz.CanAccessResource(userID, schedule.UserId, schedule.DatabaseId, TeamActionOperate) {
 		return nil, fmt.Errorf("accès non autorisé")
 	}
 
@@ -183,6 +230,11 @@ func (s *ScheduleService) UpdateSchedule(id uint, userID uint, name string, cron
 		if _, err := cron.ParseStandard(cronExpression); err != nil {
 			return nil, fmt.Errorf("expression cron invalide: %v", err)
 		}
+		if cronExpression != oldCronExpression {
+			if err := s.quotas.CheckScheduleInterval(schedule.UserId, schedule.Database.TeamId, cronExpression); err != nil {
+				return nil, err
+			}
+		}
 		schedule.CronExpression = cronExpression
 	}
 
@@ -208,14 +260,7 @@ func (s *ScheduleService) UpdateSchedule(id uint, userID uint, name string, cron
 		if err != nil {
 			return nil, fmt.Errorf("base de données introuvable: %v", err)
 		}
-		jobID, err := s.cronScheduler.AddFunc(schedule.CronExpression, func() {
-			_, err := s.backupService.CreateBackup(db.Id, db.UserId, "127.0.0.1", "Scheduled Task")
-			if err != nil {
-				fmt.Printf("erreur lors de la création de la sauvegarde planifiée pour la base de données %s: %v\n", db.Name, err)
-			} else {
-				fmt.Printf("sauvegarde planifiée créée avec succès pour la base de données %s\n", db.Name)
-			}
-		})
+		jobID, err := s.cronScheduler.AddFunc(schedule.CronExpression, s.scheduledBackupJob(schedule.Id, db))
 		if err != nil {
 			return nil, fmt.Errorf("erreur lors de la rescheduling: %v", err)
 		}
@@ -302,8 +347,8 @@ func (s *ScheduleService) DeleteSchedule(id uint, userID uint, ipAddress string,
 		return err
 	}
 
-	// Verify user ownership
-	if schedule.UserId != userID {
+	// Verify that the user may manage the schedules of the database
+	if !s.authz.CanAccessResource(userID, schedule.UserId, schedule.DatabaseId, TeamActionOperate) {
 		return fmt.Errorf("accès non autorisé")
 	}
 
@@ -340,3 +385,33 @@ func (s *ScheduleService) DeleteSchedule(id uint, userID uint, ipAddress string,
 
 	return nil
 }
+
+// Unschedule removes the cron entry of a schedule (the record is left untouched)
+func (s *ScheduleService) Unschedule(scheduleID uint) {
+	if entryID, exists := s.jobs[scheduleID]; exists {
+		s.cronScheduler.Remove(entryID)
+		delete(s.jobs, scheduleID)
+	}
+}
+
+// Reschedule reloads the cron entry of a schedule after its database changed hands
+func (s *ScheduleService) Reschedule(scheduleID uint) error {
+	s.Unschedule(scheduleID)
+	schedule, err := s.scheduleRepo.GetByID(scheduleID)
+	if err != nil {
+		return err
+	}
+	if !schedule.Active {
+		return nil
+	}
+	db, err := s.databaseRepo.GetByID(schedule.DatabaseId)
+	if err != nil {
+		return fmt.Errorf("base de données introuvable: %v", err)
+	}
+	jobID, err := s.cronScheduler.AddFunc(schedule.CronExpression, s.scheduledBackupJob(schedule.Id, db))
+	if err != nil {
+		return err
+	}
+	s.jobs[schedule.Id] = jobID
+	return nil
+}
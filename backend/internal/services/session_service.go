@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+)
+
+// sessionTouchInterval limits how often the last activity of a session is written
+const sessionTouchInterval = time.Minute
+
+// ErrSessionRevoked is returned when a valid JWT belongs to a session that no longer exists
+var ErrSessionRevoked = errors.New("session revoked or expired")
+
+// ValidateSession checks that the session of an access token still exists and returns its ID.
+// It is called by the auth middleware on every request, so revoking a session takes effect immediately.
+func (s *AuthService) ValidateSession(token string) (uint, error) {
+	session, err := s.sessionRepo.GetSessionByToken(token)
+	if err != nil {
+		return 0, ErrSessionRevoked
+	}
+
+	if now := time.Now(); now.Sub(session.LastUsedAt) > sessionTouchInterval {
+		if err := s.sessionRepo.TouchSession(session.Id, now); err != nil {
+			slog.Warn("failed to update session activity", "session_id", session.Id, "error", err)
+		}
+	}
+	return session.Id, nil
+}
+
+// ListSessions returns the active sessions (devices) of a user, most recently used first
+func (s *AuthService) ListSessions(userID uint) ([]models.Session, error) {
+	return s.sessionRepo.GetActiveSessionsForUser(userID)
+}
+
+// RevokeSession closes one session of the user
+func (s *AuthService) RevokeSession(userID, sessionID uint, ipAddress, userAgent string) error {
+	session, err := s.sessionRepo.GetSessionByID(sessionID)
+	if err != nil || session.UserId != userID {
+		return errors.New("session introuvable")
+	}
+	if err := s.sessionRepo.DeleteByID(session.Id); err != nil {
+		return fmt.Errorf("erreur lors de la révocation de la session: %w", err)
+	}
+
+	s.logSessionAction(userID, "session_revoked", "session", session.Id,
+		fmt.Sprintf("Session révoquée (%s)", session.DeviceName),
+		map[string]interface{}{"session_id": session.Id, "device_name": session.DeviceName, "session_ip": session.IPAddress},
+		ipAddress, userAgent)
+	return nil
+}
+
+// RevokeOtherSessions closes every session of the user except the current one ("log out everywhere else")
+func (s *AuthService) RevokeOtherSessions(userID, currentSessionID uint, ipAddress, userAgent string) (int64, error) {
+	count, err := s.sessionRepo.DeleteOtherSessions(userID, currentSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("erreur lors de la révocation des sessions: %w", err)
+	}
+
+	s.logSessionAction(userID, "sessions_revoked", "session", currentSessionID,
+		fmt.Sprintf("Déconnexion des autres appareils (%d session(s))", count),
+		map[string]interface{}{"revoked_count": count, "kept_session_id": currentSessionID},
+		ipAddress, userAgent)
+	return count, nil
+}
+
+// RevokeAllSessions closes every session of a user on behalf of an administrator
+func (s *AuthService) RevokeAllSessions(adminID, userID uint, ipAddress, userAgent string) (int64, error) {
+	if _, err := s.userRepo.GetUserById(userID); err != nil {
+		return 0, errors.New("utilisateur introuvable")
+	}
+	count, err := s.sessionRepo.DeleteAllForUser(userID)
+	if err != nil {
+		return 0, fmt.Errorf("erreur lors de la révocation des sessions: %w", err)
+	}
+
+	s.logSessionAction(adminID, "sessions_revoked", "user", userID,
+		fmt.Sprintf("Sessions de l'utilisateur %d révoquées par un administrateur (%d session(s))", userID, count),
+		map[string]interface{}{"revoked_count": count, "target_user_id": userID},
+		ipAddress, userAgent)
+	return count, nil
+}
+
+// logSessionAction records a session security event in the action history
+func (s *AuthService) logSessionAction(userID uint, action, resourceType string, resourceID uint, description string, metadata map[string]interface{}, ipAddress, userAgent string) {
+	if s.actionHistoryService == nil {
+		return
+	}
+	if err := s.actionHistoryService.LogAction(userID, action, resourceType, resourceID, description, metadata, ipAddress, userAgent); err != nil {
+		slog.Error("failed to record session action", "action", action, "user_id", userID, "error", err)
+	}
+}
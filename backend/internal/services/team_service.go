@@ -0,0 +1,351 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+)
+
+// ErrTeamAccessDenied is returned when the team role of a user does not allow an operation
+var ErrTeamAccessDenied = errors.New("accès non autorisé à cette équipe")
+
+// TeamService manages teams, their members and the migration of per-user data into personal workspaces
+type TeamService struct {
+	teamRepo     *repositories.TeamRepository
+	userRepo     *repositories.UserRepository
+	databaseRepo *repositories.DatabaseRepository
+	authz        *AuthorizationService
+	historyRepo  *repositories.ActionHistoryRepository
+}
+
+// NewTeamService constructor
+func NewTeamService(teamRepo *repositories.TeamRepository, userRepo *repositories.UserRepository, databaseRepo *repositories.DatabaseRepository, authz *AuthorizationService, historyRepo *repositories.ActionHistoryRepository) *TeamService {
+	return &TeamService{
+		teamRepo:     teamRepo,
+		userRepo:     userRepo,
+		databaseRepo: databaseRepo,
+		authz:        authz,
+		historyRepo:  historyRepo,
+	}
+}
+
+// MigratePersonalWorkspaces moves the databases created before teams existed into the personal
+// workspace of their creator. It is idempotent and called at startup.
+func (s *TeamService) MigratePersonalWorkspaces() (int64, error) {
+	userIDs, err := s.teamRepo.GetUserIDsWithoutTeamDatabases()
+	if err != nil {
+		return 0, err
+	}
+	var migrated int64
+	for _, userID := range userIDs {
+		team, err := s.authz.PersonalTeam(userID)
+		if err != nil {
+			return migrated, fmt.Errorf("espace personnel de l'utilisateur %d: %w", userID, err)
+		}
+		count, err := s.teamRepo.AssignUserDatabasesToTeam(userID, team.Id)
+		if err != nil {
+			return migrated, err
+		}
+		migrated += count
+	}
+	return migrated, nil
+}
+
+// ListTeams returns the teams of a user, with the role of the user in each of them
+func (s *TeamService) ListTeams(userID uint) ([]models.Team, error) {
+	// Crée l'espace personnel des utilisateurs qui n'ont encore aucune base
+	if _, err := s.authz.PersonalTeam(userID); err != nil {
+		return nil, err
+	}
+	teams, err := s.teamRepo.GetTeamsForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := s.teamRepo.GetMemberships(userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[uint]string, len(memberships))
+	for _, membership := range memberships {
+		roles[membership.TeamId] = membership.Role
+	}
+	for i := range teams {
+		teams[i].MyRole = roles[teams[i].Id]
+	}
+	return teams, nil
+}
+
+// GetTeam returns a team and its members to one of its members
+func (s *TeamService) GetTeam(userID, teamID uint) (*models.Team, error) {
+	role, err := s.requireTeamRole(userID, teamID, models.TeamRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	team, err := s.teamRepo.GetByID(teamID)
+	if err != nil {
+		return nil, fmt.Errorf("équipe introuvable: %w", err)
+	}
+	team.MyRole = role
+	return team, nil
+}
+
+// CreateTeam creates a shared team owned by the user
+func (s *TeamService) CreateTeam(userID uint, name, ipAddress, userAgent string) (*models.Team, error) {
+	name, err := validTeamName(name)
+	if err != nil {
+		return nil, err
+	}
+	team := &models.Team{Name: name}
+	if err := s.teamRepo.CreateWithOwner(team, userID); err != nil {
+		return nil, fmt.Errorf("erreur lors de la création de l'équipe: %w", err)
+	}
+	team.MyRole = models.TeamRoleOwner
+
+	s.logTeamAction(userID, "created", team, fmt.Sprintf("Équipe %s créée", team.Name), nil, ipAddress, userAgent)
+	return team, nil
+}
+
+// RenameTeam renames a team (team admins and owners)
+func (s *TeamService) RenameTeam(userID, teamID uint, name, ipAddress, userAgent string) (*models.Team, error) {
+	if _, err := s.requireTeamRole(userID, teamID, models.TeamRoleAdmin); err != nil {
+		return nil, err
+	}
+	name, err := validTeamName(name)
+	if err != nil {
+		return nil, err
+	}
+	team, err := s.teamRepo.GetByID(teamID)
+	if err != nil {
+		return nil, fmt.Errorf("équipe introuvable: %w", err)
+	}
+	previous := team.Name
+	if err := s.teamRepo.UpdateName(teamID, name); err != nil {
+		return nil, err
+	}
+	team.Name = name
+
+	s.logTeamAction(userID, "renamed", team, fmt.Sprintf("Équipe %s renommée en %s", previous, name),
+		map[string]interface{}{"previous": previous}, ipAddress, userAgent)
+	return team, nil
+}
+
+// DeleteTeam deletes a shared team that no longer has databases (owners only)
+func (s *TeamService) DeleteTeam(userID, teamID uint, ipAddress, userAgent string) error {
+	if _, err := s.requireTeamRole(userID, teamID, models.TeamRoleOwner); err != nil {
+		return err
+	}
+	team, err := s.teamRepo.GetByID(teamID)
+	if err != nil {
+		return fmt.Errorf("équipe introuvable: %w", err)
+	}
+	if team.Personal {
+		return errors.New("un espace personnel ne peut pas être supprimé")
+	}
+	count, err := s.teamRepo.CountDatabases(teamID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("l'équipe possède encore %d base(s) de données : déplacez-les ou supprimez-les d'abord", count)
+	}
+	if err := s.teamRepo.Delete(teamID); err != nil {
+		return err
+	}
+
+	s.logTeamAction(userID, "deleted", team, fmt.Sprintf("Équipe %s supprimée", team.Name), nil, ipAddress, userAgent)
+	return nil
+}
+
+// AddMember adds an existing user to a shared team
+func (s *TeamService) AddMember(userID, teamID uint, email, role, ipAddress, userAgent string) (*models.TeamMember, error) {
+	actorRole, err := s.requireTeamRole(userID, teamID, models.TeamRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantableRole(actorRole, role); err != nil {
+		return nil, err
+	}
+	team, err := s.teamRepo.GetByID(teamID)
+	if err != nil {
+		return nil, fmt.Errorf("équipe introuvable: %w", err)
+	}
+	if team.Personal {
+		return nil, errors.New("un espace personnel ne peut pas avoir d'autres membres")
+	}
+	user, err := s.userRepo.GetUserByEmail(strings.TrimSpace(strings.ToLower(email)))
+	if err != nil || !user.Active {
+		return nil, errors.New("utilisateur introuvable")
+	}
+	if _, err := s.teamRepo.GetMembership(teamID, user.Id); err == nil {
+		return nil, errors.New("cet utilisateur est déjà membre de l'équipe")
+	}
+
+	member := &models.TeamMember{TeamId: teamID, UserId: user.Id, Role: role}
+	if err := s.teamRepo.AddMember(member); err != nil {
+		return nil, fmt.Errorf("erreur lors de l'ajout du membre: %w", err)
+	}
+	member.User = *user
+
+	s.logTeamAction(userID, "member_added", team, fmt.Sprintf("%s ajouté à l'équipe %s (%s)", user.Email, team.Name, role),
+		map[string]interface{}{"member_id": user.Id, "role": role}, ipAddress, userAgent)
+	return member, nil
+}
+
+// UpdateMemberRole changes the role of a member; only owners may grant or take away the owner role
+func (s *TeamService) UpdateMemberRole(userID, teamID, memberID uint, role, ipAddress, userAgent string) error {
+	actorRole, err := s.requireTeamRole(userID, teamID, models.TeamRoleAdmin)
+	if err != nil {
+		return err
+	}
+	if err := checkGrantableRole(actorRole, role); err != nil {
+		return err
+	}
+	member, err := s.teamRepo.GetMembership(teamID, memberID)
+	if err != nil {
+		return errors.New("membre introuvable")
+	}
+	if member.Role == role {
+		return nil
+	}
+	if member.Role == models.TeamRoleOwner {
+		if actorRole != models.TeamRoleOwner {
+			return ErrTeamAccessDenied
+		}
+		if err := s.keepAnOwner(teamID); err != nil {
+			return err
+		}
+	}
+	if err := s.teamRepo.UpdateMemberRole(teamID, memberID, role); err != nil {
+		return err
+	}
+
+	team := &models.Team{Id: teamID}
+	s.logTeamAction(userID, "member_role_updated", team, fmt.Sprintf("Rôle du membre %d changé de %s à %s", memberID, member.Role, role),
+		map[string]interface{}{"member_id": memberID, "previous": member.Role, "role": role}, ipAddress, userAgent)
+	return nil
+}
+
+// RemoveMember removes a member from a team (team admins and owners, or the member leaving the team)
+func (s *TeamService) RemoveMember(userID, teamID, memberID uint, ipAddress, userAgent string) error {
+	minimum := models.TeamRoleAdmin
+	if memberID == userID {
+		minimum = models.TeamRoleViewer
+	}
+	actorRole, err := s.requireTeamRole(userID, teamID, minimum)
+	if err != nil {
+		return err
+	}
+	member, err := s.teamRepo.GetMembership(teamID, memberID)
+	if err != nil {
+		return errors.New("membre introuvable")
+	}
+	if member.Role == models.TeamRoleOwner {
+		if actorRole != models.TeamRoleOwner {
+			return ErrTeamAccessDenied
+		}
+		if err := s.keepAnOwner(teamID); err != nil {
+			return err
+		}
+	}
+	if err := s.teamRepo.RemoveMember(teamID, memberID); err != nil {
+		return err
+	}
+
+	team := &models.Team{Id: teamID}
+	s.logTeamAction(userID, "member_removed", team, fmt.Sprintf("Membre %d retiré de l'équipe", memberID),
+		map[string]interface{}{"member_id": memberID, "role": member.Role}, ipAddress, userAgent)
+	return nil
+}
+
+// MoveDatabase moves a database (with its backups, schedules and restores) to another team;
+// the user must manage the databases of both teams
+func (s *TeamService) MoveDatabase(userID, databaseID, teamID uint, ipAddress, userAgent string) error {
+	database, err := s.databaseRepo.GetByID(databaseID)
+	if err != nil {
+		return fmt.Errorf("base de données introuvable: %w", err)
+	}
+	if err := s.authz.AuthorizeDatabase(userID, database, TeamActionManage); err != nil {
+		return ErrTeamAccessDenied
+	}
+	if !s.authz.CanInTeam(userID, teamID, TeamActionManage) {
+		return ErrTeamAccessDenied
+	}
+	if err := s.teamRepo.MoveDatabase(databaseID, teamID); err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{"database_id": databaseID, "database_name": database.Name}
+	if database.TeamId != nil {
+		metadata["previous_team_id"] = *database.TeamId
+	}
+	s.logTeamAction(userID, "database_moved", &models.Team{Id: teamID},
+		fmt.Sprintf("Base de données %s déplacée vers l'équipe %d", database.Name, teamID), metadata, ipAddress, userAgent)
+	return nil
+}
+
+// requireTeamRole returns the role of the user in the team, or ErrTeamAccessDenied when it is below minimum
+func (s *TeamService) requireTeamRole(userID, teamID uint, minimum string) (string, error) {
+	role := s.authz.TeamRole(teamID, userID)
+	if role == "" || models.TeamRoleRank(role) < models.TeamRoleRank(minimum) {
+		return "", ErrTeamAccessDenied
+	}
+	return role, nil
+}
+
+// keepAnOwner refuses to demote or remove the last owner of a team
+func (s *TeamService) keepAnOwner(teamID uint) error {
+	count, err := s.teamRepo.CountOwners(teamID)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return errors.New("l'équipe doit garder au moins un propriétaire")
+	}
+	return nil
+}
+
+// checkGrantableRole validates a team role and that the actor may grant it
+func checkGrantableRole(actorRole, role string) error {
+	if models.TeamRoleRank(role) == 0 {
+		return fmt.Errorf("rôle d'équipe invalide: %q", role)
+	}
+	if role == models.TeamRoleOwner && actorRole != models.TeamRoleOwner {
+		return ErrTeamAccessDenied
+	}
+	return nil
+}
+
+func validTeamName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", errors.New("le nom de l'équipe est requis (100 caractères maximum)")
+	}
+	return name, nil
+}
+
+// logTeamAction records a team change in the action history
+func (s *TeamService) logTeamAction(userID uint, action string, team *models.Team, description string, metadata map[string]interface{}, ipAddress, userAgent string) {
+	if s.historyRepo == nil {
+		return
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	history := &models.ActionHistory{
+		UserId:       userID,
+		Action:       action,
+		ResourceType: "team",
+		ResourceId:   team.Id,
+		Description:  description,
+		IpAddress:    ipAddress,
+		UserAgent:    userAgent,
+		Metadata:     string(metadataJSON),
+	}
+	// Don't fail the whole operation if history logging fails
+	if err := s.historyRepo.Create(history); err != nil {
+		slog.Error("failed to record team action", "action", action, "team_id", team.Id, "error", err)
+	}
+}
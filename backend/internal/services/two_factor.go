@@ -0,0 +1,486 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// twoFactorChallengeTTL is the lifetime of the partial token returned by a password login
+	twoFactorChallengeTTL = 5 * time.Minute
+	// maxTwoFactorAttempts is the number of wrong codes accepted per partial token
+	maxTwoFactorAttempts = 5
+	// recoveryCodeCount is the number of one-time recovery codes generated at enrolment
+	recoveryCodeCount = 10
+	// totpIssuer is the account issuer displayed by authenticator apps
+	totpIssuer = "SafeBase"
+)
+
+var (
+	// ErrInvalidTwoFactorCode is returned for a wrong, expired or replayed code
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorChallengeInvalid is returned for an unknown, expired or already used partial token
+	ErrTwoFactorChallengeInvalid = errors.New("two-factor challenge invalid or expired, please log in again")
+	// ErrTwoFactorAlreadyEnabled is returned when enrolling a user who already uses 2FA
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned when managing 2FA of a user who does not use it
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorEnforced is returned when a user tries to disable 2FA required by their role
+	ErrTwoFactorEnforced = errors.New("two-factor authentication is required for your role")
+)
+
+var totpCodePattern = regexp.MustCompile(`^[0-9]{6}$`)
+
+// TwoFactorChallenge is returned as an error by LoginWithMetadata when the password is correct but a
+// second factor is needed. Token is a partial token only accepted by the /auth/2fa endpoints.
+type TwoFactorChallenge struct {
+	Token         string
+	ExpiresAt     time.Time
+	SetupRequired bool // the role requires 2FA and the user has not enrolled yet
+}
+
+func (c *TwoFactorChallenge) Error() string {
+	return "two-factor authentication required"
+}
+
+// TwoFactorSetup is what an authenticator app needs to enrol (the URI is encoded in a QR code)
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"otpauth_url"`
+}
+
+// TwoFactorStatus describes the 2FA state of a user
+type TwoFactorStatus struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// challengeAttempts counts wrong codes per partial token (by JWT ID) to prevent brute force
+type challengeAttempts struct {
+	mu      sync.Mutex
+	entries map[string]*challengeAttempt
+}
+
+type challengeAttempt struct {
+	count     int
+	expiresAt time.Time
+}
+
+// SetRecoveryCodeRepository sets the repository of 2FA recovery codes
+func (s *AuthService) SetRecoveryCodeRepository(recoveryCodeRepo *repositories.RecoveryCodeRepository) {
+	s.recoveryCodeRepo = recoveryCodeRepo
+}
+
+// twoFactorRequiredByRole reports whether the role of a user enforces 2FA
+func twoFactorRequiredByRole(user *models.User) bool {
+	return user.Role != nil && user.Role.RequireTwoFactor
+}
+
+// twoFactorChallenge returns the challenge a password login must pass, or nil when none is needed
+func (s *AuthService) twoFactorChallenge(user *models.User) (*TwoFactorChallenge, error) {
+	if !user.TwoFactorEnabled && !twoFactorRequiredByRole(user) {
+		return nil, nil
+	}
+	token, err := security.GeneratePurposeJWT(s.jwtSecret, user.Id, user.Email, security.TwoFactorPurpose, twoFactorChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{
+		Token:         token,
+		ExpiresAt:     time.Now().Add(twoFactorChallengeTTL),
+		SetupRequired: !user.TwoFactorEnabled,
+	}, nil
+}
+
+// challengeUser validates a partial token and returns its user and JWT ID
+func (s *AuthService) challengeUser(challengeToken string) (*models.User, string, error) {
+	claims, err := security.VerifyJWT(s.jwtSecret, challengeToken)
+	if err != nil || claims.Purpose != security.TwoFactorPurpose || claims.ID == "" {
+		return nil, "", ErrTwoFactorChallengeInvalid
+	}
+	if s.attempts.exhausted(claims.ID) {
+		return nil, "", ErrTwoFactorChallengeInvalid
+	}
+	user, err := s.userRepo.GetUserById(claims.UserID)
+	if err != nil || !user.Active {
+		return nil, "", ErrTwoFactorChallengeInvalid
+	}
+	return user, claims.ID, nil
+}
+
+// VerifyTwoFactor completes a login with a TOTP or recovery code and opens the session.
+// For a user enrolling during login (role enforcement) the code confirms the enrolment and the
+// recovery codes are returned: they are shown only once.
+func (s *AuthService) VerifyTwoFactor(challengeToken, code string, metadata SessionMetadata) (*TokenPair, []string, error) {
+	user, challengeID, err := s.challengeUser(challengeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkLoginAllowed(user, metadata); err != nil {
+		return nil, nil, err
+	}
+
+	var recoveryCodes []string
+	if user.TwoFactorEnabled {
+		usedRecovery, err := s.verifySecondFactor(user, code, true)
+		if err != nil {
+			s.failTwoFactor(user, challengeID, metadata)
+			return nil, nil, err
+		}
+		if usedRecovery {
+			remaining, _ := s.recoveryCodeRepo.CountRemaining(user.Id)
+			s.logSessionAction(user.Id, "recovery_code_used", "user", user.Id,
+				fmt.Sprintf("Connexion avec un code de secours (%d restant(s))", remaining),
+				map[string]interface{}{"recovery_codes_remaining": remaining},
+				metadata.IPAddress, metadata.UserAgent)
+		}
+	} else {
+		if user.TOTPSecret == "" {
+			return nil, nil, errors.New("two-factor setup not started")
+		}
+		step, err := s.verifyTOTP(user, code)
+		if err != nil {
+			s.failTwoFactor(user, challengeID, metadata)
+			return nil, nil, err
+		}
+		if recoveryCodes, err = s.enableTwoFactor(user, step, metadata.IPAddress, metadata.UserAgent); err != nil {
+			return nil, nil, err
+		}
+	}
+	s.attempts.consume(challengeID)
+
+	pair, err := s.startSession(user, metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.recordLoginSuccess(user, "two_factor", metadata)
+	return pair, recoveryCodes, nil
+}
+
+// BeginTwoFactorSetupWithChallenge starts the enrolment of a user whose role requires 2FA during login
+func (s *AuthService) BeginTwoFactorSetupWithChallenge(challengeToken string) (*TwoFactorSetup, error) {
+	user, _, err := s.challengeUser(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.BeginTwoFactorSetup(user.Id)
+}
+
+// BeginTwoFactorSetup generates a new TOTP secret. 2FA is only enabled once a code is confirmed.
+func (s *AuthService) BeginTwoFactorSetup(userID uint) (*TwoFactorSetup, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := security.EncryptDatabasePassword(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt TOTP secret: %w", err)
+	}
+	if err := s.userRepo.UpdateUserById(user.Id, map[string]interface{}{"totp_secret": encrypted, "totp_last_step": 0}); err != nil {
+		return nil, err
+	}
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: security.TOTPProvisioningURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables 2FA with a first code from the authenticator app and returns the recovery codes
+func (s *AuthService) ConfirmTwoFactor(userID uint, code, ipAddress, userAgent string) ([]string, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("two-factor setup not started")
+	}
+	step, err := s.verifyTOTP(user, code)
+	if err != nil {
+		return nil, err
+	}
+	return s.enableTwoFactor(user, step, ipAddress, userAgent)
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a current code
+func (s *AuthService) DisableTwoFactor(userID uint, password, code, ipAddress, userAgent string) error {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if twoFactorRequiredByRole(user) {
+		return ErrTwoFactorEnforced
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return errors.New("invalid password")
+	}
+	if _, err := s.verifySecondFactor(user, code, true); err != nil {
+		return err
+	}
+
+	if err := s.clearTwoFactor(user.Id); err != nil {
+		return err
+	}
+	s.logSessionAction(user.Id, "two_factor_disabled", "user", user.Id,
+		"Double authentification désactivée", nil, ipAddress, userAgent)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user (the previous ones stop working)
+func (s *AuthService) RegenerateRecoveryCodes(userID uint, code, ipAddress, userAgent string) ([]string, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if _, err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.newRecoveryCodes(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	s.logSessionAction(user.Id, "recovery_codes_regenerated", "user", user.Id,
+		"Codes de secours régénérés", map[string]interface{}{"count": len(codes)}, ipAddress, userAgent)
+	return codes, nil
+}
+
+// ResetTwoFactor removes the 2FA of a user on behalf of an administrator (lost device).
+// The user enrols again at next login when their role requires it.
+func (s *AuthService) ResetTwoFactor(adminID, userID uint, ipAddress, userAgent string) error {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return errors.New("utilisateur introuvable")
+	}
+	if err := s.clearTwoFactor(user.Id); err != nil {
+		return err
+	}
+	s.logSessionAction(adminID, "two_factor_reset", "user", user.Id,
+		fmt.Sprintf("Double authentification de l'utilisateur %s réinitialisée par un administrateur", user.Email),
+		map[string]interface{}{"target_user_id": user.Id, "was_enabled": user.TwoFactorEnabled},
+		ipAddress, userAgent)
+	return nil
+}
+
+// GetTwoFactorStatus returns the 2FA state of a user
+func (s *AuthService) GetTwoFactorStatus(userID uint) (*TwoFactorStatus, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	status := &TwoFactorStatus{Enabled: user.TwoFactorEnabled, Required: twoFactorRequiredByRole(user)}
+	if user.TwoFactorEnabled && s.recoveryCodeRepo != nil {
+		status.RecoveryCodesRemaining, _ = s.recoveryCodeRepo.CountRemaining(user.Id)
+	}
+	return status, nil
+}
+
+// enableTwoFactor marks 2FA as enabled and generates the first recovery codes
+func (s *AuthService) enableTwoFactor(user *models.User, step int64, ipAddress, userAgent string) ([]string, error) {
+	if err := s.userRepo.UpdateUserById(user.Id, map[string]interface{}{"two_factor_enabled": true, "totp_last_step": step}); err != nil {
+		return nil, err
+	}
+	codes, err := s.newRecoveryCodes(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	s.logSessionAction(user.Id, "two_factor_enabled", "user", user.Id,
+		"Double authentification activée", nil, ipAddress, userAgent)
+	return codes, nil
+}
+
+// clearTwoFactor removes the secret and the recovery codes of a user
+func (s *AuthService) clearTwoFactor(userID uint) error {
+	if err := s.userRepo.UpdateUserById(userID, map[string]interface{}{
+		"two_factor_enabled": false,
+		"totp_secret":        "",
+		"totp_last_step":     0,
+	}); err != nil {
+		return err
+	}
+	if s.recoveryCodeRepo != nil {
+		return s.recoveryCodeRepo.DeleteForUser(userID)
+	}
+	return nil
+}
+
+// newRecoveryCodes generates recovery codes and stores their hashes
+func (s *AuthService) newRecoveryCodes(userID uint) ([]string, error) {
+	if s.recoveryCodeRepo == nil {
+		return nil, errors.New("recovery codes not available")
+	}
+	codes, err := security.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = security.HashToken(security.NormalizeRecoveryCode(code))
+	}
+	if err := s.recoveryCodeRepo.ReplaceForUser(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifySecondFactor accepts a TOTP code or, when allowed, an unused recovery code
+func (s *AuthService) verifySecondFactor(user *models.User, code string, allowRecovery bool) (usedRecovery bool, err error) {
+	if totpCodePattern.MatchString(code) || !allowRecovery || s.recoveryCodeRepo == nil {
+		_, err := s.verifyTOTP(user, code)
+		return false, err
+	}
+	used, err := s.recoveryCodeRepo.UseCode(user.Id, security.HashToken(security.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	if !used {
+		return false, ErrInvalidTwoFactorCode
+	}
+	return true, nil
+}
+
+// verifyTOTP checks a TOTP code and records its time step so that it cannot be used twice
+func (s *AuthService) verifyTOTP(user *models.User, code string) (int64, error) {
+	secret, err := security.DecryptDatabasePassword(user.TOTPSecret)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	step, ok := security.ValidateTOTP(secret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return 0, ErrInvalidTwoFactorCode
+	}
+	if user.TwoFactorEnabled {
+		advanced, err := s.userRepo.AdvanceTOTPStep(user.Id, step)
+		if err != nil {
+			return 0, err
+		}
+		if !advanced {
+			return 0, ErrInvalidTwoFactorCode
+		}
+	}
+
+	// Secrets sealed with a retired master key move to the primary key on use
+	if keyring, err := security.DefaultKeyring(); err == nil && security.EncryptedValueKeyID(user.TOTPSecret) != keyring.PrimaryKeyID() {
+		if encrypted, err := keyring.EncryptValue(secret); err == nil {
+			if err := s.userRepo.UpdateUserById(user.Id, map[string]interface{}{"totp_secret": encrypted}); err != nil {
+				slog.Warn("failed to re-encrypt TOTP secret", "user_id", user.Id, "error", err)
+			}
+		}
+	}
+	return step, nil
+}
+
+// failTwoFactor counts a wrong code against the partial token and records it.
+// It also counts as a failed login so that a known password does not give unlimited codes.
+func (s *AuthService) failTwoFactor(user *models.User, challengeID string, metadata SessionMetadata) {
+	attempts := s.attempts.fail(challengeID, time.Now().Add(twoFactorChallengeTTL))
+	s.logSessionAction(user.Id, "two_factor_failed", "user", user.Id,
+		"Code de double authentification invalide",
+		map[string]interface{}{"attempt": attempts},
+		metadata.IPAddress, metadata.UserAgent)
+	s.recordLoginFailure(user, "invalid_second_factor", metadata)
+}
+
+// fail records a wrong code and returns the number of failures of the challenge
+func (a *challengeAttempts) fail(id string, expiresAt time.Time) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prune()
+	entry, ok := a.entries[id]
+	if !ok {
+		entry = &challengeAttempt{expiresAt: expiresAt}
+		a.entries[id] = entry
+	}
+	entry.count++
+	return entry.count
+}
+
+// consume makes a challenge unusable once the login is complete
+func (a *challengeAttempts) consume(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prune()
+	a.entries[id] = &challengeAttempt{count: maxTwoFactorAttempts, expiresAt: time.Now().Add(twoFactorChallengeTTL)}
+}
+
+// exhausted reports whether a challenge was used or failed too many times
+func (a *challengeAttempts) exhausted(id string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.entries[id]
+	return ok && entry.count >= maxTwoFactorAttempts
+}
+
+// prune forgets expired challenges (their partial token is rejected anyway)
+func (a *challengeAttempts) prune() {
+	if a.entries == nil {
+		a.entries = make(map[string]*challengeAttempt)
+	}
+	now := time.Now()
+	for id, entry := range a.entries {
+		if now.After(entry.expiresAt) {
+			delete(a.entries, id)
+		}
+	}
+}
+
+// SetRoleTwoFactorRequired makes 2FA mandatory (or optional) for the users of a role
+func (s *UserService) SetRoleTwoFactorRequired(adminID, roleID uint, required bool, ipAddress, userAgent string) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid role ID: %w", err)
+	}
+	if err := s.roleRepo.SetRequireTwoFactor(role.Id, required); err != nil {
+		return nil, err
+	}
+	role.RequireTwoFactor = required
+
+	description := fmt.Sprintf("Double authentification rendue facultative pour le rôle %s", role.Name)
+	if required {
+		description = fmt.Sprintf("Double authentification rendue obligatoire pour le rôle %s", role.Name)
+	}
+	history := &models.ActionHistory{
+		UserId:       adminID,
+		Action:       "updated",
+		ResourceType: "role",
+		ResourceId:   role.Id,
+		Description:  description,
+		IpAddress:    ipAddress,
+		UserAgent:    userAgent,
+		Metadata:     fmt.Sprintf(`{"require_two_factor":%t}`, required),
+	}
+	// Don't fail the whole operation if history logging fails
+	_ = s.historyRepo.Create(history)
+	return role, nil
+}
+
+// GetAllRoles returns every role with its 2FA policy
+func (s *UserService) GetAllRoles() ([]models.Role, error) {
+	return s.roleRepo.GetAll()
+}
@@ -0,0 +1,663 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/logger"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/tracing"
+)
+
+// What happens to the databases, schedules and backups of a deleted user
+const (
+	UserDeletionReassign = "reassign" // given to another user
+	UserDeletionPurge    = "purge"    // deleted, with the backup objects in cloud storage
+)
+
+// User deletion job statuses
+const (
+	UserDeletionPending   = "pending"
+	UserDeletionRunning   = "running"
+	UserDeletionCompleted = "completed"
+	UserDeletionFailed    = "failed"
+)
+
+// ErrLastTeamOwner is returned when erasing an account would leave a shared team without an owner
+var ErrLastTeamOwner = errors.New("vous êtes le dernier propriétaire d'une équipe : nommez un autre propriétaire ou supprimez l'équipe avant d'effacer votre compte")
+
+// maxUserDeletionErrors bounds the error list kept on a job
+const maxUserDeletionErrors = 100
+
+// UserDeletionProgress counts the records handled by one phase of a deletion
+type UserDeletionProgress struct {
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+	Failed    int `json:"failed"`
+}
+
+// UserDeletionJob describes the background deletion of a user account and of its resources
+type UserDeletionJob struct {
+	ID               string                         `json:"id"`
+	UserId           uint                           `json:"user_id"`
+	Email            string                         `json:"email"`
+	Mode             string                         `json:"mode"`
+	TargetUserId     uint                           `json:"target_user_id,omitempty"` // New owner in reassign mode
+	Status           string                         `json:"status"`
+	Phase            string                         `json:"phase,omitempty"` // handover, schedules, backups, databases, reassignment, account
+	Databases        UserDeletionProgress           `json:"databases"`
+	Schedules        UserDeletionProgress           `json:"schedules"`
+	Backups          UserDeletionProgress           `json:"backups"`
+	Reassigned       *repositories.UserReassignment `json:"reassigned,omitempty"`
+	HandedOver       *repositories.UserReassignment `json:"handed_over,omitempty"`       // Rows on team databases given to a team owner when purging
+	TeamsTransferred map[uint]uint                  `json:"teams_transferred,omitempty"` // Team ID -> new owner, for the teams the user was the only owner of
+	Errors           []string                       `json:"errors,omitempty"`
+	Erasure          bool                           `json:"erasure,omitempty"` // Requested by the user (see PrivacyService)
+	StartedBy        uint                           `json:"started_by"`
+	StartedAt        time.Time                      `json:"started_at"`
+	FinishedAt       *time.Time                     `json:"finished_at,omitempty"`
+}
+
+// UserDeletionService deletes user accounts in the background. Their databases, schedules and backups are either
+// reassigned to another user or purged, including the backup objects in cloud storage and the cron entries. A
+// failed job leaves the account deactivated and can be started again.
+type UserDeletionService struct {
+	deletionRepo         *repositories.UserDeletionRepository
+	userRepo             *repositories.UserRepository
+	teamRepo             *repositories.TeamRepository
+	backupService        *BackupService
+	scheduleService      *ScheduleService
+	authz                *AuthorizationService
+	actionHistoryService *ActionHistoryService
+
+	mu      sync.Mutex
+	jobs    map[string]*UserDeletionJob
+	running map[uint]string // user ID -> ID of the running job
+}
+
+// NewUserDeletionService constructor
+func NewUserDeletionService(deletionRepo *repositories.UserDeletionRepository, userRepo *repositories.UserRepository, teamRepo *repositories.TeamRepository, backupService *BackupService, scheduleService *ScheduleService) *UserDeletionService {
+	return &UserDeletionService{
+		deletionRepo:    deletionRepo,
+		userRepo:        userRepo,
+		teamRepo:        teamRepo,
+		backupService:   backupService,
+		scheduleService: scheduleService,
+		jobs:            make(map[string]*UserDeletionJob),
+		running:         make(map[uint]string),
+	}
+}
+
+// SetActionHistoryService sets the action history service for logging deletions
+func (s *UserDeletionService) SetActionHistoryService(actionHistoryService *ActionHistoryService) {
+	s.actionHistoryService = actionHistoryService
+}
+
+// SetAuthorizationService sets the authorization service (personal workspaces and team access of the new owner)
+func (s *UserDeletionService) SetAuthorizationService(authz *AuthorizationService) {
+	s.authz = authz
+}
+
+// StartDeletion deactivates the account, closes its sessions and API tokens, then deletes it in the background
+func (s *UserDeletionService) StartDeletion(adminID, userID uint, mode string, targetUserID uint, ipAddress, userAgent string) (*UserDeletionJob, error) {
+	if mode != UserDeletionReassign && mode != UserDeletionPurge {
+		return nil, fmt.Errorf("mode de suppression invalide: %q (reassign ou purge)", mode)
+	}
+	if adminID == userID {
+		return nil, errors.New("vous ne pouvez pas supprimer votre propre compte")
+	}
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, errors.New("utilisateur introuvable")
+	}
+	if mode == UserDeletionReassign {
+		if err := s.checkNewOwner(userID, targetUserID); err != nil {
+			return nil, err
+		}
+	} else {
+		targetUserID = 0
+	}
+	return s.start(adminID, user, mode, targetUserID, false, ipAddress, userAgent)
+}
+
+// StartErasure purges the personal workspace of a user who asked for the erasure of their account, then
+// anonymizes the IP addresses and user agents of their action history. The history itself is kept for the audit
+// trail. Team databases are never purged: the rows of the user on them are handed over to the team (see purge).
+func (s *UserDeletionService) StartErasure(userID uint) (*UserDeletionJob, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, errors.New("utilisateur introuvable")
+	}
+	if err := s.CheckErasure(userID); err != nil {
+		return nil, err
+	}
+	return s.start(userID, user, UserDeletionPurge, 0, true, "", "")
+}
+
+// CheckErasure refuses the erasure of an account that is the only owner of a shared team: nobody could manage the
+// team afterwards. The user must name another owner or delete the team first.
+func (s *UserDeletionService) CheckErasure(userID uint) error {
+	teams, err := s.soleOwnedTeams(userID)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la vérification des équipes: %v", err)
+	}
+	if len(teams) == 0 {
+		return nil
+	}
+	names := make([]string, len(teams))
+	for i, team := range teams {
+		names[i] = team.Name
+	}
+	return fmt.Errorf("%w (%s)", ErrLastTeamOwner, strings.Join(names, ", "))
+}
+
+// soleOwnedTeams returns the shared teams whose only owner is the user, with their members
+func (s *UserDeletionService) soleOwnedTeams(userID uint) ([]models.Team, error) {
+	memberships, err := s.teamRepo.GetMemberships(userID)
+	if err != nil {
+		return nil, err
+	}
+	var teams []models.Team
+	for _, membership := range memberships {
+		if membership.Role != models.TeamRoleOwner {
+			continue
+		}
+		team, err := s.teamRepo.GetByID(membership.TeamId)
+		if err != nil {
+			return nil, err
+		}
+		if team.Personal {
+			continue
+		}
+		owners, err := s.teamRepo.CountOwners(team.Id)
+		if err != nil {
+			return nil, err
+		}
+		if owners <= 1 {
+			teams = append(teams, *team)
+		}
+	}
+	return teams, nil
+}
+
+// transferTeamOwnership names a new owner for the shared teams whose only owner is the deleted user: the member
+// with the highest role, else the administrator deleting the account. An erasure cannot start in that case
+// (see CheckErasure), so the user never hands a team over to themselves.
+func (s *UserDeletionService) transferTeamOwnership(ctx context.Context, job *UserDeletionJob) error {
+	teams, err := s.soleOwnedTeams(job.UserId)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la récupération des équipes: %v", err)
+	}
+	for _, team := range teams {
+		// Members are ordered by ID: the oldest member wins between equal roles
+		var successor *models.TeamMember
+		for i := range team.Members {
+			member := &team.Members[i]
+			if member.UserId != job.UserId && (successor == nil || models.TeamRoleRank(member.Role) > models.TeamRoleRank(successor.Role)) {
+				successor = member
+			}
+		}
+
+		var newOwnerID uint
+		switch {
+		case successor != nil:
+			newOwnerID = successor.UserId
+			err = s.teamRepo.UpdateMemberRole(team.Id, newOwnerID, models.TeamRoleOwner)
+		case job.StartedBy != job.UserId:
+			newOwnerID = job.StartedBy
+			err = s.teamRepo.AddMember(&models.TeamMember{TeamId: team.Id, UserId: newOwnerID, Role: models.TeamRoleOwner})
+		default:
+			return fmt.Errorf("l'équipe %s n'aurait plus de propriétaire", team.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("erreur lors du transfert de l'équipe %s: %v", team.Name, err)
+		}
+		slog.InfoContext(ctx, "team ownership transferred", "user_id", job.UserId, "team_id", team.Id, "new_owner_id", newOwnerID)
+		s.update(job, func(j *UserDeletionJob) {
+			if j.TeamsTransferred == nil {
+				j.TeamsTransferred = map[uint]uint{}
+			}
+			j.TeamsTransferred[team.Id] = newOwnerID
+		})
+	}
+	return nil
+}
+
+// start registers a deletion job, revokes the access of the user and runs the job in the background
+func (s *UserDeletionService) start(startedBy uint, user *models.User, mode string, targetUserID uint, erasure bool, ipAddress, userAgent string) (*UserDeletionJob, error) {
+	userID := user.Id
+	s.mu.Lock()
+	if jobID, exists := s.running[userID]; exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("une suppression de ce compte est déjà en cours (%s)", jobID)
+	}
+	job := &UserDeletionJob{
+		ID:           logger.NewID(),
+		UserId:       userID,
+		Email:        user.Email,
+		Mode:         mode,
+		TargetUserId: targetUserID,
+		Status:       UserDeletionPending,
+		Erasure:      erasure,
+		StartedBy:    startedBy,
+		StartedAt:    time.Now(),
+	}
+	s.jobs[job.ID] = job
+	s.running[userID] = job.ID
+	snapshot := job.snapshot()
+	s.mu.Unlock()
+
+	// The account can no longer be used while its resources are being removed
+	if err := s.deletionRepo.RevokeAccess(userID); err != nil {
+		slog.Warn("failed to revoke access of user being deleted", "user_id", userID, "error", err)
+	}
+
+	metadata := map[string]interface{}{"job_id": job.ID, "email": user.Email, "mode": mode, "target_user_id": targetUserID}
+	s.logAction(startedBy, userID, "user_deletion_started", fmt.Sprintf("Suppression du compte %s démarrée (%s)", user.Email, mode), metadata, ipAddress, userAgent)
+
+	// The job outlives the request: it must not be cancelled when the response is sent
+	ctx := logger.WithJobID(context.Background(), job.ID)
+	go s.runJob(ctx, job, ipAddress, userAgent)
+
+	return snapshot, nil
+}
+
+// checkNewOwner verifies that the new owner can take over the databases of the deleted user
+func (s *UserDeletionService) checkNewOwner(userID, targetUserID uint) error {
+	if targetUserID == 0 || targetUserID == userID {
+		return errors.New("un nouveau propriétaire différent de l'utilisateur supprimé est requis")
+	}
+	target, err := s.userRepo.GetUserById(targetUserID)
+	if err != nil || !target.Active {
+		return errors.New("nouveau propriétaire introuvable ou désactivé")
+	}
+	if !s.authz.Enabled() {
+		return nil
+	}
+
+	// Databases of the personal workspace follow the new owner, those of a team stay in the team
+	var personalTeamID uint
+	if team, err := s.teamRepo.GetPersonalTeam(userID); err == nil {
+		personalTeamID = team.Id
+	}
+	databases, err := s.deletionRepo.GetOwnedDatabases(userID)
+	if err != nil {
+		return err
+	}
+	for i := range databases {
+		database := &databases[i]
+		if database.DeletedAt.Valid || database.TeamId == nil || *database.TeamId == personalTeamID {
+			continue
+		}
+		if !s.authz.CanAccessDatabase(targetUserID, database, TeamActionManage) {
+			return fmt.Errorf("le nouveau propriétaire doit pouvoir gérer la base de données '%s' dans son équipe", database.Name)
+		}
+	}
+	return nil
+}
+
+// GetJob returns a copy of a deletion job
+func (s *UserDeletionService) GetJob(id string) (*UserDeletionJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("suppression introuvable")
+	}
+	return job.snapshot(), nil
+}
+
+// runJob removes or reassigns the resources, then the account when nothing failed
+func (s *UserDeletionService) runJob(ctx context.Context, job *UserDeletionJob, ipAddress, userAgent string) {
+	ctx, span := tracing.Start(ctx, "users.delete")
+	var runErr error
+	defer func() { tracing.End(span, runErr) }()
+
+	s.update(job, func(j *UserDeletionJob) { j.Status = UserDeletionRunning })
+	slog.InfoContext(ctx, "user deletion started", "user_id", job.UserId, "mode", job.Mode)
+
+	var personalTeamID uint
+	if team, err := s.teamRepo.GetPersonalTeam(job.UserId); err == nil {
+		personalTeamID = team.Id
+	}
+
+	// The teams must keep an owner, who also takes over the rows of the user on their databases
+	runErr = s.transferTeamOwnership(ctx, job)
+	if runErr == nil && job.Mode == UserDeletionReassign {
+		runErr = s.reassign(ctx, job, personalTeamID)
+	} else if runErr == nil {
+		runErr = s.purge(ctx, job, personalTeamID)
+	}
+
+	s.mu.Lock()
+	failed := runErr != nil || job.Schedules.Failed > 0 || job.Backups.Failed > 0 || job.Databases.Failed > 0
+	s.mu.Unlock()
+	if !failed {
+		s.update(job, func(j *UserDeletionJob) { j.Phase = "account" })
+		runErr = s.deletionRepo.DeleteAccount(job.UserId, personalTeamID)
+	}
+
+	finishedAt := time.Now()
+	s.mu.Lock()
+	job.FinishedAt = &finishedAt
+	job.Phase = ""
+	if runErr != nil {
+		job.addError(runErr.Error())
+	}
+	if failed || runErr != nil {
+		job.Status = UserDeletionFailed
+	} else {
+		job.Status = UserDeletionCompleted
+	}
+	delete(s.running, job.UserId)
+	result := job.snapshot()
+	s.mu.Unlock()
+
+	slog.InfoContext(ctx, "user deletion finished", "user_id", result.UserId, "status", result.Status,
+		"databases", result.Databases.Processed, "schedules", result.Schedules.Processed, "backups", result.Backups.Processed,
+		"backups_failed", result.Backups.Failed)
+
+	metadata := map[string]interface{}{
+		"job_id":         result.ID,
+		"email":          result.Email,
+		"mode":           result.Mode,
+		"target_user_id": result.TargetUserId,
+		"status":         result.Status,
+		"databases":      result.Databases,
+		"schedules":      result.Schedules,
+		"backups":        result.Backups,
+	}
+	if result.Status == UserDeletionCompleted && result.Erasure {
+		s.logAction(result.StartedBy, result.UserId, "account_erased", fmt.Sprintf("Compte %s effacé à la demande de l'utilisateur", result.Email), metadata, ipAddress, userAgent)
+		anonymized, err := s.deletionRepo.AnonymizeActionHistory(result.UserId)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to anonymize action history of erased user", "user_id", result.UserId, "error", err)
+		} else {
+			slog.InfoContext(ctx, "action history anonymized", "user_id", result.UserId, "rows", anonymized)
+		}
+	} else if result.Status == UserDeletionCompleted {
+		s.logAction(result.StartedBy, result.UserId, "user_deleted", fmt.Sprintf("Compte %s supprimé", result.Email), metadata, ipAddress, userAgent)
+	} else {
+		metadata["errors"] = result.Errors
+		s.logAction(result.StartedBy, result.UserId, "user_deletion_failed", fmt.Sprintf("Échec de la suppression du compte %s, le compte reste désactivé", result.Email), metadata, ipAddress, userAgent)
+	}
+}
+
+// reassign gives the resources of the user to the new owner and reloads the cron entries of their schedules
+func (s *UserDeletionService) reassign(ctx context.Context, job *UserDeletionJob, personalTeamID uint) error {
+	s.update(job, func(j *UserDeletionJob) { j.Phase = "reassignment" })
+	schedules, err := s.deletionRepo.GetSchedulesOfUser(job.UserId)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la récupération des planifications: %v", err)
+	}
+
+	var targetTeamID uint
+	if personalTeamID != 0 && s.authz.Enabled() {
+		team, err := s.authz.PersonalTeam(job.TargetUserId)
+		if err != nil {
+			return fmt.Errorf("espace personnel du nouveau propriétaire: %v", err)
+		}
+		targetTeamID = team.Id
+	}
+	counts, err := s.deletionRepo.ReassignResources(job.UserId, job.TargetUserId, personalTeamID, targetTeamID)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la réattribution: %v", err)
+	}
+
+	// The cron jobs captured the previous owner of the database
+	failedSchedules := 0
+	if s.scheduleService != nil {
+		for _, schedule := range schedules {
+			if err := s.scheduleService.Reschedule(schedule.Id); err != nil {
+				slog.WarnContext(ctx, "failed to reschedule reassigned schedule", "schedule_id", schedule.Id, "error", err)
+				failedSchedules++
+				s.update(job, func(j *UserDeletionJob) {
+					j.addError(fmt.Sprintf("planification %d: %v", schedule.Id, err))
+				})
+			}
+		}
+	}
+
+	s.update(job, func(j *UserDeletionJob) {
+		j.Reassigned = &counts
+		j.Databases = UserDeletionProgress{Total: int(counts.Databases), Processed: int(counts.Databases)}
+		j.Schedules = UserDeletionProgress{Total: int(counts.Schedules), Processed: int(counts.Schedules), Failed: failedSchedules}
+		j.Backups = UserDeletionProgress{Total: int(counts.Backups), Processed: int(counts.Backups)}
+	})
+	return nil
+}
+
+// purge hands the rows of the user on team databases over to the team, then deletes the schedules, the backups
+// with their objects and the databases of their personal workspace. Team databases are never purged: they belong
+// to the team, whoever created them.
+func (s *UserDeletionService) purge(ctx context.Context, job *UserDeletionJob, personalTeamID uint) error {
+	if err := s.handOver(ctx, job, personalTeamID); err != nil {
+		return err
+	}
+
+	schedules, err := s.deletionRepo.GetSchedulesToPurge(job.UserId, personalTeamID)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la récupération des planifications: %v", err)
+	}
+	s.update(job, func(j *UserDeletionJob) {
+		j.Phase = "schedules"
+		j.Schedules.Total = len(schedules)
+	})
+	for _, schedule := range schedules {
+		if s.scheduleService != nil {
+			s.scheduleService.Unschedule(schedule.Id)
+		}
+		err := s.deletionRepo.PurgeSchedule(schedule.Id)
+		s.update(job, func(j *UserDeletionJob) {
+			j.Schedules.Processed++
+			if err != nil {
+				j.Schedules.Failed++
+				j.addError(fmt.Sprintf("planification %d: %v", schedule.Id, err))
+			}
+		})
+	}
+
+	backups, err := s.deletionRepo.GetBackupsToPurge(job.UserId, personalTeamID)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la récupération des sauvegardes: %v", err)
+	}
+	s.update(job, func(j *UserDeletionJob) {
+		j.Phase = "backups"
+		j.Backups.Total = len(backups)
+	})
+	for i := range backups {
+		err := s.purgeBackup(ctx, &backups[i])
+		s.update(job, func(j *UserDeletionJob) {
+			j.Backups.Processed++
+			if err != nil {
+				j.Backups.Failed++
+				j.addError(fmt.Sprintf("sauvegarde %d: %v", backups[i].Id, err))
+			}
+		})
+	}
+
+	databases, err := s.deletionRepo.GetPersonalDatabases(job.UserId, personalTeamID)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la récupération des bases de données: %v", err)
+	}
+	s.update(job, func(j *UserDeletionJob) {
+		j.Phase = "databases"
+		j.Databases.Total = len(databases)
+	})
+	for _, database := range databases {
+		err := s.deletionRepo.PurgeDatabase(database.Id)
+		s.update(job, func(j *UserDeletionJob) {
+			j.Databases.Processed++
+			if err != nil {
+				j.Databases.Failed++
+				j.addError(fmt.Sprintf("base de données %d: %v", database.Id, err))
+			}
+		})
+	}
+	return nil
+}
+
+// handOver gives the databases the user created in their teams, and their schedules, backups and restores on the
+// databases of these teams, to an owner of each team, else an admin, else the administrator deleting the account
+func (s *UserDeletionService) handOver(ctx context.Context, job *UserDeletionJob, personalTeamID uint) error {
+	s.update(job, func(j *UserDeletionJob) { j.Phase = "handover" })
+	teamIDs, err := s.deletionRepo.GetTeamsWithUserResources(job.UserId, personalTeamID)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la récupération des équipes: %v", err)
+	}
+
+	var total repositories.UserReassignment
+	for _, teamID := range teamIDs {
+		newOwnerID, err := s.teamSuccessor(teamID, job.UserId, job.StartedBy)
+		if err != nil {
+			return err
+		}
+		schedules, err := s.deletionRepo.GetUserSchedulesOnTeam(job.UserId, teamID)
+		if err != nil {
+			return fmt.Errorf("erreur lors de la récupération des planifications: %v", err)
+		}
+		counts, err := s.deletionRepo.HandOverTeamResources(job.UserId, teamID, newOwnerID)
+		if err != nil {
+			return fmt.Errorf("erreur lors de la transmission des ressources de l'équipe %d: %v", teamID, err)
+		}
+		slog.InfoContext(ctx, "team resources handed over", "user_id", job.UserId, "team_id", teamID, "new_owner_id", newOwnerID,
+			"databases", counts.Databases, "schedules", counts.Schedules, "backups", counts.Backups, "restores", counts.Restores)
+		total.Databases += counts.Databases
+		total.Schedules += counts.Schedules
+		total.Backups += counts.Backups
+		total.Restores += counts.Restores
+
+		// The cron jobs captured the previous owner of the schedules
+		if s.scheduleService != nil {
+			for _, schedule := range schedules {
+				if err := s.scheduleService.Reschedule(schedule.Id); err != nil {
+					slog.WarnContext(ctx, "failed to reschedule handed over schedule", "schedule_id", schedule.Id, "error", err)
+					s.update(job, func(j *UserDeletionJob) {
+						j.addError(fmt.Sprintf("planification %d: %v", schedule.Id, err))
+					})
+				}
+			}
+		}
+	}
+	s.update(job, func(j *UserDeletionJob) { j.HandedOver = &total })
+	return nil
+}
+
+// teamSuccessor returns the member taking over the resources of a deleted user in a team: the first owner, else
+// the first admin, else the administrator who started the deletion
+func (s *UserDeletionService) teamSuccessor(teamID, userID, startedBy uint) (uint, error) {
+	members, err := s.teamRepo.GetMembersWithRoles(teamID, []string{models.TeamRoleOwner, models.TeamRoleAdmin})
+	if err != nil {
+		return 0, fmt.Errorf("erreur lors de la récupération des membres de l'équipe %d: %v", teamID, err)
+	}
+	var successor *models.TeamMember
+	for i := range members {
+		member := &members[i]
+		if member.UserId == userID {
+			continue
+		}
+		if successor == nil || models.TeamRoleRank(member.Role) > models.TeamRoleRank(successor.Role) ||
+			(member.Role == successor.Role && member.Id < successor.Id) {
+			successor = member
+		}
+	}
+	if successor != nil {
+		return successor.UserId, nil
+	}
+	if startedBy != 0 && startedBy != userID {
+		return startedBy, nil
+	}
+	return 0, fmt.Errorf("aucun propriétaire ni administrateur de l'équipe %d ne peut reprendre les ressources du compte", teamID)
+}
+
+// purgeBackup stops a running backup, deletes its object from cloud storage then its record.
+// The record is kept when the object cannot be deleted, so a new deletion job retries it.
+func (s *UserDeletionService) purgeBackup(ctx context.Context, backup *models.Backup) error {
+	if s.backupService != nil && (backup.Status == "pending" || backup.Status == "running") {
+		if _, err := s.backupService.CancelBackup(backup.Id); err != nil && !errors.Is(err, ErrJobNotCancellable) {
+			slog.WarnContext(ctx, "failed to cancel backup of deleted user", "backup_id", backup.Id, "error", err)
+		}
+	}
+
+	if backup.Filepath != "" {
+		var storage CloudStorageService
+		if s.backupService != nil {
+			storage = s.backupService.cloudStorage
+		}
+		if storage == nil {
+			return fmt.Errorf("service de stockage cloud non disponible")
+		}
+		exists, err := storage.FileExists(backup.Filepath)
+		if err != nil {
+			return fmt.Errorf("vérification du fichier cloud: %v", err)
+		}
+		if exists {
+			if err := deleteFileTraced(ctx, storage, backup.Filepath); err != nil {
+				return fmt.Errorf("suppression du fichier cloud: %v", err)
+			}
+		}
+	}
+	return s.deletionRepo.PurgeBackup(backup.Id)
+}
+
+// logAction records a deletion step as an admin action on the deleted user, or as their own action for an erasure
+func (s *UserDeletionService) logAction(adminID, userID uint, action, description string, metadata map[string]interface{}, ipAddress, userAgent string) {
+	if s.actionHistoryService == nil {
+		return
+	}
+	var err error
+	if adminID == userID {
+		err = s.actionHistoryService.LogAction(userID, action, "user", userID, description, metadata, ipAddress, userAgent)
+	} else {
+		err = s.actionHistoryService.LogAdminAction(adminID, userID, action, "user", userID, description, metadata, ipAddress, userAgent)
+	}
+	if err != nil {
+		slog.Warn("failed to log user deletion", "user_id", userID, "action", action, "error", err)
+	}
+}
+
+// update applies a change to a job under the service lock
+func (s *UserDeletionService) update(job *UserDeletionJob, change func(*UserDeletionJob)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(job)
+}
+
+// addError records an error, keeping the list bounded (caller holds the lock)
+func (j *UserDeletionJob) addError(message string) {
+	if len(j.Errors) < maxUserDeletionErrors {
+		j.Errors = append(j.Errors, message)
+	}
+}
+
+// snapshot returns a copy safe to expose while the job runs (caller holds the lock)
+func (j *UserDeletionJob) snapshot() *UserDeletionJob {
+	copied := *j
+	copied.Errors = append([]string(nil), j.Errors...)
+	if j.Reassigned != nil {
+		reassigned := *j.Reassigned
+		copied.Reassigned = &reassigned
+	}
+	if j.HandedOver != nil {
+		handedOver := *j.HandedOver
+		copied.HandedOver = &handedOver
+	}
+	if j.TeamsTransferred != nil {
+		copied.TeamsTransferred = make(map[uint]uint, len(j.TeamsTransferred))
+		for teamID, ownerID := range j.TeamsTransferred {
+			copied.TeamsTransferred[teamID] = ownerID
+		}
+	}
+	if j.FinishedAt != nil {
+		finishedAt := *j.FinishedAt
+		copied.FinishedAt = &finishedAt
+	}
+	return &copied
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/pkg/mailer"
+)
+
+// DefaultInvitationTTL is the lifetime of an invitation link when SetInvitationTTL is not called
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+// SetInvitationTTL sets how long the set-password link of an invitation stays valid
+func (s *AuthService) SetInvitationTTL(ttl time.Duration) {
+	s.invitationTTL = ttl
+}
+
+// InviteUser emails an account created by an administrator a link to choose its password. The link is a
+// reset token with a longer lifetime: it goes through the reset-password page and replaces any previous link.
+func (s *AuthService) InviteUser(adminID, userID uint, ipAddress, userAgent string) error {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return errors.New("utilisateur introuvable")
+	}
+	if !user.Active {
+		return errors.New("le compte est désactivé")
+	}
+	if !s.localLoginAllowed(user) {
+		return ErrLocalLoginDisabled
+	}
+
+	ttl := s.invitationTTL
+	if ttl <= 0 {
+		ttl = DefaultInvitationTTL
+	}
+	token, expiresAt, err := s.issueResetToken(user.Id, ttl, ipAddress, userAgent)
+	if err != nil {
+		return err
+	}
+
+	s.logSessionAction(adminID, "user_invited", "user", user.Id,
+		fmt.Sprintf("Invitation envoyée à %s", user.Email),
+		map[string]interface{}{"email": user.Email, "expires_at": expiresAt},
+		ipAddress, userAgent)
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Invitation à rejoindre SafeBase",
+		Body: fmt.Sprintf("Bonjour %s,\n\n"+
+			"Un administrateur vous a créé un compte SafeBase (%s).\n"+
+			"Pour l'activer, choisissez votre mot de passe en ouvrant le lien suivant (valable %s, utilisable une seule fois) :\n\n"+
+			"%s\n\n"+
+			"Passé ce délai, demandez une nouvelle invitation à votre administrateur.\n",
+			user.Firstname, user.Email, ttl, s.resetLink(token)),
+	}
+	go s.sendMail(msg)
+	return nil
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/models"
 	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -217,3 +219,70 @@ func (s *UserService) ChangeUserPassword(userID uint, currentPassword, newPasswo
 
 	return nil
 }
+
+// CreateUser creates an account for an administrator. Without a password, the account gets an unusable random
+// password and the user chooses their own through an invitation (see AuthService.InviteUser).
+func (s *UserService) CreateUser(adminID uint, firstname, lastname, email, password string, roleID *uint, ipAddress, userAgent string) (*models.User, error) {
+	firstname, lastname, email = strings.TrimSpace(firstname), strings.TrimSpace(lastname), strings.TrimSpace(email)
+	if firstname == "" || lastname == "" || email == "" {
+		return nil, errors.New("le prénom, le nom et l'email sont requis")
+	}
+	if existingUser, err := s.userRepo.GetUserByEmail(email); err == nil && existingUser != nil {
+		return nil, errors.New("cet email est déjà utilisé")
+	}
+	if roleID != nil {
+		if _, err := s.roleRepo.GetByID(*roleID); err != nil {
+			return nil, fmt.Errorf("invalid role ID: %w", err)
+		}
+	}
+
+	invited := password == ""
+	if invited {
+		random, err := security.RandomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		password = random
+	} else if err := ValidatePassword(password); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors du hachage du mot de passe: %w", err)
+	}
+
+	user := &models.User{
+		Firstname: firstname,
+		Lastname:  lastname,
+		Email:     email,
+		Password:  string(hashedPassword),
+		Active:    true,
+		RoleID:    roleID,
+	}
+	if err := s.userRepo.CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{
+		"email":   user.Email,
+		"role_id": user.RoleID,
+		"invited": invited,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+
+	history := &models.ActionHistory{
+		UserId:       adminID,
+		Action:       "user_created",
+		ResourceType: "user",
+		ResourceId:   user.Id,
+		Description:  fmt.Sprintf("Compte créé par un administrateur: %s %s (%s)", firstname, lastname, email),
+		IpAddress:    ipAddress,
+		UserAgent:    userAgent,
+		Metadata:     string(metadataJSON),
+	}
+
+	// Don't fail the whole operation if history logging fails
+	_ = s.historyRepo.Create(history)
+
+	return s.userRepo.GetUserById(user.Id)
+}
@@ -0,0 +1,217 @@
+// Package auditlog forwards the SafeBase action history to external collectors (syslog servers, SIEM, files).
+// Events are queued per sink and delivered in the background with retries, so a slow or unreachable sink never
+// delays the request that recorded the action.
+package auditlog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event is an action history row as forwarded to the sinks
+type Event struct {
+	ID           uint                   `json:"id"`
+	Time         time.Time              `json:"time"`
+	UserID       uint                   `json:"user_id"`
+	Action       string                 `json:"action"`
+	ResourceType string                 `json:"resource_type"`
+	ResourceID   uint                   `json:"resource_id"`
+	Description  string                 `json:"description"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	IPAddress    string                 `json:"ip_address,omitempty"`
+	UserAgent    string                 `json:"user_agent,omitempty"`
+	AdminAction  bool                   `json:"admin_action"`
+	Hash         string                 `json:"hash,omitempty"` // Hash of the row in the action history chain
+}
+
+// Syslog severities used for the events (RFC 5424 section 6.2.1)
+const (
+	SeverityWarning       = 4
+	SeverityNotice        = 5
+	SeverityInformational = 6
+)
+
+// warningActions are the action keywords reported with the warning severity
+var warningActions = []string{"failed", "denied", "locked", "rejected", "deleted", "erased", "revoked"}
+
+// Severity returns the syslog severity of an event: warning for failures and destructive actions, notice for
+// the actions of an administrator on the resources of another user, informational otherwise
+func (e Event) Severity() int {
+	for _, keyword := range warningActions {
+		if strings.Contains(e.Action, keyword) {
+			return SeverityWarning
+		}
+	}
+	if e.AdminAction {
+		return SeverityNotice
+	}
+	return SeverityInformational
+}
+
+// Sink delivers events to a collector. Write is only called from the delivery goroutine of the sink.
+type Sink interface {
+	Name() string
+	Write(e Event) error
+	Close() error
+}
+
+// Options tunes the delivery of the events
+type Options struct {
+	BufferSize int           // Events queued per sink; further events are dropped while the queue is full
+	MaxRetries int           // Retries of an event after a failed write before it is dropped
+	RetryDelay time.Duration // Delay before the first retry, doubled for each following one
+}
+
+// maxRetryDelay caps the delay between two retries
+const maxRetryDelay = 30 * time.Second
+
+// SinkStats counts the deliveries of a sink
+type SinkStats struct {
+	Name    string `json:"name"`
+	Sent    int64  `json:"sent"`
+	Failed  int64  `json:"failed"`  // Dropped after every retry failed
+	Dropped int64  `json:"dropped"` // Dropped because the queue was full
+}
+
+// queue is the delivery goroutine state of a sink
+type queue struct {
+	sink    Sink
+	events  chan Event
+	sent    atomic.Int64
+	failed  atomic.Int64
+	dropped atomic.Int64
+}
+
+// Forwarder fans the events out to its sinks
+type Forwarder struct {
+	options Options
+	queues  []*queue
+	stop    chan struct{} // Closed to abandon pending retries on shutdown
+	wg      sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewForwarder starts a delivery goroutine per sink
+func NewForwarder(options Options, sinks ...Sink) *Forwarder {
+	if options.BufferSize <= 0 {
+		options.BufferSize = 1000
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = time.Second
+	}
+
+	f := &Forwarder{options: options, stop: make(chan struct{})}
+	for _, sink := range sinks {
+		q := &queue{sink: sink, events: make(chan Event, options.BufferSize)}
+		f.queues = append(f.queues, q)
+		f.wg.Add(1)
+		go f.deliver(q)
+	}
+	return f
+}
+
+// Publish queues an event for every sink without blocking
+func (f *Forwarder) Publish(e Event) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.closed {
+		return
+	}
+	for _, q := range f.queues {
+		select {
+		case q.events <- e:
+		default:
+			if q.dropped.Add(1) == 1 {
+				slog.Warn("audit sink queue full, events are dropped", "sink", q.sink.Name())
+			}
+		}
+	}
+}
+
+// deliver writes the events of a sink, retrying each one with an exponential backoff
+func (f *Forwarder) deliver(q *queue) {
+	defer f.wg.Done()
+	for e := range q.events {
+		delay := f.options.RetryDelay
+		for attempt := 0; ; attempt++ {
+			err := q.sink.Write(e)
+			if err == nil {
+				q.sent.Add(1)
+				break
+			}
+			if attempt >= f.options.MaxRetries || f.stopping() {
+				q.failed.Add(1)
+				slog.Warn("audit event not forwarded", "sink", q.sink.Name(), "history_id", e.ID, "attempts", attempt+1, "error", err)
+				break
+			}
+			select {
+			case <-time.After(delay):
+			case <-f.stop:
+			}
+			delay = min(delay*2, maxRetryDelay)
+		}
+	}
+}
+
+// stopping reports whether pending retries are abandoned
+func (f *Forwarder) stopping() bool {
+	select {
+	case <-f.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stats returns the delivery counters of every sink
+func (f *Forwarder) Stats() []SinkStats {
+	stats := make([]SinkStats, len(f.queues))
+	for i, q := range f.queues {
+		stats[i] = SinkStats{Name: q.sink.Name(), Sent: q.sent.Load(), Failed: q.failed.Load(), Dropped: q.dropped.Load()}
+	}
+	return stats
+}
+
+// Close delivers the queued events, until the context is done, then closes the sinks
+func (f *Forwarder) Close(ctx context.Context) error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	for _, q := range f.queues {
+		close(q.events)
+	}
+	f.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// Write the remaining events once, without retries
+		close(f.stop)
+		<-done
+	}
+
+	var firstErr error
+	for _, q := range f.queues {
+		if err := q.sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,321 @@
+package auditlog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog transports
+const (
+	NetworkUDP = "udp"
+	NetworkTCP = "tcp"
+	NetworkTLS = "tls"
+)
+
+// Syslog message formats
+const (
+	FormatRFC5424 = "rfc5424" // Event in the structured data, description as message
+	FormatCEF     = "cef"     // ArcSight Common Event Format as message
+)
+
+// sdID identifies the structured data of the events. 32473 is the private enterprise number reserved for
+// documentation (RFC 5612).
+const sdID = "safebase@32473"
+
+// syslogWriteTimeout bounds the connection and each write to the syslog server
+const syslogWriteTimeout = 5 * time.Second
+
+// SyslogConfig holds the settings of a syslog sink
+type SyslogConfig struct {
+	Address   string      // host:port of the collector
+	Network   string      // udp (default), tcp or tls
+	Format    string      // rfc5424 (default) or cef
+	Facility  int         // Syslog facility (default 13, log audit)
+	AppName   string      // APP-NAME of the messages (default safebase)
+	Version   string      // Product version reported in CEF messages
+	CAFile    string      // PEM certificates trusted for tls, in addition to the system ones
+	TLSConfig *tls.Config // Overrides CAFile when set
+}
+
+// SyslogSink sends the events to a syslog server as RFC 5424 messages. Messages are sent one per datagram over
+// UDP and with octet-counting framing over TCP and TLS (RFC 6587, RFC 5425). A broken connection is reopened on
+// the next write.
+type SyslogSink struct {
+	config    SyslogConfig
+	tlsConfig *tls.Config
+	hostname  string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink constructor. The connection is opened on the first event.
+func NewSyslogSink(config SyslogConfig) (*SyslogSink, error) {
+	if config.Address == "" {
+		return nil, errors.New("syslog address is required")
+	}
+	if _, _, err := net.SplitHostPort(config.Address); err != nil {
+		return nil, fmt.Errorf("invalid syslog address: %w", err)
+	}
+	if config.Network == "" {
+		config.Network = NetworkUDP
+	}
+	if config.Network != NetworkUDP && config.Network != NetworkTCP && config.Network != NetworkTLS {
+		return nil, fmt.Errorf("unsupported syslog network %q (udp, tcp or tls)", config.Network)
+	}
+	if config.Format == "" {
+		config.Format = FormatRFC5424
+	}
+	if config.Format != FormatRFC5424 && config.Format != FormatCEF {
+		return nil, fmt.Errorf("unsupported syslog format %q (rfc5424 or cef)", config.Format)
+	}
+	if config.Facility <= 0 || config.Facility > 23 {
+		config.Facility = 13
+	}
+	if config.AppName == "" {
+		config.AppName = "safebase"
+	}
+
+	sink := &SyslogSink{config: config, hostname: "-"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		sink.hostname = printASCII(hostname, 255)
+	}
+
+	if config.Network == NetworkTLS {
+		sink.tlsConfig = config.TLSConfig
+		if sink.tlsConfig == nil {
+			host, _, _ := net.SplitHostPort(config.Address)
+			sink.tlsConfig = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+			if config.CAFile != "" {
+				pem, err := os.ReadFile(config.CAFile)
+				if err != nil {
+					return nil, fmt.Errorf("read syslog CA file: %w", err)
+				}
+				pool, err := x509.SystemCertPool()
+				if err != nil {
+					pool = x509.NewCertPool()
+				}
+				if !pool.AppendCertsFromPEM(pem) {
+					return nil, errors.New("no certificate found in syslog CA file")
+				}
+				sink.tlsConfig.RootCAs = pool
+			}
+		}
+	}
+	return sink, nil
+}
+
+// Name identifies the sink in logs and statistics
+func (s *SyslogSink) Name() string {
+	return fmt.Sprintf("syslog+%s://%s", s.config.Network, s.config.Address)
+}
+
+// Write sends an event
+func (s *SyslogSink) Write(e Event) error {
+	msg := s.format(e)
+	if s.config.Network != NetworkUDP {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil && s.config.Network != NetworkUDP && !connectionAlive(s.conn) {
+		s.conn.Close()
+		s.conn = nil
+	}
+	if s.conn == nil {
+		conn, err := s.dial()
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// dial opens the connection to the syslog server
+func (s *SyslogSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogWriteTimeout}
+	if s.config.Network == NetworkTLS {
+		return tls.DialWithDialer(dialer, "tcp", s.config.Address, s.tlsConfig)
+	}
+	return dialer.Dial(s.config.Network, s.config.Address)
+}
+
+// connectionAlive reports whether a stream connection is still open. A syslog server never writes, so a read
+// that does not time out means the server closed the connection: writing to it could silently lose the event.
+func connectionAlive(conn net.Conn) bool {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	defer conn.SetReadDeadline(time.Time{})
+	var b [1]byte
+	_, err := conn.Read(b[:])
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Close closes the connection
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// format builds the RFC 5424 message of an event
+func (s *SyslogSink) format(e Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d %s ", s.config.Facility*8+e.Severity(), e.Time.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		s.hostname, printASCII(s.config.AppName, 48), os.Getpid(), nilValue(printASCII(e.Action, 32)))
+	if s.config.Format == FormatCEF {
+		b.WriteString("- ")
+		b.WriteString(FormatCEFEvent(e, s.config.Version))
+		return b.String()
+	}
+	b.WriteString(structuredData(e))
+	if e.Description != "" {
+		b.WriteString(" ")
+		b.WriteString(strings.NewReplacer("\r", " ", "\n", " ").Replace(e.Description))
+	}
+	return b.String()
+}
+
+// structuredData returns the SD-ELEMENT describing an event
+func structuredData(e Event) string {
+	params := [][2]string{
+		{"id", strconv.FormatUint(uint64(e.ID), 10)},
+		{"user_id", strconv.FormatUint(uint64(e.UserID), 10)},
+		{"action", e.Action},
+		{"resource_type", e.ResourceType},
+		{"resource_id", strconv.FormatUint(uint64(e.ResourceID), 10)},
+		{"admin_action", strconv.FormatBool(e.AdminAction)},
+	}
+	if e.IPAddress != "" {
+		params = append(params, [2]string{"ip", e.IPAddress})
+	}
+	if e.UserAgent != "" {
+		params = append(params, [2]string{"user_agent", e.UserAgent})
+	}
+	if len(e.Metadata) > 0 {
+		if data, err := json.Marshal(e.Metadata); err == nil {
+			params = append(params, [2]string{"metadata", string(data)})
+		}
+	}
+	if e.Hash != "" {
+		params = append(params, [2]string{"hash", e.Hash})
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	var b strings.Builder
+	b.WriteString("[" + sdID)
+	for _, param := range params {
+		b.WriteString(" " + param[0] + `="` + escaper.Replace(param[1]) + `"`)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// FormatCEFEvent returns the ArcSight Common Event Format line of an event
+func FormatCEFEvent(e Event, version string) string {
+	if version == "" {
+		version = "dev"
+	}
+	header := strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	signature := e.ResourceType + ":" + e.Action
+	fields := []string{"CEF:0", "SafeBase", "SafeBase", header.Replace(version), header.Replace(signature),
+		header.Replace(e.Description), strconv.Itoa(cefSeverity(e.Severity()))}
+
+	extension := map[string]string{
+		"rt":         strconv.FormatInt(e.Time.UnixMilli(), 10),
+		"externalId": strconv.FormatUint(uint64(e.ID), 10),
+		"act":        e.Action,
+		"suid":       strconv.FormatUint(uint64(e.UserID), 10),
+		"cs1Label":   "resourceType",
+		"cs1":        e.ResourceType,
+		"cn1Label":   "resourceId",
+		"cn1":        strconv.FormatUint(uint64(e.ResourceID), 10),
+		"cs2Label":   "adminAction",
+		"cs2":        strconv.FormatBool(e.AdminAction),
+	}
+	if e.IPAddress != "" {
+		extension["src"] = e.IPAddress
+	}
+	if e.UserAgent != "" {
+		extension["requestClientApplication"] = e.UserAgent
+	}
+	if e.Hash != "" {
+		extension["cs3Label"] = "chainHash"
+		extension["cs3"] = e.Hash
+	}
+	if len(e.Metadata) > 0 {
+		if data, err := json.Marshal(e.Metadata); err == nil {
+			extension["cs4Label"] = "metadata"
+			extension["cs4"] = string(data)
+		}
+	}
+
+	keys := make([]string, 0, len(extension))
+	for key := range extension {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	escaper := strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + escaper.Replace(extension[key])
+	}
+	return strings.Join(fields, "|") + "|" + strings.Join(pairs, " ")
+}
+
+// cefSeverity converts a syslog severity to the 0-10 scale of CEF
+func cefSeverity(severity int) int {
+	switch severity {
+	case SeverityWarning:
+		return 6
+	case SeverityNotice:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// printASCII keeps the printable ASCII characters (without space) allowed in the header fields, truncated
+func printASCII(value string, maxLength int) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r > 32 && r < 127 {
+			b.WriteRune(r)
+		}
+		if b.Len() == maxLength {
+			break
+		}
+	}
+	return b.String()
+}
+
+// nilValue returns the NILVALUE of RFC 5424 for an empty header field
+func nilValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,228 @@
+// Package syslogtest runs a local syslog collector for tests and development. It accepts RFC 5424 messages over
+// UDP, TCP and TLS (octet-counting framing) and keeps them in memory.
+package syslogtest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a syslog collector listening on 127.0.0.1
+type Server struct {
+	Network string // udp, tcp or tls
+	Addr    string // host:port to send the messages to
+
+	listener   net.Listener
+	packetConn net.PacketConn
+	rootCAs    *x509.CertPool
+
+	mu       sync.Mutex
+	messages []string
+	received chan struct{}
+	conns    map[net.Conn]bool
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a collector for a network (udp, tcp or tls)
+func NewServer(network string) *Server {
+	s := &Server{Network: network, received: make(chan struct{}, 1), conns: make(map[net.Conn]bool)}
+	switch network {
+	case "udp":
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			panic(err)
+		}
+		s.packetConn = conn
+		s.Addr = conn.LocalAddr().String()
+		s.wg.Add(1)
+		go s.readPackets()
+		return s
+	case "tcp":
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			panic(err)
+		}
+		s.listener = listener
+	case "tls":
+		certificate, pool := selfSignedCertificate()
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+		if err != nil {
+			panic(err)
+		}
+		s.listener = listener
+		s.rootCAs = pool
+	default:
+		panic("syslogtest: unsupported network " + network)
+	}
+	s.Addr = s.listener.Addr().String()
+	s.wg.Add(1)
+	go s.accept()
+	return s
+}
+
+// ClientTLSConfig returns a client configuration trusting the certificate of a tls collector
+func (s *Server) ClientTLSConfig() *tls.Config {
+	return &tls.Config{RootCAs: s.rootCAs, ServerName: "127.0.0.1", MinVersion: tls.VersionTLS12}
+}
+
+// Messages returns the messages received so far
+func (s *Server) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+// WaitForMessages waits until at least count messages were received and returns them. Fewer messages are
+// returned when the timeout expires.
+func (s *Server) WaitForMessages(count int, timeout time.Duration) []string {
+	deadline := time.After(timeout)
+	for {
+		if messages := s.Messages(); len(messages) >= count {
+			return messages
+		}
+		select {
+		case <-s.received:
+		case <-deadline:
+			return s.Messages()
+		}
+	}
+}
+
+// DropConnections closes the open connections, as a restarted collector would
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// Close stops the collector
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	if s.packetConn != nil {
+		s.packetConn.Close()
+	}
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	s.DropConnections()
+	s.wg.Wait()
+}
+
+// record stores a message
+func (s *Server) record(message string) {
+	s.mu.Lock()
+	s.messages = append(s.messages, message)
+	s.mu.Unlock()
+	select {
+	case s.received <- struct{}{}:
+	default:
+	}
+}
+
+// readPackets receives one message per datagram
+func (s *Server) readPackets() {
+	defer s.wg.Done()
+	buffer := make([]byte, 65536)
+	for {
+		n, _, err := s.packetConn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		s.record(string(buffer[:n]))
+	}
+}
+
+// accept serves the stream connections
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.readStream(conn)
+	}
+}
+
+// readStream receives octet-counted messages: MSG-LEN SP SYSLOG-MSG
+func (s *Server) readStream(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	reader := bufio.NewReader(conn)
+	for {
+		prefix, err := reader.ReadString(' ')
+		if err != nil {
+			return
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(prefix))
+		if err != nil || length <= 0 {
+			return
+		}
+		message := make([]byte, length)
+		if _, err := io.ReadFull(reader, message); err != nil {
+			return
+		}
+		s.record(string(message))
+	}
+}
+
+// selfSignedCertificate returns a certificate for 127.0.0.1 and the pool trusting it
+func selfSignedCertificate() (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "syslogtest"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
@@ -0,0 +1,301 @@
+// Package container implements the portable format of encrypted backup objects.
+//
+// Layout (integers are big endian):
+//
+//	magic "SAFEBASE" | version uint16 | header length uint32 | header (JSON)
+//	chunk*: final flag uint8 | sealed length uint32 | nonce (12) | AES-256-GCM(chunk)
+//
+// Every chunk is authenticated together with a hash of the header, its index and the final
+// flag, so the header cannot be altered and chunks cannot be reordered, dropped or truncated.
+// The header describes the object well enough to decrypt it without the SafeBase database.
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Magic starts every container
+const Magic = "SAFEBASE"
+
+// Version is the current format version
+const Version = 1
+
+// CipherAES256GCM is the only cipher of version 1
+const CipherAES256GCM = "AES-256-GCM"
+
+// DefaultChunkSize is the plaintext size of each encrypted chunk
+const DefaultChunkSize = 1 << 20
+
+const (
+	maxHeaderSize = 64 << 10
+	maxChunkSize  = 64 << 20
+	keySize       = 32
+)
+
+var (
+	// ErrNotContainer is returned when data does not start with the container magic bytes
+	ErrNotContainer = errors.New("not a SafeBase container")
+	// ErrChecksumMismatch is returned when the decrypted content does not match the header checksum
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// PassphraseKey carries what is needed to open the data key of a zero-knowledge backup with its passphrase
+type PassphraseKey struct {
+	KDF               string `json:"kdf"`
+	WrappedPrivateKey string `json:"wrapped_private_key"` // base64
+}
+
+// Header describes an encrypted backup object
+type Header struct {
+	Version       int            `json:"version"`
+	Cipher        string         `json:"cipher"`
+	KeyID         string         `json:"key_id"`      // master key wrapping the data key at creation time, or "passphrase"
+	WrappedKey    string         `json:"wrapped_key"` // base64 data key wrapped with KeyID
+	Passphrase    *PassphraseKey `json:"passphrase,omitempty"`
+	ChunkSize     int            `json:"chunk_size"`
+	Filename      string         `json:"filename"`
+	DBType        string         `json:"db_type"`
+	DatabaseName  string         `json:"database_name,omitempty"`
+	UserID        uint           `json:"user_id,omitempty"`
+	BackupID      uint           `json:"backup_id,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	PlaintextSize int64          `json:"plaintext_size"`
+	Checksum      string         `json:"checksum"` // "sha256:<hex>" of the plaintext
+}
+
+// IsContainer reports whether data starts with the container magic bytes
+func IsContainer(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// Checksum returns the "sha256:<hex>" checksum of a reader's content and its size
+func Checksum(r io.Reader) (string, int64, error) {
+	hash := sha256.New()
+	size, err := io.Copy(hash, r)
+	if err != nil {
+		return "", 0, err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// Encrypt writes the header then the plaintext of r encrypted with dataKey.
+// header.Checksum and header.PlaintextSize must describe r (see Checksum).
+func Encrypt(w io.Writer, r io.Reader, header Header, dataKey []byte) error {
+	header.Version = Version
+	header.Cipher = CipherAES256GCM
+	if header.ChunkSize <= 0 {
+		header.ChunkSize = DefaultChunkSize
+	}
+	if header.ChunkSize > maxChunkSize {
+		return fmt.Errorf("chunk size %d exceeds %d", header.ChunkSize, maxChunkSize)
+	}
+	if header.CreatedAt.IsZero() {
+		header.CreatedAt = time.Now().UTC()
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	prefix, err := encodePrefix(&header)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+	headerHash := sha256.Sum256(prefix)
+
+	// Read one chunk ahead to know which chunk is the last one
+	reader := bufio.NewReaderSize(r, header.ChunkSize)
+	current := make([]byte, header.ChunkSize)
+	next := make([]byte, header.ChunkSize)
+	n, err := io.ReadFull(reader, current)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	for index := uint64(0); ; index++ {
+		m, err := io.ReadFull(reader, next)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		final := m == 0
+		if err := writeChunk(w, aead, headerHash[:], index, final, current[:n]); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		current, next = next, current
+		n = m
+	}
+}
+
+// ReadHeader reads and validates the header at the start of r
+func ReadHeader(r io.Reader) (*Header, []byte, error) {
+	fixed := make([]byte, len(Magic)+2+4)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, nil, ErrNotContainer
+		}
+		return nil, nil, err
+	}
+	if string(fixed[:len(Magic)]) != Magic {
+		return nil, nil, ErrNotContainer
+	}
+	version := binary.BigEndian.Uint16(fixed[len(Magic):])
+	if version != Version {
+		return nil, nil, fmt.Errorf("unsupported container version %d", version)
+	}
+	length := binary.BigEndian.Uint32(fixed[len(Magic)+2:])
+	if length > maxHeaderSize {
+		return nil, nil, fmt.Errorf("container header too large (%d bytes)", length)
+	}
+
+	raw := make([]byte, length)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, nil, fmt.Errorf("truncated container header: %w", err)
+	}
+	var header Header
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, nil, fmt.Errorf("invalid container header: %w", err)
+	}
+	if header.Cipher != CipherAES256GCM {
+		return nil, nil, fmt.Errorf("unsupported cipher %q", header.Cipher)
+	}
+	if header.ChunkSize <= 0 || header.ChunkSize > maxChunkSize {
+		return nil, nil, fmt.Errorf("invalid chunk size %d", header.ChunkSize)
+	}
+	return &header, append(fixed, raw...), nil
+}
+
+// Decrypt reads a container from r and writes the plaintext to w. The data key is obtained
+// from the header through keyFor, so callers can unwrap it with a keyring or a passphrase.
+func Decrypt(w io.Writer, r io.Reader, keyFor func(*Header) ([]byte, error)) (*Header, error) {
+	header, prefix, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := keyFor(header)
+	if err != nil {
+		return header, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return header, err
+	}
+	headerHash := sha256.Sum256(prefix)
+
+	hash := sha256.New()
+	out := io.MultiWriter(w, hash)
+	var size int64
+	maxSealed := uint32(header.ChunkSize + aead.NonceSize() + aead.Overhead())
+	for index := uint64(0); ; index++ {
+		var meta [5]byte
+		if _, err := io.ReadFull(r, meta[:]); err != nil {
+			return header, fmt.Errorf("truncated container: missing chunk %d", index)
+		}
+		final := meta[0] == 1
+		length := binary.BigEndian.Uint32(meta[1:])
+		if length > maxSealed || int(length) < aead.NonceSize() {
+			return header, fmt.Errorf("invalid chunk %d length", index)
+		}
+		sealed := make([]byte, length)
+		if _, err := io.ReadFull(r, sealed); err != nil {
+			return header, fmt.Errorf("truncated container: chunk %d", index)
+		}
+
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, chunkAAD(headerHash[:], index, final))
+		if err != nil {
+			return header, fmt.Errorf("chunk %d: authentication failed (wrong key or corrupted data)", index)
+		}
+		if _, err := out.Write(plaintext); err != nil {
+			return header, err
+		}
+		size += int64(len(plaintext))
+
+		if final {
+			break
+		}
+	}
+
+	if extra, _ := r.Read(make([]byte, 1)); extra > 0 {
+		return header, fmt.Errorf("unexpected data after the final chunk")
+	}
+	if size != header.PlaintextSize || "sha256:"+hex.EncodeToString(hash.Sum(nil)) != header.Checksum {
+		return header, ErrChecksumMismatch
+	}
+	return header, nil
+}
+
+// encodePrefix serializes the magic, version and header
+func encodePrefix(header *Header) ([]byte, error) {
+	raw, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > maxHeaderSize {
+		return nil, fmt.Errorf("container header too large (%d bytes)", len(raw))
+	}
+
+	prefix := make([]byte, 0, len(Magic)+6+len(raw))
+	prefix = append(prefix, Magic...)
+	prefix = binary.BigEndian.AppendUint16(prefix, Version)
+	prefix = binary.BigEndian.AppendUint32(prefix, uint32(len(raw)))
+	return append(prefix, raw...), nil
+}
+
+// writeChunk seals one chunk with a random nonce
+func writeChunk(w io.Writer, aead cipher.AEAD, headerHash []byte, index uint64, final bool, plaintext []byte) error {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, chunkAAD(headerHash, index, final))
+
+	var meta [5]byte
+	if final {
+		meta[0] = 1
+	}
+	binary.BigEndian.PutUint32(meta[1:], uint32(len(sealed)))
+	if _, err := w.Write(meta[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(sealed)
+	return err
+}
+
+// chunkAAD binds a chunk to the header, its position and whether it is the last one
+func chunkAAD(headerHash []byte, index uint64, final bool) []byte {
+	aad := make([]byte, 0, len(headerHash)+9)
+	aad = append(aad, headerHash...)
+	aad = binary.BigEndian.AppendUint64(aad, index)
+	if final {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("data key must be %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
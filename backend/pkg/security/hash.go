@@ -1,13 +1,9 @@
 package security
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"encoding/base64"
-	"fmt"
-	"io"
 	"log"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -31,61 +27,63 @@ func CheckPassword(hashedPassword, plainPassword string) bool {
 	return err == nil // true = password is correct
 }
 
-// EncryptDatabasePassword encrypts a database password using AES-GCM (AEAD mode)
-func EncryptDatabasePassword(password string) (string, error) {
-	// In production, this key should come from environment variables
-	key := []byte("your-32-byte-secret-key-here!!!!") // 32 bytes for AES-256
+// Encrypted credentials are stored as "enc:<key ID>:<base64(nonce||ciphertext)>".
+// Values without the prefix were written before key IDs existed and use the legacy key.
+const encryptedValuePrefix = "enc:"
 
-	block, err := aes.NewCipher(key)
+// EncryptDatabasePassword encrypts a database password using AES-GCM (AEAD mode) with the primary key
+func EncryptDatabasePassword(password string) (string, error) {
+	keyring, err := DefaultKeyring()
 	if err != nil {
 		return "", err
 	}
+	return keyring.EncryptValue(password)
+}
 
-	aesGCM, err := cipher.NewGCM(block)
+// DecryptDatabasePassword decrypts a database password using AES-GCM (AEAD mode) with the key it was sealed with
+func DecryptDatabasePassword(encryptedPassword string) (string, error) {
+	keyring, err := DefaultKeyring()
 	if err != nil {
 		return "", err
 	}
-
-	plaintext := []byte(password)
-	nonce := make([]byte, aesGCM.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
-	}
-
-	ciphertext := aesGCM.Seal(nonce, nonce, plaintext, nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return keyring.DecryptValue(encryptedPassword)
 }
 
-// DecryptDatabasePassword decrypts a database password using AES-GCM (AEAD mode)
-func DecryptDatabasePassword(encryptedPassword string) (string, error) {
-	// In production, this key should come from environment variables
-	key := []byte("your-32-byte-secret-key-here!!!!") // 32 bytes for AES-256
-
-	ciphertext, err := base64.StdEncoding.DecodeString(encryptedPassword)
+// EncryptValue seals a credential with the primary key in the "enc:<key ID>:<base64>" format
+func (k *Keyring) EncryptValue(value string) (string, error) {
+	keyID, sealed, err := k.Encrypt([]byte(value))
 	if err != nil {
 		return "", err
 	}
+	return encryptedValuePrefix + keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
 
-	block, err := aes.NewCipher(key)
+// DecryptValue opens a credential produced by EncryptValue (or a legacy unprefixed value)
+func (k *Keyring) DecryptValue(encryptedValue string) (string, error) {
+	keyID, encoded := splitEncryptedValue(encryptedValue)
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return "", err
 	}
 
-	aesGCM, err := cipher.NewGCM(block)
+	plaintext, err := k.Decrypt(keyID, sealed)
 	if err != nil {
 		return "", err
 	}
+	return string(plaintext), nil
+}
 
-	nonceSize := aesGCM.NonceSize()
-	if len(ciphertext) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
-	}
+// EncryptedValueKeyID returns the ID of the key an encrypted credential was sealed with
+func EncryptedValueKeyID(encryptedValue string) string {
+	keyID, _ := splitEncryptedValue(encryptedValue)
+	return keyID
+}
 
-	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return "", err
+func splitEncryptedValue(value string) (keyID, encoded string) {
+	if rest, ok := strings.CutPrefix(value, encryptedValuePrefix); ok {
+		if keyID, encoded, ok := strings.Cut(rest, ":"); ok {
+			return keyID, encoded
+		}
 	}
-
-	return string(plaintext), nil
+	return LegacyKeyID, value
 }
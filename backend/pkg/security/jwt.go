@@ -8,20 +8,48 @@ import (
 
 // Structure of the data contained in the token
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`     // session (refresh token family) the token was issued for
+	Purpose   string `json:"purpose,omitempty"` // set on restricted tokens (e.g. TwoFactorPurpose), empty for access tokens
 	jwt.RegisteredClaims
 }
 
+// TwoFactorPurpose marks the partial token returned by a password login that still needs a second factor
+const TwoFactorPurpose = "2fa"
+
 // Generate a signed JWT token with the secret key
 func GenerateJWT(secret string, userID uint, email, role string, duration time.Duration) (string, error) {
+	return GenerateSessionJWT(secret, userID, email, role, "", duration)
+}
+
+// GenerateSessionJWT generates a signed JWT token bound to a session
+func GenerateSessionJWT(secret string, userID uint, email, role, sessionID string, duration time.Duration) (string, error) {
+	return generateJWT(secret, userID, email, role, sessionID, "", duration)
+}
+
+// GeneratePurposeJWT generates a restricted token that is only accepted by the endpoint of its purpose
+func GeneratePurposeJWT(secret string, userID uint, email, purpose string, duration time.Duration) (string, error) {
+	return generateJWT(secret, userID, email, "", "", purpose, duration)
+}
+
+func generateJWT(secret string, userID uint, email, role, sessionID, purpose string, duration time.Duration) (string, error) {
+	// Unique token ID: two tokens issued in the same second for the same user must differ
+	tokenID, err := RandomID(16)
+	if err != nil {
+		return "", err
+	}
+
 	// Create the claims (data embedded in the token)
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
+		Purpose:   purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)), // validity duration (which will be defined in user_service.go)
 			IssuedAt:  jwt.NewNumericDate(time.Now()),               // creation date
 			Issuer:    "safebase",                                   // service name
@@ -0,0 +1,267 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// KeySize is the size of every master key (AES-256)
+const KeySize = 32
+
+// LegacyKeyID identifies the key used for ciphertexts written before key IDs existed
+const LegacyKeyID = "legacy"
+
+// ErrKeyringNotConfigured is returned when encryption is used before a keyring was loaded
+var ErrKeyringNotConfigured = errors.New("encryption keyring not configured")
+
+// Key IDs end up inside ciphertext prefixes and object metadata: keep them short and unambiguous
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+// KeyringConfig describes where the master keys come from.
+// Keys are base64-encoded 32-byte values (generate one with: openssl rand -base64 32).
+type KeyringConfig struct {
+	KeyFile          string            // JSON key file, takes precedence over inline keys
+	Keys             map[string]string // key ID -> base64 key
+	PrimaryKeyID     string            // key used for new ciphertexts
+	LegacyKey        string            // raw key of ciphertexts written without key ID (read only)
+	LegacyBackupSalt string            // salt of backups written without key ID (read only)
+}
+
+// keyFile is the on-disk format of KeyringConfig.KeyFile
+type keyFile struct {
+	Primary          string            `json:"primary"`
+	Keys             map[string]string `json:"keys"`
+	LegacyKey        string            `json:"legacy_key,omitempty"`
+	LegacyBackupSalt string            `json:"legacy_backup_salt,omitempty"`
+}
+
+// Keyring holds the master keys indexed by key ID
+type Keyring struct {
+	primaryID        string
+	keys             map[string][]byte
+	legacyBackupSalt string
+}
+
+// NewKeyring creates a keyring from raw keys; primaryID must be one of them
+func NewKeyring(primaryID string, keys map[string][]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no key provided", ErrKeyringNotConfigured)
+	}
+
+	k := &Keyring{primaryID: primaryID, keys: make(map[string][]byte, len(keys))}
+	for id, key := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid key ID %q: use 1-32 letters, digits, '.', '_' or '-'", id)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, KeySize, len(key))
+		}
+		k.keys[id] = append([]byte(nil), key...)
+	}
+
+	if _, ok := k.keys[PassphraseKeyID]; ok {
+		return nil, fmt.Errorf("key ID %q is reserved for passphrase protected backups", PassphraseKeyID)
+	}
+	if _, ok := k.keys[primaryID]; !ok || primaryID == LegacyKeyID {
+		return nil, fmt.Errorf("primary key %q not found in keyring", primaryID)
+	}
+	return k, nil
+}
+
+// LoadKeyring builds the keyring from a key file or inline keys and fails if no usable key is found
+func LoadKeyring(cfg KeyringConfig) (*Keyring, error) {
+	if cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		var file keyFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("invalid key file %s: %w", cfg.KeyFile, err)
+		}
+		cfg.Keys = file.Keys
+		cfg.PrimaryKeyID = file.Primary
+		if file.LegacyKey != "" {
+			cfg.LegacyKey = file.LegacyKey
+		}
+		if file.LegacyBackupSalt != "" {
+			cfg.LegacyBackupSalt = file.LegacyBackupSalt
+		}
+	}
+
+	keys := make(map[string][]byte, len(cfg.Keys)+1)
+	for id, encoded := range cfg.Keys {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+	if cfg.LegacyKey != "" {
+		keys[LegacyKeyID] = []byte(cfg.LegacyKey)
+	}
+
+	primaryID := cfg.PrimaryKeyID
+	if primaryID == "" && len(cfg.Keys) == 1 {
+		for id := range cfg.Keys {
+			primaryID = id
+		}
+	}
+	if primaryID == "" {
+		return nil, fmt.Errorf("%w: primary key ID is required when several keys are configured", ErrKeyringNotConfigured)
+	}
+
+	keyring, err := NewKeyring(primaryID, keys)
+	if err != nil {
+		return nil, err
+	}
+	keyring.legacyBackupSalt = cfg.LegacyBackupSalt
+	return keyring, nil
+}
+
+// PrimaryKeyID returns the ID of the key used for new ciphertexts
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primaryID
+}
+
+// Key returns the key with the given ID
+func (k *Keyring) Key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+	return key, nil
+}
+
+// KeyIDs returns the sorted IDs of every key in the ring
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// LegacyBackupSalt returns the salt of backups encrypted before key IDs were recorded (may be empty)
+func (k *Keyring) LegacyBackupSalt() string {
+	return k.legacyBackupSalt
+}
+
+// Encrypt seals plaintext with the primary key and returns its ID with nonce||ciphertext
+func (k *Keyring) Encrypt(plaintext []byte) (string, []byte, error) {
+	sealed, err := sealAESGCM(k.keys[k.primaryID], plaintext)
+	return k.primaryID, sealed, err
+}
+
+// Decrypt opens nonce||ciphertext sealed with the given key ID
+func (k *Keyring) Decrypt(keyID string, sealed []byte) ([]byte, error) {
+	key, err := k.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return openAESGCM(key, sealed)
+}
+
+// GenerateDataKey returns a random AES-256 data key (envelope encryption: one key per object)
+func GenerateDataKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// WrapDataKey encrypts a data key with the primary master key and returns the master key ID
+func (k *Keyring) WrapDataKey(dataKey []byte) (string, []byte, error) {
+	if len(dataKey) != KeySize {
+		return "", nil, fmt.Errorf("data key must be %d bytes, got %d", KeySize, len(dataKey))
+	}
+	return k.Encrypt(dataKey)
+}
+
+// UnwrapDataKey decrypts a data key wrapped with the given master key ID
+func (k *Keyring) UnwrapDataKey(keyID string, wrapped []byte) ([]byte, error) {
+	dataKey, err := k.Decrypt(keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with key %q: %w", keyID, err)
+	}
+	if len(dataKey) != KeySize {
+		return nil, fmt.Errorf("unwrapped data key has an invalid size (%d bytes)", len(dataKey))
+	}
+	return dataKey, nil
+}
+
+var (
+	defaultKeyringMu sync.RWMutex
+	defaultKeyring   *Keyring
+)
+
+// SetDefaultKeyring installs the keyring used by the package-level helpers (database credentials)
+func SetDefaultKeyring(k *Keyring) {
+	defaultKeyringMu.Lock()
+	defer defaultKeyringMu.Unlock()
+	defaultKeyring = k
+}
+
+// DefaultKeyring returns the installed keyring or ErrKeyringNotConfigured
+func DefaultKeyring() (*Keyring, error) {
+	defaultKeyringMu.RLock()
+	defer defaultKeyringMu.RUnlock()
+	if defaultKeyring == nil {
+		return nil, ErrKeyringNotConfigured
+	}
+	return defaultKeyring, nil
+}
+
+// sealAESGCM encrypts with AES-256-GCM and prepends the random nonce
+func sealAESGCM(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aesGCM.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openAESGCM decrypts nonce||ciphertext produced by sealAESGCM
+func openAESGCM(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := aesGCM.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := sealed[:nonceSize], sealed[nonceSize:]
+	return aesGCM.Open(nil, nonce, ciphertext, nil)
+}
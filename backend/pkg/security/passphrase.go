@@ -0,0 +1,238 @@
+package security
+
+import (
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// PassphraseKeyID is recorded as key ID on backups whose data key is sealed to a passphrase key pair.
+// It is never a master key: the operator cannot decrypt these backups.
+const PassphraseKeyID = "passphrase"
+
+// MinPassphraseLength is the minimum number of characters of a backup passphrase
+const MinPassphraseLength = 12
+
+var (
+	// ErrPassphraseRequired is returned when a passphrase protected backup is read without passphrase
+	ErrPassphraseRequired = errors.New("passphrase required")
+	// ErrWrongPassphrase is returned when the key-check value does not match the given passphrase
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// Argon2id cost (RFC 9106 second recommended option: 64 MiB, 3 passes)
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2SaltLen = 16
+	keyCheckLen   = 16
+)
+
+var (
+	keyCheckLabel = []byte("safebase passphrase key check")
+	sealInfo      = []byte("safebase backup data key")
+)
+
+// PassphraseKey is the key pair protecting the backups of a database in zero-knowledge mode.
+// Backups are sealed to the public key, so they can be created (even by the scheduler) without
+// the passphrase; the private key is only stored encrypted with a key derived from the passphrase.
+type PassphraseKey struct {
+	KDF               string // argon2id parameters, salt and key-check value
+	PublicKey         []byte // X25519 public key
+	WrappedPrivateKey []byte // X25519 private key sealed with the passphrase derived key
+}
+
+// passphraseKDF holds the decoded Argon2id parameters
+type passphraseKDF struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	check   []byte
+}
+
+// NewPassphraseKey generates a key pair protected by the given passphrase
+func NewPassphraseKey(passphrase string) (*PassphraseKey, error) {
+	if err := ValidatePassphrase(passphrase); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return wrapPrivateKey(privateKey, passphrase)
+}
+
+// ValidatePassphrase checks the passphrase policy
+func ValidatePassphrase(passphrase string) error {
+	if utf8.RuneCountInString(passphrase) < MinPassphraseLength {
+		return fmt.Errorf("la phrase secrète doit contenir au moins %d caractères", MinPassphraseLength)
+	}
+	return nil
+}
+
+// Unlock checks the passphrase against the key-check value then decrypts the private key
+func (k *PassphraseKey) Unlock(passphrase string) (*ecdh.PrivateKey, error) {
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+
+	kdf, err := parsePassphraseKDF(k.KDF)
+	if err != nil {
+		return nil, err
+	}
+	key := kdf.derive(passphrase)
+
+	// Reject a wrong passphrase before touching the ciphertext
+	if !hmac.Equal(keyCheck(key), kdf.check) {
+		return nil, ErrWrongPassphrase
+	}
+
+	raw, err := openAESGCM(key, k.WrappedPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt passphrase private key: %w", err)
+	}
+	return ecdh.X25519().NewPrivateKey(raw)
+}
+
+// ChangePassphrase re-wraps the private key: backups sealed to the key pair stay readable with the new passphrase
+func (k *PassphraseKey) ChangePassphrase(current, next string) (*PassphraseKey, error) {
+	if err := ValidatePassphrase(next); err != nil {
+		return nil, err
+	}
+	privateKey, err := k.Unlock(current)
+	if err != nil {
+		return nil, err
+	}
+	return wrapPrivateKey(privateKey, next)
+}
+
+// SealToPublicKey encrypts a data key for the holder of the private key (ephemeral X25519 + HKDF + AES-GCM).
+// Output: ephemeral public key (32 bytes) || nonce || ciphertext.
+func SealToPublicKey(publicKey, dataKey []byte) ([]byte, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase public key: %w", err)
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := sealingKey(ephemeral, recipient, ephemeral.PublicKey().Bytes(), recipient.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealAESGCM(key, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephemeral.PublicKey().Bytes(), sealed...), nil
+}
+
+// OpenWithPrivateKey decrypts a data key produced by SealToPublicKey
+func OpenWithPrivateKey(privateKey *ecdh.PrivateKey, sealed []byte) ([]byte, error) {
+	const publicKeySize = 32
+	if len(sealed) < publicKeySize {
+		return nil, fmt.Errorf("sealed data key too short")
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(sealed[:publicKeySize])
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := sealingKey(privateKey, ephemeral, sealed[:publicKeySize], privateKey.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := openAESGCM(key, sealed[publicKeySize:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+// sealingKey derives the AES key shared by the ephemeral and recipient key pairs
+func sealingKey(private *ecdh.PrivateKey, public *ecdh.PublicKey, ephemeralPublic, recipientPublic []byte) ([]byte, error) {
+	shared, err := private.ECDH(public)
+	if err != nil {
+		return nil, err
+	}
+	salt := append(append([]byte(nil), ephemeralPublic...), recipientPublic...)
+	return hkdf.Key(sha256.New, shared, salt, string(sealInfo), KeySize)
+}
+
+// wrapPrivateKey encrypts a private key with a key derived from the passphrase
+func wrapPrivateKey(privateKey *ecdh.PrivateKey, passphrase string) (*PassphraseKey, error) {
+	kdf := &passphraseKDF{time: argon2Time, memory: argon2Memory, threads: argon2Threads, salt: make([]byte, argon2SaltLen)}
+	if _, err := rand.Read(kdf.salt); err != nil {
+		return nil, err
+	}
+	key := kdf.derive(passphrase)
+	kdf.check = keyCheck(key)
+
+	wrapped, err := sealAESGCM(key, privateKey.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &PassphraseKey{
+		KDF:               kdf.String(),
+		PublicKey:         privateKey.PublicKey().Bytes(),
+		WrappedPrivateKey: wrapped,
+	}, nil
+}
+
+// derive runs Argon2id with the stored parameters
+func (p *passphraseKDF) derive(passphrase string) []byte {
+	return argon2.IDKey([]byte(passphrase), p.salt, p.time, p.memory, p.threads, KeySize)
+}
+
+// String encodes the parameters PHC-style: argon2id$v=19$m=65536,t=3,p=4$<salt>$<check>
+func (p *passphraseKDF) String() string {
+	return fmt.Sprintf("argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(p.salt), base64.RawStdEncoding.EncodeToString(p.check))
+}
+
+// parsePassphraseKDF decodes parameters produced by passphraseKDF.String
+func parsePassphraseKDF(encoded string) (*passphraseKDF, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 || parts[0] != "argon2id" {
+		return nil, fmt.Errorf("unsupported passphrase key derivation")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[1], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version %q", parts[1])
+	}
+
+	p := &passphraseKDF{}
+	if _, err := fmt.Sscanf(parts[2], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	if p.check, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid key-check value: %w", err)
+	}
+	return p, nil
+}
+
+// keyCheck computes the key-check value stored next to the salt (it does not reveal the key)
+func keyCheck(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(keyCheckLabel)
+	return mac.Sum(nil)[:keyCheckLen]
+}
@@ -0,0 +1,68 @@
+package version
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// Version can be set at build time:
+//
+//	go build -ldflags "-X github.com/RyanLadmia/plateforme-safebase/pkg/version.Version=v1.2.0" ./cmd
+var Version = ""
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+var readInfo = sync.OnceValue(func() Info {
+	info := Info{Version: Version}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		if info.Version == "" {
+			info.Version = "dev"
+		}
+		return info
+	}
+
+	info.GoVersion = build.GoVersion
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.time":
+			info.BuildTime = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+
+	// Fall back to the module version (set by "go install module@version"), then to the VCS revision
+	if info.Version == "" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+		info.Version = build.Main.Version
+	}
+	if info.Version == "" && info.Commit != "" {
+		info.Version = "dev-" + shortCommit(info.Commit)
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+})
+
+// Get returns the version information of the running binary (read once from the build info)
+func Get() Info {
+	return readInfo()
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
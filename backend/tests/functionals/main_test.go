@@ -0,0 +1,24 @@
+package functionals
+
+import (
+	"encoding/base64"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+)
+
+// TestMain installs a throwaway keyring: the application refuses to encrypt without one
+func TestMain(m *testing.M) {
+	keyring, err := security.LoadKeyring(security.KeyringConfig{
+		Keys:             map[string]string{"test": base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))},
+		LegacyBackupSalt: "SafeBaseBackupSalt2025!", // backups written before key IDs existed
+	})
+	if err != nil {
+		log.Fatalf("failed to load test keyring: %v", err)
+	}
+	security.SetDefaultKeyring(keyring)
+
+	os.Exit(m.Run())
+}
@@ -58,6 +58,7 @@ func SetupTestSuite(t *testing.T) *TestSuite {
 		&models.User{},
 		&models.Role{},
 		&models.Session{},
+		&models.ConsumedRefreshToken{},
 		&models.Database{},
 		&models.Backup{},
 		&models.Schedule{},
@@ -0,0 +1,137 @@
+package units
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// setupAdminTest returns the admin service; backup jobs wait in the returned queue
+func setupAdminTest(t *testing.T) (*gorm.DB, *services.AdminService, *queuedWorkerPool, *services.TeamService) {
+	db, teamService, authz := setupTeamTest(t)
+	require.NoError(t, db.AutoMigrate(&models.Restore{}, &models.Schedule{}))
+
+	databaseRepo := repositories.NewDatabaseRepository(db)
+	backupRepo := repositories.NewBackupRepository(db)
+	restoreRepo := repositories.NewRestoreRepository(db)
+	scheduleRepo := repositories.NewScheduleRepository(db)
+	databaseService := services.NewDatabaseService(databaseRepo, backupRepo, restoreRepo, scheduleRepo, nil)
+	databaseService.SetAuthorizationService(authz)
+	backupService := services.NewBackupService(backupRepo, databaseService, nil, t.TempDir())
+	backupService.SetAuthorizationService(authz)
+	pool := &queuedWorkerPool{}
+	backupService.SetWorkerPool(pool)
+	restoreService := services.NewRestoreService(restoreRepo, backupService, databaseService, nil)
+
+	adminService := services.NewAdminService(databaseRepo, backupRepo, restoreRepo, scheduleRepo, backupService, restoreService)
+	adminService.SetActionHistoryService(services.NewActionHistoryService(repositories.NewActionHistoryRepository(db)))
+	return db, adminService, pool, teamService
+}
+
+// ============================================================================
+// UNIT TESTS - Admin views
+// ============================================================================
+
+// TestAdminService_ListWithFilters tests the listings across users with their owner
+func TestAdminService_ListWithFilters(t *testing.T) {
+	db, adminService, _, teamService := setupAdminTest(t)
+	alice := createTestUser(db, "alice@example.com", "Password123!", 2)
+	bob := createTestUser(db, "bob@example.com", "Password123!", 2)
+	team, err := teamService.CreateTeam(bob.Id, "Ops", "", "")
+	require.NoError(t, err)
+
+	aliceDB := newQuotaTestDatabase("alice_prod", alice.Id, nil)
+	bobDB := newQuotaTestDatabase("bob_billing", bob.Id, &team.Id)
+	require.NoError(t, db.Create(aliceDB).Error)
+	require.NoError(t, db.Create(bobDB).Error)
+	createTestBackup(db, aliceDB.Id, alice.Id, "completed")
+	createTestBackup(db, bobDB.Id, bob.Id, "failed")
+	createTestBackup(db, bobDB.Id, bob.Id, "completed")
+	schedule := &models.Schedule{Name: "Nuit", CronExpression: "0 3 * * *", UserId: bob.Id, DatabaseId: bobDB.Id}
+	require.NoError(t, db.Create(schedule).Error)
+	require.NoError(t, db.Model(schedule).Update("active", false).Error)
+
+	databases, total, err := adminService.ListDatabases(repositories.AdminFilter{Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, databases, 2)
+
+	databases, total, err = adminService.ListDatabases(repositories.AdminFilter{Search: "BILL", Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "bob@example.com", databases[0].Owner.Email)
+
+	backups, total, err := adminService.ListBackups(repositories.AdminFilter{TeamId: team.Id, Status: "completed", Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "bob_billing", backups[0].DatabaseName)
+	assert.Equal(t, bob.Id, backups[0].Owner.Id)
+
+	backups, total, err = adminService.ListBackups(repositories.AdminFilter{UserId: alice.Id, Page: 1, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, backups, 1)
+
+	schedules, total, err := adminService.ListSchedules(repositories.AdminFilter{Status: "inactive", Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "Nuit", schedules[0].Name)
+	assert.Equal(t, "bob@example.com", schedules[0].Owner.Email)
+}
+
+// TestAdminService_TriggerAndCancelJobs tests jobs started and cancelled on behalf of a user
+func TestAdminService_TriggerAndCancelJobs(t *testing.T) {
+	db, adminService, pool, _ := setupAdminTest(t)
+	admin := createTestUser(db, "admin@example.com", "Password123!", 1)
+	user := createTestUser(db, "user@example.com", "Password123!", 2)
+	database := newQuotaTestDatabase("prod", user.Id, nil)
+	require.NoError(t, db.Create(database).Error)
+
+	// The backup runs as the owner of the database
+	backup, err := adminService.TriggerBackup(context.Background(), admin.Id, database.Id, "127.0.0.1", "test")
+	require.NoError(t, err)
+	assert.Equal(t, user.Id, backup.UserId)
+	assert.Equal(t, "pending", backup.Status)
+	require.Len(t, pool.tasks, 1)
+
+	// Cancelled while queued: the job does not start
+	cancelled, err := adminService.CancelBackup(admin.Id, backup.Id, "127.0.0.1", "test")
+	require.NoError(t, err)
+	assert.Equal(t, models.BackupStatusCancelled, cancelled.Status)
+	pool.tasks[0]()
+	var stored models.Backup
+	require.NoError(t, db.First(&stored, backup.Id).Error)
+	assert.Equal(t, models.BackupStatusCancelled, stored.Status)
+	_, err = adminService.CancelBackup(admin.Id, backup.Id, "", "")
+	assert.ErrorIs(t, err, services.ErrJobNotCancellable)
+
+	// Only restores that have not started can be cancelled
+	completed := createTestBackup(db, database.Id, user.Id, "completed")
+	pending := &models.Restore{Status: "pending", UserId: user.Id, BackupId: completed.Id, DatabaseId: database.Id}
+	running := &models.Restore{Status: "running", UserId: user.Id, BackupId: completed.Id, DatabaseId: database.Id}
+	require.NoError(t, db.Create(pending).Error)
+	require.NoError(t, db.Create(running).Error)
+	_, err = adminService.CancelRestore(admin.Id, pending.Id, "", "")
+	require.NoError(t, err)
+	_, err = adminService.CancelRestore(admin.Id, running.Id, "", "")
+	assert.ErrorIs(t, err, services.ErrJobNotCancellable)
+
+	// Every admin action is recorded as such, on behalf of the owner
+	var actions []models.ActionHistory
+	require.NoError(t, db.Where("admin_action = ?", true).Order("id").Find(&actions).Error)
+	require.Len(t, actions, 3)
+	assert.Equal(t, []string{"admin_backup", "admin_backup_cancelled", "admin_restore_cancelled"},
+		[]string{actions[0].Action, actions[1].Action, actions[2].Action})
+	assert.Equal(t, admin.Id, actions[0].UserId)
+	assert.Contains(t, actions[0].Metadata, `"on_behalf_of":`)
+}
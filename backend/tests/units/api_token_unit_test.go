@@ -0,0 +1,154 @@
+package units
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// setupAPITokenTest enables API tokens and creates two databases for the session test user
+func setupAPITokenTest(t *testing.T) (*gorm.DB, *services.AuthService, *models.User, []models.Database) {
+	db, authService, _, user := setupSessionTest(t)
+	require.NoError(t, db.AutoMigrate(&models.Database{}, &models.APIToken{}))
+	authService.SetAPITokenRepository(repositories.NewAPITokenRepository(db))
+
+	databases := []models.Database{
+		{Name: "prod", Type: "postgresql", Host: "localhost", Port: "5432", Username: "u", Password: "p", DbName: "prod", UserId: user.Id},
+		{Name: "staging", Type: "postgresql", Host: "localhost", Port: "5432", Username: "u", Password: "p", DbName: "staging", UserId: user.Id},
+	}
+	require.NoError(t, db.Create(&databases).Error)
+	return db, authService, user, databases
+}
+
+// setupAPITokenRouter exposes stub handlers on real route paths behind the auth middleware
+func setupAPITokenRouter(authService *services.AuthService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	authMiddleware := middlewares.NewAuthMiddleware("test-secret-key")
+	authMiddleware.SetSessionValidator(authService)
+	authMiddleware.SetAPITokenValidator(authService)
+
+	ok := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id"), "prod_allowed": middlewares.APITokenAllowsDatabase(c, 1)})
+	}
+	router := gin.New()
+	backups := router.Group("/api/backups", authMiddleware.RequireAuth())
+	backups.GET("", ok)
+	backups.DELETE("/:id", ok)
+	backups.POST("/database/:database_id", ok)
+	router.GET("/api/profile", authMiddleware.RequireAuth(), ok)
+	return router
+}
+
+// ============================================================================
+// UNIT TESTS - Personal API tokens
+// ============================================================================
+
+// TestAuthService_CreateAPIToken tests that a token is returned once, stored hashed and validated
+func TestAuthService_CreateAPIToken(t *testing.T) {
+	db, authService, user, databases := setupAPITokenTest(t)
+	expires := time.Now().Add(24 * time.Hour)
+
+	plaintext, token, err := authService.CreateAPIToken(user.Id, services.CreateAPITokenInput{
+		Name:        "CI nightly",
+		Scopes:      []string{services.ScopeBackupsCreate, services.ScopeBackupsRead, services.ScopeBackupsCreate},
+		DatabaseIDs: []uint{databases[0].Id},
+		ExpiresAt:   &expires,
+	}, "10.0.0.1", "")
+	require.NoError(t, err)
+	assert.True(t, len(plaintext) > 40 && plaintext[:3] == "sb_")
+	assert.Equal(t, plaintext[:9], token.Prefix)
+	assert.Equal(t, []string{services.ScopeBackupsCreate, services.ScopeBackupsRead}, token.ScopeList())
+
+	var stored models.APIToken
+	require.NoError(t, db.First(&stored, token.Id).Error)
+	assert.Equal(t, security.HashToken(plaintext), stored.TokenHash)
+	assert.NotContains(t, stored.TokenHash, plaintext[3:])
+	assert.Equal(t, int64(1), countActions(t, db, "api_token_created"))
+
+	validated, err := authService.ValidateAPIToken(plaintext, "192.0.2.10")
+	require.NoError(t, err)
+	assert.Equal(t, user.Email, validated.User.Email)
+	require.NoError(t, db.First(&stored, token.Id).Error)
+	require.NotNil(t, stored.LastUsedAt)
+	assert.Equal(t, "192.0.2.10", stored.LastUsedIP)
+
+	_, err = authService.ValidateAPIToken(plaintext+"x", "")
+	assert.ErrorIs(t, err, services.ErrInvalidAPIToken)
+}
+
+// TestAuthService_CreateAPITokenValidation tests the checks on scopes, databases and expiry
+func TestAuthService_CreateAPITokenValidation(t *testing.T) {
+	db, authService, user, _ := setupAPITokenTest(t)
+	other := createTestUser(db, "other@example.com", sessionTestPassword, 2)
+	foreign := models.Database{Name: "theirs", Type: "mysql", Host: "h", Port: "3306", Username: "u", Password: "p", DbName: "d", UserId: other.Id}
+	require.NoError(t, db.Create(&foreign).Error)
+	past := time.Now().Add(-time.Hour)
+
+	for name, input := range map[string]services.CreateAPITokenInput{
+		"no name":          {Scopes: []string{services.ScopeBackupsRead}},
+		"no scope":         {Name: "t"},
+		"unknown scope":    {Name: "t", Scopes: []string{"users:admin"}},
+		"foreign database": {Name: "t", Scopes: []string{services.ScopeBackupsRead}, DatabaseIDs: []uint{foreign.Id}},
+		"expired":          {Name: "t", Scopes: []string{services.ScopeBackupsRead}, ExpiresAt: &past},
+	} {
+		_, _, err := authService.CreateAPIToken(user.Id, input, "", "")
+		assert.Error(t, err, name)
+	}
+}
+
+// TestAuthMiddleware_APIToken tests scopes, database restrictions, denied routes, revocation and expiry
+func TestAuthMiddleware_APIToken(t *testing.T) {
+	db, authService, user, databases := setupAPITokenTest(t)
+	router := setupAPITokenRouter(authService)
+	prod, staging := strconv.Itoa(int(databases[0].Id)), strconv.Itoa(int(databases[1].Id))
+
+	plaintext, token, err := authService.CreateAPIToken(user.Id, services.CreateAPITokenInput{
+		Name: "CI", Scopes: []string{services.ScopeBackupsCreate}, DatabaseIDs: []uint{databases[1].Id},
+	}, "", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, sessionRequest(router, http.MethodPost, "/api/backups/database/"+staging, plaintext).Code)
+	assert.Equal(t, http.StatusForbidden, sessionRequest(router, http.MethodPost, "/api/backups/database/"+prod, plaintext).Code, "database outside the token")
+	assert.Equal(t, http.StatusBadRequest, sessionRequest(router, http.MethodPost, "/api/backups/database/"+staging+"x", plaintext).Code, "unparsable database id")
+	assert.Equal(t, http.StatusBadRequest, sessionRequest(router, http.MethodPost, "/api/backups/database/-1", plaintext).Code, "negative database id")
+	assert.Equal(t, http.StatusForbidden, sessionRequest(router, http.MethodGet, "/api/backups", plaintext).Code, "missing backups:read scope")
+	assert.Equal(t, http.StatusForbidden, sessionRequest(router, http.MethodDelete, "/api/backups/1", plaintext).Code, "deletion is never allowed")
+	assert.Equal(t, http.StatusForbidden, sessionRequest(router, http.MethodGet, "/api/profile", plaintext).Code, "profile is never allowed")
+
+	// Handlers filter the resources whose database is not in the URL
+	reader, _, err := authService.CreateAPIToken(user.Id, services.CreateAPITokenInput{
+		Name: "reader", Scopes: []string{services.ScopeBackupsRead}, DatabaseIDs: []uint{databases[1].Id},
+	}, "", "")
+	require.NoError(t, err)
+	w := sessionRequest(router, http.MethodGet, "/api/backups", reader)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":`+strconv.Itoa(int(user.Id))+`,"prod_allowed":false}`, w.Body.String())
+
+	require.NoError(t, authService.RevokeAPIToken(user.Id, token.Id, "", ""))
+	assert.Equal(t, http.StatusUnauthorized, sessionRequest(router, http.MethodPost, "/api/backups/database/"+staging, plaintext).Code)
+	assert.Error(t, authService.RevokeAPIToken(user.Id, token.Id, "", ""), "already revoked")
+
+	require.NoError(t, db.Model(&models.APIToken{}).Where("name = ?", "reader").Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	assert.Equal(t, http.StatusUnauthorized, sessionRequest(router, http.MethodGet, "/api/backups", reader).Code)
+
+	// A deactivated account cannot use its tokens
+	active, _, err := authService.CreateAPIToken(user.Id, services.CreateAPITokenInput{Name: "a", Scopes: []string{services.ScopeBackupsRead}}, "", "")
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.Id).Update("active", false).Error)
+	assert.Equal(t, http.StatusUnauthorized, sessionRequest(router, http.MethodGet, "/api/backups", active).Code)
+}
@@ -0,0 +1,213 @@
+package units
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// setupAuditChainTest returns an audit service anchoring into the returned mock cloud storage
+func setupAuditChainTest(t *testing.T) (*gorm.DB, *services.AuditService, *services.ActionHistoryService, *MockCloudStorage) {
+	db := setupAuthTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ActionHistory{}, &models.AuditAnchor{}, &models.Backup{}))
+
+	historyRepo := repositories.NewActionHistoryRepository(db)
+	backupService := services.NewBackupService(repositories.NewBackupRepository(db), nil, nil, t.TempDir())
+	mockCloud := NewMockCloudStorage()
+	backupService.SetCloudStorage(mockCloud)
+	return db, services.NewAuditService(historyRepo, backupService), services.NewActionHistoryService(historyRepo), mockCloud
+}
+
+// logAuditTestActions records actions of a user with metadata, IP address and user agent
+func logAuditTestActions(t *testing.T, historyService *services.ActionHistoryService, userID uint, count int) {
+	for i := 0; i < count; i++ {
+		require.NoError(t, historyService.LogAction(userID, "created", "database", uint(i+1), "Base créée",
+			map[string]interface{}{"database_name": "prod", "size": 1500000, "tags": []string{"a", "b"}}, "10.0.0.1", "Firefox"))
+	}
+}
+
+// verifyAuditChain runs a verification that must not fail technically
+func verifyAuditChain(t *testing.T, auditService *services.AuditService) *services.AuditVerification {
+	result, err := auditService.VerifyChain(context.Background())
+	require.NoError(t, err)
+	return result
+}
+
+// ============================================================================
+// UNIT TESTS - Tamper-evident action history
+// ============================================================================
+
+// TestAuditChain_DetectsTampering tests that edited, deleted and inserted rows break the chain at the right row
+func TestAuditChain_DetectsTampering(t *testing.T) {
+	db, auditService, historyService, _ := setupAuditChainTest(t)
+	user := createTestUser(db, "audit@example.com", "Password123!", 2)
+	logAuditTestActions(t, historyService, user.Id, 5)
+
+	var rows []models.ActionHistory
+	require.NoError(t, db.Order("id").Find(&rows).Error)
+	require.Len(t, rows, 5)
+	assert.Empty(t, rows[0].PrevHash)
+	assert.Equal(t, rows[0].Hash, rows[1].PrevHash)
+
+	result := verifyAuditChain(t, auditService)
+	assert.True(t, result.Valid, result.Reason)
+	assert.Equal(t, int64(5), result.Checked)
+	assert.Equal(t, rows[4].Id, result.Head.HistoryId)
+
+	// Edited content
+	require.NoError(t, db.Model(&models.ActionHistory{}).Where("id = ?", rows[2].Id).Update("description", "Rien").Error)
+	result = verifyAuditChain(t, auditService)
+	assert.False(t, result.Valid)
+	require.NotNil(t, result.BrokenAt)
+	assert.Equal(t, rows[2].Id, *result.BrokenAt)
+	require.NoError(t, db.Model(&models.ActionHistory{}).Where("id = ?", rows[2].Id).Update("description", rows[2].Description).Error)
+
+	// Edited IP address
+	require.NoError(t, db.Model(&models.ActionHistory{}).Where("id = ?", rows[1].Id).Update("ip_address", "192.168.1.1").Error)
+	result = verifyAuditChain(t, auditService)
+	assert.False(t, result.Valid)
+	assert.Equal(t, rows[1].Id, *result.BrokenAt)
+
+	// Blanked IP address without erasure of the account
+	require.NoError(t, db.Model(&models.ActionHistory{}).Where("id = ?", rows[1].Id).
+		Updates(map[string]interface{}{"ip_address": "", "user_agent": ""}).Error)
+	result = verifyAuditChain(t, auditService)
+	assert.False(t, result.Valid)
+	assert.Equal(t, rows[1].Id, *result.BrokenAt)
+	require.NoError(t, db.Model(&models.ActionHistory{}).Where("id = ?", rows[1].Id).
+		Updates(map[string]interface{}{"ip_address": "10.0.0.1", "user_agent": "Firefox"}).Error)
+	assert.True(t, verifyAuditChain(t, auditService).Valid)
+
+	// Deleted row: the next one no longer follows
+	require.NoError(t, db.Delete(&models.ActionHistory{}, rows[3].Id).Error)
+	result = verifyAuditChain(t, auditService)
+	assert.False(t, result.Valid)
+	assert.Equal(t, rows[4].Id, *result.BrokenAt)
+}
+
+// TestAuditChain_LegacyRowsAndErasure tests the chaining of existing rows and the anonymization of erased accounts
+func TestAuditChain_LegacyRowsAndErasure(t *testing.T) {
+	db, auditService, historyService, _ := setupAuditChainTest(t)
+	user := createTestUser(db, "legacy@example.com", "Password123!", 2)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, db.Create(&models.ActionHistory{UserId: user.Id, Action: "created", ResourceType: "database", ResourceId: 1,
+			Description: "Ancienne action", Metadata: `{"b": 1, "a": "x"}`, IpAddress: "10.0.0.2", CreatedAt: time.Now()}).Error)
+	}
+	result := verifyAuditChain(t, auditService)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(3), result.Unsealed)
+
+	historyRepo := repositories.NewActionHistoryRepository(db)
+	sealed, err := historyRepo.SealLegacyRows()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), sealed)
+	sealed, err = historyRepo.SealLegacyRows()
+	require.NoError(t, err)
+	assert.Zero(t, sealed, "rows are sealed once")
+
+	logAuditTestActions(t, historyService, user.Id, 2)
+	result = verifyAuditChain(t, auditService)
+	assert.True(t, result.Valid, result.Reason)
+	assert.Equal(t, int64(5), result.Checked)
+	assert.Zero(t, result.Unsealed)
+
+	// An unchained row inserted after the start of the chain
+	require.NoError(t, db.Exec("INSERT INTO action_histories (user_id, action, resource_type, resource_id, description, created_at) VALUES (?, 'deleted', 'database', 1, 'Faux', ?)",
+		user.Id, time.Now()).Error)
+	result = verifyAuditChain(t, auditService)
+	assert.False(t, result.Valid)
+	require.NoError(t, db.Where("description = ?", "Faux").Delete(&models.ActionHistory{}).Error)
+
+	// Client IP addresses in the metadata are covered like the IP address column
+	require.NoError(t, historyService.LogAction(user.Id, "session_revoked", "session", 1, "Session révoquée",
+		map[string]interface{}{"session_id": 1, "device_name": "Firefox sur Linux", "session_ip": "10.0.0.9"}, "", ""))
+	var revoked models.ActionHistory
+	require.NoError(t, db.Where("action = ?", "session_revoked").First(&revoked).Error)
+	original := revoked.Metadata
+	require.NoError(t, db.Model(&revoked).Update("metadata", `{"session_id": 1, "device_name": "Firefox sur Linux", "session_ip": "10.6.6.6"}`).Error)
+	result = verifyAuditChain(t, auditService)
+	assert.False(t, result.Valid)
+	assert.Equal(t, revoked.Id, *result.BrokenAt)
+	require.NoError(t, db.Model(&revoked).Update("metadata", `{"session_id": 1}`).Error)
+	assert.False(t, verifyAuditChain(t, auditService).Valid, "removed without an erasure")
+	require.NoError(t, db.Model(&revoked).Update("metadata", original).Error)
+
+	// Erasure of the account: blank IP addresses, user agents and privacy metadata are accepted
+	require.NoError(t, historyService.LogAction(user.Id, "account_erased", "user", user.Id, "Compte effacé", nil, "", ""))
+	_, err = repositories.NewUserDeletionRepository(db).AnonymizeActionHistory(user.Id)
+	require.NoError(t, err)
+	result = verifyAuditChain(t, auditService)
+	assert.True(t, result.Valid, result.Reason)
+	assert.Equal(t, int64(6), result.Anonymized)
+
+	require.NoError(t, db.First(&revoked, revoked.Id).Error)
+	assert.NotContains(t, revoked.Metadata, "10.0.0.9")
+	assert.NotContains(t, revoked.Metadata, "Firefox")
+	assert.Contains(t, revoked.Metadata, "session_id", "the rest of the metadata is kept")
+}
+
+// TestAuditChain_Anchoring tests that anchors detect deleted tail rows and a rewritten anchors table
+func TestAuditChain_Anchoring(t *testing.T) {
+	db, auditService, historyService, mockCloud := setupAuditChainTest(t)
+	user := createTestUser(db, "anchor@example.com", "Password123!", 2)
+
+	_, _, err := auditService.AnchorHead(context.Background())
+	assert.Error(t, err, "nothing to anchor")
+
+	logAuditTestActions(t, historyService, user.Id, 3)
+	anchor, created, err := auditService.AnchorHead(context.Background())
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, int64(3), anchor.Rows)
+	assert.Contains(t, mockCloud.uploadedFiles, anchor.RemotePath)
+	_, created, err = auditService.AnchorHead(context.Background())
+	require.NoError(t, err)
+	assert.False(t, created, "the head is already anchored")
+
+	logAuditTestActions(t, historyService, user.Id, 1)
+	result := verifyAuditChain(t, auditService)
+	assert.True(t, result.Valid, result.Reason)
+	assert.Equal(t, 1, result.AnchorsChecked)
+	assert.True(t, result.RemoteChecked)
+
+	// Deleting the end of the chain down to the anchored row leaves a consistent chain, but not the anchor
+	require.NoError(t, db.Where("id >= ?", anchor.HistoryId).Delete(&models.ActionHistory{}).Error)
+	result = verifyAuditChain(t, auditService)
+	assert.False(t, result.Valid)
+	assert.Equal(t, anchor.HistoryId, *result.BrokenAt)
+}
+
+// TestAuditChain_RemoteAnchorMismatch tests that a rewritten anchors table is detected through the stored copy
+func TestAuditChain_RemoteAnchorMismatch(t *testing.T) {
+	db, auditService, historyService, mockCloud := setupAuditChainTest(t)
+	user := createTestUser(db, "remote@example.com", "Password123!", 2)
+	logAuditTestActions(t, historyService, user.Id, 3)
+	anchor, _, err := auditService.AnchorHead(context.Background())
+	require.NoError(t, err)
+
+	// Rows deleted and the local anchor moved back: only the stored copy still knows the real head
+	require.NoError(t, db.Where("id = ?", anchor.HistoryId).Delete(&models.ActionHistory{}).Error)
+	var previous models.ActionHistory
+	require.NoError(t, db.Order("id DESC").First(&previous).Error)
+	require.NoError(t, db.Model(&models.AuditAnchor{}).Where("id = ?", anchor.Id).
+		Updates(map[string]interface{}{"history_id": previous.Id, "hash": previous.Hash}).Error)
+
+	result := verifyAuditChain(t, auditService)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Reason, "copie")
+
+	delete(mockCloud.uploadedFiles, anchor.RemotePath)
+	result = verifyAuditChain(t, auditService)
+	assert.False(t, result.Valid)
+}
@@ -0,0 +1,208 @@
+package units
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/auditlog"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/auditlog/syslogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// setupAuditForwardingTest returns an action history service forwarding to the given sinks
+func setupAuditForwardingTest(t *testing.T, options auditlog.Options, sinks ...auditlog.Sink) (*services.ActionHistoryService, *repositories.ActionHistoryRepository, *auditlog.Forwarder) {
+	db := setupHistoryTestDB(t)
+	historyRepo := repositories.NewActionHistoryRepository(db)
+	historyService := services.NewActionHistoryService(historyRepo)
+	forwarder := auditlog.NewForwarder(options, sinks...)
+	historyService.SetForwarder(forwarder)
+	t.Cleanup(func() { forwarder.Close(context.Background()) })
+	return historyService, historyRepo, forwarder
+}
+
+// newTestSyslogSink returns a sink sending to a local collector
+func newTestSyslogSink(t *testing.T, server *syslogtest.Server, format string) *auditlog.SyslogSink {
+	config := auditlog.SyslogConfig{Address: server.Addr, Network: server.Network, Format: format, Version: "1.2.3"}
+	if server.Network == auditlog.NetworkTLS {
+		config.TLSConfig = server.ClientTLSConfig()
+	}
+	sink, err := auditlog.NewSyslogSink(config)
+	require.NoError(t, err)
+	return sink
+}
+
+// blockingSink blocks every write until released, like an unresponsive collector
+type blockingSink struct {
+	release chan struct{}
+	mu      sync.Mutex
+	written int
+}
+
+func (s *blockingSink) Name() string { return "blocking" }
+func (s *blockingSink) Close() error { return nil }
+func (s *blockingSink) Write(e auditlog.Event) error {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written++
+	return nil
+}
+
+// flakySink fails a number of writes before accepting events
+type flakySink struct {
+	mu       sync.Mutex
+	failures int
+	events   []auditlog.Event
+}
+
+func (s *flakySink) Name() string { return "flaky" }
+func (s *flakySink) Close() error { return nil }
+func (s *flakySink) Write(e auditlog.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("collector unavailable")
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+// ============================================================================
+// UNIT TESTS - Action history forwarding
+// ============================================================================
+
+// TestAuditForwarding_SyslogUDP tests RFC 5424 messages over UDP, including the actions recorded through the repository
+func TestAuditForwarding_SyslogUDP(t *testing.T) {
+	server := syslogtest.NewServer(auditlog.NetworkUDP)
+	defer server.Close()
+	historyService, historyRepo, _ := setupAuditForwardingTest(t, auditlog.Options{}, newTestSyslogSink(t, server, auditlog.FormatRFC5424))
+
+	require.NoError(t, historyService.LogAction(1, "created", "database", 3, "Base \"prod\" ajoutée",
+		map[string]interface{}{"database_id": 3}, "10.0.0.1", "Firefox"))
+	require.NoError(t, historyRepo.Create(&models.ActionHistory{UserId: 2, Action: "role_changed", ResourceType: "user", ResourceId: 1,
+		Description: "Rôle modifié", AdminAction: true}))
+
+	messages := server.WaitForMessages(2, 5*time.Second)
+	require.Len(t, messages, 2)
+	assert.Regexp(t, `^<110>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}Z \S+ safebase \d+ created \[safebase@32473 id="1" user_id="1" action="created"`, messages[0])
+	assert.Contains(t, messages[0], `ip="10.0.0.1" user_agent="Firefox" metadata="{\"database_id\":3}" hash="`)
+	assert.Contains(t, messages[0], `] Base "prod" ajoutée`)
+	assert.Regexp(t, `^<109>1 .* role_changed \[safebase@32473 id="2" user_id="2"`, messages[1], "admin actions are notices")
+}
+
+// TestAuditForwarding_SyslogTLSWithCEF tests CEF messages over TLS and the reconnection after the collector closed the connection
+func TestAuditForwarding_SyslogTLSWithCEF(t *testing.T) {
+	server := syslogtest.NewServer(auditlog.NetworkTLS)
+	defer server.Close()
+	historyService, _, _ := setupAuditForwardingTest(t, auditlog.Options{RetryDelay: 10 * time.Millisecond},
+		newTestSyslogSink(t, server, auditlog.FormatCEF))
+
+	require.NoError(t, historyService.LogAction(1, "login_failed", "user", 1, "Échec | mot de passe", nil, "10.0.0.1", "curl"))
+	messages := server.WaitForMessages(1, 5*time.Second)
+	require.Len(t, messages, 1)
+	assert.Regexp(t, `^<108>1 .* login_failed - CEF:0\|SafeBase\|SafeBase\|1\.2\.3\|user:login_failed\|Échec \\\| mot de passe\|6\|`, messages[0])
+	assert.Contains(t, messages[0], "src=10.0.0.1")
+	assert.Contains(t, messages[0], "suid=1")
+	assert.Contains(t, messages[0], "requestClientApplication=curl")
+
+	server.DropConnections()
+	require.NoError(t, historyService.LogAction(1, "logout", "user", 1, "Déconnexion", map[string]interface{}{"reason": "a=b"}, "10.0.0.1", "curl"))
+	messages = server.WaitForMessages(2, 5*time.Second)
+	require.Len(t, messages, 2, "the sink reconnects")
+	assert.Contains(t, messages[1], `cs4={"reason":"a\=b"}`)
+}
+
+// TestAuditForwarding_SyslogTCP tests the octet-counting framing over TCP
+func TestAuditForwarding_SyslogTCP(t *testing.T) {
+	server := syslogtest.NewServer(auditlog.NetworkTCP)
+	defer server.Close()
+	historyService, _, _ := setupAuditForwardingTest(t, auditlog.Options{}, newTestSyslogSink(t, server, auditlog.FormatRFC5424))
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, historyService.LogAction(1, "executed", "schedule", 1, "Ligne\nsuivante", nil, "", ""))
+	}
+	messages := server.WaitForMessages(20, 5*time.Second)
+	require.Len(t, messages, 20)
+	for _, message := range messages {
+		assert.Contains(t, message, "] Ligne suivante", "one message per event, without line breaks")
+	}
+}
+
+// TestAuditForwarding_SlowSinkDoesNotBlock tests that an unresponsive collector neither delays the actions nor grows memory
+func TestAuditForwarding_SlowSinkDoesNotBlock(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	historyService, _, forwarder := setupAuditForwardingTest(t, auditlog.Options{BufferSize: 2}, sink)
+
+	started := time.Now()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, historyService.LogAction(1, "created", "database", uint(i), "Base ajoutée", nil, "", ""))
+	}
+	assert.Less(t, time.Since(started), 2*time.Second)
+	stats := forwarder.Stats()
+	require.Len(t, stats, 1)
+	assert.GreaterOrEqual(t, stats[0].Dropped, int64(7), "events beyond the queue are dropped")
+
+	close(sink.release)
+	require.NoError(t, forwarder.Close(context.Background()))
+	stats = forwarder.Stats()
+	assert.Equal(t, int64(10), stats[0].Sent+stats[0].Dropped)
+}
+
+// TestAuditForwarding_RetryAndFile tests the retries of a failing sink and the JSON lines file
+func TestAuditForwarding_RetryAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	fileSink, err := auditlog.NewFileSink(path)
+	require.NoError(t, err)
+	flaky := &flakySink{failures: 2}
+	historyService, _, forwarder := setupAuditForwardingTest(t, auditlog.Options{MaxRetries: 3, RetryDelay: time.Millisecond}, flaky, fileSink)
+
+	require.NoError(t, historyService.LogAction(1, "created", "database", 3, "Base ajoutée", map[string]interface{}{"database_id": 3}, "10.0.0.1", "Firefox"))
+	require.NoError(t, historyService.LogAction(1, "deleted", "database", 3, "Base supprimée", nil, "10.0.0.1", "Firefox"))
+	require.NoError(t, forwarder.Close(context.Background()))
+
+	require.Len(t, flaky.events, 2, "delivered after the retries")
+	assert.Equal(t, "created", flaky.events[0].Action)
+	for _, stats := range forwarder.Stats() {
+		assert.Equal(t, int64(2), stats.Sent, stats.Name)
+		assert.Zero(t, stats.Failed, stats.Name)
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var events []auditlog.Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event auditlog.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.Len(t, events, 2)
+	assert.Equal(t, float64(3), events[0].Metadata["database_id"])
+	assert.NotEmpty(t, events[1].Hash)
+	assert.Equal(t, auditlog.SeverityWarning, events[1].Severity())
+
+	// Failing past the retries drops the event
+	failing := &flakySink{failures: 10}
+	forwarder = auditlog.NewForwarder(auditlog.Options{MaxRetries: 1, RetryDelay: time.Millisecond}, failing)
+	forwarder.Publish(auditlog.Event{ID: 1, Action: "created"})
+	require.NoError(t, forwarder.Close(context.Background()))
+	assert.Equal(t, int64(1), forwarder.Stats()[0].Failed)
+	assert.Empty(t, failing.events)
+}
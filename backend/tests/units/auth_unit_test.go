@@ -26,7 +26,7 @@ func setupAuthTestDB(t *testing.T) *gorm.DB {
 	}
 
 	// Auto migrate the schema
-	err = db.AutoMigrate(&models.User{}, &models.Role{}, &models.Session{})
+	err = db.AutoMigrate(&models.User{}, &models.Role{}, &models.Session{}, &models.ConsumedRefreshToken{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
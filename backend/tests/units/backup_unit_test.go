@@ -1,6 +1,7 @@
 package units
 
 import (
+	"encoding/base64"
 	"os"
 	"testing"
 
@@ -220,10 +221,15 @@ func TestBackupService_DownloadBackup(t *testing.T) {
 	mockCloud := NewMockCloudStorage()
 	backupService.SetCloudStorage(mockCloud)
 
-	// Generate user key and encryption service
-	userKey := services.GenerateUserKey(1, "SafeBaseBackupSalt2025!")
+	// Backups written before key IDs existed are decrypted with the legacy salt of the service keyring
+	keyring, err := security.LoadKeyring(security.KeyringConfig{
+		Keys:             map[string]string{"download": base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))},
+		LegacyBackupSalt: "DownloadTestLegacySalt",
+	})
+	require.NoError(t, err)
+	backupService.SetKeyring(keyring)
+	userKey := services.GenerateUserKey(1, "DownloadTestLegacySalt")
 	encryptionService := services.NewEncryptionService(userKey)
-	backupService.SetEncryptionService(encryptionService)
 
 	// Create test backup
 	testBackup := createTestBackup(db, 1, 1, "completed")
@@ -233,7 +239,7 @@ func TestBackupService_DownloadBackup(t *testing.T) {
 	
 	// Create a temporary file to encrypt
 	tmpFile := "/tmp/test_backup_data.txt"
-	err := os.WriteFile(tmpFile, testData, 0644)
+	err = os.WriteFile(tmpFile, testData, 0644)
 	require.NoError(t, err, "Should write test file successfully")
 	defer os.Remove(tmpFile)
 	
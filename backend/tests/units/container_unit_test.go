@@ -0,0 +1,195 @@
+package units
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/container"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// sealContainer encrypts content with a small chunk size so that several chunks are written
+func sealContainer(t *testing.T, content []byte, header container.Header, dataKey []byte) []byte {
+	checksum, size, err := container.Checksum(bytes.NewReader(content))
+	require.NoError(t, err)
+	header.Checksum, header.PlaintextSize = checksum, size
+	if header.ChunkSize == 0 {
+		header.ChunkSize = 16
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, container.Encrypt(&out, bytes.NewReader(content), header, dataKey))
+	return out.Bytes()
+}
+
+// keyOf returns a static data key for keyFor callbacks
+func keyOf(key []byte) func(*container.Header) ([]byte, error) {
+	return func(*container.Header) ([]byte, error) { return key, nil }
+}
+
+// ============================================================================
+// UNIT TESTS - Container format
+// ============================================================================
+
+// TestContainer_RoundTrip tests the header and chunked content across chunk boundaries
+func TestContainer_RoundTrip(t *testing.T) {
+	dataKey, err := security.GenerateDataKey()
+	require.NoError(t, err)
+
+	for _, size := range []int{0, 1, 16, 17, 100} {
+		content := bytes.Repeat([]byte("x"), size)
+		sealed := sealContainer(t, content, container.Header{
+			KeyID:      "k1",
+			WrappedKey: "d3JhcHBlZA==",
+			Filename:   "shop_mysql_20250101_120000.zip",
+			DBType:     "mysql",
+		}, dataKey)
+		assert.True(t, container.IsContainer(sealed))
+
+		var out bytes.Buffer
+		header, err := container.Decrypt(&out, bytes.NewReader(sealed), keyOf(dataKey))
+		require.NoError(t, err, "size %d", size)
+		assert.Equal(t, string(content), out.String())
+		assert.Equal(t, container.Version, header.Version)
+		assert.Equal(t, container.CipherAES256GCM, header.Cipher)
+		assert.Equal(t, "k1", header.KeyID)
+		assert.Equal(t, 16, header.ChunkSize)
+		assert.Equal(t, "shop_mysql_20250101_120000.zip", header.Filename)
+		assert.Equal(t, "mysql", header.DBType)
+		assert.Equal(t, int64(size), header.PlaintextSize)
+		assert.True(t, strings.HasPrefix(header.Checksum, "sha256:"))
+	}
+
+	// The header is readable without any key
+	sealed := sealContainer(t, []byte("dump"), container.Header{KeyID: "k1", DBType: "postgresql"}, dataKey)
+	header, _, err := container.ReadHeader(bytes.NewReader(sealed))
+	require.NoError(t, err)
+	assert.Equal(t, "postgresql", header.DBType)
+}
+
+// TestContainer_RejectsTampering tests that header edits, truncation, wrong keys and foreign data are detected
+func TestContainer_RejectsTampering(t *testing.T) {
+	dataKey, err := security.GenerateDataKey()
+	require.NoError(t, err)
+	content := bytes.Repeat([]byte("0123456789"), 10)
+	sealed := sealContainer(t, content, container.Header{KeyID: "k1", DBType: "mysql"}, dataKey)
+
+	t.Run("header", func(t *testing.T) {
+		tampered := bytes.Replace(sealed, []byte(`"db_type":"mysql"`), []byte(`"db_type":"MYSQL"`), 1)
+		require.NotEqual(t, sealed, tampered)
+		_, err := container.Decrypt(&bytes.Buffer{}, bytes.NewReader(tampered), keyOf(dataKey))
+		assert.Error(t, err)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := container.Decrypt(&bytes.Buffer{}, bytes.NewReader(sealed[:len(sealed)-50]), keyOf(dataKey))
+		assert.Error(t, err)
+	})
+
+	t.Run("trailing data", func(t *testing.T) {
+		_, err := container.Decrypt(&bytes.Buffer{}, bytes.NewReader(append(append([]byte(nil), sealed...), 0)), keyOf(dataKey))
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		otherKey, err := security.GenerateDataKey()
+		require.NoError(t, err)
+		_, err = container.Decrypt(&bytes.Buffer{}, bytes.NewReader(sealed), keyOf(otherKey))
+		assert.Error(t, err)
+	})
+
+	t.Run("not a container", func(t *testing.T) {
+		_, err := container.Decrypt(&bytes.Buffer{}, bytes.NewReader([]byte("PK\x03\x04 plain zip")), keyOf(dataKey))
+		assert.ErrorIs(t, err, container.ErrNotContainer)
+	})
+}
+
+// TestContainer_OfflineDecryption tests that the header alone is enough to recover the data key
+func TestContainer_OfflineDecryption(t *testing.T) {
+	dataKey, err := security.GenerateDataKey()
+	require.NoError(t, err)
+
+	t.Run("master key", func(t *testing.T) {
+		keyring, err := security.NewKeyring("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+		require.NoError(t, err)
+		keyID, wrapped, err := keyring.WrapDataKey(dataKey)
+		require.NoError(t, err)
+		sealed := sealContainer(t, []byte("dump"), container.Header{KeyID: keyID, WrappedKey: base64.StdEncoding.EncodeToString(wrapped)}, dataKey)
+
+		var out bytes.Buffer
+		_, err = container.Decrypt(&out, bytes.NewReader(sealed), func(h *container.Header) ([]byte, error) {
+			wrapped, err := base64.StdEncoding.DecodeString(h.WrappedKey)
+			if err != nil {
+				return nil, err
+			}
+			return keyring.UnwrapDataKey(h.KeyID, wrapped)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "dump", out.String())
+	})
+
+	t.Run("passphrase", func(t *testing.T) {
+		passphraseKey, err := security.NewPassphraseKey(testPassphrase)
+		require.NoError(t, err)
+		sealedKey, err := security.SealToPublicKey(passphraseKey.PublicKey, dataKey)
+		require.NoError(t, err)
+		sealed := sealContainer(t, []byte("dump"), container.Header{
+			KeyID:      security.PassphraseKeyID,
+			WrappedKey: base64.StdEncoding.EncodeToString(sealedKey),
+			Passphrase: &container.PassphraseKey{
+				KDF:               passphraseKey.KDF,
+				WrappedPrivateKey: base64.StdEncoding.EncodeToString(passphraseKey.WrappedPrivateKey),
+			},
+		}, dataKey)
+
+		var out bytes.Buffer
+		_, err = container.Decrypt(&out, bytes.NewReader(sealed), func(h *container.Header) ([]byte, error) {
+			wrappedPrivateKey, err := base64.StdEncoding.DecodeString(h.Passphrase.WrappedPrivateKey)
+			if err != nil {
+				return nil, err
+			}
+			privateKey, err := (&security.PassphraseKey{KDF: h.Passphrase.KDF, WrappedPrivateKey: wrappedPrivateKey}).Unlock(testPassphrase)
+			if err != nil {
+				return nil, err
+			}
+			sealedKey, err := base64.StdEncoding.DecodeString(h.WrappedKey)
+			if err != nil {
+				return nil, err
+			}
+			return security.OpenWithPrivateKey(privateKey, sealedKey)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "dump", out.String())
+	})
+}
+
+// TestEncryptionService_ReadsBothFormats tests that objects written before the container format stay readable
+func TestEncryptionService_ReadsBothFormats(t *testing.T) {
+	dataKey, err := security.GenerateDataKey()
+	require.NoError(t, err)
+	encryption, err := services.NewEncryptionServiceWithKey(dataKey)
+	require.NoError(t, err)
+
+	raw, err := encryption.EncryptData([]byte("legacy object"))
+	require.NoError(t, err)
+	assert.False(t, container.IsContainer(raw))
+	plain, err := encryption.DecryptData(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy object", string(plain))
+
+	sealed, err := encryption.EncryptDataToContainer([]byte("container object"), container.Header{Filename: "a.zip"})
+	require.NoError(t, err)
+	assert.True(t, container.IsContainer(sealed))
+	plain, err = encryption.DecryptData(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "container object", string(plain))
+}
@@ -0,0 +1,179 @@
+package units
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/routes"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/version"
+	"github.com/RyanLadmia/plateforme-safebase/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS & MOCKS
+// ============================================================================
+
+// fakeWorkerPoolStats reports a fixed worker pool load
+type fakeWorkerPoolStats struct {
+	workers, active, queued, capacity int
+}
+
+func (f fakeWorkerPoolStats) Workers() int       { return f.workers }
+func (f fakeWorkerPoolStats) ActiveWorkers() int { return f.active }
+func (f fakeWorkerPoolStats) QueueLength() int   { return f.queued }
+func (f fakeWorkerPoolStats) QueueCapacity() int { return f.capacity }
+
+// setupHealthService wires a health service with a running scheduler and mock storage
+func setupHealthService(t *testing.T) (*services.HealthService, *MockCloudStorage, *services.ScheduleService) {
+	db := setupBackupTestDB(t)
+
+	backupRepo := repositories.NewBackupRepository(db)
+	backupService := services.NewBackupService(backupRepo, nil, nil, t.TempDir())
+	mockCloud := NewMockCloudStorage()
+	backupService.SetCloudStorage(mockCloud)
+
+	restoreService := services.NewRestoreService(repositories.NewRestoreRepository(db), backupService, nil, nil)
+	scheduleService := services.NewScheduleService(repositories.NewScheduleRepository(db), repositories.NewDatabaseRepository(db), backupService)
+	scheduleService.StartScheduler()
+	t.Cleanup(scheduleService.StopScheduler)
+
+	healthService := services.NewHealthService(db, backupService, restoreService, scheduleService)
+	healthService.SetWorkerPool(fakeWorkerPoolStats{workers: 5, capacity: 100})
+	return healthService, mockCloud, scheduleService
+}
+
+// ============================================================================
+// UNIT TESTS - Health checks
+// ============================================================================
+
+// TestHealthService_Ready tests that critical dependencies up make the service ready
+func TestHealthService_Ready(t *testing.T) {
+	healthService, _, _ := setupHealthService(t)
+
+	report := healthService.CheckReadiness(context.Background())
+
+	assert.True(t, report.Ready())
+	assert.Equal(t, services.HealthUp, report.Checks["database"].Status)
+	assert.Equal(t, services.HealthUp, report.Checks["storage"].Status)
+	assert.Equal(t, services.HealthSentinelPath, report.Checks["storage"].Details["sentinel"])
+	assert.Equal(t, services.HealthUp, report.Checks["worker_pool"].Status)
+	assert.Equal(t, services.HealthUp, report.Checks["scheduler"].Status)
+	for _, tool := range []string{"pg_dump", "mysqldump", "psql", "mysql"} {
+		assert.Contains(t, report.Checks, tool, "dump and restore clients should be reported")
+		assert.False(t, report.Checks[tool].Critical, "a missing client should not make the API unready")
+	}
+	assert.Equal(t, version.Get().Version, report.Version.Version)
+	assert.NotEmpty(t, report.Version.Version)
+}
+
+// TestHealthService_StorageUnreachable tests that a storage error fails readiness
+func TestHealthService_StorageUnreachable(t *testing.T) {
+	healthService, mockCloud, _ := setupHealthService(t)
+	mockCloud.shouldFailOnOp = "exists"
+
+	report := healthService.CheckReadiness(context.Background())
+
+	assert.False(t, report.Ready())
+	assert.Equal(t, services.HealthDown, report.Status)
+	assert.Equal(t, services.HealthDown, report.Checks["storage"].Status)
+	assert.NotEmpty(t, report.Checks["storage"].Error)
+}
+
+// TestHealthService_SchedulerStopped tests that a stopped scheduler fails readiness
+func TestHealthService_SchedulerStopped(t *testing.T) {
+	healthService, _, scheduleService := setupHealthService(t)
+	scheduleService.StopScheduler()
+
+	report := healthService.CheckReadiness(context.Background())
+
+	assert.False(t, report.Ready())
+	assert.Equal(t, services.HealthDown, report.Checks["scheduler"].Status)
+}
+
+// TestHealthService_WorkerPoolSaturation tests busy and saturated worker pools
+func TestHealthService_WorkerPoolSaturation(t *testing.T) {
+	healthService, _, _ := setupHealthService(t)
+
+	t.Run("Busy", func(t *testing.T) {
+		healthService.SetWorkerPool(fakeWorkerPoolStats{workers: 5, active: 5, queued: 10, capacity: 100})
+		report := healthService.CheckReadiness(context.Background())
+
+		assert.Equal(t, services.HealthDegraded, report.Checks["worker_pool"].Status)
+		assert.True(t, report.Ready(), "a busy pool degrades the service without making it unready")
+	})
+
+	t.Run("Saturated", func(t *testing.T) {
+		healthService.SetWorkerPool(fakeWorkerPoolStats{workers: 5, active: 5, queued: 100, capacity: 100})
+		report := healthService.CheckReadiness(context.Background())
+
+		assert.Equal(t, services.HealthDown, report.Checks["worker_pool"].Status)
+		assert.False(t, report.Ready())
+	})
+}
+
+// TestWorkerPool_Stats tests the load reported by the real worker pool
+func TestWorkerPool_Stats(t *testing.T) {
+	pool := utils.NewWorkerPool(2) // not started: tasks stay queued
+	pool.Submit(func() {})
+	pool.Submit(func() {})
+
+	assert.Equal(t, 2, pool.Workers())
+	assert.Equal(t, 0, pool.ActiveWorkers())
+	assert.Equal(t, 2, pool.QueueLength())
+	assert.Equal(t, 100, pool.QueueCapacity())
+}
+
+// TestHealthRoutes tests the HTTP status codes of the probes
+func TestHealthRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	healthService, mockCloud, _ := setupHealthService(t)
+	router := gin.New()
+	routes.SetupHealthRoutes(router, handlers.NewHealthHandler(healthService))
+
+	t.Run("Live", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "alive", body["status"])
+		assert.Equal(t, version.Get().Version, body["version"])
+	})
+
+	t.Run("Legacy health uses build version", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), `"1.0.0"`)
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Not ready", func(t *testing.T) {
+		mockCloud.shouldFailOnOp = "exists"
+		defer func() { mockCloud.shouldFailOnOp = "" }()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		var report services.HealthReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, services.HealthDown, report.Checks["storage"].Status)
+	})
+}
@@ -0,0 +1,135 @@
+package units
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// UNIT TESTS - Action history search and export
+// ============================================================================
+
+// TestActionHistoryService_SearchFilters tests the filters of the action history search and its cursor pagination
+func TestActionHistoryService_SearchFilters(t *testing.T) {
+	db := setupHistoryTestDB(t)
+	service := services.NewActionHistoryService(repositories.NewActionHistoryRepository(db))
+
+	require.NoError(t, service.LogAction(1, "created", "database", 3, "Base 'Prod' ajoutée", map[string]interface{}{"database_id": 3}, "10.0.0.1", "Firefox"))
+	require.NoError(t, service.LogAction(1, "created", "backup", 7, "Sauvegarde à 100% de 'prod'", map[string]interface{}{"database_id": 3}, "10.0.0.2", "Firefox"))
+	require.NoError(t, service.LogAction(1, "deleted", "backup", 8, "Sauvegarde de 'test' supprimée", map[string]interface{}{"database_id": 4}, "10.0.0.1", "Firefox"))
+	require.NoError(t, service.LogAction(1, "login", "user", 1, "Connexion", nil, "10.0.0.1", "Firefox"))
+	require.NoError(t, service.LogAction(2, "created", "database", 3, "Base 'Prod' ajoutée", map[string]interface{}{"database_id": 3}, "10.0.0.9", "Chrome"))
+
+	search := func(filter repositories.HistoryFilter) []services.ActionHistoryResponse {
+		histories, _, err := service.SearchActionHistory(filter)
+		require.NoError(t, err)
+		return histories
+	}
+
+	assert.Len(t, search(repositories.HistoryFilter{UserId: 1}), 4)
+	assert.Len(t, search(repositories.HistoryFilter{UserId: 1, Actions: []string{"created", "deleted"}}), 3)
+	assert.Len(t, search(repositories.HistoryFilter{ResourceType: "database", ResourceId: 3}), 2)
+	assert.Len(t, search(repositories.HistoryFilter{UserId: 1, IpAddress: "10.0.0.1"}), 3)
+	assert.Len(t, search(repositories.HistoryFilter{Search: "prod"}), 3, "the search ignores case")
+	assert.Len(t, search(repositories.HistoryFilter{Search: "100%"}), 1, "wildcards are searched literally")
+	assert.Len(t, search(repositories.HistoryFilter{UserId: 1, Metadata: map[string]string{"database_id": "3"}}), 2)
+	assert.Empty(t, search(repositories.HistoryFilter{Metadata: map[string]string{"database_id') OR 1=1 --": "3"}}))
+
+	// Date range
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, db.Model(&models.ActionHistory{}).Where("action = ?", "login").Update("created_at", past).Error)
+	from := time.Now().Add(-time.Minute)
+	assert.Len(t, search(repositories.HistoryFilter{UserId: 1, From: &from}), 3)
+	assert.Len(t, search(repositories.HistoryFilter{UserId: 1, To: &from}), 1)
+
+	// Cursor pagination walks every row once, most recent first
+	var ids []uint
+	filter := repositories.HistoryFilter{Limit: 2}
+	for pages := 0; pages < 5; pages++ {
+		histories, nextCursor, err := service.SearchActionHistory(filter)
+		require.NoError(t, err)
+		for _, history := range histories {
+			ids = append(ids, history.Id)
+		}
+		if nextCursor == 0 {
+			break
+		}
+		filter.Cursor = nextCursor
+	}
+	require.Len(t, ids, 5)
+	for i := 1; i < len(ids); i++ {
+		assert.Less(t, ids[i], ids[i-1])
+	}
+
+	total, err := service.CountActionHistory(repositories.HistoryFilter{Actions: []string{"created"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+}
+
+// TestActionHistoryService_ExportFormats tests the CSV, JSON and NDJSON exports over several batches
+func TestActionHistoryService_ExportFormats(t *testing.T) {
+	db := setupHistoryTestDB(t)
+	service := services.NewActionHistoryService(repositories.NewActionHistoryRepository(db))
+
+	require.NoError(t, service.LogAction(1, "created", "database", 1, "=HYPERLINK(\"http://evil\")", map[string]interface{}{"database_id": 1}, "10.0.0.1", "Firefox"))
+	for i := 0; i < 520; i++ {
+		require.NoError(t, service.LogAction(1, "executed", "schedule", 2, "Planification exécutée", nil, "10.0.0.1", "cron"))
+	}
+	require.NoError(t, service.LogAction(2, "created", "database", 5, "Autre utilisateur", nil, "10.0.0.2", "Chrome"))
+	filter := repositories.HistoryFilter{UserId: 1}
+
+	_, err := service.ExportActionHistory(&bytes.Buffer{}, "xml", filter, 1, "", "")
+	assert.ErrorIs(t, err, services.ErrInvalidExportFormat)
+
+	// CSV: a header, one record per row and no formula
+	var out bytes.Buffer
+	rows, err := service.ExportActionHistory(&out, services.HistoryExportCSV, filter, 1, "10.0.0.1", "test")
+	require.NoError(t, err)
+	assert.Equal(t, int64(521), rows)
+	records, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 522)
+	assert.Equal(t, "id", records[0][0])
+	first := records[len(records)-1]
+	assert.Equal(t, "'=HYPERLINK(\"http://evil\")", first[7])
+	assert.Equal(t, "test@example.com", first[3])
+	assert.JSONEq(t, `{"database_id": 1}`, first[8])
+
+	// NDJSON: one object per line, the previous export is part of the history
+	out.Reset()
+	rows, err = service.ExportActionHistory(&out, services.HistoryExportNDJSON, filter, 1, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(522), rows)
+	scanner := bufio.NewScanner(&out)
+	lines := 0
+	for scanner.Scan() {
+		var row map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &row))
+		assert.NotEmpty(t, row["hash"])
+		lines++
+	}
+	assert.Equal(t, 522, lines)
+
+	// JSON: a single array
+	out.Reset()
+	_, err = service.ExportActionHistory(&out, services.HistoryExportJSON, repositories.HistoryFilter{UserId: 2}, 1, "", "")
+	require.NoError(t, err)
+	var exported []map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &exported))
+	require.Len(t, exported, 1)
+	assert.Equal(t, "Autre utilisateur", exported[0]["description"])
+
+	var logged int64
+	db.Model(&models.ActionHistory{}).Where("action = ?", "history_exported").Count(&logged)
+	assert.Equal(t, int64(3), logged)
+}
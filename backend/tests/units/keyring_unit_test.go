@@ -0,0 +1,168 @@
+package units
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// testKey returns a base64 encoded 32-byte key filled with the given character
+func testKey(fill string) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(fill, security.KeySize)))
+}
+
+// useKeyring installs a keyring for the test and restores the previous one afterwards
+func useKeyring(t *testing.T, keyring *security.Keyring) {
+	previous, _ := security.DefaultKeyring()
+	security.SetDefaultKeyring(keyring)
+	t.Cleanup(func() { security.SetDefaultKeyring(previous) })
+}
+
+// ============================================================================
+// UNIT TESTS - Keyring
+// ============================================================================
+
+// TestKeyring_LoadFailsFast tests that missing or invalid keys are rejected at load time
+func TestKeyring_LoadFailsFast(t *testing.T) {
+	t.Run("No key", func(t *testing.T) {
+		_, err := security.LoadKeyring(security.KeyringConfig{})
+		assert.ErrorIs(t, err, security.ErrKeyringNotConfigured)
+	})
+
+	t.Run("Only a legacy key", func(t *testing.T) {
+		_, err := security.LoadKeyring(security.KeyringConfig{LegacyKey: "your-32-byte-secret-key-here!!!!"})
+		assert.Error(t, err, "a legacy key alone cannot encrypt new values")
+	})
+
+	t.Run("Invalid base64", func(t *testing.T) {
+		_, err := security.LoadKeyring(security.KeyringConfig{Keys: map[string]string{"k1": "not base64!"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("Wrong key size", func(t *testing.T) {
+		_, err := security.LoadKeyring(security.KeyringConfig{Keys: map[string]string{"k1": base64.StdEncoding.EncodeToString([]byte("short"))}})
+		assert.Error(t, err)
+	})
+
+	t.Run("Several keys without primary", func(t *testing.T) {
+		_, err := security.LoadKeyring(security.KeyringConfig{Keys: map[string]string{"k1": testKey("a"), "k2": testKey("b")}})
+		assert.ErrorIs(t, err, security.ErrKeyringNotConfigured)
+	})
+
+	t.Run("Unknown primary", func(t *testing.T) {
+		_, err := security.LoadKeyring(security.KeyringConfig{Keys: map[string]string{"k1": testKey("a")}, PrimaryKeyID: "k9"})
+		assert.Error(t, err)
+	})
+}
+
+// TestKeyring_LoadKeyFile tests loading the keyring from a JSON key file
+func TestKeyring_LoadKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	content := `{"primary": "2025-02", "keys": {"2025-01": "` + testKey("a") + `", "2025-02": "` + testKey("b") + `"}, "legacy_backup_salt": "old-salt"}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	keyring, err := security.LoadKeyring(security.KeyringConfig{KeyFile: path})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-02", keyring.PrimaryKeyID())
+	assert.Equal(t, []string{"2025-01", "2025-02"}, keyring.KeyIDs())
+	assert.Equal(t, "old-salt", keyring.LegacyBackupSalt())
+}
+
+// TestKeyring_CredentialsCarryKeyID tests that encrypted credentials stay readable after a rotation
+func TestKeyring_CredentialsCarryKeyID(t *testing.T) {
+	oldKeyring, err := security.LoadKeyring(security.KeyringConfig{Keys: map[string]string{"k1": testKey("a")}})
+	require.NoError(t, err)
+	useKeyring(t, oldKeyring)
+
+	encrypted, err := security.EncryptDatabasePassword("s3cret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, "enc:k1:"), "ciphertext should be prefixed with its key ID")
+	assert.Equal(t, "k1", security.EncryptedValueKeyID(encrypted))
+
+	// Rotate: k2 becomes primary, k1 is kept to read existing values
+	rotated, err := security.LoadKeyring(security.KeyringConfig{
+		Keys:         map[string]string{"k1": testKey("a"), "k2": testKey("b")},
+		PrimaryKeyID: "k2",
+	})
+	require.NoError(t, err)
+	security.SetDefaultKeyring(rotated)
+
+	decrypted, err := security.DecryptDatabasePassword(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", decrypted)
+
+	reEncrypted, err := security.EncryptDatabasePassword("s3cret")
+	require.NoError(t, err)
+	assert.Equal(t, "k2", security.EncryptedValueKeyID(reEncrypted))
+
+	// Without k1 the old value can no longer be read
+	withoutOld, err := security.LoadKeyring(security.KeyringConfig{Keys: map[string]string{"k2": testKey("b")}})
+	require.NoError(t, err)
+	security.SetDefaultKeyring(withoutOld)
+	_, err = security.DecryptDatabasePassword(encrypted)
+	assert.Error(t, err)
+}
+
+// TestKeyring_LegacyCredentials tests that values stored before key IDs existed are read with the legacy key
+func TestKeyring_LegacyCredentials(t *testing.T) {
+	legacyKey := "your-32-byte-secret-key-here!!!!"
+
+	// Legacy format: base64(nonce || AES-GCM ciphertext) without prefix
+	block, err := aes.NewCipher([]byte(legacyKey))
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, gcm.NonceSize())
+	legacyValue := base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte("old-password"), nil))
+
+	keyring, err := security.LoadKeyring(security.KeyringConfig{
+		Keys:      map[string]string{"k1": testKey("a")},
+		LegacyKey: legacyKey,
+	})
+	require.NoError(t, err)
+	useKeyring(t, keyring)
+
+	assert.Equal(t, security.LegacyKeyID, security.EncryptedValueKeyID(legacyValue))
+	decrypted, err := security.DecryptDatabasePassword(legacyValue)
+	require.NoError(t, err)
+	assert.Equal(t, "old-password", decrypted)
+}
+
+// TestKeyring_NotConfigured tests that encryption fails instead of silently using a built-in key
+func TestKeyring_NotConfigured(t *testing.T) {
+	useKeyring(t, nil)
+
+	_, err := security.EncryptDatabasePassword("s3cret")
+	assert.ErrorIs(t, err, security.ErrKeyringNotConfigured)
+}
+
+// TestBackupService_UnknownKeyID tests that a backup sealed with a removed key is reported as such
+func TestBackupService_UnknownKeyID(t *testing.T) {
+	db := setupBackupTestDB(t)
+	backupRepo := repositories.NewBackupRepository(db)
+	backupService := services.NewBackupService(backupRepo, nil, nil, t.TempDir())
+	mockCloud := NewMockCloudStorage()
+	backupService.SetCloudStorage(mockCloud)
+
+	backup := createTestBackup(db, 1, 1, "completed")
+	require.NoError(t, backupRepo.UpdateEncryptionKey(backup.Id, "retired-key", base64.StdEncoding.EncodeToString([]byte("wrapped"))))
+	mockCloud.uploadedFiles[backup.Filepath] = []byte("encrypted")
+
+	_, err := backupService.DownloadBackup(backup.Id, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "retired-key")
+}
@@ -0,0 +1,148 @@
+package units
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// countActions counts the history records of an action
+func countActions(t *testing.T, db *gorm.DB, action string) int64 {
+	var count int64
+	require.NoError(t, db.Model(&models.ActionHistory{}).Where("action = ?", action).Count(&count).Error)
+	return count
+}
+
+// lockedUntil reads the lockout date of a user
+func lockedUntil(t *testing.T, db *gorm.DB, userID uint) *time.Time {
+	var user models.User
+	require.NoError(t, db.First(&user, userID).Error)
+	return user.LockedUntil
+}
+
+// ============================================================================
+// UNIT TESTS - Login protection
+// ============================================================================
+
+// TestAuthService_AccountLockout tests that an account is locked after repeated failures and unlocked by an admin
+func TestAuthService_AccountLockout(t *testing.T) {
+	db, authService, _, user := setupSessionTest(t)
+	authService.SetLoginProtection(models.LoginProtectionConfig{MaxAttempts: 3, LockoutDuration: time.Minute, MaxLockout: time.Hour})
+	meta := services.SessionMetadata{IPAddress: "10.0.0.1"}
+
+	for i := 0; i < 3; i++ {
+		_, err := authService.LoginWithMetadata(user.Email, "WrongP@ssw0rd", meta)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, services.ErrTooManyLoginAttempts), "attempt %d must not be locked yet", i+1)
+	}
+
+	// Even the right password is refused during the lockout
+	_, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, meta)
+	var locked *services.LoginLockedError
+	require.True(t, errors.As(err, &locked), "expected a lockout, got %v", err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), locked.Until, 5*time.Second)
+
+	assert.Equal(t, int64(3), countActions(t, db, "login_failed"))
+	assert.Equal(t, int64(1), countActions(t, db, "account_locked"))
+	assert.Equal(t, int64(1), countActions(t, db, "login_blocked"))
+
+	require.NoError(t, authService.UnlockAccount(1, user.Id, "", ""))
+	assert.Equal(t, int64(1), countActions(t, db, "account_unlocked"))
+	_, err = authService.LoginWithMetadata(user.Email, sessionTestPassword, meta)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), countActions(t, db, "login_succeeded"))
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.Id).Error)
+	assert.Equal(t, 0, stored.FailedLoginAttempts)
+	assert.Nil(t, stored.LockedUntil)
+}
+
+// TestAuthService_LockoutBackoff tests that the lockout doubles when failures continue after it expires
+func TestAuthService_LockoutBackoff(t *testing.T) {
+	db, authService, _, user := setupSessionTest(t)
+	authService.SetLoginProtection(models.LoginProtectionConfig{MaxAttempts: 2, LockoutDuration: time.Minute, MaxLockout: 3 * time.Minute})
+
+	fail := func() {
+		_, err := authService.LoginWithMetadata(user.Email, "WrongP@ssw0rd", services.SessionMetadata{})
+		require.Error(t, err)
+	}
+	expire := func() {
+		require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.Id).Update("locked_until", time.Now().Add(-time.Second)).Error)
+	}
+
+	fail()
+	fail()
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *lockedUntil(t, db, user.Id), 5*time.Second)
+
+	expire()
+	fail()
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), *lockedUntil(t, db, user.Id), 5*time.Second)
+
+	expire()
+	fail()
+	assert.WithinDuration(t, time.Now().Add(3*time.Minute), *lockedUntil(t, db, user.Id), 5*time.Second, "lockout is capped")
+}
+
+// TestAuthService_IPThrottle tests that an address failing on many accounts is blocked without locking other clients
+func TestAuthService_IPThrottle(t *testing.T) {
+	_, authService, _, user := setupSessionTest(t)
+	authService.SetLoginProtection(models.LoginProtectionConfig{IPMaxAttempts: 3, IPWindow: time.Minute, LockoutDuration: time.Minute, MaxLockout: time.Hour})
+	attacker := services.SessionMetadata{IPAddress: "203.0.113.7"}
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		_, err := authService.LoginWithMetadata(email, "whatever", attacker)
+		assert.EqualError(t, err, "invalid email or password")
+	}
+
+	_, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, attacker)
+	assert.ErrorIs(t, err, services.ErrTooManyLoginAttempts)
+
+	_, err = authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{IPAddress: "10.0.0.1"})
+	assert.NoError(t, err, "other addresses are not affected")
+}
+
+// TestAuthService_NewIPLoginAlert tests that the user is warned of a login from an unusual address
+func TestAuthService_NewIPLoginAlert(t *testing.T) {
+	_, authService, _, user := setupSessionTest(t)
+	mail := newFakeMailer()
+	authService.SetMailer(mail, "https://safebase.test/reset-password")
+	config := models.DefaultLoginProtectionConfig()
+	config.NotifyUser = true
+	authService.SetLoginProtection(config)
+
+	login := func(ip string) {
+		_, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{IPAddress: ip})
+		require.NoError(t, err)
+	}
+	noMail := func() {
+		select {
+		case msg := <-mail.sent:
+			t.Fatalf("unexpected email: %s", msg.Subject)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	login("10.0.0.1") // First login: nothing to compare with
+	noMail()
+	login("10.0.0.1")
+	noMail()
+	login("198.51.100.4")
+	select {
+	case msg := <-mail.sent:
+		assert.Equal(t, user.Email, msg.To)
+		assert.Contains(t, msg.Body, "198.51.100.4")
+	case <-time.After(2 * time.Second):
+		t.Fatal("new address email was not sent")
+	}
+}
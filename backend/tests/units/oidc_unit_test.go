@@ -0,0 +1,188 @@
+package units
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/routes"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/oidc"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/oidc/oidctest"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+const oidcTestCallback = "http://safebase.test/auth/oidc/callback"
+
+// setupOIDCTest connects the auth service to a local mock identity provider and exposes the auth routes
+func setupOIDCTest(t *testing.T) (*gorm.DB, *services.AuthService, *oidctest.Server, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	db, authService, _, _ := setupSessionTest(t)
+	require.NoError(t, db.AutoMigrate(&models.Setting{}))
+	authService.SetSettingRepository(repositories.NewSettingRepository(db))
+
+	idp := oidctest.NewServer("safebase", "s3cret")
+	t.Cleanup(idp.Close)
+	provider, err := oidc.NewProvider(context.Background(), idp.Config(oidcTestCallback), nil)
+	require.NoError(t, err)
+	authService.SetOIDCProvider(provider, []services.OIDCGroupRole{
+		{Group: "safebase-admins", Role: "admin"},
+		{Group: "safebase-users", Role: "user"},
+	})
+
+	handler := handlers.NewAuthHandler(authService)
+	handler.SetFrontendURL("http://frontend.test")
+	router := gin.New()
+	routes.AuthRoutes(router, handler, "test-secret-key")
+	return db, authService, idp, router
+}
+
+// ssoLogin runs the browser side of the flow: SafeBase → mock IdP → SafeBase callback
+func ssoLogin(t *testing.T, router *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	authURL := w.Header().Get("Location")
+	var stateCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "oidc_state" {
+			stateCookie = cookie
+		}
+	}
+	require.NotNil(t, stateCookie)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// hasCookie reports whether a response sets a cookie
+func hasCookie(w *httptest.ResponseRecorder, name string) bool {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name && cookie.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// UNIT TESTS - OpenID Connect single sign-on
+// ============================================================================
+
+// TestOIDC_FirstLoginCreatesUser tests account creation on first SSO login with the role mapped from groups
+func TestOIDC_FirstLoginCreatesUser(t *testing.T) {
+	db, _, idp, router := setupOIDCTest(t)
+	idp.SetUser(oidctest.User{Subject: "idp-42", Email: "jane@corp.test", EmailVerified: true,
+		GivenName: "Jane", FamilyName: "Doe", Groups: []string{"staff", "safebase-admins"}})
+
+	w := ssoLogin(t, router)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "http://frontend.test/", w.Header().Get("Location"))
+	assert.True(t, hasCookie(w, "auth_token"))
+	assert.True(t, hasCookie(w, "refresh_token"))
+
+	var user models.User
+	require.NoError(t, db.Preload("Role").Where("email = ?", "jane@corp.test").First(&user).Error)
+	assert.Equal(t, "Jane", user.Firstname)
+	require.NotNil(t, user.OIDCSubject)
+	assert.Equal(t, "idp-42", *user.OIDCSubject)
+	assert.Equal(t, "admin", user.Role.Name)
+
+	// Second login: same account, role follows the groups
+	idp.SetUser(oidctest.User{Subject: "idp-42", Email: "jane@corp.test", EmailVerified: true, Groups: []string{"safebase-users"}})
+	assert.True(t, hasCookie(ssoLogin(t, router), "auth_token"))
+	var count int64
+	db.Model(&models.User{}).Where("email = ?", "jane@corp.test").Count(&count)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, db.Preload("Role").First(&user, user.Id).Error)
+	assert.Equal(t, "user", user.Role.Name)
+	assert.Equal(t, int64(1), countActions(t, db, "role_synced"))
+}
+
+// TestOIDC_LinksExistingAccount tests that a verified email links the existing local account
+func TestOIDC_LinksExistingAccount(t *testing.T) {
+	db, _, idp, router := setupOIDCTest(t)
+
+	// Unverified email: no link
+	idp.SetUser(oidctest.User{Subject: "idp-7", Email: "sessions@example.com", EmailVerified: false})
+	w := ssoLogin(t, router)
+	assert.Contains(t, w.Header().Get("Location"), "/login?sso_error=")
+	assert.False(t, hasCookie(w, "auth_token"))
+
+	idp.SetUser(oidctest.User{Subject: "idp-7", Email: "sessions@example.com", EmailVerified: true})
+	assert.True(t, hasCookie(ssoLogin(t, router), "auth_token"))
+	assert.Equal(t, int64(1), countActions(t, db, "oidc_account_linked"))
+
+	// Another identity with the same email cannot take over the account
+	idp.SetUser(oidctest.User{Subject: "idp-8", Email: "sessions@example.com", EmailVerified: true})
+	w = ssoLogin(t, router)
+	assert.Contains(t, w.Header().Get("Location"), "/login?sso_error=")
+}
+
+// TestOIDC_CallbackRejectsForgedState tests that the callback needs the state cookie of the browser
+func TestOIDC_CallbackRejectsForgedState(t *testing.T) {
+	_, _, _, router := setupOIDCTest(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+	state, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+
+	// Same state, but without the cookie (e.g. a link sent to the victim)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?code=x&state="+state.Query().Get("state"), nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Contains(t, w.Header().Get("Location"), "/login?sso_error=")
+	assert.False(t, hasCookie(w, "auth_token"))
+}
+
+// TestAuthService_LocalLoginDisabled tests that only SSO (and admins as break-glass) can log in once disabled
+func TestAuthService_LocalLoginDisabled(t *testing.T) {
+	db, authService, _, _ := setupOIDCTest(t)
+	createTestUser(db, "admin@example.com", sessionTestPassword, 1)
+
+	require.NoError(t, authService.SetLocalLoginEnabled(1, false, "", ""))
+	assert.False(t, authService.GetAuthSettings().LocalLoginEnabled)
+
+	_, err := authService.LoginWithMetadata("sessions@example.com", sessionTestPassword, services.SessionMetadata{})
+	assert.ErrorIs(t, err, services.ErrLocalLoginDisabled)
+	_, err = authService.LoginWithMetadata("admin@example.com", sessionTestPassword, services.SessionMetadata{})
+	assert.NoError(t, err, "administrators keep a break-glass password login")
+
+	// A wrong password gets the same answer whatever the account, so administrator emails cannot be told apart
+	_, adminErr := authService.LoginWithMetadata("admin@example.com", "Wrong-Passw0rd", services.SessionMetadata{})
+	_, userErr := authService.LoginWithMetadata("sessions@example.com", "Wrong-Passw0rd", services.SessionMetadata{})
+	_, unknownErr := authService.LoginWithMetadata("nobody@example.com", "Wrong-Passw0rd", services.SessionMetadata{})
+	require.Error(t, adminErr)
+	assert.Equal(t, adminErr.Error(), userErr.Error())
+	assert.Equal(t, adminErr.Error(), unknownErr.Error())
+	assert.ErrorIs(t, authService.Register(&models.User{Email: "new@example.com", Password: sessionTestPassword}), services.ErrLocalLoginDisabled)
+
+	require.NoError(t, authService.SetLocalLoginEnabled(1, true, "", ""))
+	_, err = authService.LoginWithMetadata("sessions@example.com", sessionTestPassword, services.SessionMetadata{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), countActions(t, db, "local_login_disabled"))
+}
@@ -0,0 +1,201 @@
+package units
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+const testPassphrase = "correct horse battery staple"
+
+// setupPassphraseTest enables zero-knowledge mode on the test database and returns the wired services
+func setupPassphraseTest(t *testing.T) (*gorm.DB, *services.DatabaseService, *services.BackupService, *MockCloudStorage) {
+	db := setupBackupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Restore{}, &models.Schedule{}))
+
+	databaseRepo := repositories.NewDatabaseRepository(db)
+	backupRepo := repositories.NewBackupRepository(db)
+	databaseService := services.NewDatabaseService(databaseRepo, backupRepo, repositories.NewRestoreRepository(db), repositories.NewScheduleRepository(db), nil)
+	backupService := services.NewBackupService(backupRepo, databaseService, nil, t.TempDir())
+	databaseService.SetBackupService(backupService)
+	mockCloud := NewMockCloudStorage()
+	backupService.SetCloudStorage(mockCloud)
+
+	require.NoError(t, databaseService.SetBackupPassphrase(1, 1, testPassphrase, "", "", ""))
+	return db, databaseService, backupService, mockCloud
+}
+
+// createZeroKnowledgeBackup stores a backup whose data key is sealed to the database public key, like the backup job does
+func createZeroKnowledgeBackup(t *testing.T, db *gorm.DB, mockCloud *MockCloudStorage, content string) *models.Backup {
+	var database models.Database
+	require.NoError(t, db.First(&database, 1).Error)
+	publicKey, err := base64.StdEncoding.DecodeString(database.PassphrasePublicKey)
+	require.NoError(t, err)
+
+	dataKey, err := security.GenerateDataKey()
+	require.NoError(t, err)
+	sealed, err := security.SealToPublicKey(publicKey, dataKey)
+	require.NoError(t, err)
+
+	encryption, err := services.NewEncryptionServiceWithKey(dataKey)
+	require.NoError(t, err)
+	plainFile := filepath.Join(t.TempDir(), "dump.zip")
+	require.NoError(t, os.WriteFile(plainFile, []byte(content), 0600))
+	encrypted, err := encryption.EncryptFile(plainFile)
+	require.NoError(t, err)
+
+	backup := createTestBackup(db, 1, 1, "completed")
+	backup.KeyID = security.PassphraseKeyID
+	backup.WrappedKey = base64.StdEncoding.EncodeToString(sealed)
+	require.NoError(t, db.Save(backup).Error)
+	mockCloud.uploadedFiles[backup.Filepath] = encrypted
+	return backup
+}
+
+// ============================================================================
+// UNIT TESTS - Zero-knowledge backups
+// ============================================================================
+
+// TestPassphraseKey_KeyCheck tests that the key-check value rejects wrong passphrases before decryption
+func TestPassphraseKey_KeyCheck(t *testing.T) {
+	_, err := security.NewPassphraseKey("too short")
+	assert.Error(t, err)
+
+	key, err := security.NewPassphraseKey(testPassphrase)
+	require.NoError(t, err)
+	assert.Contains(t, key.KDF, "argon2id$")
+	assert.NotContains(t, string(key.WrappedPrivateKey), testPassphrase)
+
+	_, err = key.Unlock("")
+	assert.ErrorIs(t, err, security.ErrPassphraseRequired)
+	_, err = key.Unlock("wrong passphrase!")
+	assert.ErrorIs(t, err, security.ErrWrongPassphrase)
+
+	privateKey, err := key.Unlock(testPassphrase)
+	require.NoError(t, err)
+
+	dataKey, err := security.GenerateDataKey()
+	require.NoError(t, err)
+	sealed, err := security.SealToPublicKey(key.PublicKey, dataKey)
+	require.NoError(t, err)
+	opened, err := security.OpenWithPrivateKey(privateKey, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, opened)
+
+	// Changing the passphrase keeps the key pair: sealed keys stay readable
+	changed, err := key.ChangePassphrase(testPassphrase, "another long passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey, changed.PublicKey)
+	_, err = changed.Unlock(testPassphrase)
+	assert.ErrorIs(t, err, security.ErrWrongPassphrase)
+	privateKey, err = changed.Unlock("another long passphrase")
+	require.NoError(t, err)
+	opened, err = security.OpenWithPrivateKey(privateKey, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, opened)
+}
+
+// TestBackupService_ZeroKnowledgeDownload tests that a protected backup requires its passphrase
+func TestBackupService_ZeroKnowledgeDownload(t *testing.T) {
+	db, databaseService, backupService, mockCloud := setupPassphraseTest(t)
+	backup := createZeroKnowledgeBackup(t, db, mockCloud, "secret dump")
+
+	var database models.Database
+	require.NoError(t, db.First(&database, 1).Error)
+	assert.True(t, database.PassphraseProtected)
+	assert.NotContains(t, database.PassphraseKDF+database.PassphrasePrivateKey, testPassphrase, "the passphrase is never stored")
+
+	_, err := backupService.DownloadBackup(backup.Id, 1)
+	assert.ErrorIs(t, err, security.ErrPassphraseRequired, "the master keys alone cannot decrypt the backup")
+
+	_, err = backupService.DownloadBackupWithPassphraseContext(context.Background(), backup.Id, 1, "wrong passphrase!")
+	assert.ErrorIs(t, err, security.ErrWrongPassphrase)
+
+	data, err := backupService.DownloadBackupWithPassphraseContext(context.Background(), backup.Id, 1, testPassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, "secret dump", string(data))
+
+	t.Run("Change passphrase", func(t *testing.T) {
+		err := databaseService.SetBackupPassphrase(1, 1, "a brand new passphrase", "wrong passphrase!", "", "")
+		assert.ErrorIs(t, err, security.ErrWrongPassphrase)
+
+		require.NoError(t, databaseService.SetBackupPassphrase(1, 1, "a brand new passphrase", testPassphrase, "", ""))
+		data, err := backupService.DownloadBackupWithPassphraseContext(context.Background(), backup.Id, 1, "a brand new passphrase")
+		require.NoError(t, err)
+		assert.Equal(t, "secret dump", string(data))
+	})
+
+	t.Run("Disable keeps existing backups protected", func(t *testing.T) {
+		require.NoError(t, databaseService.DisableBackupPassphrase(1, 1, "a brand new passphrase", "", ""))
+
+		require.NoError(t, db.First(&database, 1).Error)
+		assert.False(t, database.PassphraseProtected)
+		_, err := backupService.DownloadBackup(backup.Id, 1)
+		assert.ErrorIs(t, err, security.ErrPassphraseRequired)
+	})
+
+	t.Run("Other users cannot change the passphrase", func(t *testing.T) {
+		err := databaseService.SetBackupPassphrase(1, 2, "attacker passphrase", "", "", "")
+		assert.Error(t, err)
+	})
+}
+
+// TestRestoreService_ZeroKnowledgeRejectsWrongPassphraseEarly tests that no restore job is queued with a wrong passphrase
+func TestRestoreService_ZeroKnowledgeRejectsWrongPassphraseEarly(t *testing.T) {
+	db, databaseService, backupService, mockCloud := setupPassphraseTest(t)
+	backup := createZeroKnowledgeBackup(t, db, mockCloud, "secret dump")
+	restoreService := services.NewRestoreService(repositories.NewRestoreRepository(db), backupService, databaseService, nil)
+
+	_, err := restoreService.CreateRestoreWithPassphraseContext(context.Background(), backup.Id, 1, 1, "wrong passphrase!", "", "")
+	assert.ErrorIs(t, err, security.ErrWrongPassphrase)
+	_, err = restoreService.CreateRestoreContext(context.Background(), backup.Id, 1, 1, "", "")
+	assert.ErrorIs(t, err, security.ErrPassphraseRequired)
+
+	var count int64
+	db.Model(&models.Restore{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+// TestBackupHandler_DownloadPassphraseStatus tests the HTTP status of missing and wrong passphrases
+func TestBackupHandler_DownloadPassphraseStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, backupService, mockCloud := setupPassphraseTest(t)
+	backup := createZeroKnowledgeBackup(t, db, mockCloud, "secret dump")
+
+	router := gin.New()
+	router.GET("/api/backups/download/:id", func(c *gin.Context) { c.Set("user_id", uint(1)) }, handlers.NewBackupHandler(backupService).DownloadBackup)
+	download := func(passphrase string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/backups/download/"+strconv.FormatUint(uint64(backup.Id), 10), nil)
+		if passphrase != "" {
+			req.Header.Set(handlers.PassphraseHeader, passphrase)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, download("").Code)
+	assert.Equal(t, http.StatusForbidden, download("wrong passphrase!").Code)
+	w := download(testPassphrase)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "secret dump", w.Body.String())
+}
@@ -0,0 +1,162 @@
+package units
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/mailer"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// fakeMailer records the messages it is asked to send
+type fakeMailer struct {
+	sent chan mailer.Message
+}
+
+func newFakeMailer() *fakeMailer {
+	return &fakeMailer{sent: make(chan mailer.Message, 10)}
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+// waitResetToken waits for a reset email and extracts the token from its link
+func waitResetToken(t *testing.T, m *fakeMailer) string {
+	select {
+	case msg := <-m.sent:
+		for _, line := range strings.Split(msg.Body, "\n") {
+			if strings.HasPrefix(line, "https://safebase.test/reset-password?") {
+				link, err := url.Parse(line)
+				require.NoError(t, err)
+				return link.Query().Get("token")
+			}
+		}
+		t.Fatalf("no reset link in email: %q", msg.Body)
+	case <-time.After(2 * time.Second):
+		t.Fatal("reset email was not sent")
+	}
+	return ""
+}
+
+// ============================================================================
+// UNIT TESTS - Password reset
+// ============================================================================
+
+// TestAuthService_PasswordReset tests the full reset flow: emailed token, new password, sessions closed
+func TestAuthService_PasswordReset(t *testing.T) {
+	db, authService, sessionRepo, user := setupSessionTest(t)
+	mail := newFakeMailer()
+	authService.SetMailer(mail, "https://safebase.test/reset-password")
+
+	session, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{IPAddress: "10.0.0.1"})
+	require.NoError(t, err)
+
+	authService.RequestPasswordReset(user.Email, "10.0.0.9", "test-agent")
+	token := waitResetToken(t, mail)
+	require.NotEmpty(t, token)
+
+	var request models.Session
+	require.NoError(t, db.Where("user_id = ? AND reset_token <> ''", user.Id).First(&request).Error)
+	assert.Equal(t, security.HashToken(token), request.ResetToken, "reset token must be stored hashed")
+	assert.True(t, request.ResetExpiresAt.After(time.Now()))
+
+	// The pending request is neither a session nor removed by the expired sessions cleanup
+	sessions, err := sessionRepo.GetActiveSessionsForUser(user.Id)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	require.NoError(t, sessionRepo.DeleteExpiredSessions())
+
+	newPassword := "N3w-Secure-Passw0rd"
+	assert.Error(t, authService.ResetPassword(token, "weak", "", ""))
+	require.NoError(t, authService.ResetPassword(token, newPassword, "10.0.0.9", "test-agent"))
+
+	var updated models.User
+	require.NoError(t, db.First(&updated, user.Id).Error)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte(newPassword)))
+
+	// Single use, and every existing session is closed
+	assert.ErrorIs(t, authService.ResetPassword(token, "An0ther-Passw0rd!", "", ""), services.ErrInvalidResetToken)
+	_, err = sessionRepo.GetSessionByToken(session.AccessToken)
+	assert.Error(t, err)
+
+	var actions []models.ActionHistory
+	require.NoError(t, db.Where("user_id = ? AND resource_type = ?", user.Id, "password").Order("id").Find(&actions).Error)
+	require.Len(t, actions, 2)
+	assert.Equal(t, "password_reset_requested", actions[0].Action)
+	assert.Equal(t, "password_reset", actions[1].Action)
+	assert.Equal(t, "10.0.0.9", actions[1].IpAddress)
+}
+
+// TestAuthService_PasswordResetExpiredAndReplaced tests that expired tokens and superseded links are rejected
+func TestAuthService_PasswordResetExpiredAndReplaced(t *testing.T) {
+	db, authService, _, user := setupSessionTest(t)
+	mail := newFakeMailer()
+	authService.SetMailer(mail, "https://safebase.test/reset-password")
+
+	authService.RequestPasswordReset(user.Email, "", "")
+	first := waitResetToken(t, mail)
+	authService.RequestPasswordReset(user.Email, "", "")
+	second := waitResetToken(t, mail)
+
+	assert.ErrorIs(t, authService.ResetPassword(first, "N3w-Secure-Passw0rd", "", ""), services.ErrInvalidResetToken)
+
+	require.NoError(t, db.Model(&models.Session{}).Where("user_id = ?", user.Id).
+		Update("reset_expires_at", time.Now().Add(-time.Minute)).Error)
+	assert.ErrorIs(t, authService.ResetPassword(second, "N3w-Secure-Passw0rd", "", ""), services.ErrInvalidResetToken)
+}
+
+// TestAuthHandler_ForgotPasswordDoesNotRevealAccounts tests that known and unknown emails get the same response
+func TestAuthHandler_ForgotPasswordDoesNotRevealAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, authService, _, user := setupSessionTest(t)
+	mail := newFakeMailer()
+	authService.SetMailer(mail, "https://safebase.test/reset-password")
+	handler := handlers.NewAuthHandler(authService)
+	router := gin.New()
+	router.POST("/auth/forgot-password", handler.ForgotPassword)
+	router.POST("/auth/reset-password", handler.ResetPassword)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	known := post("/auth/forgot-password", `{"email":"`+user.Email+`"}`)
+	unknown := post("/auth/forgot-password", `{"email":"nobody@example.com"}`)
+	assert.Equal(t, http.StatusOK, known.Code)
+	assert.Equal(t, known.Code, unknown.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String())
+
+	token := waitResetToken(t, mail)
+	select {
+	case msg := <-mail.sent:
+		t.Fatalf("unexpected email to %s", msg.To)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w := post("/auth/reset-password", `{"token":"`+token+`","password":"N3w-Secure-Passw0rd","confirm_password":"Different-Passw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = post("/auth/reset-password", `{"token":"`+token+`","password":"N3w-Secure-Passw0rd","confirm_password":"N3w-Secure-Passw0rd"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = post("/auth/reset-password", `{"token":"`+token+`","password":"N3w-Secure-Passw0rd","confirm_password":"N3w-Secure-Passw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
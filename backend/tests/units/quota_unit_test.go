@@ -0,0 +1,138 @@
+package units
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// setupQuotaTest returns the quota service with the given defaults and database and schedule services enforcing it
+func setupQuotaTest(t *testing.T, defaults models.QuotaLimits) (*gorm.DB, *services.QuotaService, *services.DatabaseService, *services.ScheduleService, *services.TeamService) {
+	db, teamService, authz := setupTeamTest(t)
+	require.NoError(t, db.AutoMigrate(&models.Schedule{}, &models.Restore{}, &models.Quota{}))
+
+	databaseRepo := repositories.NewDatabaseRepository(db)
+	quotaService := services.NewQuotaService(repositories.NewQuotaRepository(db), repositories.NewUserRepository(db),
+		repositories.NewTeamRepository(db), defaults)
+	quotaService.SetActionHistoryService(services.NewActionHistoryService(repositories.NewActionHistoryRepository(db)))
+
+	databaseService := services.NewDatabaseService(databaseRepo, repositories.NewBackupRepository(db), repositories.NewRestoreRepository(db), repositories.NewScheduleRepository(db), nil)
+	databaseService.SetAuthorizationService(authz)
+	databaseService.SetQuotaService(quotaService)
+	scheduleService := services.NewScheduleService(repositories.NewScheduleRepository(db), databaseRepo, nil)
+	scheduleService.SetAuthorizationService(authz)
+	scheduleService.SetQuotaService(quotaService)
+	return db, quotaService, databaseService, scheduleService, teamService
+}
+
+func newQuotaTestDatabase(name string, userID uint, teamID *uint) *models.Database {
+	return &models.Database{Name: name, Type: "postgresql", Host: "localhost", Port: "5432", Username: "u", DbName: name, UserId: userID, TeamId: teamID}
+}
+
+func intPtr(value int) *int { return &value }
+
+// ============================================================================
+// UNIT TESTS - Quotas
+// ============================================================================
+
+// TestQuota_DatabasesAndOverrides tests the database limit, the admin override per user and the team quota
+func TestQuota_DatabasesAndOverrides(t *testing.T) {
+	db, quotaService, databaseService, _, teamService := setupQuotaTest(t, models.QuotaLimits{MaxDatabases: 1})
+	admin := createTestUser(db, "admin@example.com", "Password123!", 1)
+	user := createTestUser(db, "user@example.com", "Password123!", 2)
+
+	require.NoError(t, databaseService.CreateDatabase(newQuotaTestDatabase("first", user.Id, nil), user.Id, "", ""))
+	err := databaseService.CreateDatabase(newQuotaTestDatabase("second", user.Id, nil), user.Id, "", "")
+	assert.ErrorIs(t, err, services.ErrQuotaExceeded)
+
+	// The administrator raises the limit of this user only
+	report, err := quotaService.SetUserQuota(admin.Id, user.Id, models.QuotaOverride{MaxDatabases: intPtr(3)}, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Limits.MaxDatabases)
+	_, err = quotaService.SetUserQuota(admin.Id, user.Id, models.QuotaOverride{MaxDatabases: intPtr(-1)}, "", "")
+	assert.Error(t, err)
+	require.NoError(t, databaseService.CreateDatabase(newQuotaTestDatabase("second", user.Id, nil), user.Id, "", ""))
+
+	// A team quota applies on top of the quota of its members
+	team, err := teamService.CreateTeam(user.Id, "Ops", "", "")
+	require.NoError(t, err)
+	_, err = quotaService.SetTeamQuota(admin.Id, team.Id, models.QuotaOverride{MaxDatabases: intPtr(1)}, "", "")
+	require.NoError(t, err)
+	require.NoError(t, databaseService.CreateDatabase(newQuotaTestDatabase("ops1", user.Id, &team.Id), user.Id, "", ""))
+	err = databaseService.CreateDatabase(newQuotaTestDatabase("ops2", user.Id, &team.Id), user.Id, "", "")
+	assert.ErrorIs(t, err, services.ErrQuotaExceeded)
+
+	usage, err := quotaService.GetUsage(user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), usage.Usage.Databases)
+	require.Len(t, usage.Teams, 1)
+	assert.Equal(t, "Ops", usage.Teams[0].TeamName)
+	assert.Equal(t, int64(1), usage.Teams[0].Usage.Databases)
+
+	// Back to the defaults
+	require.NoError(t, quotaService.ResetUserQuota(admin.Id, user.Id, "", ""))
+	err = databaseService.CreateDatabase(newQuotaTestDatabase("third", user.Id, nil), user.Id, "", "")
+	assert.ErrorIs(t, err, services.ErrQuotaExceeded)
+	assert.Equal(t, int64(2), countActions(t, db, "quota_updated"))
+	assert.Equal(t, int64(1), countActions(t, db, "quota_reset"))
+}
+
+// TestQuota_Schedules tests the schedule count and the minimum interval between runs
+func TestQuota_Schedules(t *testing.T) {
+	interval, err := services.MinCronInterval("*/5 * * * *")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, interval)
+	interval, err = services.MinCronInterval("0 9,17 * * 1-5")
+	require.NoError(t, err)
+	assert.Equal(t, 8*time.Hour, interval)
+
+	db, _, databaseService, scheduleService, _ := setupQuotaTest(t, models.QuotaLimits{MaxSchedules: 1, MinScheduleIntervalMinutes: 60})
+	user := createTestUser(db, "user@example.com", "Password123!", 2)
+	database := newQuotaTestDatabase("prod", user.Id, nil)
+	require.NoError(t, databaseService.CreateDatabase(database, user.Id, "", ""))
+
+	_, err = scheduleService.CreateSchedule(database.Id, user.Id, "Toutes les 5 minutes", "*/5 * * * *", "", "")
+	assert.ErrorIs(t, err, services.ErrQuotaExceeded)
+	schedule, err := scheduleService.CreateSchedule(database.Id, user.Id, "Horaire", "0 * * * *", "", "")
+	require.NoError(t, err)
+	_, err = scheduleService.CreateSchedule(database.Id, user.Id, "Quotidienne", "0 3 * * *", "", "")
+	assert.ErrorIs(t, err, services.ErrQuotaExceeded, "one schedule at most")
+
+	_, err = scheduleService.UpdateSchedule(schedule.Id, user.Id, "", "* * * * *", nil, "", "")
+	assert.ErrorIs(t, err, services.ErrQuotaExceeded)
+	_, err = scheduleService.UpdateSchedule(schedule.Id, user.Id, "", "0 */2 * * *", nil, "", "")
+	require.NoError(t, err)
+}
+
+// TestQuota_BackupStorage tests that completed backups count against the storage quota
+func TestQuota_BackupStorage(t *testing.T) {
+	db, quotaService, _, _, _ := setupQuotaTest(t, models.QuotaLimits{MaxBackupBytes: 3000})
+	user := createTestUser(db, "user@example.com", "Password123!", 2)
+	database := createTeamTestDatabase(t, db, "prod", user.Id)
+
+	createTestBackup(db, database.Id, user.Id, "completed") // 1024 bytes
+	createTestBackup(db, database.Id, user.Id, "failed")    // not counted
+	require.NoError(t, quotaService.CheckBackupStart(user.Id, nil))
+
+	createTestBackup(db, database.Id, user.Id, "completed")
+	createTestBackup(db, database.Id, user.Id, "completed")
+	report, err := quotaService.GetUserReport(user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3072), report.Usage.BackupBytes)
+	assert.ErrorIs(t, quotaService.CheckBackupStart(user.Id, nil), services.ErrQuotaExceeded)
+	assert.ErrorIs(t, quotaService.CheckBackupStorage(user.Id, nil), services.ErrQuotaExceeded, "the last backup pushed the user over the quota")
+
+	// A nil quota service enforces nothing
+	var noQuota *services.QuotaService
+	assert.NoError(t, noQuota.CheckBackupStart(user.Id, nil))
+}
@@ -0,0 +1,219 @@
+package units
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// queuedWorkerPool records submitted tasks without running them
+type queuedWorkerPool struct {
+	tasks []func()
+}
+
+func (p *queuedWorkerPool) Submit(task func()) { p.tasks = append(p.tasks, task) }
+
+// restoreApprovalTest holds a protected database shared by a team and the services around it
+type restoreApprovalTest struct {
+	db              *gorm.DB
+	restoreService  *services.RestoreService
+	databaseService *services.DatabaseService
+	pool            *queuedWorkerPool
+	mailer          *fakeMailer
+	requester       *models.User
+	approver        *models.User
+	database        *models.Database
+	backup          *models.Backup
+}
+
+// setupRestoreApprovalTest creates a team (owner = approver, member = requester) owning a protected database
+func setupRestoreApprovalTest(t *testing.T) *restoreApprovalTest {
+	db, teamService, authz := setupTeamTest(t)
+	require.NoError(t, db.AutoMigrate(&models.Restore{}, &models.Schedule{}))
+	approver := createTestUser(db, "approver@example.com", "Password123!", 2)
+	requester := createTestUser(db, "requester@example.com", "Password123!", 2)
+
+	team, err := teamService.CreateTeam(approver.Id, "DBA", "", "")
+	require.NoError(t, err)
+	_, err = teamService.AddMember(approver.Id, team.Id, requester.Email, models.TeamRoleMember, "", "")
+	require.NoError(t, err)
+	database := &models.Database{Name: "prod", Type: "postgresql", Host: "localhost", Port: "5432", Username: "u", DbName: "prod",
+		UserId: approver.Id, TeamId: &team.Id}
+	require.NoError(t, db.Create(database).Error)
+
+	databaseRepo := repositories.NewDatabaseRepository(db)
+	backupRepo := repositories.NewBackupRepository(db)
+	restoreRepo := repositories.NewRestoreRepository(db)
+	databaseService := services.NewDatabaseService(databaseRepo, backupRepo, restoreRepo, repositories.NewScheduleRepository(db), nil)
+	backupService := services.NewBackupService(backupRepo, databaseService, nil, t.TempDir())
+	databaseService.SetBackupService(backupService)
+	actionHistoryService := services.NewActionHistoryService(repositories.NewActionHistoryRepository(db))
+	databaseService.SetActionHistoryService(actionHistoryService)
+	databaseService.SetAuthorizationService(authz)
+
+	restoreService := services.NewRestoreService(restoreRepo, backupService, databaseService, nil)
+	restoreService.SetActionHistoryService(actionHistoryService)
+	restoreService.SetAuthorizationService(authz)
+	pool := &queuedWorkerPool{}
+	restoreService.SetWorkerPool(pool)
+	m := newFakeMailer()
+	restoreService.SetMailer(m, "https://safebase.test/user/backups")
+
+	// Members cannot protect a database, team admins can
+	_, err = databaseService.SetDatabaseProtection(database.Id, requester.Id, true, "", "")
+	assert.Error(t, err)
+	_, err = databaseService.SetDatabaseProtection(database.Id, approver.Id, true, "", "")
+	require.NoError(t, err)
+
+	return &restoreApprovalTest{
+		db:              db,
+		restoreService:  restoreService,
+		databaseService: databaseService,
+		pool:            pool,
+		mailer:          m,
+		requester:       requester,
+		approver:        approver,
+		database:        database,
+		backup:          createTestBackup(db, database.Id, approver.Id, "completed"),
+	}
+}
+
+// requestRestore asks for a restore of the test backup into the protected database
+func (a *restoreApprovalTest) requestRestore(t *testing.T) *models.Restore {
+	restore, err := a.restoreService.CreateRestoreWithPassphraseContext(context.Background(), a.backup.Id, a.database.Id, a.requester.Id, "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.RestoreStatusAwaitingApproval, restore.Status)
+	assert.Empty(t, a.pool.tasks, "nothing runs before the approval")
+	select {
+	case msg := <-a.mailer.sent:
+		assert.Equal(t, a.approver.Email, msg.To)
+	case <-time.After(2 * time.Second):
+		t.Fatal("approvers were not notified")
+	}
+	return restore
+}
+
+// ============================================================================
+// UNIT TESTS - Four-eyes restore approvals
+// ============================================================================
+
+// TestRestoreApproval_Approve tests that a restore into a protected database only runs once a second user approves it
+func TestRestoreApproval_Approve(t *testing.T) {
+	a := setupRestoreApprovalTest(t)
+	restore := a.requestRestore(t)
+
+	// The requester cannot approve their own request and does not see it as pending
+	_, err := a.restoreService.ApproveRestore(context.Background(), restore.Id, a.requester.Id, "", "", "")
+	assert.ErrorIs(t, err, services.ErrSelfApproval)
+	pending, err := a.restoreService.GetPendingApprovals(a.requester.Id)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	pending, err = a.restoreService.GetPendingApprovals(a.approver.Id)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, restore.Id, pending[0].Id)
+
+	approved, err := a.restoreService.ApproveRestore(context.Background(), restore.Id, a.approver.Id, "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", approved.Status)
+	require.NotNil(t, approved.ReviewerId)
+	assert.Equal(t, a.approver.Id, *approved.ReviewerId)
+	assert.Len(t, a.pool.tasks, 1, "the restore starts once approved")
+
+	// A request is reviewed once
+	_, err = a.restoreService.RejectRestore(restore.Id, a.approver.Id, "trop tard", "", "")
+	assert.ErrorIs(t, err, services.ErrRestoreNotAwaitingApproval)
+
+	assert.Equal(t, int64(1), countActions(t, a.db, "restore_requested"))
+	assert.Equal(t, int64(1), countActions(t, a.db, "restore_approved"))
+	assert.Equal(t, int64(1), countActions(t, a.db, "protection_enabled"))
+}
+
+// TestRestoreApproval_RejectAndExpire tests rejected and expired requests
+func TestRestoreApproval_RejectAndExpire(t *testing.T) {
+	a := setupRestoreApprovalTest(t)
+	restore := a.requestRestore(t)
+
+	_, err := a.restoreService.RejectRestore(restore.Id, a.approver.Id, "   ", "", "")
+	assert.Error(t, err, "a reason is required")
+	rejected, err := a.restoreService.RejectRestore(restore.Id, a.approver.Id, "Mauvaise sauvegarde", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.RestoreStatusRejected, rejected.Status)
+	assert.Equal(t, "Mauvaise sauvegarde", rejected.RejectionReason)
+	select {
+	case msg := <-a.mailer.sent:
+		assert.Equal(t, a.requester.Email, msg.To, "the requester is told about the rejection")
+	case <-time.After(2 * time.Second):
+		t.Fatal("the requester was not notified")
+	}
+
+	// Requests not reviewed in time expire and cannot be approved anymore
+	a.restoreService.SetApprovalTTL(time.Millisecond)
+	restore = a.requestRestore(t)
+	time.Sleep(5 * time.Millisecond)
+	_, err = a.restoreService.ApproveRestore(context.Background(), restore.Id, a.approver.Id, "", "", "")
+	assert.ErrorIs(t, err, services.ErrRestoreApprovalExpired)
+	require.NoError(t, a.db.First(restore, restore.Id).Error)
+	assert.Equal(t, models.RestoreStatusExpired, restore.Status)
+	assert.Empty(t, a.pool.tasks)
+	assert.Equal(t, int64(1), countActions(t, a.db, "restore_rejected"))
+	assert.Equal(t, int64(1), countActions(t, a.db, "restore_expired"))
+
+	// Without anyone else able to approve, restores into a protected database are refused
+	require.NoError(t, a.db.Model(&models.TeamMember{}).Where("user_id = ?", a.approver.Id).Update("role", models.TeamRoleMember).Error)
+	require.NoError(t, a.db.Model(&models.TeamMember{}).Where("user_id = ?", a.requester.Id).Update("role", models.TeamRoleOwner).Error)
+	_, err = a.restoreService.CreateRestoreWithPassphraseContext(context.Background(), a.backup.Id, a.database.Id, a.requester.Id, "", "", "")
+	assert.Error(t, err)
+}
+
+// TestRestoreApproval_Unprotect tests that the requester of a restore cannot lift the protection alone
+func TestRestoreApproval_Unprotect(t *testing.T) {
+	a := setupRestoreApprovalTest(t)
+	require.NoError(t, a.db.Model(&models.TeamMember{}).Where("user_id = ?", a.requester.Id).Update("role", models.TeamRoleAdmin).Error)
+	restore := a.requestRestore(t)
+
+	// Nothing can be unprotected while a restore awaits its approval
+	_, err := a.databaseService.SetDatabaseProtection(a.database.Id, a.requester.Id, false, "", "")
+	assert.ErrorIs(t, err, services.ErrUnprotectPendingRestores)
+	_, err = a.restoreService.RejectRestore(restore.Id, a.approver.Id, "Pas maintenant", "", "")
+	require.NoError(t, err)
+	<-a.mailer.sent
+
+	// The request is recorded but the database stays protected until another user confirms it
+	awaiting, err := a.databaseService.SetDatabaseProtection(a.database.Id, a.requester.Id, false, "", "")
+	require.NoError(t, err)
+	assert.True(t, awaiting)
+	_, err = a.databaseService.SetDatabaseProtection(a.database.Id, a.requester.Id, false, "", "")
+	assert.ErrorIs(t, err, services.ErrSelfUnprotect)
+	require.NoError(t, a.db.First(a.database, a.database.Id).Error)
+	assert.True(t, a.database.Protected)
+
+	restore, err = a.restoreService.CreateRestoreWithPassphraseContext(context.Background(), a.backup.Id, a.database.Id, a.requester.Id, "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.RestoreStatusAwaitingApproval, restore.Status, "restores still need an approval")
+	<-a.mailer.sent
+	_, err = a.restoreService.RejectRestore(restore.Id, a.approver.Id, "Pas maintenant", "", "")
+	require.NoError(t, err)
+	<-a.mailer.sent
+
+	awaiting, err = a.databaseService.SetDatabaseProtection(a.database.Id, a.approver.Id, false, "", "")
+	require.NoError(t, err)
+	assert.False(t, awaiting)
+	require.NoError(t, a.db.First(a.database, a.database.Id).Error)
+	assert.False(t, a.database.Protected)
+	assert.Nil(t, a.database.UnprotectRequestedBy)
+	assert.Equal(t, int64(1), countActions(t, a.db, "protection_disable_requested"))
+	assert.Equal(t, int64(1), countActions(t, a.db, "protection_disabled"))
+}
@@ -0,0 +1,140 @@
+package units
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// setupRoleTest seeds the permission catalog and the built-in roles
+func setupRoleTest(t *testing.T) (*gorm.DB, *services.RoleService) {
+	db := setupAuthTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Permission{}, &models.ActionHistory{}))
+	roleService := services.NewRoleService(repositories.NewRoleRepository(db), repositories.NewActionHistoryRepository(db))
+	require.NoError(t, roleService.SyncBuiltInRoles())
+	return db, roleService
+}
+
+// roleID returns the ID of a role
+func roleID(t *testing.T, db *gorm.DB, name string) uint {
+	var role models.Role
+	require.NoError(t, db.Where("name = ?", name).First(&role).Error)
+	return role.Id
+}
+
+// ============================================================================
+// UNIT TESTS - Roles and permissions
+// ============================================================================
+
+// TestRoleService_BuiltInRoles tests the seeded roles and their permissions
+func TestRoleService_BuiltInRoles(t *testing.T) {
+	db, roleService := setupRoleTest(t)
+	require.NoError(t, roleService.SyncBuiltInRoles(), "sync is idempotent")
+
+	assert.Equal(t, uint(1), roleID(t, db, "admin"))
+	assert.Equal(t, uint(2), roleID(t, db, "user"))
+	for _, permission := range models.PermissionCatalog {
+		assert.True(t, roleService.HasPermission("admin", permission.Name), permission.Name)
+	}
+
+	assert.True(t, roleService.HasPermission("operator", models.PermBackupCreate))
+	assert.True(t, roleService.HasPermission("operator", models.PermRestoreExecute))
+	assert.False(t, roleService.HasPermission("operator", models.PermUserManage))
+	assert.False(t, roleService.HasPermission("operator", models.PermDatabaseDelete))
+
+	permissions, err := roleService.RolePermissions("auditor")
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.PermHistoryRead, models.PermAuditRead}, permissions)
+	assert.False(t, roleService.HasPermission("user", models.PermAuditRead))
+	assert.False(t, roleService.HasPermission("unknown", models.PermHistoryRead))
+
+	_, err = roleService.UpdateRolePermissions(1, roleID(t, db, "operator"), []string{models.PermUserManage}, "", "")
+	assert.ErrorIs(t, err, services.ErrBuiltInRole)
+	assert.ErrorIs(t, roleService.DeleteRole(1, roleID(t, db, "auditor"), "", ""), services.ErrBuiltInRole)
+}
+
+// TestRoleService_CustomRoles tests creating, editing and deleting a custom role
+func TestRoleService_CustomRoles(t *testing.T) {
+	db, roleService := setupRoleTest(t)
+
+	_, err := roleService.CreateRole(1, "dba", "", []string{"backup.everything"}, false, "", "")
+	assert.Error(t, err, "unknown permission")
+	_, err = roleService.CreateRole(1, "Bad Name!", "", nil, false, "", "")
+	assert.Error(t, err)
+	_, err = roleService.CreateRole(1, "operator", "", nil, false, "", "")
+	assert.ErrorIs(t, err, services.ErrBuiltInRole)
+
+	role, err := roleService.CreateRole(1, "DBA-Readonly", "Lecture seule", []string{models.PermDatabaseRead, models.PermBackupRead}, true, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "dba-readonly", role.Name)
+	assert.True(t, roleService.HasPermission("dba-readonly", models.PermBackupRead))
+	assert.False(t, roleService.HasPermission("dba-readonly", models.PermBackupDownload))
+
+	// A change applies immediately (no stale cache)
+	_, err = roleService.UpdateRolePermissions(1, role.Id, []string{models.PermBackupDownload}, "", "")
+	require.NoError(t, err)
+	assert.True(t, roleService.HasPermission("dba-readonly", models.PermBackupDownload))
+	assert.False(t, roleService.HasPermission("dba-readonly", models.PermBackupRead))
+
+	user := createTestUser(db, "dba@example.com", "Password123!", role.Id)
+	assert.Error(t, roleService.DeleteRole(1, role.Id, "", ""), "role still assigned")
+	require.NoError(t, db.Model(user).Update("role_id", 2).Error)
+	require.NoError(t, roleService.DeleteRole(1, role.Id, "", ""))
+	assert.False(t, roleService.HasPermission("dba-readonly", models.PermBackupDownload))
+
+	_, err = roleService.CreateRole(1, "dba-readonly", "", nil, false, "", "")
+	assert.NoError(t, err, "the name of a deleted role can be reused")
+
+	var count int64
+	db.Model(&models.ActionHistory{}).Where("resource_type = ?", "role").Count(&count)
+	assert.Equal(t, int64(4), count)
+}
+
+// TestAuthMiddleware_RequirePermission tests that routes are granted by permission rather than role name
+func TestAuthMiddleware_RequirePermission(t *testing.T) {
+	_, roleService := setupRoleTest(t)
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(checker middlewares.PermissionChecker) *gin.Engine {
+		authMiddleware := middlewares.NewAuthMiddleware("test-secret-key")
+		if checker != nil {
+			authMiddleware.SetPermissionChecker(checker)
+		}
+		router := gin.New()
+		router.POST("/api/backups/database/:database_id", authMiddleware.RequireAuth(),
+			authMiddleware.RequirePermission(models.PermBackupCreate), func(c *gin.Context) { c.Status(http.StatusOK) })
+		router.GET("/api/admin/users", authMiddleware.RequireAuth(),
+			authMiddleware.RequirePermission(models.PermUserManage), func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	token := func(role string) string {
+		jwt, err := security.GenerateJWT("test-secret-key", 1, role+"@example.com", role, time.Hour)
+		require.NoError(t, err)
+		return jwt
+	}
+
+	router := newRouter(roleService)
+	assert.Equal(t, http.StatusOK, sessionRequest(router, http.MethodPost, "/api/backups/database/1", token("operator")).Code)
+	assert.Equal(t, http.StatusForbidden, sessionRequest(router, http.MethodGet, "/api/admin/users", token("operator")).Code)
+	assert.Equal(t, http.StatusForbidden, sessionRequest(router, http.MethodPost, "/api/backups/database/1", token("auditor")).Code)
+	assert.Equal(t, http.StatusOK, sessionRequest(router, http.MethodGet, "/api/admin/users", token("admin")).Code)
+
+	// Without a permission checker only administrators get through
+	router = newRouter(nil)
+	assert.Equal(t, http.StatusOK, sessionRequest(router, http.MethodGet, "/api/admin/users", token("admin")).Code)
+	assert.Equal(t, http.StatusForbidden, sessionRequest(router, http.MethodPost, "/api/backups/database/1", token("user")).Code)
+}
@@ -0,0 +1,330 @@
+package units
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/handlers"
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+const sessionTestPassword = "TestP@ssw0rd123"
+
+// setupSessionTest creates an auth service with short access tokens and a user
+func setupSessionTest(t *testing.T) (*gorm.DB, *services.AuthService, *repositories.SessionRepository, *models.User) {
+	db := setupAuthTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ActionHistory{}))
+
+	userRepo := repositories.NewUserRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	authService := services.NewAuthService(userRepo, sessionRepo, "test-secret-key", 15*time.Minute)
+	authService.SetRefreshTokenTTL(24 * time.Hour)
+	authService.SetActionHistoryService(services.NewActionHistoryService(repositories.NewActionHistoryRepository(db)))
+
+	user := createTestUser(db, "sessions@example.com", sessionTestPassword, 2)
+	return db, authService, sessionRepo, user
+}
+
+// ============================================================================
+// UNIT TESTS - Refresh tokens and sessions
+// ============================================================================
+
+// TestAuthService_MultipleSessions tests that logging in on a second device keeps the first session
+func TestAuthService_MultipleSessions(t *testing.T) {
+	_, authService, sessionRepo, user := setupSessionTest(t)
+
+	laptop, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{
+		IPAddress: "10.0.0.1",
+		UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0",
+	})
+	require.NoError(t, err)
+	desktop, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{DeviceName: "Bureau", IPAddress: "10.0.0.2"})
+	require.NoError(t, err)
+	assert.NotEqual(t, laptop.AccessToken, desktop.AccessToken)
+
+	sessions, err := sessionRepo.GetActiveSessionsForUser(user.Id)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	first, err := sessionRepo.GetSessionByToken(laptop.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "Firefox sur Linux", first.DeviceName)
+	assert.Equal(t, "10.0.0.1", first.IPAddress)
+	assert.NotEqual(t, laptop.RefreshToken, first.RefreshToken, "refresh token must be stored hashed")
+	assert.Equal(t, security.HashToken(laptop.RefreshToken), first.RefreshToken)
+
+	second, err := sessionRepo.GetSessionByToken(desktop.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "Bureau", second.DeviceName)
+
+	claims, err := security.VerifyJWT("test-secret-key", laptop.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, first.FamilyID, claims.SessionID)
+}
+
+// TestAuthService_RefreshRotation tests rotation and reuse detection revoking the session family
+func TestAuthService_RefreshRotation(t *testing.T) {
+	db, authService, sessionRepo, user := setupSessionTest(t)
+
+	login, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{})
+	require.NoError(t, err)
+	other, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{})
+	require.NoError(t, err)
+
+	refreshed, err := authService.Refresh(login.RefreshToken, services.SessionMetadata{IPAddress: "10.0.0.9"})
+	require.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+	assert.NotEqual(t, login.AccessToken, refreshed.AccessToken)
+	assert.Equal(t, "10.0.0.9", refreshed.Session.IPAddress)
+
+	_, err = sessionRepo.GetSessionByToken(login.AccessToken)
+	assert.Error(t, err, "the previous access token is replaced")
+	_, err = sessionRepo.GetSessionByToken(refreshed.AccessToken)
+	assert.NoError(t, err)
+
+	// A forged secret on the public family ID is rejected without logging the user out
+	familyID, _, _ := strings.Cut(refreshed.RefreshToken, ".")
+	_, err = authService.Refresh(familyID+".forged", services.SessionMetadata{})
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+	assert.ErrorIs(t, authService.RevokeRefreshToken(familyID+".forged"), services.ErrInvalidRefreshToken)
+	_, err = sessionRepo.GetSessionByToken(refreshed.AccessToken)
+	assert.NoError(t, err, "the session is still open")
+
+	// Replaying the rotated token revokes the family, including the legitimate new token
+	_, err = authService.Refresh(login.RefreshToken, services.SessionMetadata{IPAddress: "203.0.113.7"})
+	assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+	_, err = authService.Refresh(refreshed.RefreshToken, services.SessionMetadata{})
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+	_, err = sessionRepo.GetSessionByToken(refreshed.AccessToken)
+	assert.Error(t, err)
+
+	var event models.ActionHistory
+	require.NoError(t, db.Where("action = ?", "refresh_token_reused").First(&event).Error)
+	assert.Equal(t, user.Id, event.UserId)
+	assert.Equal(t, "203.0.113.7", event.IpAddress)
+
+	// Other devices are not affected
+	_, err = authService.Refresh(other.RefreshToken, services.SessionMetadata{})
+	assert.NoError(t, err)
+
+	_, err = authService.Refresh("not-a-token", services.SessionMetadata{})
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+}
+
+// TestAuthService_RefreshReuseAfterSeveralRotations tests that a token rotated long ago still revokes the family
+func TestAuthService_RefreshReuseAfterSeveralRotations(t *testing.T) {
+	db, authService, sessionRepo, user := setupSessionTest(t)
+
+	stolen, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{})
+	require.NoError(t, err)
+	// The attacker rotates the stolen token twice before the owner uses it again
+	first, err := authService.Refresh(stolen.RefreshToken, services.SessionMetadata{IPAddress: "203.0.113.7"})
+	require.NoError(t, err)
+	second, err := authService.Refresh(first.RefreshToken, services.SessionMetadata{IPAddress: "203.0.113.7"})
+	require.NoError(t, err)
+
+	_, err = authService.Refresh(stolen.RefreshToken, services.SessionMetadata{IPAddress: "10.0.0.9"})
+	assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+	_, err = authService.Refresh(second.RefreshToken, services.SessionMetadata{})
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken, "the attacker loses the session")
+	_, err = sessionRepo.GetSessionByToken(second.AccessToken)
+	assert.Error(t, err)
+
+	// Closing the family forgets its consumed tokens
+	var consumed int64
+	require.NoError(t, db.Model(&models.ConsumedRefreshToken{}).Count(&consumed).Error)
+	assert.Zero(t, consumed)
+}
+
+// TestAuthService_RefreshExpiredOrDisabled tests that expired sessions and disabled accounts cannot refresh
+func TestAuthService_RefreshExpiredOrDisabled(t *testing.T) {
+	db, authService, _, user := setupSessionTest(t)
+
+	expired, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.Session{}).Where("id = ?", expired.Session.Id).
+		Update("refresh_expires_at", time.Now().Add(-time.Minute)).Error)
+	_, err = authService.Refresh(expired.RefreshToken, services.SessionMetadata{})
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+
+	active, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.Id).Update("active", false).Error)
+	_, err = authService.Refresh(active.RefreshToken, services.SessionMetadata{})
+	assert.Error(t, err)
+}
+
+// TestAuthHandler_RefreshCookie tests the refresh endpoint with the HTTP-only cookie
+func TestAuthHandler_RefreshCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, authService, _, user := setupSessionTest(t)
+	handler := handlers.NewAuthHandler(authService)
+	router := gin.New()
+	router.POST("/auth/login", handler.Login)
+	router.POST("/auth/refresh", handler.Refresh)
+
+	w := httptest.NewRecorder()
+	body := `{"email":"` + user.Email + `","password":"` + sessionTestPassword + `","device_name":"CLI"}`
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var refreshCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "refresh_token" {
+			refreshCookie = cookie
+		}
+	}
+	require.NotNil(t, refreshCookie)
+	assert.True(t, refreshCookie.HttpOnly)
+	assert.Equal(t, "/auth", refreshCookie.Path)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	req.AddCookie(refreshCookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotEmpty(t, response["token"])
+	assert.NotEqual(t, refreshCookie.Value, response["refresh_token"])
+
+	// Replay of the first cookie
+	req = httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	req.AddCookie(refreshCookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// setupSessionRouter wires the profile and admin session routes behind the auth middleware
+func setupSessionRouter(authService *services.AuthService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	authMiddleware := middlewares.NewAuthMiddleware("test-secret-key")
+	authMiddleware.SetSessionValidator(authService)
+
+	router := gin.New()
+	profileHandler := handlers.NewProfileHandler(nil, authService)
+	profile := router.Group("/api/profile", authMiddleware.RequireAuth())
+	profile.GET("/sessions", profileHandler.GetSessions)
+	profile.DELETE("/sessions", profileHandler.RevokeOtherSessions)
+	profile.DELETE("/sessions/:id", profileHandler.RevokeSession)
+
+	userHandler := handlers.NewUserHandler(nil)
+	userHandler.SetAuthService(authService)
+	router.DELETE("/api/admin/users/:id/sessions", authMiddleware.RequireAuth(), authMiddleware.RequireRole("admin"), userHandler.RevokeUserSessions)
+	return router
+}
+
+// sessionRequest performs an authenticated request with a bearer access token
+func sessionRequest(router *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestProfileHandler_ListAndRevokeSessions tests the session list, single revocation and "log out everywhere else"
+func TestProfileHandler_ListAndRevokeSessions(t *testing.T) {
+	_, authService, _, user := setupSessionTest(t)
+	router := setupSessionRouter(authService)
+
+	current, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{DeviceName: "Laptop", IPAddress: "10.0.0.1"})
+	require.NoError(t, err)
+	phone, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{DeviceName: "Phone"})
+	require.NoError(t, err)
+	tablet, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{DeviceName: "Tablet"})
+	require.NoError(t, err)
+
+	w := sessionRequest(router, http.MethodGet, "/api/profile/sessions", current.AccessToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Sessions []struct {
+			Id         uint   `json:"id"`
+			DeviceName string `json:"device_name"`
+			IPAddress  string `json:"ip_address"`
+			Current    bool   `json:"current"`
+		} `json:"sessions"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Sessions, 3)
+	for _, session := range list.Sessions {
+		assert.Equal(t, session.Id == current.Session.Id, session.Current, session.DeviceName)
+	}
+
+	// Revoking a session takes effect immediately, even though its JWT is still valid
+	w = sessionRequest(router, http.MethodDelete, "/api/profile/sessions/"+strconv.Itoa(int(phone.Session.Id)), current.AccessToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = sessionRequest(router, http.MethodGet, "/api/profile/sessions", phone.AccessToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	_, err = authService.GetUserFromToken(phone.AccessToken)
+	assert.ErrorIs(t, err, services.ErrSessionRevoked, "/auth/me no longer returns the user")
+
+	// Log out everywhere else keeps the current session only
+	w = sessionRequest(router, http.MethodDelete, "/api/profile/sessions", current.AccessToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, sessionRequest(router, http.MethodGet, "/api/profile/sessions", tablet.AccessToken).Code)
+	assert.Equal(t, http.StatusOK, sessionRequest(router, http.MethodGet, "/api/profile/sessions", current.AccessToken).Code)
+}
+
+// TestProfileHandler_RevokeSessionOfOtherUser tests that users cannot revoke sessions they do not own
+func TestProfileHandler_RevokeSessionOfOtherUser(t *testing.T) {
+	db, authService, _, user := setupSessionTest(t)
+	router := setupSessionRouter(authService)
+	other := createTestUser(db, "other@example.com", sessionTestPassword, 2)
+
+	mine, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{})
+	require.NoError(t, err)
+	theirs, err := authService.LoginWithMetadata(other.Email, sessionTestPassword, services.SessionMetadata{})
+	require.NoError(t, err)
+
+	w := sessionRequest(router, http.MethodDelete, "/api/profile/sessions/"+strconv.Itoa(int(theirs.Session.Id)), mine.AccessToken)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, http.StatusOK, sessionRequest(router, http.MethodGet, "/api/profile/sessions", theirs.AccessToken).Code)
+}
+
+// TestUserHandler_AdminRevokeSessions tests that an admin can revoke every session of a user
+func TestUserHandler_AdminRevokeSessions(t *testing.T) {
+	db, authService, _, user := setupSessionTest(t)
+	router := setupSessionRouter(authService)
+	admin := createTestUser(db, "admin@example.com", sessionTestPassword, 1)
+
+	adminLogin, err := authService.LoginWithMetadata(admin.Email, sessionTestPassword, services.SessionMetadata{})
+	require.NoError(t, err)
+	first, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{})
+	require.NoError(t, err)
+	second, err := authService.LoginWithMetadata(user.Email, sessionTestPassword, services.SessionMetadata{})
+	require.NoError(t, err)
+
+	// Regular users cannot use the admin route
+	w := sessionRequest(router, http.MethodDelete, "/api/admin/users/"+strconv.Itoa(int(user.Id))+"/sessions", first.AccessToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = sessionRequest(router, http.MethodDelete, "/api/admin/users/"+strconv.Itoa(int(user.Id))+"/sessions", adminLogin.AccessToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"revoked":2`)
+	assert.Equal(t, http.StatusUnauthorized, sessionRequest(router, http.MethodGet, "/api/profile/sessions", first.AccessToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, sessionRequest(router, http.MethodGet, "/api/profile/sessions", second.AccessToken).Code)
+
+	var event models.ActionHistory
+	require.NoError(t, db.Where("action = ? AND resource_type = ?", "sessions_revoked", "user").First(&event).Error)
+	assert.Equal(t, admin.Id, event.UserId)
+	assert.Equal(t, user.Id, event.ResourceId)
+}
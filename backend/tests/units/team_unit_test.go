@@ -0,0 +1,178 @@
+package units
+
+import (
+	"testing"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// setupTeamTest migrates the team tables and returns the team and authorization services
+func setupTeamTest(t *testing.T) (*gorm.DB, *services.TeamService, *services.AuthorizationService) {
+	db := setupAuthTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Team{}, &models.TeamMember{}, &models.Database{}, &models.Backup{}, &models.ActionHistory{}))
+	teamRepo := repositories.NewTeamRepository(db)
+	authz := services.NewAuthorizationService(teamRepo)
+	teamService := services.NewTeamService(teamRepo, repositories.NewUserRepository(db), repositories.NewDatabaseRepository(db),
+		authz, repositories.NewActionHistoryRepository(db))
+	return db, teamService, authz
+}
+
+// createTeamTestDatabase creates a database without a team, as before teams existed
+func createTeamTestDatabase(t *testing.T, db *gorm.DB, name string, userID uint) *models.Database {
+	database := &models.Database{Name: name, Type: "postgresql", Host: "localhost", Port: "5432", Username: "u", Password: "p", DbName: name, UserId: userID}
+	require.NoError(t, db.Create(database).Error)
+	return database
+}
+
+// ============================================================================
+// UNIT TESTS - Teams and shared databases
+// ============================================================================
+
+// TestTeamService_MigratePersonalWorkspaces tests that per-user databases move into personal workspaces
+func TestTeamService_MigratePersonalWorkspaces(t *testing.T) {
+	db, teamService, authz := setupTeamTest(t)
+	alice := createTestUser(db, "alice@example.com", "Password123!", 2)
+	bob := createTestUser(db, "bob@example.com", "Password123!", 2)
+	aliceDB := createTeamTestDatabase(t, db, "alice_prod", alice.Id)
+	deletedDB := createTeamTestDatabase(t, db, "alice_old", alice.Id)
+	require.NoError(t, db.Delete(deletedDB).Error)
+	bobDB := createTeamTestDatabase(t, db, "bob_prod", bob.Id)
+
+	// Before the migration the historical ownership rule applies
+	assert.True(t, authz.CanAccessDatabase(alice.Id, aliceDB, services.TeamActionManage))
+	assert.False(t, authz.CanAccessDatabase(bob.Id, aliceDB, services.TeamActionView))
+
+	migrated, err := teamService.MigratePersonalWorkspaces()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), migrated, "soft deleted databases are migrated too")
+	migrated, err = teamService.MigratePersonalWorkspaces()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), migrated, "the migration is idempotent")
+
+	personal, err := authz.PersonalTeam(alice.Id)
+	require.NoError(t, err)
+	assert.True(t, personal.Personal)
+	require.NoError(t, db.First(aliceDB, aliceDB.Id).Error)
+	require.NotNil(t, aliceDB.TeamId)
+	assert.Equal(t, personal.Id, *aliceDB.TeamId)
+	assert.Equal(t, models.TeamRoleOwner, authz.TeamRole(personal.Id, alice.Id))
+
+	databases, err := repositories.NewDatabaseRepository(db).GetAccessibleByUser(bob.Id)
+	require.NoError(t, err)
+	require.Len(t, databases, 1)
+	assert.Equal(t, bobDB.Id, databases[0].Id)
+
+	teams, err := teamService.ListTeams(alice.Id)
+	require.NoError(t, err)
+	require.Len(t, teams, 1)
+	assert.Equal(t, models.TeamRoleOwner, teams[0].MyRole)
+}
+
+// TestTeamService_SharedDatabases tests that team members share databases and their backups according to their role
+func TestTeamService_SharedDatabases(t *testing.T) {
+	db, teamService, authz := setupTeamTest(t)
+	owner := createTestUser(db, "owner@example.com", "Password123!", 2)
+	member := createTestUser(db, "member@example.com", "Password123!", 2)
+	viewer := createTestUser(db, "viewer@example.com", "Password123!", 2)
+	outsider := createTestUser(db, "outsider@example.com", "Password123!", 2)
+	database := createTeamTestDatabase(t, db, "shared", owner.Id)
+	_, err := teamService.MigratePersonalWorkspaces()
+	require.NoError(t, err)
+
+	team, err := teamService.CreateTeam(owner.Id, "  DBA  ", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "DBA", team.Name)
+	_, err = teamService.AddMember(owner.Id, team.Id, "MEMBER@example.com", models.TeamRoleMember, "", "")
+	require.NoError(t, err)
+	_, err = teamService.AddMember(owner.Id, team.Id, viewer.Email, models.TeamRoleViewer, "", "")
+	require.NoError(t, err)
+
+	// Only someone managing both teams can move a database
+	assert.ErrorIs(t, teamService.MoveDatabase(member.Id, database.Id, team.Id, "", ""), services.ErrTeamAccessDenied)
+	require.NoError(t, teamService.MoveDatabase(owner.Id, database.Id, team.Id, "", ""))
+	require.NoError(t, db.First(database, database.Id).Error)
+
+	assert.True(t, authz.CanAccessDatabase(member.Id, database, services.TeamActionOperate))
+	assert.False(t, authz.CanAccessDatabase(member.Id, database, services.TeamActionManage))
+	assert.True(t, authz.CanAccessDatabase(viewer.Id, database, services.TeamActionView))
+	assert.False(t, authz.CanAccessDatabase(viewer.Id, database, services.TeamActionOperate))
+	assert.False(t, authz.CanAccessDatabase(outsider.Id, database, services.TeamActionView))
+
+	// A backup created by the owner is visible to the team, even after the database is deleted
+	backup := &models.Backup{UserId: owner.Id, DatabaseId: database.Id, Status: "completed", Filename: "shared.zip"}
+	require.NoError(t, db.Create(backup).Error)
+	backups, err := repositories.NewBackupRepository(db).GetAccessibleByUser(viewer.Id)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	require.NoError(t, db.Delete(database).Error)
+	assert.True(t, authz.CanAccessResource(member.Id, backup.UserId, backup.DatabaseId, services.TeamActionOperate))
+	assert.False(t, authz.CanAccessResource(outsider.Id, backup.UserId, backup.DatabaseId, services.TeamActionView))
+
+	// Removed members lose access immediately
+	require.NoError(t, teamService.RemoveMember(owner.Id, team.Id, member.Id, "", ""))
+	assert.False(t, authz.CanAccessResource(member.Id, backup.UserId, backup.DatabaseId, services.TeamActionView))
+
+	var logged int64
+	db.Model(&models.ActionHistory{}).Where("resource_type = ?", "team").Count(&logged)
+	assert.Equal(t, int64(5), logged, "created, 2 members added, database moved, member removed")
+}
+
+// TestTeamService_MembershipRules tests the safeguards on team membership
+func TestTeamService_MembershipRules(t *testing.T) {
+	db, teamService, authz := setupTeamTest(t)
+	owner := createTestUser(db, "owner@example.com", "Password123!", 2)
+	admin := createTestUser(db, "admin@example.com", "Password123!", 2)
+	viewer := createTestUser(db, "viewer@example.com", "Password123!", 2)
+
+	personal, err := authz.PersonalTeam(owner.Id)
+	require.NoError(t, err)
+	_, err = teamService.AddMember(owner.Id, personal.Id, admin.Email, models.TeamRoleMember, "", "")
+	assert.Error(t, err, "personal workspaces have a single member")
+	assert.Error(t, teamService.DeleteTeam(owner.Id, personal.Id, "", ""))
+
+	team, err := teamService.CreateTeam(owner.Id, "Ops", "", "")
+	require.NoError(t, err)
+	_, err = teamService.AddMember(owner.Id, team.Id, admin.Email, models.TeamRoleAdmin, "", "")
+	require.NoError(t, err)
+	_, err = teamService.AddMember(owner.Id, team.Id, admin.Email, models.TeamRoleViewer, "", "")
+	assert.Error(t, err, "already a member")
+	_, err = teamService.AddMember(owner.Id, team.Id, "nobody@example.com", models.TeamRoleViewer, "", "")
+	assert.Error(t, err)
+	_, err = teamService.AddMember(owner.Id, team.Id, viewer.Email, "superuser", "", "")
+	assert.Error(t, err)
+
+	// Team admins manage members but cannot create owners nor touch the owner
+	_, err = teamService.AddMember(admin.Id, team.Id, viewer.Email, models.TeamRoleOwner, "", "")
+	assert.ErrorIs(t, err, services.ErrTeamAccessDenied)
+	_, err = teamService.AddMember(admin.Id, team.Id, viewer.Email, models.TeamRoleViewer, "", "")
+	require.NoError(t, err)
+	assert.ErrorIs(t, teamService.RemoveMember(admin.Id, team.Id, owner.Id, "", ""), services.ErrTeamAccessDenied)
+	assert.ErrorIs(t, teamService.DeleteTeam(admin.Id, team.Id, "", ""), services.ErrTeamAccessDenied)
+	_, err = teamService.RenameTeam(viewer.Id, team.Id, "Viewers", "", "")
+	assert.ErrorIs(t, err, services.ErrTeamAccessDenied)
+
+	// The last owner stays
+	assert.Error(t, teamService.UpdateMemberRole(owner.Id, team.Id, owner.Id, models.TeamRoleAdmin, "", ""))
+	assert.Error(t, teamService.RemoveMember(owner.Id, team.Id, owner.Id, "", ""))
+	require.NoError(t, teamService.UpdateMemberRole(owner.Id, team.Id, admin.Id, models.TeamRoleOwner, "", ""))
+	require.NoError(t, teamService.RemoveMember(owner.Id, team.Id, owner.Id, "", ""), "another owner remains")
+
+	// Any member can leave; a team with databases cannot be deleted
+	require.NoError(t, teamService.RemoveMember(viewer.Id, team.Id, viewer.Id, "", ""))
+	database := createTeamTestDatabase(t, db, "ops", admin.Id)
+	require.NoError(t, db.Model(database).Update("team_id", team.Id).Error)
+	assert.Error(t, teamService.DeleteTeam(admin.Id, team.Id, "", ""))
+	require.NoError(t, db.Delete(database).Error)
+	require.NoError(t, teamService.DeleteTeam(admin.Id, team.Id, "", ""))
+	_, err = teamService.GetTeam(admin.Id, team.Id)
+	assert.ErrorIs(t, err, services.ErrTeamAccessDenied)
+}
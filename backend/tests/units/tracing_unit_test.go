@@ -0,0 +1,193 @@
+package units
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// setupSpanRecorder installs an in-memory tracer provider for the duration of the test
+func setupSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	_, err := tracing.Init(context.Background(), tracing.Config{Enabled: false})
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return recorder
+}
+
+// findSpan returns the first ended span with the given name
+func findSpan(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+// syncWorkerPool runs submitted tasks inline so async jobs finish before assertions
+type syncWorkerPool struct{}
+
+func (syncWorkerPool) Submit(task func()) { task() }
+
+// ============================================================================
+// UNIT TESTS - Tracing
+// ============================================================================
+
+// TestTracingMiddleware_ContinuesIncomingTrace tests that the server span joins the caller's trace
+func TestTracingMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	recorder := setupSpanRecorder(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.Tracing())
+	router.GET("/api/backups/:id", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/backups/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	span := findSpan(recorder.Ended(), "GET /api/backups/:id")
+	require.NotNil(t, span, "request span should be recorded with the route template as name")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, span.Status().Code, "5xx responses should mark the span as failed")
+}
+
+// TestTracing_EndRedactsErrors tests that secrets in error messages never reach the exporter
+func TestTracing_EndRedactsErrors(t *testing.T) {
+	recorder := setupSpanRecorder(t)
+
+	_, span := tracing.Start(context.Background(), "backup.dump")
+	tracing.End(span, errors.New("pg_dump failed: PGPASSWORD=topsecret postgresql://admin:hunter2@db/app"))
+
+	ended := findSpan(recorder.Ended(), "backup.dump")
+	require.NotNil(t, ended)
+	assert.Equal(t, codes.Error, ended.Status().Code)
+	assert.NotContains(t, ended.Status().Description, "topsecret")
+	assert.NotContains(t, ended.Status().Description, "hunter2")
+	for _, event := range ended.Events() {
+		for _, attr := range event.Attributes {
+			assert.NotContains(t, attr.Value.Emit(), "topsecret")
+		}
+	}
+}
+
+// TestTracing_GormPlugin tests that queries become child spans of the context span
+func TestTracing_GormPlugin(t *testing.T) {
+	recorder := setupSpanRecorder(t)
+	db := setupBackupTestDB(t)
+	require.NoError(t, db.Use(tracing.NewGormPlugin()))
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	_, err := repositories.NewBackupRepository(db).WithContext(ctx).GetByUserID(1)
+	require.NoError(t, err)
+	parent.End()
+
+	span := findSpan(recorder.Ended(), "gorm.select backups")
+	require.NotNil(t, span, "query should produce a span named after the operation and table")
+	assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+}
+
+// TestTracing_BackupJobPropagatesRequestTrace tests that async backup phases stay in the request trace
+func TestTracing_BackupJobPropagatesRequestTrace(t *testing.T) {
+	recorder := setupSpanRecorder(t)
+	db := setupBackupTestDB(t)
+
+	backupRepo := repositories.NewBackupRepository(db)
+	databaseRepo := repositories.NewDatabaseRepository(db)
+	userService := services.NewUserService(repositories.NewUserRepository(db), nil, nil)
+	databaseService := services.NewDatabaseService(databaseRepo, backupRepo, nil, nil, nil)
+	backupService := services.NewBackupService(backupRepo, databaseService, userService, t.TempDir())
+	backupService.SetWorkerPool(syncWorkerPool{})
+
+	ctx, requestSpan := tracing.Start(context.Background(), "POST /api/backups/database/:database_id")
+	_, err := backupService.CreateBackupContext(ctx, 1, 1, "127.0.0.1", "test-agent")
+	require.NoError(t, err)
+	requestSpan.End()
+
+	spans := recorder.Ended()
+	job := findSpan(spans, "backup.job")
+	require.NotNil(t, job, "the backup job should be traced")
+	assert.Equal(t, requestSpan.SpanContext().TraceID(), job.SpanContext().TraceID(), "job should continue the request trace")
+	assert.Equal(t, requestSpan.SpanContext().SpanID(), job.Parent().SpanID())
+
+	// The test environment has no real MySQL server: the dump phase fails and so does the job
+	dump := findSpan(spans, "backup.dump")
+	require.NotNil(t, dump, "the dump phase should be traced")
+	assert.Equal(t, job.SpanContext().SpanID(), dump.Parent().SpanID())
+	assert.Equal(t, codes.Error, dump.Status().Code)
+	assert.Equal(t, codes.Error, job.Status().Code)
+}
+
+// TestTracing_ExportsOverOTLP tests the OTLP/HTTP exporter against an in-process collector
+func TestTracing_ExportsOverOTLP(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		paths    []string
+		payloads int
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		if len(body) > 0 {
+			payloads++
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	shutdown, err := tracing.Init(context.Background(), tracing.Config{
+		Enabled:     true,
+		Endpoint:    collector.URL + "/v1/traces",
+		ServiceName: "safebase-test",
+	})
+	require.NoError(t, err)
+
+	_, span := tracing.Start(context.Background(), "backup.job")
+	span.End()
+
+	// Shutdown flushes the batch processor
+	require.NoError(t, shutdown(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, paths, "/v1/traces")
+	assert.Positive(t, payloads, "collector should receive a non-empty export request")
+}
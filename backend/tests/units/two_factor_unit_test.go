@@ -0,0 +1,197 @@
+package units
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/middlewares"
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/RyanLadmia/plateforme-safebase/pkg/security"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// setupTwoFactorTest creates an auth service with recovery codes and a user
+func setupTwoFactorTest(t *testing.T) (*gorm.DB, *services.AuthService, *models.User) {
+	db, authService, _, user := setupSessionTest(t)
+	require.NoError(t, db.AutoMigrate(&models.RecoveryCode{}))
+	authService.SetRecoveryCodeRepository(repositories.NewRecoveryCodeRepository(db))
+	return db, authService, user
+}
+
+// totpCode returns the code of a secret for the current time step plus offset
+func totpCode(t *testing.T, secret string, offset int64) string {
+	code, err := security.TOTPCode(secret, security.TOTPStep(time.Now())+offset)
+	require.NoError(t, err)
+	return code
+}
+
+// loginChallenge logs in with the password and expects a second factor challenge
+func loginChallenge(t *testing.T, authService *services.AuthService, email string) *services.TwoFactorChallenge {
+	_, err := authService.LoginWithMetadata(email, sessionTestPassword, services.SessionMetadata{})
+	var challenge *services.TwoFactorChallenge
+	require.True(t, errors.As(err, &challenge), "expected a two-factor challenge, got %v", err)
+	return challenge
+}
+
+// ============================================================================
+// UNIT TESTS - TOTP
+// ============================================================================
+
+// TestTOTP_RFC6238Vector tests the code generation against the RFC 6238 SHA-1 test vector
+func TestTOTP_RFC6238Vector(t *testing.T) {
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // base32 of "12345678901234567890"
+	code, err := security.TOTPCode(secret, security.TOTPStep(time.Unix(59, 0)))
+	require.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	step, ok := security.ValidateTOTP(secret, "287082", time.Unix(59, 0), 0)
+	assert.True(t, ok)
+	_, ok = security.ValidateTOTP(secret, "287082", time.Unix(59, 0), step)
+	assert.False(t, ok, "a code must not be accepted twice")
+
+	uri := security.TOTPProvisioningURI("SafeBase", "alice@example.com", secret)
+	assert.Contains(t, uri, "otpauth://totp/SafeBase:alice@example.com?")
+	assert.Contains(t, uri, "secret="+secret)
+}
+
+// ============================================================================
+// UNIT TESTS - Two-factor authentication
+// ============================================================================
+
+// TestAuthService_TwoFactorEnrolmentAndLogin tests enrolment, step-up login and recovery codes
+func TestAuthService_TwoFactorEnrolmentAndLogin(t *testing.T) {
+	db, authService, user := setupTwoFactorTest(t)
+
+	setup, err := authService.BeginTwoFactorSetup(user.Id)
+	require.NoError(t, err)
+	assert.Contains(t, setup.ProvisioningURI, "otpauth://totp/")
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.Id).Error)
+	assert.NotContains(t, stored.TOTPSecret, setup.Secret, "TOTP secret must be encrypted at rest")
+	assert.False(t, stored.TwoFactorEnabled, "2FA must not be enabled before confirmation")
+
+	_, err = authService.ConfirmTwoFactor(user.Id, "000000", "", "")
+	assert.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+	recoveryCodes, err := authService.ConfirmTwoFactor(user.Id, totpCode(t, setup.Secret, 0), "", "")
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, 10)
+
+	// Password only: partial token, no session
+	challenge := loginChallenge(t, authService, user.Email)
+	assert.False(t, challenge.SetupRequired)
+	sessions, err := authService.ListSessions(user.Id)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	// The code used for the confirmation cannot be replayed
+	_, _, err = authService.VerifyTwoFactor(challenge.Token, totpCode(t, setup.Secret, 0), services.SessionMetadata{})
+	assert.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+	pair, codes, err := authService.VerifyTwoFactor(challenge.Token, totpCode(t, setup.Secret, 1), services.SessionMetadata{})
+	require.NoError(t, err)
+	assert.Nil(t, codes)
+	assert.NotEmpty(t, pair.AccessToken)
+
+	// A partial token is single use
+	_, _, err = authService.VerifyTwoFactor(challenge.Token, recoveryCodes[0], services.SessionMetadata{})
+	assert.ErrorIs(t, err, services.ErrTwoFactorChallengeInvalid)
+
+	// Recovery codes work once each
+	challenge = loginChallenge(t, authService, user.Email)
+	_, _, err = authService.VerifyTwoFactor(challenge.Token, recoveryCodes[0], services.SessionMetadata{})
+	require.NoError(t, err)
+	challenge = loginChallenge(t, authService, user.Email)
+	_, _, err = authService.VerifyTwoFactor(challenge.Token, recoveryCodes[0], services.SessionMetadata{})
+	assert.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+
+	status, err := authService.GetTwoFactorStatus(user.Id)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, int64(9), status.RecoveryCodesRemaining)
+
+	var used int64
+	require.NoError(t, db.Model(&models.ActionHistory{}).Where("action = ?", "recovery_code_used").Count(&used).Error)
+	assert.Equal(t, int64(1), used)
+}
+
+// TestAuthService_TwoFactorAttemptsLimited tests that a partial token is burnt after too many wrong codes
+func TestAuthService_TwoFactorAttemptsLimited(t *testing.T) {
+	_, authService, user := setupTwoFactorTest(t)
+	setup, err := authService.BeginTwoFactorSetup(user.Id)
+	require.NoError(t, err)
+	_, err = authService.ConfirmTwoFactor(user.Id, totpCode(t, setup.Secret, 0), "", "")
+	require.NoError(t, err)
+
+	challenge := loginChallenge(t, authService, user.Email)
+	for i := 0; i < 5; i++ {
+		_, _, err = authService.VerifyTwoFactor(challenge.Token, "000000", services.SessionMetadata{})
+		assert.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+	}
+	_, _, err = authService.VerifyTwoFactor(challenge.Token, totpCode(t, setup.Secret, 1), services.SessionMetadata{})
+	assert.ErrorIs(t, err, services.ErrTwoFactorChallengeInvalid)
+}
+
+// TestAuthService_TwoFactorEnforcedByRole tests enrolment during login when the role requires 2FA
+func TestAuthService_TwoFactorEnforcedByRole(t *testing.T) {
+	db, authService, user := setupTwoFactorTest(t)
+	userService := services.NewUserService(repositories.NewUserRepository(db), repositories.NewRoleRepository(db), repositories.NewActionHistoryRepository(db))
+	role, err := userService.SetRoleTwoFactorRequired(1, *user.RoleID, true, "", "")
+	require.NoError(t, err)
+	assert.True(t, role.RequireTwoFactor)
+
+	challenge := loginChallenge(t, authService, user.Email)
+	assert.True(t, challenge.SetupRequired)
+
+	setup, err := authService.BeginTwoFactorSetupWithChallenge(challenge.Token)
+	require.NoError(t, err)
+	pair, recoveryCodes, err := authService.VerifyTwoFactor(challenge.Token, totpCode(t, setup.Secret, 0), services.SessionMetadata{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, pair.AccessToken)
+	assert.Len(t, recoveryCodes, 10, "recovery codes are returned when enrolment completes during login")
+
+	assert.ErrorIs(t, authService.DisableTwoFactor(user.Id, sessionTestPassword, totpCode(t, setup.Secret, 1), "", ""), services.ErrTwoFactorEnforced)
+
+	// Admin reset: the user enrols again at next login
+	require.NoError(t, authService.ResetTwoFactor(1, user.Id, "", ""))
+	status, err := authService.GetTwoFactorStatus(user.Id)
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+	assert.True(t, status.Required)
+	assert.True(t, loginChallenge(t, authService, user.Email).SetupRequired)
+}
+
+// TestAuthMiddleware_RejectsPartialToken tests that the partial token does not give access to the API
+func TestAuthMiddleware_RejectsPartialToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, authService, user := setupTwoFactorTest(t)
+	setup, err := authService.BeginTwoFactorSetup(user.Id)
+	require.NoError(t, err)
+	_, err = authService.ConfirmTwoFactor(user.Id, totpCode(t, setup.Secret, 0), "", "")
+	require.NoError(t, err)
+	challenge := loginChallenge(t, authService, user.Email)
+
+	router := gin.New()
+	router.GET("/protected", middlewares.NewAuthMiddleware("test-secret-key").RequireAuth(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+challenge.Token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	_, err = authService.GetUserFromToken(challenge.Token)
+	assert.Error(t, err)
+}
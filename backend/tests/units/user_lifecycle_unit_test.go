@@ -0,0 +1,319 @@
+package units
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RyanLadmia/plateforme-safebase/internal/models"
+	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// setupUserDeletionTest returns a deletion service whose backups live in the returned mock cloud storage
+func setupUserDeletionTest(t *testing.T) (*gorm.DB, *services.UserDeletionService, *MockCloudStorage, *services.AuthorizationService) {
+	db, _, authz := setupTeamTest(t)
+	require.NoError(t, db.AutoMigrate(&models.Restore{}, &models.Schedule{}, &models.APIToken{}, &models.RecoveryCode{}, &models.Quota{}))
+
+	backupRepo := repositories.NewBackupRepository(db)
+	backupService := services.NewBackupService(backupRepo, nil, nil, t.TempDir())
+	mockCloud := NewMockCloudStorage()
+	backupService.SetCloudStorage(mockCloud)
+
+	deletionService := services.NewUserDeletionService(repositories.NewUserDeletionRepository(db), repositories.NewUserRepository(db),
+		repositories.NewTeamRepository(db), backupService, nil)
+	deletionService.SetAuthorizationService(authz)
+	deletionService.SetActionHistoryService(services.NewActionHistoryService(repositories.NewActionHistoryRepository(db)))
+	return db, deletionService, mockCloud, authz
+}
+
+// waitUserDeletion polls a deletion job until it finishes
+func waitUserDeletion(t *testing.T, deletionService *services.UserDeletionService, id string) *services.UserDeletionJob {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := deletionService.GetJob(id)
+		require.NoError(t, err)
+		if job.Status == services.UserDeletionCompleted || job.Status == services.UserDeletionFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("user deletion did not finish")
+	return nil
+}
+
+// ============================================================================
+// UNIT TESTS - User creation, invitation and deletion
+// ============================================================================
+
+// TestUserService_CreateAndInvite tests an account created without a password then activated through its invitation
+func TestUserService_CreateAndInvite(t *testing.T) {
+	db, authService, _, _ := setupSessionTest(t)
+	mail := newFakeMailer()
+	authService.SetMailer(mail, "https://safebase.test/reset-password")
+	userService := services.NewUserService(repositories.NewUserRepository(db), repositories.NewRoleRepository(db), repositories.NewActionHistoryRepository(db))
+	admin := createTestUser(db, "admin@example.com", "Password123!", 1)
+
+	_, err := userService.CreateUser(admin.Id, "Jane", "Doe", "sessions@example.com", "", nil, "", "")
+	assert.Error(t, err, "email already used")
+	_, err = userService.CreateUser(admin.Id, "Jane", "Doe", "jane@example.com", "weak", nil, "", "")
+	assert.Error(t, err, "explicit passwords are validated")
+
+	roleID := uint(2)
+	user, err := userService.CreateUser(admin.Id, " Jane ", "Doe", "jane@example.com", "", &roleID, "10.0.0.1", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", user.Firstname)
+	assert.True(t, user.Active)
+	_, err = authService.LoginWithMetadata("jane@example.com", "", services.SessionMetadata{})
+	assert.Error(t, err, "the random password is unusable")
+
+	require.NoError(t, authService.InviteUser(admin.Id, user.Id, "10.0.0.1", "test"))
+	token := waitResetToken(t, mail)
+	require.NoError(t, authService.ResetPassword(token, "Ch0sen-Passw0rd!", "", ""))
+	_, err = authService.LoginWithMetadata("jane@example.com", "Ch0sen-Passw0rd!", services.SessionMetadata{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), countActions(t, db, "user_created"))
+	var invited models.ActionHistory
+	require.NoError(t, db.Where("action = ?", "user_invited").First(&invited).Error)
+	assert.Equal(t, admin.Id, invited.UserId)
+	assert.Equal(t, user.Id, invited.ResourceId)
+}
+
+// TestUserDeletion_Purge tests that purging a user removes their cloud objects, rows and personal data
+func TestUserDeletion_Purge(t *testing.T) {
+	db, deletionService, mockCloud, authz := setupUserDeletionTest(t)
+	admin := createTestUser(db, "admin@example.com", "Password123!", 1)
+	user := createTestUser(db, "leaving@example.com", "Password123!", 2)
+	other := createTestUser(db, "other@example.com", "Password123!", 2)
+	team, err := authz.PersonalTeam(user.Id)
+	require.NoError(t, err)
+
+	database := newQuotaTestDatabase("leaving_prod", user.Id, &team.Id)
+	require.NoError(t, db.Create(database).Error)
+	backup := createTestBackup(db, database.Id, user.Id, "completed")
+	mockCloud.uploadedFiles[backup.Filepath] = []byte("backup data")
+	require.NoError(t, db.Create(&models.Schedule{Name: "Nuit", CronExpression: "0 3 * * *", UserId: user.Id, DatabaseId: database.Id}).Error)
+	otherDB := newQuotaTestDatabase("other_prod", other.Id, nil)
+	require.NoError(t, db.Create(otherDB).Error)
+	otherBackup := createTestBackup(db, otherDB.Id, other.Id, "completed")
+
+	_, err = deletionService.StartDeletion(user.Id, user.Id, services.UserDeletionPurge, 0, "", "")
+	assert.Error(t, err, "self-deletion is rejected")
+	_, err = deletionService.StartDeletion(admin.Id, user.Id, "archive", 0, "", "")
+	assert.Error(t, err)
+
+	job, err := deletionService.StartDeletion(admin.Id, user.Id, services.UserDeletionPurge, 0, "10.0.0.1", "test")
+	require.NoError(t, err)
+	job = waitUserDeletion(t, deletionService, job.ID)
+	require.Equal(t, services.UserDeletionCompleted, job.Status, job.Errors)
+	assert.Equal(t, services.UserDeletionProgress{Total: 1, Processed: 1}, job.Backups)
+	assert.Equal(t, 1, job.Schedules.Processed)
+	assert.Equal(t, 1, job.Databases.Processed)
+
+	// The object and every row are gone, the resources of other users are kept
+	assert.NotContains(t, mockCloud.uploadedFiles, backup.Filepath)
+	var count int64
+	db.Unscoped().Model(&models.Backup{}).Where("id = ?", backup.Id).Count(&count)
+	assert.Zero(t, count)
+	db.Unscoped().Model(&models.Database{}).Where("id = ?", database.Id).Count(&count)
+	assert.Zero(t, count)
+	db.Unscoped().Model(&models.Schedule{}).Count(&count)
+	assert.Zero(t, count)
+	db.Model(&models.Team{}).Where("id = ?", team.Id).Count(&count)
+	assert.Zero(t, count)
+	require.NoError(t, db.First(&models.Backup{}, otherBackup.Id).Error)
+
+	// The account is scrubbed and soft deleted, its email can be used again
+	var deleted models.User
+	require.NoError(t, db.Unscoped().First(&deleted, user.Id).Error)
+	assert.True(t, deleted.DeletedAt.Valid)
+	assert.False(t, deleted.Active)
+	assert.NotEqual(t, "leaving@example.com", deleted.Email)
+	assert.Empty(t, deleted.Password)
+	assert.Error(t, db.Where("email = ?", "leaving@example.com").First(&models.User{}).Error)
+
+	var action models.ActionHistory
+	require.NoError(t, db.Where("action = ?", "user_deleted").First(&action).Error)
+	assert.Equal(t, admin.Id, action.UserId)
+	assert.True(t, action.AdminAction)
+}
+
+// TestUserDeletion_PurgeKeepsAccountWhenStorageFails tests that a backup whose object cannot be deleted stops the deletion
+func TestUserDeletion_PurgeKeepsAccountWhenStorageFails(t *testing.T) {
+	db, deletionService, mockCloud, _ := setupUserDeletionTest(t)
+	admin := createTestUser(db, "admin@example.com", "Password123!", 1)
+	user := createTestUser(db, "leaving@example.com", "Password123!", 2)
+	database := newQuotaTestDatabase("leaving_prod", user.Id, nil)
+	require.NoError(t, db.Create(database).Error)
+	backup := createTestBackup(db, database.Id, user.Id, "completed")
+	mockCloud.uploadedFiles[backup.Filepath] = []byte("backup data")
+	mockCloud.shouldFailOnOp = "delete"
+
+	job, err := deletionService.StartDeletion(admin.Id, user.Id, services.UserDeletionPurge, 0, "", "")
+	require.NoError(t, err)
+	job = waitUserDeletion(t, deletionService, job.ID)
+	assert.Equal(t, services.UserDeletionFailed, job.Status)
+	assert.Equal(t, 1, job.Backups.Failed)
+	assert.NotEmpty(t, job.Errors)
+
+	// The account stays deactivated with its backup, a new job can retry
+	var kept models.User
+	require.NoError(t, db.First(&kept, user.Id).Error)
+	assert.False(t, kept.Active)
+	assert.Equal(t, "leaving@example.com", kept.Email)
+	require.NoError(t, db.First(&models.Backup{}, backup.Id).Error)
+	assert.Equal(t, int64(1), countActions(t, db, "user_deletion_failed"))
+
+	mockCloud.shouldFailOnOp = ""
+	job, err = deletionService.StartDeletion(admin.Id, user.Id, services.UserDeletionPurge, 0, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, services.UserDeletionCompleted, waitUserDeletion(t, deletionService, job.ID).Status)
+}
+
+// TestUserDeletion_PurgeKeepsTeamResources tests that purging a member of a team only purges their personal
+// workspace: the team database and the backups of the other member survive, the rows of the user move to the owner
+func TestUserDeletion_PurgeKeepsTeamResources(t *testing.T) {
+	db, deletionService, mockCloud, authz := setupUserDeletionTest(t)
+	admin := createTestUser(db, "admin@example.com", "Password123!", 1)
+	owner := createTestUser(db, "owner@example.com", "Password123!", 2)
+	user := createTestUser(db, "leaving@example.com", "Password123!", 2)
+	personal, err := authz.PersonalTeam(user.Id)
+	require.NoError(t, err)
+
+	team := &models.Team{Name: "Ops", OwnerId: &owner.Id}
+	require.NoError(t, db.Create(team).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamId: team.Id, UserId: owner.Id, Role: models.TeamRoleOwner}).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamId: team.Id, UserId: user.Id, Role: models.TeamRoleAdmin}).Error)
+
+	// The user created the team database and backed it up, like the owner
+	teamDB := newQuotaTestDatabase("team_prod", user.Id, &team.Id)
+	require.NoError(t, db.Create(teamDB).Error)
+	ownerBackup := createTestBackup(db, teamDB.Id, owner.Id, "completed")
+	ownerBackup.Filepath = "/test/path/owner.zip"
+	require.NoError(t, db.Save(ownerBackup).Error)
+	mockCloud.uploadedFiles[ownerBackup.Filepath] = []byte("owner backup")
+	userTeamBackup := createTestBackup(db, teamDB.Id, user.Id, "completed")
+	userTeamBackup.Filepath = "/test/path/user_team.zip"
+	require.NoError(t, db.Save(userTeamBackup).Error)
+	mockCloud.uploadedFiles[userTeamBackup.Filepath] = []byte("user backup")
+	schedule := &models.Schedule{Name: "Nuit", CronExpression: "0 3 * * *", UserId: user.Id, DatabaseId: teamDB.Id}
+	require.NoError(t, db.Create(schedule).Error)
+
+	personalDB := newQuotaTestDatabase("leaving_prod", user.Id, &personal.Id)
+	require.NoError(t, db.Create(personalDB).Error)
+	personalBackup := createTestBackup(db, personalDB.Id, user.Id, "completed")
+	personalBackup.Filepath = "/test/path/personal.zip"
+	require.NoError(t, db.Save(personalBackup).Error)
+	mockCloud.uploadedFiles[personalBackup.Filepath] = []byte("personal backup")
+
+	job, err := deletionService.StartDeletion(admin.Id, user.Id, services.UserDeletionPurge, 0, "", "")
+	require.NoError(t, err)
+	job = waitUserDeletion(t, deletionService, job.ID)
+	require.Equal(t, services.UserDeletionCompleted, job.Status, job.Errors)
+	assert.Equal(t, services.UserDeletionProgress{Total: 1, Processed: 1}, job.Databases)
+	assert.Equal(t, services.UserDeletionProgress{Total: 1, Processed: 1}, job.Backups)
+	require.NotNil(t, job.HandedOver)
+	assert.Equal(t, repositories.UserReassignment{Databases: 1, Schedules: 1, Backups: 1}, *job.HandedOver)
+
+	// Only the personal workspace is purged
+	var count int64
+	db.Unscoped().Model(&models.Database{}).Where("id = ?", personalDB.Id).Count(&count)
+	assert.Zero(t, count)
+	assert.NotContains(t, mockCloud.uploadedFiles, personalBackup.Filepath)
+
+	// The team keeps its database, every backup and object, and the schedule, now owned by the team owner
+	var kept models.Database
+	require.NoError(t, db.First(&kept, teamDB.Id).Error)
+	assert.Equal(t, owner.Id, kept.UserId)
+	require.NoError(t, db.First(&models.Backup{}, ownerBackup.Id).Error)
+	assert.Contains(t, mockCloud.uploadedFiles, ownerBackup.Filepath)
+	var handedOver models.Backup
+	require.NoError(t, db.First(&handedOver, userTeamBackup.Id).Error)
+	assert.Equal(t, owner.Id, handedOver.UserId)
+	assert.Contains(t, mockCloud.uploadedFiles, userTeamBackup.Filepath)
+	var keptSchedule models.Schedule
+	require.NoError(t, db.First(&keptSchedule, schedule.Id).Error)
+	assert.Equal(t, owner.Id, keptSchedule.UserId)
+	db.Model(&models.TeamMember{}).Where("team_id = ?", team.Id).Count(&count)
+	assert.Equal(t, int64(1), count, "the owner stays in the team")
+}
+
+// TestUserDeletion_TransfersTeamOwnership tests that a team whose only owner is deleted gets a new owner
+func TestUserDeletion_TransfersTeamOwnership(t *testing.T) {
+	db, deletionService, _, _ := setupUserDeletionTest(t)
+	admin := createTestUser(db, "admin@example.com", "Password123!", 1)
+	user := createTestUser(db, "leaving@example.com", "Password123!", 2)
+	viewer := createTestUser(db, "viewer@example.com", "Password123!", 2)
+	editor := createTestUser(db, "editor@example.com", "Password123!", 2)
+	staffed := &models.Team{Name: "Ops"}
+	require.NoError(t, db.Create(staffed).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamId: staffed.Id, UserId: user.Id, Role: models.TeamRoleOwner}).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamId: staffed.Id, UserId: viewer.Id, Role: models.TeamRoleViewer}).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamId: staffed.Id, UserId: editor.Id, Role: models.TeamRoleMember}).Error)
+	alone := &models.Team{Name: "Solo"}
+	require.NoError(t, db.Create(alone).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamId: alone.Id, UserId: user.Id, Role: models.TeamRoleOwner}).Error)
+
+	assert.ErrorIs(t, deletionService.CheckErasure(user.Id), services.ErrLastTeamOwner)
+
+	job, err := deletionService.StartDeletion(admin.Id, user.Id, services.UserDeletionPurge, 0, "", "")
+	require.NoError(t, err)
+	job = waitUserDeletion(t, deletionService, job.ID)
+	require.Equal(t, services.UserDeletionCompleted, job.Status, job.Errors)
+	assert.Equal(t, map[uint]uint{staffed.Id: editor.Id, alone.Id: admin.Id}, job.TeamsTransferred)
+
+	// The highest remaining role becomes owner, the administrator takes over a team without other members
+	var owner models.TeamMember
+	require.NoError(t, db.Where("team_id = ? AND role = ?", staffed.Id, models.TeamRoleOwner).First(&owner).Error)
+	assert.Equal(t, editor.Id, owner.UserId)
+	var adminOwner models.TeamMember
+	require.NoError(t, db.Where("team_id = ? AND role = ?", alone.Id, models.TeamRoleOwner).First(&adminOwner).Error)
+	assert.Equal(t, admin.Id, adminOwner.UserId)
+}
+
+// TestUserDeletion_Reassign tests that the resources of a deleted user move to the new owner and their workspace
+func TestUserDeletion_Reassign(t *testing.T) {
+	db, deletionService, mockCloud, authz := setupUserDeletionTest(t)
+	admin := createTestUser(db, "admin@example.com", "Password123!", 1)
+	user := createTestUser(db, "leaving@example.com", "Password123!", 2)
+	target := createTestUser(db, "target@example.com", "Password123!", 2)
+	personal, err := authz.PersonalTeam(user.Id)
+	require.NoError(t, err)
+
+	database := newQuotaTestDatabase("leaving_prod", user.Id, &personal.Id)
+	require.NoError(t, db.Create(database).Error)
+	backup := createTestBackup(db, database.Id, user.Id, "completed")
+	mockCloud.uploadedFiles[backup.Filepath] = []byte("backup data")
+
+	_, err = deletionService.StartDeletion(admin.Id, user.Id, services.UserDeletionReassign, 0, "", "")
+	assert.Error(t, err, "a new owner is required")
+	_, err = deletionService.StartDeletion(admin.Id, user.Id, services.UserDeletionReassign, user.Id, "", "")
+	assert.Error(t, err)
+
+	job, err := deletionService.StartDeletion(admin.Id, user.Id, services.UserDeletionReassign, target.Id, "", "")
+	require.NoError(t, err)
+	job = waitUserDeletion(t, deletionService, job.ID)
+	require.Equal(t, services.UserDeletionCompleted, job.Status, job.Errors)
+	require.NotNil(t, job.Reassigned)
+	assert.Equal(t, int64(1), job.Reassigned.Databases)
+	assert.Equal(t, int64(1), job.Reassigned.Backups)
+
+	targetTeam, err := authz.PersonalTeam(target.Id)
+	require.NoError(t, err)
+	var moved models.Database
+	require.NoError(t, db.First(&moved, database.Id).Error)
+	assert.Equal(t, target.Id, moved.UserId)
+	require.NotNil(t, moved.TeamId)
+	assert.Equal(t, targetTeam.Id, *moved.TeamId)
+	var movedBackup models.Backup
+	require.NoError(t, db.First(&movedBackup, backup.Id).Error)
+	assert.Equal(t, target.Id, movedBackup.UserId)
+	assert.Contains(t, mockCloud.uploadedFiles, backup.Filepath, "reassigned backups keep their object")
+}
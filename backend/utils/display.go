@@ -10,15 +10,37 @@ import (
 func DisplayEndpoints(port string) {
 	fmt.Printf(config.Cyan + "Available endpoints:\n")
 	fmt.Printf("   GET  /test                              - Test endpoint\n")
+	fmt.Printf("   GET  /health/live                       - Liveness probe\n")
+	fmt.Printf("   GET  /health/ready                      - Readiness probe (dependencies)\n")
 	fmt.Printf("   POST /auth/register                     - User registration\n")
 	fmt.Printf("   POST /auth/login                        - User login\n")
+	fmt.Printf("   POST /auth/refresh                      - Rotate refresh token, new access token\n")
 	fmt.Printf("   POST /auth/logout                       - User logout\n")
+	fmt.Printf("   POST /auth/2fa/setup                    - Enrol TOTP during login (role requires 2FA)\n")
+	fmt.Printf("   POST /auth/2fa/verify                   - Second factor (TOTP or recovery code)\n")
+	fmt.Printf("   POST /auth/forgot-password              - Email a password reset link\n")
+	fmt.Printf("   POST /auth/reset-password               - Reset password with emailed token\n")
+	fmt.Printf("   GET  /auth/oidc/config                  - Login options (SSO, password login)\n")
+	fmt.Printf("   GET  /auth/oidc/login                   - Start OIDC single sign-on (redirect)\n")
+	fmt.Printf("   GET  /auth/oidc/callback                - OIDC callback, opens the session\n")
 	fmt.Printf("   GET  /auth/me                           - Get current user\n")
 	fmt.Printf("   POST /api/databases                     - Create database\n")
 	fmt.Printf("   GET  /api/databases                     - Get user databases\n")
 	fmt.Printf("   GET  /api/databases/:id                 - Get database by ID\n")
 	fmt.Printf("   PUT  /api/databases/:id                 - Update database\n")
 	fmt.Printf("   DELETE /api/databases/:id               - Delete database\n")
+	fmt.Printf("   PUT  /api/databases/:id/passphrase      - Enable/change zero-knowledge backups\n")
+	fmt.Printf("   DELETE /api/databases/:id/passphrase    - Disable zero-knowledge for new backups\n")
+	fmt.Printf("   PUT  /api/databases/:id/team            - Move database to another team\n")
+	fmt.Printf("   PUT  /api/databases/:id/protection      - Require approval of restores (four eyes)\n")
+	fmt.Printf("   GET  /api/teams                         - List my teams\n")
+	fmt.Printf("   POST /api/teams                         - Create team\n")
+	fmt.Printf("   GET  /api/teams/:id                     - Get team and members\n")
+	fmt.Printf("   PUT  /api/teams/:id                     - Rename team\n")
+	fmt.Printf("   DELETE /api/teams/:id                   - Delete team\n")
+	fmt.Printf("   POST /api/teams/:id/members             - Add team member\n")
+	fmt.Printf("   PUT  /api/teams/:id/members/:user_id    - Change member role\n")
+	fmt.Printf("   DELETE /api/teams/:id/members/:user_id  - Remove member / leave team\n")
 	fmt.Printf("   POST /api/backups/database/:database_id - Create backup\n")
 	fmt.Printf("   GET  /api/backups                       - Get user backups\n")
 	fmt.Printf("   GET  /api/backups/:id                   - Get backup by ID\n")
@@ -34,15 +56,68 @@ func DisplayEndpoints(port string) {
 	fmt.Printf("   GET  /api/restores/:id                  - Get restore by ID\n")
 	fmt.Printf("   GET  /api/restores/database/:database_id - Get restores by database\n")
 	fmt.Printf("   GET  /api/restores/backup/:backup_id    - Get restores by backup\n")
+	fmt.Printf("   GET  /api/restores/approvals            - Restores awaiting my approval\n")
+	fmt.Printf("   POST /api/restores/:id/approve          - Approve and start a restore\n")
+	fmt.Printf("   POST /api/restores/:id/reject           - Reject a restore\n")
+	fmt.Printf("   GET  /api/profile/sessions              - List my sessions (devices)\n")
+	fmt.Printf("   DELETE /api/profile/sessions            - Log out all other sessions\n")
+	fmt.Printf("   DELETE /api/profile/sessions/:id        - Revoke one session\n")
+	fmt.Printf("   GET  /api/profile/2fa                   - Two-factor status\n")
+	fmt.Printf("   POST /api/profile/2fa/setup             - Start TOTP enrolment (QR provisioning URI)\n")
+	fmt.Printf("   POST /api/profile/2fa/confirm           - Confirm TOTP code, get recovery codes\n")
+	fmt.Printf("   POST /api/profile/2fa/recovery-codes    - Regenerate recovery codes\n")
+	fmt.Printf("   DELETE /api/profile/2fa                 - Disable two-factor authentication\n")
+	fmt.Printf("   GET  /api/profile/tokens                - List my API tokens\n")
+	fmt.Printf("   POST /api/profile/tokens                - Create an API token (shown once)\n")
+	fmt.Printf("   DELETE /api/profile/tokens/:id          - Revoke an API token\n")
+	fmt.Printf("   GET  /api/profile/usage                 - My quotas and usage\n")
+	fmt.Printf("   GET  /api/profile/export                - Export my personal data (ZIP)\n")
+	fmt.Printf("   DELETE /api/profile                     - Request erasure of my account\n")
+	fmt.Printf("   POST /api/profile/erasure/cancel        - Cancel the erasure of my account\n")
 	fmt.Printf("   GET  /api/admin/users                   - Get all users (admin)\n")
 	fmt.Printf("   GET  /api/admin/users/active            - Get active users (admin)\n")
 	fmt.Printf("   GET  /api/admin/users/:id               - Get user by ID (admin)\n")
+	fmt.Printf("   POST /api/admin/users                   - Create a user, invited by email without password (admin)\n")
+	fmt.Printf("   POST /api/admin/users/:id/invite        - Send a new invitation link (admin)\n")
+	fmt.Printf("   DELETE /api/admin/users/:id             - Delete a user, reassign or purge resources (admin)\n")
+	fmt.Printf("   GET  /api/admin/user-deletions/:id      - User deletion progress (admin)\n")
 	fmt.Printf("   PUT  /api/admin/users/:id               - Update user (admin)\n")
 	fmt.Printf("   PUT  /api/admin/users/:id/role          - Change user role (admin)\n")
 	fmt.Printf("   PUT  /api/admin/users/:id/deactivate    - Deactivate user (admin)\n")
 	fmt.Printf("   PUT  /api/admin/users/:id/activate      - Activate user (admin)\n")
-	fmt.Printf("   GET  /api/history                       - Get user action history\n")
+	fmt.Printf("   DELETE /api/admin/users/:id/sessions    - Revoke all user sessions (admin)\n")
+	fmt.Printf("   DELETE /api/admin/users/:id/2fa         - Reset user two-factor (admin)\n")
+	fmt.Printf("   POST /api/admin/users/:id/unlock        - Unlock account after failed logins (admin)\n")
+	fmt.Printf("   GET|PUT|DELETE /api/admin/users/:id/quota - User quota override (admin)\n")
+	fmt.Printf("   GET|PUT|DELETE /api/admin/teams/:id/quota - Team quota (admin)\n")
+	fmt.Printf("   GET  /api/admin/auth/settings           - Authentication settings (admin)\n")
+	fmt.Printf("   PUT  /api/admin/auth/settings           - Enable/disable password login (admin)\n")
+	fmt.Printf("   GET  /api/admin/roles                   - List roles (admin)\n")
+	fmt.Printf("   GET  /api/admin/roles/permissions       - Permission catalog (admin)\n")
+	fmt.Printf("   POST /api/admin/roles                   - Create a custom role (admin)\n")
+	fmt.Printf("   PUT  /api/admin/roles/:id/permissions   - Set permissions of a custom role (admin)\n")
+	fmt.Printf("   DELETE /api/admin/roles/:id             - Delete a custom role (admin)\n")
+	fmt.Printf("   PUT  /api/admin/roles/:id/two-factor    - Require 2FA for a role (admin)\n")
+	fmt.Printf("   GET  /api/admin/databases               - Databases of every user, filterable (admin)\n")
+	fmt.Printf("   GET  /api/admin/backups                 - Backups of every user, filterable (admin)\n")
+	fmt.Printf("   GET  /api/admin/restores                - Restores of every user, filterable (admin)\n")
+	fmt.Printf("   GET  /api/admin/schedules               - Schedules of every user, filterable (admin)\n")
+	fmt.Printf("   POST /api/admin/databases/:id/backup    - Backup on behalf of the owner (admin)\n")
+	fmt.Printf("   POST /api/admin/schedules/:id/run       - Run a schedule now (admin)\n")
+	fmt.Printf("   POST /api/admin/backups/:id/cancel      - Cancel a pending or running backup (admin)\n")
+	fmt.Printf("   POST /api/admin/restores/:id/cancel     - Cancel a restore not started yet (admin)\n")
+	fmt.Printf("   GET  /api/admin/keys                    - Encryption keys usage (admin)\n")
+	fmt.Printf("   POST /api/admin/keys/rotate             - Re-encrypt data to the primary key (admin)\n")
+	fmt.Printf("   POST /api/admin/keys/migrate-objects    - Re-encrypt backups with derived keys (admin)\n")
+	fmt.Printf("   GET  /api/admin/keys/rotations/:id      - Key job progress (admin)\n")
+	fmt.Printf("   GET  /api/history                       - Search user action history (filters, cursor)\n")
+	fmt.Printf("   GET  /api/history/export                - Export user action history (csv, json, ndjson)\n")
 	fmt.Printf("   GET  /api/history/type/:type            - Get action history by type\n")
 	fmt.Printf("   GET  /api/history/resource/:type/:id    - Get action history for resource\n")
-	fmt.Printf("   GET  /api/history/recent                - Get recent action history\n" + config.Reset)
+	fmt.Printf("   GET  /api/history/recent                - Get recent action history (audit.read)\n")
+	fmt.Printf("   GET  /api/history/audit                 - Search action history of every user (audit.read)\n")
+	fmt.Printf("   GET  /api/history/audit/export          - Export action history of every user (audit.read)\n")
+	fmt.Printf("   GET  /api/admin/audit/verify            - Verify the action history hash chain (audit.read)\n")
+	fmt.Printf("   GET  /api/admin/audit/anchors           - Chain heads anchored in backup storage (audit.read)\n")
+	fmt.Printf("   POST /api/admin/audit/anchor            - Anchor the chain head now (admin)\n" + config.Reset)
 }
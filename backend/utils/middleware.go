@@ -33,8 +33,8 @@ func CORSMiddleware() gin.HandlerFunc {
 		}
 
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, Cookie")
-		c.Header("Access-Control-Expose-Headers", "Set-Cookie")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, Cookie, X-Request-ID, X-Backup-Passphrase, traceparent, tracestate")
+		c.Header("Access-Control-Expose-Headers", "Set-Cookie, X-Request-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
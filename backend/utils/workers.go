@@ -1,17 +1,22 @@
 package utils
 
 import (
+	"context"
 	"log"
+	"log/slog"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/RyanLadmia/plateforme-safebase/internal/repositories"
+	"github.com/RyanLadmia/plateforme-safebase/internal/services"
 )
 
 // Worker pool for background tasks
 type WorkerPool struct {
 	workers   int
 	taskQueue chan func()
+	active    atomic.Int32 // Workers currently running a task
 }
 
 func NewWorkerPool(workers int) *WorkerPool {
@@ -36,11 +41,33 @@ func (wp *WorkerPool) worker(id int) {
 					log.Printf("Worker %d panic: %v", id, r)
 				}
 			}()
+			wp.active.Add(1)
+			defer wp.active.Add(-1)
 			task()
 		}()
 	}
 }
 
+// Workers returns the number of workers of the pool
+func (wp *WorkerPool) Workers() int {
+	return wp.workers
+}
+
+// ActiveWorkers returns the number of workers currently running a task
+func (wp *WorkerPool) ActiveWorkers() int {
+	return int(wp.active.Load())
+}
+
+// QueueLength returns the number of tasks waiting for a worker
+func (wp *WorkerPool) QueueLength() int {
+	return len(wp.taskQueue)
+}
+
+// QueueCapacity returns the size of the task buffer (Submit runs tasks synchronously beyond it)
+func (wp *WorkerPool) QueueCapacity() int {
+	return cap(wp.taskQueue)
+}
+
 func (wp *WorkerPool) Submit(task func()) {
 	select {
 	case wp.taskQueue <- task:
@@ -67,6 +94,38 @@ func StartSessionCleanupWorker(sessionRepo *repositories.SessionRepository) {
 	}
 }
 
+// StartAccountErasureWorker starts the erasure of the accounts whose grace period has ended
+func StartAccountErasureWorker(privacyService *services.PrivacyService) {
+	slog.Info("starting account erasure worker")
+	ticker := time.NewTicker(1 * time.Hour) // Check every hour
+	defer ticker.Stop()
+
+	for range ticker.C {
+		started, err := privacyService.ProcessDueErasures()
+		if err != nil {
+			slog.Error("account erasure failed", "error", err)
+		} else if started > 0 {
+			slog.Info("account erasures started", "count", started)
+		}
+	}
+}
+
+// StartAuditAnchorWorker periodically copies the head of the action history chain into backup storage
+func StartAuditAnchorWorker(auditService *services.AuditService, interval time.Duration) {
+	slog.Info("starting audit anchor worker", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		anchor, created, err := auditService.AnchorHead(context.Background())
+		if err != nil {
+			slog.Error("audit anchoring failed", "error", err)
+		} else if created {
+			slog.Info("audit chain anchored", "history_id", anchor.HistoryId, "remote_path", anchor.RemotePath)
+		}
+	}
+}
+
 // StartBackupCleanupWorker starts the backup cleanup worker
 func StartBackupCleanupWorker(backupRepo *repositories.BackupRepository, workerPool *WorkerPool) {
 	log.Println("Starting backup cleanup worker...")
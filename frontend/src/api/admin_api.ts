@@ -0,0 +1,69 @@
+// API d'administration des ressources de tous les utilisateurs - Appels réseau purs avec Axios
+import { apiClient } from './axios'
+import type { Backup } from '@/types/backup'
+import type { Restore } from '@/types/restore'
+import type { AdminDatabase, AdminBackup, AdminRestore, AdminSchedule, AdminFilters, AdminListResponse } from '@/types/admin'
+
+/**
+ * Liste les bases de données de tous les utilisateurs
+ */
+export async function getAdminDatabases(filters: AdminFilters = {}): Promise<AdminListResponse<AdminDatabase>> {
+  const { data } = await apiClient.get<AdminListResponse<AdminDatabase>>('/api/admin/databases', { params: filters })
+  return data
+}
+
+/**
+ * Liste les sauvegardes de tous les utilisateurs
+ */
+export async function getAdminBackups(filters: AdminFilters = {}): Promise<AdminListResponse<AdminBackup>> {
+  const { data } = await apiClient.get<AdminListResponse<AdminBackup>>('/api/admin/backups', { params: filters })
+  return data
+}
+
+/**
+ * Liste les restaurations de tous les utilisateurs
+ */
+export async function getAdminRestores(filters: AdminFilters = {}): Promise<AdminListResponse<AdminRestore>> {
+  const { data } = await apiClient.get<AdminListResponse<AdminRestore>>('/api/admin/restores', { params: filters })
+  return data
+}
+
+/**
+ * Liste les planifications de tous les utilisateurs
+ */
+export async function getAdminSchedules(filters: AdminFilters = {}): Promise<AdminListResponse<AdminSchedule>> {
+  const { data } = await apiClient.get<AdminListResponse<AdminSchedule>>('/api/admin/schedules', { params: filters })
+  return data
+}
+
+/**
+ * Lance une sauvegarde pour le compte du propriétaire de la base
+ */
+export async function triggerAdminBackup(databaseId: number): Promise<Backup> {
+  const { data } = await apiClient.post<{ backup: Backup }>(`/api/admin/databases/${databaseId}/backup`)
+  return data.backup
+}
+
+/**
+ * Exécute immédiatement une planification
+ */
+export async function runAdminSchedule(scheduleId: number): Promise<Backup> {
+  const { data } = await apiClient.post<{ backup: Backup }>(`/api/admin/schedules/${scheduleId}/run`)
+  return data.backup
+}
+
+/**
+ * Annule une sauvegarde en attente ou en cours
+ */
+export async function cancelAdminBackup(backupId: number): Promise<Backup> {
+  const { data } = await apiClient.post<{ backup: Backup }>(`/api/admin/backups/${backupId}/cancel`)
+  return data.backup
+}
+
+/**
+ * Annule une restauration qui n'a pas encore démarré
+ */
+export async function cancelAdminRestore(restoreId: number): Promise<Restore> {
+  const { data } = await apiClient.post<{ restore: Restore }>(`/api/admin/restores/${restoreId}/cancel`)
+  return data.restore
+}
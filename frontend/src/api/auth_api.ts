@@ -1,11 +1,12 @@
 // API pour l'authentification - Appels réseau purs avec Axios
 import { apiClient } from './axios'
-import type { User, LoginRequest, RegisterRequest } from '@/types/auth'
+import type { User, LoginRequest, RegisterRequest, LoginResult, LoginOptions, TwoFactorChallenge, TwoFactorSetup } from '@/types/auth'
 
 export interface AuthResponse {
   user: User
   token?: string
   message?: string
+  recovery_codes?: string[]
 }
 
 export interface RegisterResponse {
@@ -43,12 +44,34 @@ export async function register(userData: RegisterRequest): Promise<RegisterRespo
  * Connexion d'un utilisateur
  * Le token JWT est automatiquement stocké dans un cookie HTTP-only sécurisé par le backend
  */
-export async function login(credentials: LoginRequest): Promise<User> {
-  const { data } = await apiClient.post<AuthResponse>('/auth/login', credentials)
-  
+export async function login(credentials: LoginRequest): Promise<LoginResult> {
+  const { data } = await apiClient.post<AuthResponse | TwoFactorChallenge>('/auth/login', credentials)
+
+  // Double authentification : aucun cookie n'est encore posé, il faut vérifier le code
+  if ('two_factor_required' in data) {
+    return { challenge: data }
+  }
+
   // Le token est déjà dans le cookie HTTP-only (géré par le backend)
   // Pas besoin de le stocker côté frontend (plus sécurisé)
-  return data.user
+  return { user: data.user }
+}
+
+/**
+ * Second facteur de la connexion : code TOTP à 6 chiffres ou code de secours
+ * Les codes de secours sont retournés quand l'enrôlement vient d'être terminé
+ */
+export async function verifyTwoFactor(token: string, code: string): Promise<AuthResponse> {
+  const { data } = await apiClient.post<AuthResponse>('/auth/2fa/verify', { two_factor_token: token, code })
+  return data
+}
+
+/**
+ * Démarre l'enrôlement TOTP imposé par le rôle pendant la connexion
+ */
+export async function setupTwoFactorChallenge(token: string): Promise<TwoFactorSetup> {
+  const { data } = await apiClient.post<TwoFactorSetup>('/auth/2fa/setup', { two_factor_token: token })
+  return data
 }
 
 /**
@@ -58,3 +81,41 @@ export async function logout(): Promise<void> {
   // Le cookie sera supprimé par le backend
   await apiClient.post('/auth/logout')
 }
+
+/**
+ * Demande un lien de réinitialisation du mot de passe par email
+ * La réponse est identique que le compte existe ou non
+ */
+export async function forgotPassword(email: string): Promise<string> {
+  const { data } = await apiClient.post<{ message: string }>('/auth/forgot-password', { email })
+  return data.message
+}
+
+/**
+ * Définit un nouveau mot de passe avec le token reçu par email
+ * Toutes les sessions de l'utilisateur sont fermées par le backend
+ */
+export async function resetPassword(token: string, password: string, confirmPassword: string): Promise<string> {
+  const { data } = await apiClient.post<{ message: string }>('/auth/reset-password', {
+    token,
+    password,
+    confirm_password: confirmPassword,
+  })
+  return data.message
+}
+
+/**
+ * Modes de connexion disponibles (bouton SSO, formulaire de mot de passe)
+ */
+export async function getLoginOptions(): Promise<LoginOptions> {
+  const { data } = await apiClient.get<LoginOptions>('/auth/oidc/config')
+  return data
+}
+
+/**
+ * URL de départ de la connexion SSO : le navigateur y est redirigé (pas d'appel Axios),
+ * le backend renvoie ensuite vers le frontend avec les cookies de session
+ */
+export function oidcLoginUrl(): string {
+  return `${apiClient.defaults.baseURL}/auth/oidc/login`
+}
@@ -17,10 +17,45 @@ export const apiClient = axios.create({
 // Il est automatiquement envoyé avec chaque requête grâce à withCredentials: true.
 // Cela protège contre les attaques XSS car JavaScript ne peut pas accéder au cookie.
 
+// Les tokens d'accès sont courts : sur un 401, on renouvelle la session une seule fois
+// via le refresh token (cookie HTTP-only) puis on rejoue la requête.
+// Les requêtes concurrentes partagent le même renouvellement (un refresh token ne sert qu'une fois).
+let refreshPromise: Promise<void> | null = null
+
+function refreshSession(): Promise<void> {
+  if (!refreshPromise) {
+    refreshPromise = axios
+      .post(`${API_BASE_URL}/auth/refresh`, null, { withCredentials: true })
+      .then(() => undefined)
+      .finally(() => {
+        refreshPromise = null
+      })
+  }
+  return refreshPromise
+}
+
 // Intercepteur de réponse pour gérer les erreurs globalement
 apiClient.interceptors.response.use(
   (response) => response,
-  (error) => {
+  async (error) => {
+    const original = error.config
+    const url: string = original?.url || ''
+    if (
+      error.response?.status === 401 &&
+      original &&
+      !original._retried &&
+      !url.startsWith('/auth/login') &&
+      !url.startsWith('/auth/refresh')
+    ) {
+      original._retried = true
+      try {
+        await refreshSession()
+        return apiClient(original)
+      } catch {
+        // Session expirée ou révoquée : on remonte l'erreur 401 d'origine
+      }
+    }
+
     // Extraire le message d'erreur du backend
     const message = error.response?.data?.error || error.message || 'Une erreur est survenue'
     
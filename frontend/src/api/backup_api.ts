@@ -86,3 +86,28 @@ export async function getRestoreById(id: number): Promise<any> {
   const { data } = await apiClient.get(`/api/restores/${id}`)
   return data.restore
 }
+
+/**
+ * Récupère les restaurations vers des bases protégées en attente de l'approbation de l'utilisateur
+ */
+export async function getPendingRestoreApprovals(): Promise<any[]> {
+  const { data } = await apiClient.get('/api/restores/approvals')
+  return data.restores || []
+}
+
+/**
+ * Approuve et lance une restauration (phrase secrète requise pour les sauvegardes qui en ont une)
+ */
+export async function approveRestore(id: number, passphrase?: string): Promise<any> {
+  const headers = passphrase ? { 'X-Backup-Passphrase': passphrase } : undefined
+  const { data } = await apiClient.post(`/api/restores/${id}/approve`, null, { headers })
+  return data.restore
+}
+
+/**
+ * Rejette une restauration avec un motif
+ */
+export async function rejectRestore(id: number, reason: string): Promise<any> {
+  const { data } = await apiClient.post(`/api/restores/${id}/reject`, { reason })
+  return data.restore
+}
@@ -62,3 +62,12 @@ export async function getDatabaseWithBackupCount(id: number): Promise<{ database
   const { data } = await apiClient.get<{ database: Database; backup_count: number }>(`/api/databases/${id}/details`)
   return data
 }
+
+/**
+ * Active ou désactive l'approbation des restaurations par un second utilisateur
+ * (la désactivation doit être confirmée par un autre administrateur : awaiting_confirmation)
+ */
+export async function setDatabaseProtection(id: number, isProtected: boolean): Promise<{ protected: boolean; awaiting_confirmation?: boolean }> {
+  const { data } = await apiClient.put<{ protected: boolean; awaiting_confirmation?: boolean }>(`/api/databases/${id}/protection`, { protected: isProtected })
+  return data
+}
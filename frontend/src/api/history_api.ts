@@ -1,6 +1,6 @@
 // API calls for action history
 import axios from '@/api/axios'
-import type { HistoryResponse, HistoryFilters } from '@/types/history'
+import type { HistoryResponse, HistoryFilters, AuditVerification, AuditAnchor, HistorySearchParams, HistoryExportFormat } from '@/types/history'
 
 /**
  * Get user action history
@@ -12,6 +12,53 @@ export async function getUserActionHistory(page: number = 1, limit: number = 20)
   return response.data
 }
 
+/**
+ * Convert search filters to query parameters (metadata[key]=value)
+ */
+function toHistoryQuery(filters: HistorySearchParams): Record<string, string | number | boolean> {
+  const { metadata, ...rest } = filters
+  const params: Record<string, string | number | boolean> = {}
+  for (const [key, value] of Object.entries(rest)) {
+    if (value !== undefined && value !== '') params[key] = value
+  }
+  for (const [key, value] of Object.entries(metadata || {})) {
+    params[`metadata[${key}]`] = value
+  }
+  return params
+}
+
+/**
+ * Search the action history of the user, or of every user with audit = true (audit.read)
+ */
+export async function searchActionHistory(filters: HistorySearchParams, audit: boolean = false): Promise<HistoryResponse> {
+  const response = await axios.get(audit ? '/api/history/audit' : '/api/history', {
+    params: toHistoryQuery(filters)
+  })
+  return response.data
+}
+
+/**
+ * Download an export of the action history matching the filters
+ */
+export async function exportActionHistory(format: HistoryExportFormat, filters: HistorySearchParams = {}, audit: boolean = false): Promise<void> {
+  const { data, headers } = await axios.get(audit ? '/api/history/audit/export' : '/api/history/export', {
+    params: { ...toHistoryQuery(filters), format },
+    responseType: 'blob'
+  })
+
+  const disposition = String(headers['content-disposition'] || '')
+  const match = disposition.match(/filename="?([^";]+)"?/)
+  const url = window.URL.createObjectURL(new Blob([data], { type: String(headers['content-type'] || '') }))
+  const link = document.createElement('a')
+  link.href = url
+  link.download = match?.[1]?.trim() || `historique.${format}`
+  document.body.appendChild(link)
+  link.click()
+
+  window.URL.revokeObjectURL(url)
+  document.body.removeChild(link)
+}
+
 /**
  * Get action history by resource type
  */
@@ -38,4 +85,30 @@ export async function getRecentActionHistory(page: number = 1, limit: number = 2
     params: { page, limit }
   })
   return response.data
-}
\ No newline at end of file
+}
+
+/**
+ * Verify the hash chain of the action history (a broken chain is returned with status 409)
+ */
+export async function verifyAuditChain(): Promise<AuditVerification> {
+  const response = await axios.get('/api/admin/audit/verify', {
+    validateStatus: (status) => status === 200 || status === 409
+  })
+  return response.data.verification
+}
+
+/**
+ * Get the latest anchors of the chain in backup storage
+ */
+export async function getAuditAnchors(limit: number = 50): Promise<AuditAnchor[]> {
+  const response = await axios.get('/api/admin/audit/anchors', { params: { limit } })
+  return response.data.anchors || []
+}
+
+/**
+ * Anchor the head of the chain into backup storage now
+ */
+export async function anchorAuditChain(): Promise<AuditAnchor> {
+  const response = await axios.post('/api/admin/audit/anchor')
+  return response.data.anchor
+}
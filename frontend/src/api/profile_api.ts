@@ -1,6 +1,7 @@
 // API calls for user profile management
 import { apiClient } from './axios'
 import type { User } from '@/types/user'
+import type { UsageReport } from '@/types/quota'
 
 export interface UpdateProfileRequest {
   firstname: string
@@ -42,3 +43,98 @@ export async function changePassword(passwordData: ChangePasswordRequest): Promi
   await apiClient.put('/api/profile/password', passwordData)
 }
 
+
+export type ApiTokenScope = 'databases:read' | 'backups:read' | 'backups:create' | 'backups:download'
+
+export interface ApiToken {
+  id: number
+  name: string
+  prefix: string
+  scopes: ApiTokenScope[]
+  database_ids: number[]
+  expires_at: string | null
+  last_used_at: string | null
+  last_used_ip: string
+  revoked_at: string | null
+  created_at: string
+}
+
+export interface CreateApiTokenRequest {
+  name: string
+  scopes: ApiTokenScope[]
+  database_ids?: number[]
+  expires_at?: string
+}
+
+export interface CreateApiTokenResponse {
+  message: string
+  token: string // Token en clair, affiché une seule fois
+  api_token: ApiToken
+}
+
+/**
+ * List personal API tokens (automation, CI)
+ */
+export async function getApiTokens(): Promise<ApiToken[]> {
+  const { data } = await apiClient.get<{ tokens: ApiToken[] }>('/api/profile/tokens')
+  return data.tokens
+}
+
+/**
+ * Create a personal API token (the clear-text token is only returned once)
+ */
+export async function createApiToken(request: CreateApiTokenRequest): Promise<CreateApiTokenResponse> {
+  const { data } = await apiClient.post<CreateApiTokenResponse>('/api/profile/tokens', request)
+  return data
+}
+
+/**
+ * Revoke a personal API token
+ */
+export async function revokeApiToken(id: number): Promise<void> {
+  await apiClient.delete(`/api/profile/tokens/${id}`)
+}
+
+/**
+ * Get the quotas and usage of the current user and of their limited teams
+ */
+export async function getUsage(): Promise<UsageReport> {
+  const { data } = await apiClient.get<{ usage: UsageReport }>('/api/profile/usage')
+  return data.usage
+}
+
+/**
+ * Download an archive (ZIP of JSON files) of the personal data of the current user
+ */
+export async function exportPersonalData(): Promise<void> {
+  const { data, headers } = await apiClient.get('/api/profile/export', {
+    responseType: 'blob',
+  })
+
+  const disposition = String(headers['content-disposition'] || '')
+  const match = disposition.match(/filename=([^;]+)/)
+  const url = window.URL.createObjectURL(new Blob([data], { type: 'application/zip' }))
+  const link = document.createElement('a')
+  link.href = url
+  link.download = match?.[1]?.trim() || 'safebase_export.zip'
+  document.body.appendChild(link)
+  link.click()
+
+  window.URL.revokeObjectURL(url)
+  document.body.removeChild(link)
+}
+
+/**
+ * Request the erasure of the current account, effective after the grace period
+ */
+export async function requestAccountErasure(password: string): Promise<string> {
+  const { data } = await apiClient.delete<{ erasure_scheduled_at: string }>('/api/profile', { data: { password } })
+  return data.erasure_scheduled_at
+}
+
+/**
+ * Cancel the pending erasure of the current account
+ */
+export async function cancelAccountErasure(): Promise<void> {
+  await apiClient.post('/api/profile/erasure/cancel')
+}
@@ -0,0 +1,71 @@
+// API pour la gestion des équipes - Appels réseau purs avec Axios
+import { apiClient } from './axios'
+import type { Team, TeamMember, TeamRole, TeamListResponse, TeamResponse, TeamMemberResponse } from '@/types/team'
+
+/**
+ * Récupère les équipes de l'utilisateur (dont son espace personnel)
+ */
+export async function getTeams(): Promise<Team[]> {
+  const { data } = await apiClient.get<TeamListResponse>('/api/teams')
+  return data.teams || []
+}
+
+/**
+ * Récupère une équipe et ses membres
+ */
+export async function getTeamById(id: number): Promise<Team> {
+  const { data } = await apiClient.get<TeamResponse>(`/api/teams/${id}`)
+  return data.team
+}
+
+/**
+ * Crée une équipe
+ */
+export async function createTeam(name: string): Promise<Team> {
+  const { data } = await apiClient.post<TeamResponse>('/api/teams', { name })
+  return data.team
+}
+
+/**
+ * Renomme une équipe
+ */
+export async function renameTeam(id: number, name: string): Promise<Team> {
+  const { data } = await apiClient.put<TeamResponse>(`/api/teams/${id}`, { name })
+  return data.team
+}
+
+/**
+ * Supprime une équipe (elle ne doit plus avoir de bases de données)
+ */
+export async function deleteTeam(id: number): Promise<void> {
+  await apiClient.delete(`/api/teams/${id}`)
+}
+
+/**
+ * Ajoute un membre à une équipe
+ */
+export async function addTeamMember(teamId: number, email: string, role: TeamRole): Promise<TeamMember> {
+  const { data } = await apiClient.post<TeamMemberResponse>(`/api/teams/${teamId}/members`, { email, role })
+  return data.member
+}
+
+/**
+ * Change le rôle d'un membre
+ */
+export async function updateTeamMemberRole(teamId: number, userId: number, role: TeamRole): Promise<void> {
+  await apiClient.put(`/api/teams/${teamId}/members/${userId}`, { role })
+}
+
+/**
+ * Retire un membre d'une équipe (ou quitte l'équipe)
+ */
+export async function removeTeamMember(teamId: number, userId: number): Promise<void> {
+  await apiClient.delete(`/api/teams/${teamId}/members/${userId}`)
+}
+
+/**
+ * Déplace une base de données vers une autre équipe
+ */
+export async function moveDatabaseToTeam(databaseId: number, teamId: number): Promise<void> {
+  await apiClient.put(`/api/databases/${databaseId}/team`, { team_id: teamId })
+}
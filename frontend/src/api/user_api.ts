@@ -1,7 +1,8 @@
 // API pour la gestion des utilisateurs (Admin uniquement) - Appels réseau purs avec Axios
 import { apiClient } from './axios'
-import type { User } from '@/types/auth'
-import type { UserUpdateRequest, UserRoleUpdateRequest, UserListResponse, UserResponse, MessageResponse } from '@/types/user'
+import type { User, Role, Permission } from '@/types/auth'
+import type { UserUpdateRequest, UserRoleUpdateRequest, UserListResponse, UserResponse, MessageResponse, CreateRoleRequest, CreateUserRequest, CreateUserResponse, DeleteUserRequest, UserDeletionJob } from '@/types/user'
+import type { QuotaOverride, QuotaReport } from '@/types/quota'
 
 /**
  * Récupère tous les utilisateurs (Admin uniquement)
@@ -27,6 +28,38 @@ export async function getUserById(userId: number): Promise<User> {
   return data.user
 }
 
+/**
+ * Crée un utilisateur, invité par email s'il n'a pas de mot de passe (Admin uniquement)
+ */
+export async function createUser(userData: CreateUserRequest): Promise<CreateUserResponse> {
+  const { data } = await apiClient.post<CreateUserResponse>('/api/admin/users', userData)
+  return data
+}
+
+/**
+ * Renvoie une invitation à un utilisateur (Admin uniquement)
+ */
+export async function inviteUser(userId: number): Promise<MessageResponse> {
+  const { data } = await apiClient.post<MessageResponse>(`/api/admin/users/${userId}/invite`)
+  return data
+}
+
+/**
+ * Lance la suppression d'un utilisateur en arrière-plan (Admin uniquement)
+ */
+export async function deleteUser(userId: number, request: DeleteUserRequest): Promise<UserDeletionJob> {
+  const { data } = await apiClient.delete<{ job: UserDeletionJob }>(`/api/admin/users/${userId}`, { data: request })
+  return data.job
+}
+
+/**
+ * Récupère l'avancement d'une suppression d'utilisateur (Admin uniquement)
+ */
+export async function getUserDeletion(jobId: string): Promise<UserDeletionJob> {
+  const { data } = await apiClient.get<{ job: UserDeletionJob }>(`/api/admin/user-deletions/${jobId}`)
+  return data.job
+}
+
 /**
  * Met à jour un utilisateur (Admin uniquement)
  */
@@ -58,3 +91,75 @@ export async function activateUser(userId: number): Promise<MessageResponse> {
   const { data } = await apiClient.put<MessageResponse>(`/api/admin/users/${userId}/activate`)
   return data
 }
+
+/**
+ * Déverrouille un compte bloqué après des échecs de connexion (Admin uniquement)
+ */
+export async function unlockUser(userId: number): Promise<MessageResponse> {
+  const { data } = await apiClient.post<MessageResponse>(`/api/admin/users/${userId}/unlock`)
+  return data
+}
+
+/**
+ * Récupère les rôles avec leurs permissions (Admin uniquement)
+ */
+export async function getRoles(): Promise<Role[]> {
+  const { data } = await apiClient.get<{ roles: Role[] }>('/api/admin/roles')
+  return data.roles || []
+}
+
+/**
+ * Récupère le catalogue des permissions (Admin uniquement)
+ */
+export async function getPermissions(): Promise<Permission[]> {
+  const { data } = await apiClient.get<{ permissions: Permission[] }>('/api/admin/roles/permissions')
+  return data.permissions || []
+}
+
+/**
+ * Crée un rôle personnalisé (Admin uniquement)
+ */
+export async function createRole(roleData: CreateRoleRequest): Promise<Role> {
+  const { data } = await apiClient.post<{ role: Role }>('/api/admin/roles', roleData)
+  return data.role
+}
+
+/**
+ * Remplace les permissions d'un rôle personnalisé (Admin uniquement)
+ */
+export async function updateRolePermissions(roleId: number, permissions: string[]): Promise<Role> {
+  const { data } = await apiClient.put<{ role: Role }>(`/api/admin/roles/${roleId}/permissions`, { permissions })
+  return data.role
+}
+
+/**
+ * Supprime un rôle personnalisé (Admin uniquement)
+ */
+export async function deleteRole(roleId: number): Promise<MessageResponse> {
+  const { data } = await apiClient.delete<MessageResponse>(`/api/admin/roles/${roleId}`)
+  return data
+}
+
+/**
+ * Récupère les quotas et la consommation d'un utilisateur (Admin uniquement)
+ */
+export async function getUserQuota(userId: number): Promise<QuotaReport> {
+  const { data } = await apiClient.get<{ quota: QuotaReport }>(`/api/admin/users/${userId}/quota`)
+  return data.quota
+}
+
+/**
+ * Surcharge les quotas d'un utilisateur : null = valeur par défaut, 0 = illimité (Admin uniquement)
+ */
+export async function setUserQuota(userId: number, override: QuotaOverride): Promise<QuotaReport> {
+  const { data } = await apiClient.put<{ quota: QuotaReport }>(`/api/admin/users/${userId}/quota`, override)
+  return data.quota
+}
+
+/**
+ * Rétablit les quotas par défaut d'un utilisateur (Admin uniquement)
+ */
+export async function resetUserQuota(userId: number): Promise<MessageResponse> {
+  const { data } = await apiClient.delete<MessageResponse>(`/api/admin/users/${userId}/quota`)
+  return data
+}
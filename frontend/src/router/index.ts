@@ -34,6 +34,15 @@ const routes: RouteRecordRaw[] = [
       title: 'Connexion - SafeBase'
     }
   },
+  {
+    path: '/reset-password',
+    name: 'reset-password',
+    component: () => import('../views/ResetPasswordView.vue'),
+    meta: {
+      requiresGuest: true,
+      title: 'Mot de passe oublié - SafeBase'
+    }
+  },
   {
     path: '/about',
     name: 'about',
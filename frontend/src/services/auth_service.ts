@@ -1,6 +1,6 @@
 // Service d'authentification - Logique métier
 import * as authApi from '@/api/auth_api'
-import type { User, LoginRequest, RegisterRequest } from '@/types/auth'
+import type { User, LoginRequest, RegisterRequest, LoginResult, LoginOptions, TwoFactorSetup } from '@/types/auth'
 
 /**
  * Service d'authentification qui encapsule la logique métier
@@ -35,10 +35,43 @@ export class AuthService {
    * Connecte un utilisateur
    * Le token JWT est automatiquement géré via cookie HTTP-only sécurisé
    */
-  async loginUser(credentials: LoginRequest): Promise<User> {
+  async loginUser(credentials: LoginRequest): Promise<LoginResult> {
     return await authApi.login(credentials)
   }
 
+  /**
+   * Termine une connexion avec le second facteur
+   */
+  async verifyTwoFactor(token: string, code: string): Promise<{ user: User; recoveryCodes?: string[] }> {
+    const response = await authApi.verifyTwoFactor(token, code)
+    return { user: response.user, recoveryCodes: response.recovery_codes }
+  }
+
+  /**
+   * Démarre l'enrôlement TOTP pendant la connexion
+   */
+  async setupTwoFactor(token: string): Promise<TwoFactorSetup> {
+    return await authApi.setupTwoFactorChallenge(token)
+  }
+
+  /**
+   * Modes de connexion proposés ; en cas d'erreur, seul le mot de passe est proposé
+   */
+  async getLoginOptions(): Promise<LoginOptions> {
+    try {
+      return await authApi.getLoginOptions()
+    } catch {
+      return { oidc_enabled: false, local_login_enabled: true }
+    }
+  }
+
+  /**
+   * Redirige le navigateur vers le fournisseur d'identité
+   */
+  startSsoLogin(): void {
+    window.location.href = authApi.oidcLoginUrl()
+  }
+
   /**
    * Déconnecte l'utilisateur
    */
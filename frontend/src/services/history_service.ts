@@ -1,6 +1,6 @@
 // Service de gestion de l'historique des actions - Logique métier
 import * as historyApi from '@/api/history_api'
-import type { HistoryItem, HistoryResponse, ActivityType } from '@/types/history'
+import type { HistoryItem, HistoryResponse, ActivityType, HistorySearchParams, HistoryExportFormat } from '@/types/history'
 import { CronUtils } from '@/utils/cron-utils'
 
 /**
@@ -39,6 +39,20 @@ export class HistoryService {
     return await historyApi.getRecentActionHistory(page, limit)
   }
 
+  /**
+   * Recherche dans l'historique (de tous les utilisateurs avec audit, permission audit.read)
+   */
+  async searchHistory(filters: HistorySearchParams, audit: boolean = false): Promise<HistoryResponse> {
+    return await historyApi.searchActionHistory(filters, audit)
+  }
+
+  /**
+   * Télécharge un export de l'historique filtré (CSV, JSON ou NDJSON)
+   */
+  async exportHistory(format: HistoryExportFormat, filters: HistorySearchParams = {}, audit: boolean = false): Promise<void> {
+    await historyApi.exportActionHistory(format, filters, audit)
+  }
+
   /**
    * Formate une date pour l'affichage
    */
@@ -0,0 +1,68 @@
+// Service de gestion des équipes - Logique métier
+import * as teamApi from '@/api/team_api'
+import type { Team, TeamMember, TeamRole } from '@/types/team'
+
+const TEAM_ROLE_RANK: Record<TeamRole, number> = { owner: 4, admin: 3, member: 2, viewer: 1 }
+
+/**
+ * Service de gestion des équipes
+ */
+export class TeamService {
+  async fetchTeams(): Promise<Team[]> {
+    return await teamApi.getTeams()
+  }
+
+  async fetchTeamById(id: number): Promise<Team> {
+    return await teamApi.getTeamById(id)
+  }
+
+  async createTeam(name: string): Promise<Team> {
+    return await teamApi.createTeam(this.validateName(name))
+  }
+
+  async renameTeam(id: number, name: string): Promise<Team> {
+    return await teamApi.renameTeam(id, this.validateName(name))
+  }
+
+  async deleteTeam(id: number): Promise<void> {
+    await teamApi.deleteTeam(id)
+  }
+
+  async addMember(teamId: number, email: string, role: TeamRole): Promise<TeamMember> {
+    if (!email || email.trim() === '') {
+      throw new Error("L'email du membre est requis")
+    }
+    return await teamApi.addTeamMember(teamId, email.trim().toLowerCase(), role)
+  }
+
+  async updateMemberRole(teamId: number, userId: number, role: TeamRole): Promise<void> {
+    await teamApi.updateTeamMemberRole(teamId, userId, role)
+  }
+
+  async removeMember(teamId: number, userId: number): Promise<void> {
+    await teamApi.removeTeamMember(teamId, userId)
+  }
+
+  async moveDatabase(databaseId: number, teamId: number): Promise<void> {
+    await teamApi.moveDatabaseToTeam(databaseId, teamId)
+  }
+
+  /**
+   * Indique si le rôle d'équipe permet une action (mêmes règles que le backend)
+   */
+  can(team: Team, action: 'view' | 'operate' | 'manage'): boolean {
+    const minimum: TeamRole = action === 'manage' ? 'admin' : action === 'operate' ? 'member' : 'viewer'
+    return !!team.my_role && TEAM_ROLE_RANK[team.my_role] >= TEAM_ROLE_RANK[minimum]
+  }
+
+  private validateName(name: string): string {
+    const trimmed = (name || '').trim()
+    if (trimmed === '' || trimmed.length > 100) {
+      throw new Error("Le nom de l'équipe est requis (100 caractères maximum)")
+    }
+    return trimmed
+  }
+}
+
+// Export d'une instance unique du service
+export const teamService = new TeamService()
@@ -1,7 +1,7 @@
 // Service de gestion des utilisateurs (Admin) - Logique métier
 import * as userApi from '@/api/user_api'
-import type { User } from '@/types/auth'
-import type { UserUpdateRequest, UserRoleUpdateRequest } from '@/types/user'
+import type { User, Role, Permission } from '@/types/auth'
+import type { UserUpdateRequest, UserRoleUpdateRequest, CreateRoleRequest, CreateUserRequest, CreateUserResponse, UserDeletionMode, UserDeletionJob } from '@/types/user'
 
 /**
  * Service de gestion des utilisateurs pour les administrateurs
@@ -28,6 +28,38 @@ export class UserService {
     return await userApi.getUserById(id)
   }
 
+  /**
+   * Crée un utilisateur ; sans mot de passe, il reçoit une invitation par email
+   */
+  async createUser(userData: CreateUserRequest): Promise<CreateUserResponse> {
+    this.validateUserData(userData)
+    return await userApi.createUser({ ...userData, email: userData.email.trim(), password: userData.password || undefined })
+  }
+
+  /**
+   * Renvoie une invitation, le lien précédent n'est plus valable
+   */
+  async inviteUser(id: number): Promise<void> {
+    await userApi.inviteUser(id)
+  }
+
+  /**
+   * Lance la suppression d'un utilisateur ; en mode reassign ses ressources vont au nouveau propriétaire
+   */
+  async deleteUser(id: number, mode: UserDeletionMode, targetUserId?: number): Promise<UserDeletionJob> {
+    if (mode === 'reassign' && (!targetUserId || targetUserId === id)) {
+      throw new Error('Un nouveau propriétaire différent de l\'utilisateur supprimé est requis')
+    }
+    return await userApi.deleteUser(id, mode === 'reassign' ? { mode, target_user_id: targetUserId } : { mode })
+  }
+
+  /**
+   * Récupère l'avancement d'une suppression d'utilisateur
+   */
+  async fetchUserDeletion(jobId: string): Promise<UserDeletionJob> {
+    return await userApi.getUserDeletion(jobId)
+  }
+
   /**
    * Met à jour un utilisateur avec validation
    */
@@ -62,6 +94,51 @@ export class UserService {
     await userApi.activateUser(id)
   }
 
+  /**
+   * Déverrouille un compte bloqué après des échecs de connexion
+   */
+  async unlockUser(id: number): Promise<void> {
+    await userApi.unlockUser(id)
+  }
+
+  /**
+   * Récupère les rôles avec leurs permissions
+   */
+  async fetchRoles(): Promise<Role[]> {
+    return await userApi.getRoles()
+  }
+
+  /**
+   * Récupère le catalogue des permissions
+   */
+  async fetchPermissions(): Promise<Permission[]> {
+    return await userApi.getPermissions()
+  }
+
+  /**
+   * Crée un rôle personnalisé
+   */
+  async createRole(roleData: CreateRoleRequest): Promise<Role> {
+    if (!/^[a-z][a-z0-9_-]{1,49}$/.test(roleData.name.trim().toLowerCase())) {
+      throw new Error('Le nom du rôle doit contenir 2 à 50 lettres minuscules, chiffres, - ou _')
+    }
+    return await userApi.createRole({ ...roleData, name: roleData.name.trim().toLowerCase() })
+  }
+
+  /**
+   * Remplace les permissions d'un rôle personnalisé
+   */
+  async updateRolePermissions(id: number, permissions: string[]): Promise<Role> {
+    return await userApi.updateRolePermissions(id, permissions)
+  }
+
+  /**
+   * Supprime un rôle personnalisé
+   */
+  async deleteRole(id: number): Promise<void> {
+    await userApi.deleteRole(id)
+  }
+
   /**
    * Valide les données d'un utilisateur
    */
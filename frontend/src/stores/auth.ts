@@ -2,7 +2,7 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
 import { authService } from '@/services/auth_service'
-import type { User, LoginRequest, RegisterRequest } from '@/types/auth'
+import type { User, LoginRequest, RegisterRequest, TwoFactorChallenge } from '@/types/auth'
 
 export const useAuthStore = defineStore('auth', () => {
   // État réactif
@@ -14,6 +14,9 @@ export const useAuthStore = defineStore('auth', () => {
   const isAuthenticated = computed(() => user.value !== null)
   const isAdmin = computed(() => user.value?.role?.name === 'admin')
   const isUser = computed(() => user.value?.role?.name === 'user' || user.value?.role?.name === 'admin')
+  // Permissions du rôle (ex. 'backup.download'), vérifiées aussi côté backend
+  const hasPermission = (permission: string): boolean =>
+    user.value?.role?.permissions?.some(p => p.name === permission) ?? false
 
   // Actions utilisant les services
   const checkAuth = async (): Promise<void> => {
@@ -37,11 +40,28 @@ export const useAuthStore = defineStore('auth', () => {
     }
   }
 
-  const login = async (credentials: LoginRequest): Promise<void> => {
+  // Retourne le défi de double authentification si un second facteur est requis
+  const login = async (credentials: LoginRequest): Promise<TwoFactorChallenge | null> => {
     loading.value = true
     try {
-      const authenticatedUser = await authService.loginUser(credentials)
-      user.value = authenticatedUser
+      const result = await authService.loginUser(credentials)
+      if ('challenge' in result) {
+        return result.challenge
+      }
+      user.value = result.user
+      return null
+    } finally {
+      loading.value = false
+    }
+  }
+
+  // Retourne les codes de secours quand l'enrôlement vient d'être terminé (affichés une seule fois)
+  const verifyTwoFactor = async (token: string, code: string): Promise<string[] | undefined> => {
+    loading.value = true
+    try {
+      const result = await authService.verifyTwoFactor(token, code)
+      user.value = result.user
+      return result.recoveryCodes
     } finally {
       loading.value = false
     }
@@ -69,11 +89,13 @@ export const useAuthStore = defineStore('auth', () => {
     isAuthenticated,
     isAdmin,
     isUser,
+    hasPermission,
     
     // Actions
     checkAuth,
     register,
     login,
+    verifyTwoFactor,
     logout
   }
 })
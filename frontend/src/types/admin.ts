@@ -0,0 +1,52 @@
+// Types pour les vues d'administration (ressources de tous les utilisateurs)
+import type { Database } from './database'
+import type { Backup } from './backup'
+import type { Restore } from './restore'
+import type { Schedule } from './schedule'
+
+export interface ResourceOwner {
+  id: number
+  email: string
+  firstname: string
+  lastname: string
+}
+
+export interface AdminDatabase extends Database {
+  owner: ResourceOwner
+}
+
+export interface AdminBackup extends Backup {
+  database_name: string
+  owner: ResourceOwner
+}
+
+export interface AdminRestore extends Restore {
+  backup_filename: string
+  database_name: string
+  owner: ResourceOwner
+}
+
+export interface AdminSchedule extends Schedule {
+  owner: ResourceOwner
+}
+
+export interface AdminFilters {
+  user_id?: number
+  team_id?: number
+  database_id?: number
+  status?: string // active/inactive pour les planifications
+  search?: string // Nom de la base de données
+  page?: number
+  limit?: number
+}
+
+export interface AdminListResponse<T> {
+  total: number
+  page: number
+  limit: number
+  total_pages: number
+  databases?: T[]
+  backups?: T[]
+  restores?: T[]
+  schedules?: T[]
+}
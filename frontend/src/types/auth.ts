@@ -1,10 +1,20 @@
 // Types pour l'authentification
 
+export interface Permission {
+  id: number
+  name: string // ex. 'backup.download'
+  description: string
+}
+
 export interface Role {
   id: number
   name: string
   created_at: string
   updated_at: string
+  description?: string
+  built_in?: boolean // Rôles intégrés (admin, user, operator, auditor) : non modifiables
+  require_two_factor?: boolean
+  permissions?: Permission[]
 }
 
 export interface User {
@@ -17,6 +27,9 @@ export interface User {
   role?: Role // Objet role complet du backend
   created_at: string
   updated_at: string
+  failed_login_attempts?: number
+  locked_until?: string // Compte verrouillé après trop d'échecs de connexion
+  erasure_scheduled_at?: string // Effacement du compte demandé par l'utilisateur (annulable jusqu'à cette date)
 }
 
 export interface LoginRequest {
@@ -44,5 +57,27 @@ export interface RegisterResponse {
   role_id: number
 }
 
+// Connexion en deux étapes : le mot de passe est correct, un code TOTP (ou de secours) est attendu
+export interface TwoFactorChallenge {
+  message: string
+  two_factor_required: true
+  setup_required: boolean // Le rôle impose la 2FA et l'utilisateur ne l'a pas encore configurée
+  two_factor_token: string
+  expires_in: number
+}
+
+export interface TwoFactorSetup {
+  secret: string
+  otpauth_url: string
+}
+
+// Modes de connexion proposés par le backend (SSO, mot de passe)
+export interface LoginOptions {
+  oidc_enabled: boolean
+  local_login_enabled: boolean
+}
+
+export type LoginResult = { user: User } | { challenge: TwoFactorChallenge }
+
 export type MessageType = 'success' | 'error'
 export type FormType = 'login' | 'register'
@@ -4,7 +4,7 @@ export interface Backup {
   filename: string
   filepath: string
   size: number
-  status: 'pending' | 'completed' | 'failed'
+  status: 'pending' | 'running' | 'completed' | 'failed' | 'cancelled'
   error_msg?: string
   created_at: string
   updated_at: string
@@ -11,6 +11,10 @@ export interface Database {
   created_at: string
   updated_at: string
   user_id: number
+  team_id?: number | null // Équipe propriétaire
+  protected?: boolean // Restaurations soumises à l'approbation d'un second utilisateur
+  unprotect_requested_by?: number // Désactivation de la protection en attente de confirmation
+  unprotect_requested_at?: string
 }
 
 export interface DatabaseCreateRequest {
@@ -22,6 +26,7 @@ export interface DatabaseCreateRequest {
   password?: string // Optionnel si URL fournie
   db_name?: string // Optionnel si URL fournie
   url?: string // Alternative: URL complète
+  team_id?: number // Équipe propriétaire (par défaut : espace personnel)
 }
 
 export interface DatabaseUpdateRequest {
@@ -12,6 +12,38 @@ export interface HistoryItem {
   metadata?: Record<string, any>
   ip_address?: string
   user_agent?: string
+  admin_action?: boolean // Action d'un administrateur, propriétaire dans metadata.on_behalf_of
+  hash?: string // Hash chaîné à la ligne précédente (historique infalsifiable)
+  prev_hash?: string
+}
+
+export interface AuditHead {
+  history_id: number
+  hash: string
+  rows: number
+}
+
+export interface AuditVerification {
+  valid: boolean
+  checked: number
+  unsealed: number // Lignes antérieures au chaînage
+  anonymized: number // Lignes de comptes effacés, sans adresse IP ni user agent
+  anchors_checked: number
+  broken_at?: number // Première ligne en défaut
+  reason?: string
+  head?: AuditHead
+  latest_anchor?: number
+  remote_checked: boolean
+  verified_at: string
+}
+
+export interface AuditAnchor {
+  id: number
+  history_id: number
+  hash: string
+  rows: number
+  remote_path: string
+  created_at: string
 }
 
 export interface HistoryResponse {
@@ -19,8 +51,28 @@ export interface HistoryResponse {
   total: number
   page: number
   limit: number
+  next_cursor?: number | null // À repasser dans cursor pour la page suivante (total n'est alors plus renvoyé), null sur la dernière
 }
 
+// Filtres de la recherche dans l'historique (GET /api/history, /api/history/audit et leurs exports)
+export interface HistorySearchParams {
+  from?: string // RFC 3339 ou AAAA-MM-JJ
+  to?: string
+  action?: string // Plusieurs actions séparées par des virgules
+  resource_type?: string
+  resource_id?: number
+  ip?: string
+  q?: string // Texte contenu dans la description
+  metadata?: Record<string, string | number>
+  user_id?: number // Recherche d'audit uniquement
+  admin_action?: boolean // Recherche d'audit uniquement
+  cursor?: number
+  page?: number
+  limit?: number
+}
+
+export type HistoryExportFormat = 'csv' | 'json' | 'ndjson'
+
 export interface HistoryFilters {
   type: ActivityType
   page: number
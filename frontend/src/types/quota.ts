@@ -0,0 +1,36 @@
+// Types pour les quotas (0 = illimité)
+export interface QuotaLimits {
+  max_databases: number
+  max_schedules: number
+  min_schedule_interval_minutes: number // Écart minimal entre deux exécutions d'une planification
+  max_backup_bytes: number // Taille totale des sauvegardes terminées
+}
+
+// Surcharge définie par un administrateur (null = valeur par défaut)
+export interface QuotaOverride {
+  max_databases: number | null
+  max_schedules: number | null
+  min_schedule_interval_minutes: number | null
+  max_backup_bytes: number | null
+}
+
+export interface QuotaUsage {
+  databases: number
+  schedules: number
+  backup_bytes: number
+}
+
+export interface QuotaReport {
+  limits: QuotaLimits
+  usage: QuotaUsage
+  override?: QuotaOverride & { id: number; updated_by: number; updated_at: string }
+}
+
+export interface TeamQuotaReport extends QuotaReport {
+  team_id: number
+  team_name: string
+}
+
+export interface UsageReport extends QuotaReport {
+  teams: TeamQuotaReport[]
+}
@@ -1,12 +1,16 @@
 // Types pour la gestion des restaurations
 export interface Restore {
   id: number
-  status: 'pending' | 'running' | 'success' | 'failed'
+  status: 'pending' | 'running' | 'success' | 'failed' | 'awaiting_approval' | 'rejected' | 'expired' | 'cancelled'
   created_at: string
   updated_at: string
   user_id: number
   backup_id: number
   database_id: number
+  approval_expires_at?: string | null // Bases protégées : fin du délai d'approbation
+  reviewer_id?: number | null
+  reviewed_at?: string | null
+  rejection_reason?: string
   user?: {
     id: number
     firstname: string
@@ -0,0 +1,38 @@
+// Types pour les équipes
+import type { User } from './auth'
+
+export type TeamRole = 'owner' | 'admin' | 'member' | 'viewer'
+
+export interface TeamMember {
+  id: number
+  team_id: number
+  user_id: number
+  role: TeamRole
+  created_at: string
+  user?: User
+}
+
+export interface Team {
+  id: number
+  name: string
+  personal: boolean
+  owner_id?: number
+  created_at: string
+  updated_at: string
+  members?: TeamMember[]
+  my_role?: TeamRole // Rôle de l'utilisateur courant
+}
+
+export interface TeamListResponse {
+  teams: Team[]
+}
+
+export interface TeamResponse {
+  team: Team
+  message?: string
+}
+
+export interface TeamMemberResponse {
+  member: TeamMember
+  message?: string
+}
@@ -23,6 +23,60 @@ export interface UserResponse {
   user: User
 }
 
+export interface CreateUserRequest {
+  firstname: string
+  lastname: string
+  email: string
+  password?: string // Sans mot de passe, une invitation est envoyée par email
+  role_id?: number
+}
+
+export interface CreateUserResponse {
+  message: string
+  user: User
+  invitation_sent: boolean
+}
+
+export type UserDeletionMode = 'reassign' | 'purge'
+
+export interface DeleteUserRequest {
+  mode: UserDeletionMode
+  target_user_id?: number
+}
+
+export interface UserDeletionProgress {
+  total: number
+  processed: number
+  failed: number
+}
+
+export interface UserDeletionJob {
+  id: string
+  user_id: number
+  email: string
+  mode: UserDeletionMode
+  target_user_id?: number
+  status: 'pending' | 'running' | 'completed' | 'failed'
+  phase?: string
+  databases: UserDeletionProgress
+  schedules: UserDeletionProgress
+  backups: UserDeletionProgress
+  reassigned?: { databases: number; schedules: number; backups: number; restores: number }
+  handed_over?: { databases: number; schedules: number; backups: number; restores: number }
+  teams_transferred?: Record<number, number>
+  errors?: string[]
+  started_by: number
+  started_at: string
+  finished_at?: string
+}
+
+export interface CreateRoleRequest {
+  name: string
+  description?: string
+  permissions: string[]
+  require_two_factor?: boolean
+}
+
 export interface MessageResponse {
   message: string
 }
\ No newline at end of file